@@ -7,14 +7,10 @@ layout(set = 1, binding = 0) uniform local_uniform_object {
     float shininess;
 } object_ubo;
 
-struct directional_light {
-    vec3 direction;
-    vec4 colour;
-};
+const int MAX_POINT_LIGHTS = 10;
+const int MAX_SPOT_LIGHTS = 8;
 
-struct point_light {
-    vec3 position;
-    vec4 colour;
+struct light_attenuation {
     // Usually 1, make sure denominator never gets smaller than 1
     float constant_f;
     // Reduces light intensity linearly
@@ -23,31 +19,44 @@ struct point_light {
     float quadratic;
 };
 
-// TODO: feed in from cpu
-directional_light dir_light = {
-    vec3(-0.57735, -0.57735, -0.57735),
-    //vec4(0.6, 0.6, 0.6, 1.0)
-    vec4(0.4, 0.4, 0.2, 1.0)
+struct directional_light {
+    vec4 colour;
+    vec3 direction;
+    float intensity;
 };
 
-// TODO: feed in from cpu
-point_light p_light_0 = {
-    vec3(-5.5, 0.0, -5.5),
-    vec4(0.0, 1.0, 0.0, 1.0),
-    1.0, // constant_f
-    0.35, // Linear
-    0.44  // Quadratic
+struct point_light {
+    vec4 colour;
+    vec3 position;
+    float intensity;
+    light_attenuation attenuation;
 };
 
-// TODO: feed in from cpu
-point_light p_light_1 = {
-    vec3(5.5, 0.0, -5.5),
-    vec4(1.0, 0.0, 0.0, 1.0),
-    1.0, // constant_f
-    0.35, // Linear
-    0.44  // Quadratic
+struct spot_light {
+    vec4 colour;
+    vec3 position;
+    float intensity;
+    vec3 direction;
+    // Cosines of the angles of the cone, full light within the inner one.
+    float inner_cutoff;
+    float outer_cutoff;
+    light_attenuation attenuation;
 };
 
+layout(set = 0, binding = 0) uniform global_uniform_object {
+    mat4 projection;
+	mat4 view;
+	vec4 ambient_colour;
+	vec3 view_position;
+	int mode;
+	// Fed in from the cpu by the light system.
+	directional_light dir_light;
+	uint point_light_count;
+	uint spot_light_count;
+	point_light point_lights[MAX_POINT_LIGHTS];
+	spot_light spot_lights[MAX_SPOT_LIGHTS];
+} global_ubo;
+
 // Samplers, diffuse, spec
 const int SAMP_DIFFUSE = 0;
 const int SAMP_SPECULAR = 1;
@@ -66,13 +75,17 @@ layout(location = 1) in struct dto {
 	vec3 tangent;
 } in_dto;
 
+#ifdef NORMAL_MAP
 mat3 TBN;
+#endif
 
 vec4 calculate_directional_light(directional_light light, vec3 normal, vec3 view_direction);
 vec4 calculate_point_light(point_light light, vec3 normal, vec3 frag_position, vec3 view_direction);
+vec4 calculate_spot_light(spot_light light, vec3 normal, vec3 frag_position, vec3 view_direction);
 
 void main() {
     vec3 normal = in_dto.normal;
+#ifdef NORMAL_MAP
     vec3 tangent = in_dto.tangent;
     tangent = (tangent - dot(tangent, normal) *  normal);
     vec3 bitangent = cross(in_dto.normal, in_dto.tangent);
@@ -81,14 +94,21 @@ void main() {
     // Update the normal to use a sample from the normal map.
     vec3 localNormal = 2.0 * texture(samplers[SAMP_NORMAL], in_dto.tex_coord).rgb - 1.0;
     normal = normalize(TBN * localNormal);
+#else
+    normal = normalize(normal);
+#endif
 
     if(in_mode == 0 || in_mode == 1) {
         vec3 view_direction = normalize(in_dto.view_position - in_dto.frag_position);
 
-        out_colour = calculate_directional_light(dir_light, normal, view_direction);
+        out_colour = calculate_directional_light(global_ubo.dir_light, normal, view_direction);
 
-        out_colour += calculate_point_light(p_light_0, normal, in_dto.frag_position, view_direction);
-        out_colour += calculate_point_light(p_light_1, normal, in_dto.frag_position, view_direction);
+        for(uint i = 0; i < global_ubo.point_light_count; ++i) {
+            out_colour += calculate_point_light(global_ubo.point_lights[i], normal, in_dto.frag_position, view_direction);
+        }
+        for(uint i = 0; i < global_ubo.spot_light_count; ++i) {
+            out_colour += calculate_spot_light(global_ubo.spot_lights[i], normal, in_dto.frag_position, view_direction);
+        }
     } else if(in_mode == 2) {
         out_colour = vec4(abs(normal), 1.0);
     }
@@ -101,9 +121,10 @@ vec4 calculate_directional_light(directional_light light, vec3 normal, vec3 view
     float specular_factor = pow(max(dot(half_direction, normal), 0.0), object_ubo.shininess);
 
     vec4 diff_samp = texture(samplers[SAMP_DIFFUSE], in_dto.tex_coord);
+    vec4 colour = light.colour * light.intensity;
     vec4 ambient = vec4(vec3(in_dto.ambient * object_ubo.diffuse_colour), diff_samp.a);
-    vec4 diffuse = vec4(vec3(light.colour * diffuse_factor), diff_samp.a);
-    vec4 specular = vec4(vec3(light.colour * specular_factor), diff_samp.a);
+    vec4 diffuse = vec4(vec3(colour * diffuse_factor), diff_samp.a);
+    vec4 specular = vec4(vec3(colour * specular_factor), diff_samp.a);
     
     if(in_mode == 0) {
         diffuse *= diff_samp;
@@ -123,11 +144,12 @@ vec4 calculate_point_light(point_light light, vec3 normal, vec3 frag_position, v
 
     // Calculate attenuation, or light falloff over distance.
     float distance = length(light.position - frag_position);
-    float attenuation = 1.0 / (light.constant_f + light.linear * distance + light.quadratic * (distance * distance));
+    float attenuation = 1.0 / (light.attenuation.constant_f + light.attenuation.linear * distance + light.attenuation.quadratic * (distance * distance));
 
+    vec4 colour = light.colour * light.intensity;
     vec4 ambient = in_dto.ambient;
-    vec4 diffuse = light.colour * diff;
-    vec4 specular = light.colour * spec;
+    vec4 diffuse = colour * diff;
+    vec4 specular = colour * spec;
     
     if(in_mode == 0) {
         vec4 diff_samp = texture(samplers[SAMP_DIFFUSE], in_dto.tex_coord);
@@ -140,4 +162,38 @@ vec4 calculate_point_light(point_light light, vec3 normal, vec3 frag_position, v
     diffuse *= attenuation;
     specular *= attenuation;
     return (ambient + diffuse + specular);
+}
+
+vec4 calculate_spot_light(spot_light light, vec3 normal, vec3 frag_position, vec3 view_direction) {
+    vec3 light_direction =  normalize(light.position - frag_position);
+    float diff = max(dot(normal, light_direction), 0.0);
+
+    vec3 reflect_direction = reflect(-light_direction, normal);
+    float spec = pow(max(dot(view_direction, reflect_direction), 0.0), object_ubo.shininess);
+
+    // Fade the light out between the inner and the outer cone.
+    float theta = dot(light_direction, normalize(-light.direction));
+    float cone = clamp((theta - light.outer_cutoff) / (light.inner_cutoff - light.outer_cutoff), 0.0, 1.0);
+
+    // Calculate attenuation, or light falloff over distance.
+    float distance = length(light.position - frag_position);
+    float attenuation = 1.0 / (light.attenuation.constant_f + light.attenuation.linear * distance + light.attenuation.quadratic * (distance * distance));
+
+    vec4 colour = light.colour * light.intensity;
+    vec4 ambient = in_dto.ambient;
+    vec4 diffuse = colour * diff;
+    vec4 specular = colour * spec;
+    
+    if(in_mode == 0) {
+        vec4 diff_samp = texture(samplers[SAMP_DIFFUSE], in_dto.tex_coord);
+        diffuse *= diff_samp;
+        ambient *= diff_samp;
+        specular *= vec4(texture(samplers[SAMP_SPECULAR], in_dto.tex_coord).rgb, diffuse.a);
+    }
+
+    // The ambient light is not bound to the cone.
+    ambient *= attenuation;
+    diffuse *= attenuation * cone;
+    specular *= attenuation * cone;
+    return (ambient + diffuse + specular);
 }
\ No newline at end of file
@@ -6,12 +6,54 @@ layout(location = 2) in vec2 in_texcoord;
 layout(location = 3) in vec4 in_colour;
 layout(location = 4) in vec3 in_tangent;
 
+const int MAX_POINT_LIGHTS = 10;
+const int MAX_SPOT_LIGHTS = 8;
+
+struct light_attenuation {
+    // Usually 1, make sure denominator never gets smaller than 1
+    float constant_f;
+    // Reduces light intensity linearly
+    float linear;
+    // Makes the light fall off slower at longer distances.
+    float quadratic;
+};
+
+struct directional_light {
+    vec4 colour;
+    vec3 direction;
+    float intensity;
+};
+
+struct point_light {
+    vec4 colour;
+    vec3 position;
+    float intensity;
+    light_attenuation attenuation;
+};
+
+struct spot_light {
+    vec4 colour;
+    vec3 position;
+    float intensity;
+    vec3 direction;
+    // Cosines of the angles of the cone, full light within the inner one.
+    float inner_cutoff;
+    float outer_cutoff;
+    light_attenuation attenuation;
+};
+
 layout(set = 0, binding = 0) uniform global_uniform_object {
     mat4 projection;
 	mat4 view;
 	vec4 ambient_colour;
 	vec3 view_position;
 	int mode;
+	// Fed in from the cpu by the light system.
+	directional_light dir_light;
+	uint point_light_count;
+	uint spot_light_count;
+	point_light point_lights[MAX_POINT_LIGHTS];
+	spot_light spot_lights[MAX_SPOT_LIGHTS];
 } global_ubo;
 
 layout(push_constant) uniform push_constants {
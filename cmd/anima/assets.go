@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spaghettifunk/anima/engine/assets"
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The names of the resource types, as printed by inspect.
+var resourceTypeNames = map[metadata.ResourceType]string{
+	metadata.ResourceTypeText:       "text",
+	metadata.ResourceTypeBinary:     "binary",
+	metadata.ResourceTypeImage:      "image",
+	metadata.ResourceTypeMaterial:   "material",
+	metadata.ResourceTypeShader:     "shader",
+	metadata.ResourceTypeMesh:       "mesh",
+	metadata.ResourceTypeModel:      "model",
+	metadata.ResourceTypeBitmapFont: "bitmap font",
+	metadata.ResourceTypeSystemFont: "system font",
+}
+
+// runAssets runs the subcommands working on an assets directory: check and inspect.
+func runAssets(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: anima assets check|inspect [arguments]")
+	}
+	switch args[0] {
+	case "check":
+		return runAssetsCheck(args[1:])
+	case "inspect":
+		return runAssetsInspect(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q, expected check or inspect", args[0])
+	}
+}
+
+// openAssets indexes the assets directory without writing into it.
+func openAssets(dir string) (*assets.AssetManager, error) {
+	// The asset manager publishes its changes on the engine bus.
+	if err := core.EventSystemInitialize(); err != nil {
+		return nil, err
+	}
+	am, err := assets.NewAssetManager()
+	if err != nil {
+		return nil, err
+	}
+	am.SetReadOnly(true)
+	if err := am.Initialize(dir); err != nil {
+		am.Shutdown()
+		return nil, err
+	}
+	return am, nil
+}
+
+// runAssetsCheck loads every configuration and model of the assets directory, and fails when
+// any of them has a problem.
+func runAssetsCheck(args []string) error {
+	flags := flag.NewFlagSet("assets check", flag.ExitOnError)
+	source := flags.String("source", "assets", "directory of the assets")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: anima assets check [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	am, err := openAssets(*source)
+	if err != nil {
+		return err
+	}
+	defer am.Shutdown()
+
+	problems := am.Check()
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	fmt.Println("no problems found")
+	return nil
+}
+
+// runAssetsInspect prints the details of the given assets, by path or GUID.
+func runAssetsInspect(args []string) error {
+	flags := flag.NewFlagSet("assets inspect", flag.ExitOnError)
+	source := flags.String("source", "assets", "directory of the assets")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: anima assets inspect [flags] <path or GUID>...\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	am, err := openAssets(*source)
+	if err != nil {
+		return err
+	}
+	defer am.Shutdown()
+
+	for i, name := range flags.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := inspect(am, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inspect(am *assets.AssetManager, name string) error {
+	assetPath := name
+	if p, ok := am.PathOf(name); ok {
+		assetPath = p
+	}
+	asset, ok := am.Asset(assetPath)
+	if !ok {
+		return fmt.Errorf("asset `%s` not found", name)
+	}
+	fmt.Printf("%s\n", asset.Path)
+	fmt.Printf("  type        %s\n", resourceTypeNames[asset.Type])
+	fmt.Printf("  guid        %s\n", asset.GUID)
+
+	switch asset.Type {
+	case metadata.ResourceTypeImage, metadata.ResourceTypeModel:
+		// By GUID, the asset itself rather than the preferred file of its name.
+		resource, err := am.LoadAsset(asset.GUID, asset.Type, nil)
+		if err != nil {
+			return err
+		}
+		defer am.UnloadAsset(resource)
+
+		switch data := resource.Data.(type) {
+		case *metadata.ImageResourceData:
+			fmt.Printf("  size        %dx%d\n", data.Width, data.Height)
+			fmt.Printf("  channels    %d\n", data.ChannelCount)
+			fmt.Printf("  mips        %d\n", len(data.Mips))
+		case *metadata.ModelConfig:
+			inspectModel(data)
+		}
+	}
+
+	for _, d := range am.Dependencies(asset.Path) {
+		missing := ""
+		if d.Missing {
+			missing = " (missing)"
+		}
+		fmt.Printf("  references  %s%s\n", d.Path, missing)
+	}
+	for _, d := range am.Dependents(asset.Path, false) {
+		fmt.Printf("  referenced  %s\n", d)
+	}
+	return nil
+}
+
+func inspectModel(model *metadata.ModelConfig) {
+	var vertices, indices uint32
+	for _, g := range model.Geometries {
+		vertices += g.VertexCount
+		indices += g.IndexCount
+	}
+	fmt.Printf("  nodes       %d\n", len(model.Nodes))
+	fmt.Printf("  geometries  %d\n", len(model.Geometries))
+	fmt.Printf("  vertices    %d\n", vertices)
+	fmt.Printf("  indices     %d\n", indices)
+	for _, g := range model.Geometries {
+		fmt.Printf("  geometry    %s: %d vertices, %d indices, extents %s - %s, material %s\n",
+			g.Name, g.VertexCount, g.IndexCount, formatVec3(g.MinExtents), formatVec3(g.MaxExtents), g.MaterialName)
+	}
+}
+
+func formatVec3(v math.Vec3) string {
+	return fmt.Sprintf("(%g, %g, %g)", v.X, v.Y, v.Z)
+}
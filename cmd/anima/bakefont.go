@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spaghettifunk/anima/engine/assets/fontbake"
+)
+
+// runBakeFont bakes a TrueType or OpenType font into a BMFont descriptor and its atlas.
+func runBakeFont(args []string) error {
+	flags := flag.NewFlagSet("bakefont", flag.ExitOnError)
+	size := flags.Int("size", 0, "height of the glyphs in pixels (required)")
+	ranges := flags.String("ranges", "32-126", "codepoints baked, e.g. 32-126,0xA0-0xFF,0x20AC")
+	index := flags.Int("index", 0, "index of the font in a collection (.ttc)")
+	face := flags.String("face", "", "name of the face (default: the family name of the font)")
+	padding := flags.Int("padding", 1, "pixels between the glyphs in the atlas")
+	maxAtlas := flags.Int("max-atlas", fontbake.DEFAULT_MAX_ATLAS_SIZE, "largest width and height of the atlas")
+	output := flags.String("o", "", "path of the .fnt written, the atlas is written next to it (default: <font><size>px.fnt next to the font)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: anima bakefont -size <pixels> [flags] <font.ttf>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || *size <= 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	fontPath := flags.Arg(0)
+
+	config := fontbake.NewConfig(*size)
+	config.Index = *index
+	config.Face = *face
+	config.Padding = *padding
+	config.MaxAtlasSize = *maxAtlas
+	var err error
+	if config.Ranges, err = fontbake.ParseRanges(*ranges); err != nil {
+		return err
+	}
+	if len(config.Ranges) == 0 {
+		return errors.New("no codepoints to bake")
+	}
+
+	fontBinary, err := os.ReadFile(fontPath)
+	if err != nil {
+		return err
+	}
+	font, err := fontbake.Bake(fontBinary, config)
+	if err != nil {
+		return fmt.Errorf("failed to bake `%s`: %w", fontPath, err)
+	}
+
+	fntPath := *output
+	if fntPath == "" {
+		fntPath = fmt.Sprintf("%s%dpx.fnt", strings.TrimSuffix(fontPath, filepath.Ext(fontPath)), *size)
+	}
+	if err := font.Save(fntPath); err != nil {
+		return err
+	}
+
+	if len(font.Missing) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d codepoints have no glyph in the font, left out\n", len(font.Missing))
+	}
+	fmt.Printf("baked %s: %d glyphs, %d kernings, %dx%d atlas\n",
+		fntPath, len(font.Data.Glyphs), len(font.Data.Kernings), font.Data.AtlasSizeX, font.Data.AtlasSizeY)
+	return nil
+}
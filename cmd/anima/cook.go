@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spaghettifunk/anima/engine/assets/cook"
+)
+
+// runCook cooks the assets directory, only the sources changed since the last cook.
+func runCook(args []string) error {
+	flags := flag.NewFlagSet("cook", flag.ExitOnError)
+	source := flags.String("source", "assets", "directory of the source assets")
+	output := flags.String("output", "build/assets", "directory the cooked assets are written to")
+	force := flags.Bool("force", false, "cook every source, even the unchanged ones")
+	workers := flags.Int("workers", 0, "number of sources cooked concurrently (default: number of CPUs)")
+	verbose := flags.Bool("v", false, "list every source")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: anima cook [flags]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cooker := cook.NewCooker(*source, *output)
+	cooker.Force = *force
+	if *workers > 0 {
+		cooker.Workers = *workers
+	}
+	report, err := cooker.Cook()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range report.Cooked {
+		fmt.Printf("cooked     %s\n", s)
+	}
+	for _, s := range report.Removed {
+		fmt.Printf("removed    %s\n", s)
+	}
+	if *verbose {
+		for _, s := range report.UpToDate {
+			fmt.Printf("up to date %s\n", s)
+		}
+		for _, s := range report.Skipped {
+			fmt.Printf("skipped    %s\n", s)
+		}
+	}
+	for _, err := range report.Errors {
+		fmt.Fprintf(os.Stderr, "error      %s\n", err)
+	}
+	fmt.Printf("%d cooked, %d up to date, %d skipped, %d removed, %d failed\n",
+		len(report.Cooked), len(report.UpToDate), len(report.Skipped), len(report.Removed), len(report.Errors))
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d assets failed to cook", len(report.Errors))
+	}
+	return nil
+}
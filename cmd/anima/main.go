@@ -0,0 +1,76 @@
+/*
+Command anima is the command line tool of the engine, working on the assets of a game.
+
+Usage:
+
+	anima <command> [arguments]
+
+The commands are:
+
+	cook      convert the source assets into engine-ready files
+	assets    check the assets for problems, or inspect some of them
+	bakefont  rasterize a TrueType or OpenType font into a bitmap font
+	shader    generate the attributes and uniforms of a shader config from its stages
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spaghettifunk/anima/engine/core"
+)
+
+/** @brief A subcommand of the tool. */
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+	// The level of the engine logs shown while running.
+	logLevel core.LogLevel
+}
+
+var commands = []*command{
+	{name: "cook", usage: "convert the source assets into engine-ready files", run: runCook, logLevel: core.InfoLevel},
+	// The problems are reported by the command, the logs would repeat them.
+	{name: "assets", usage: "check the assets for problems, or inspect some of them", run: runAssets, logLevel: core.FatalLevel},
+	{name: "bakefont", usage: "rasterize a TrueType or OpenType font into a bitmap font", run: runBakeFont, logLevel: core.InfoLevel},
+	{name: "shader", usage: "generate the attributes and uniforms of a shader config from its stages", run: runShader, logLevel: core.InfoLevel},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: anima <command> [arguments]\n\nThe commands are:\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-10s%s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"anima <command> -h\" for the arguments of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := core.InitializeLogger(c.logLevel); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "anima %s: %s\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "anima: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spaghettifunk/anima/engine/assets/loaders"
+)
+
+// runShader runs the subcommands working on shaders: reflect.
+func runShader(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: anima shader reflect [arguments]")
+	}
+	switch args[0] {
+	case "reflect":
+		return runShaderReflect(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q, expected reflect", args[0])
+	}
+}
+
+// runShaderReflect prints the stages, attributes and uniforms of a .shadercfg, as declared by
+// the compiled stages.
+func runShaderReflect(args []string) error {
+	flags := flag.NewFlagSet("shader reflect", flag.ExitOnError)
+	source := flags.String("source", "assets", "directory of the assets, the stage files are relative to it")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: anima shader reflect [flags] <stage.spv>...\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config, err := loaders.ShaderConfigFromStages(os.DirFS(*source), flags.Args())
+	if err != nil {
+		return err
+	}
+	return loaders.WriteShaderConfigSections(os.Stdout, config)
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/platform"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
@@ -18,4 +19,21 @@ type ApplicationConfig struct {
 	Name              string
 	LogLevel          core.LogLevel
 	RenderViewConfigs []*metadata.RenderViewConfig
+	// The renderer backend to use. Defaults to Vulkan, whose package must be imported to register it.
+	RendererBackendType metadata.RendererBackendType
+	// The platform to run on, e.g. a window from the platform/window package, or a headless one.
+	Platform platform.Platform
+	// How the game simulation is stepped. Defaults to LoopModeVariable.
+	LoopMode LoopMode
+	// The delta, in seconds, passed to FnFixedUpdate. Defaults to DEFAULT_FIXED_TIMESTEP.
+	FixedTimestep float64
+	// The maximum number of fixed updates run in a single frame. Defaults to DEFAULT_MAX_STEPS_PER_FRAME.
+	MaxStepsPerFrame uint32
+	// The frames per second the engine is limited to. 0 means uncapped.
+	TargetFPS uint32
+	// Directories or pack archives mounted in order over the assets directory.
+	// Files in later mounts override those in earlier ones.
+	AssetMounts []string
+	// The number of bytes of released assets kept in memory. Defaults to assets.ASSET_CACHE_DEFAULT_BUDGET.
+	AssetCacheBudget uint64
 }
@@ -3,6 +3,7 @@ package assets
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spaghettifunk/anima/engine/assets/loaders"
+	"github.com/spaghettifunk/anima/engine/assets/vfs"
 	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
@@ -19,19 +21,82 @@ type AssetInfo struct {
 	Path       string
 	Type       metadata.ResourceType
 	LastLoaded time.Time
+	// The identifier of the asset, stable across renames and moves.
+	GUID string
+	// The sidecar of the asset, with its import settings.
+	Meta *metadata.AssetMeta
+
+	// Used to recognize the file when it is renamed or moved.
+	size    int64
+	modTime time.Time
 }
 
+/**
+ * @brief Published on the engine event bus when a watched asset changed on disk.
+ * Changes are debounced and posted, so listeners are called on the main thread
+ * between frames, once per burst of writes.
+ */
+type AssetChangedEvent struct {
+	/** @brief The path of the file in the virtual file system (e.g. textures/foo.png). */
+	Path string
+	/**
+	 * @brief The name the asset is loaded by: the file name without extension (e.g. foo), or
+	 * the path itself for binaries (e.g. shaders/foo.vert.spv).
+	 */
+	Name string
+	/** @brief The type of the asset. */
+	Type metadata.ResourceType
+	/** @brief The GUID of the asset, systems may have requested it by GUID instead of name. */
+	GUID string
+	/**
+	 * @brief The path of the changed file when the asset itself did not change, but references
+	 * it (directly or not). Empty when the asset changed.
+	 */
+	Cause string
+}
+
+// The time a file must stay untouched before its change is published.
+const ASSET_RELOAD_DEBOUNCE = 150 * time.Millisecond
+
 type AssetManager struct {
 	assets  map[string]*AssetInfo
 	loaders map[metadata.ResourceType]Loader
+	// The paths of the assets by GUID.
+	guids map[string]string
+	// The assets removed recently, they may be renamed or moved ones.
+	orphans []*orphanAsset
 
 	mutex sync.RWMutex
+	// Serializes the creation of the sidecars.
+	metaMutex sync.Mutex
+
+	// The mounts every asset is read through.
+	vfs *vfs.FileSystem
+	// The files created at runtime, mounted below everything else.
+	memory *vfs.MemoryFS
+	// The absolute roots of the watched directory mounts.
+	watchedRoots []string
+	// Nothing is written into the mounted directories.
+	readOnly bool
+
+	// The loaded resources, shared by the loads of the same asset.
+	cacheMutex sync.Mutex
+	cache      *assetCache
+	// The references between the loaded assets.
+	dependencies *dependencyGraph
+	// Limits the number of concurrent asynchronous loads.
+	loadSlots chan struct{}
+	// Delivers the completions of the asynchronous loads on the main thread, nil when there is
+	// no event system and they complete on the loading goroutine.
+	events *core.EventBus
+
+	// Pending change notifications by path, reset on every write.
+	pendingMutex sync.Mutex
+	pending      map[string]*pendingChange
 
 	done     chan struct{}
 	fsnotify *fsnotify.Watcher
 	isClosed bool
-	events   chan fsnotify.Event
-	errors   chan error
 }
 
 func NewAssetManager() (*AssetManager, error) {
@@ -41,35 +106,145 @@ func NewAssetManager() (*AssetManager, error) {
 	}
 
 	return &AssetManager{
-		assets:   make(map[string]*AssetInfo),
-		loaders:  make(map[metadata.ResourceType]Loader),
-		fsnotify: fsWatch,
-		events:   make(chan fsnotify.Event),
-		errors:   make(chan error),
-		done:     make(chan struct{}),
+		assets:       make(map[string]*AssetInfo),
+		guids:        make(map[string]string),
+		loaders:      make(map[metadata.ResourceType]Loader),
+		pending:      make(map[string]*pendingChange),
+		vfs:          vfs.New(),
+		memory:       vfs.NewMemoryFS(),
+		cache:        newAssetCache(ASSET_CACHE_DEFAULT_BUDGET),
+		dependencies: newDependencyGraph(),
+		loadSlots:    make(chan struct{}, ASSET_LOAD_WORKERS),
+		fsnotify:     fsWatch,
+		done:         make(chan struct{}),
 	}, nil
 }
 
-func (am *AssetManager) Initialize(assetsDir string) error {
+// Initialize mounts the assets directory, then every extra mount in order on top of it. Mounts
+// are directories or pack archives; the files of later mounts override those of earlier ones.
+// The assets directory may be missing when packs provide the assets.
+func (am *AssetManager) Initialize(assetsDir string, mounts ...string) error {
 	go am.start()
 
-	if err := am.addRecursive(assetsDir); err != nil {
-		return err
+	am.vfs.MountFS("memory", am.memory)
+	if err := am.Mount(assetsDir); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) || len(mounts) == 0 {
+			return err
+		}
+		core.LogWarn("assets directory %s not found, relying on the mounts", assetsDir)
+	}
+	for _, m := range mounts {
+		if err := am.Mount(m); err != nil {
+			return err
+		}
 	}
 
 	// Register loaders
-	am.registerLoader(metadata.ResourceTypeShader, &loaders.ShaderLoader{})
-	am.registerLoader(metadata.ResourceTypeBinary, &loaders.BinaryLoader{})
-	am.registerLoader(metadata.ResourceTypeImage, &loaders.ImageLoader{})
-	am.registerLoader(metadata.ResourceTypeMaterial, &loaders.MaterialLoader{})
-	am.registerLoader(metadata.ResourceTypeBitmapFont, &loaders.BitmapFontLoader{
-		ResourcePath: assetsDir,
-	})
-	am.registerLoader(metadata.ResourceTypeSystemFont, &loaders.SystemFontLoader{})
+	am.registerLoader(metadata.ResourceTypeShader, &loaders.ShaderLoader{FS: am.vfs})
+	am.registerLoader(metadata.ResourceTypeBinary, &loaders.BinaryLoader{FS: am.vfs})
+	am.registerLoader(metadata.ResourceTypeImage, &loaders.ImageLoader{FS: am.vfs})
+	am.registerLoader(metadata.ResourceTypeMaterial, &loaders.MaterialLoader{FS: am.vfs})
+	am.registerLoader(metadata.ResourceTypeBitmapFont, &loaders.BitmapFontLoader{FS: am.vfs})
+	am.registerLoader(metadata.ResourceTypeSystemFont, &loaders.SystemFontLoader{FS: am.vfs})
+	modelLoader := &loaders.ModelLoader{FS: am.vfs, WriteFile: am.writeAsset, EmbedFile: am.embedAsset, GUID: am.referenceGUID}
+	am.registerLoader(metadata.ResourceTypeModel, modelLoader)
+	am.registerLoader(metadata.ResourceTypeMesh, modelLoader)
+
+	for _, err := range am.scanDependencies() {
+		core.LogWarn(err.Error())
+	}
+
+	// The asynchronous loads complete on the main thread when the engine loop dispatches the
+	// events; tools and tests run without it.
+	am.events = core.Events()
+	if am.events != nil {
+		core.Subscribe(am.events, am.onAssetLoaded)
+		core.Subscribe(am.events, func(event loadGroupChanged) {
+			event.group.check()
+		})
+	}
+	return nil
+}
+
+// Mount mounts a directory or a pack archive on top of the current mounts and indexes its
+// files. Directories are watched for changes.
+func (am *AssetManager) Mount(path string) error {
+	m, err := am.vfs.Mount(path)
+	if err != nil {
+		return err
+	}
+	if m.IsDirectory {
+		root, err := filepath.Abs(m.Source)
+		if err != nil {
+			return err
+		}
+		am.mutex.Lock()
+		am.watchedRoots = append(am.watchedRoots, root)
+		am.mutex.Unlock()
+		if err := am.addRecursive(root); err != nil {
+			return err
+		}
+	}
+	return am.reindex()
+}
+
+// SetReadOnly keeps the asset manager from writing into the mounted directories, e.g. to check
+// a checkout: the sidecars are not created and the materials imported with the models stay in
+// memory. It must be called before Initialize.
+func (am *AssetManager) SetReadOnly(readOnly bool) {
+	am.readOnly = readOnly
+}
+
+// FileSystem returns the virtual file system the assets are read from.
+func (am *AssetManager) FileSystem() *vfs.FileSystem {
+	return am.vfs
+}
+
+// writeAsset writes the file into the first directory mount, and indexes it right away. Read
+// only, the file is kept in memory.
+func (am *AssetManager) writeAsset(name string, data []byte) error {
+	if am.readOnly {
+		return am.embedAsset(name, data)
+	}
+	for _, m := range am.vfs.Mounts() {
+		if !m.IsDirectory {
+			continue
+		}
+		diskPath := filepath.Join(m.Source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(diskPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(diskPath, data, 0o644); err != nil {
+			return err
+		}
+		am.handleFileEvent(name)
+		return nil
+	}
+	return fmt.Errorf("no directory mounted to write `%s` into", name)
+}
 
+// embedAsset stores the file in memory, and indexes it right away.
+func (am *AssetManager) embedAsset(name string, data []byte) error {
+	if err := am.memory.WriteFile(name, data); err != nil {
+		return err
+	}
+	am.handleFileEvent(name)
 	return nil
 }
 
+// reindex rebuilds the index of the assets from the mounted files.
+func (am *AssetManager) reindex() error {
+	return fs.WalkDir(am.vfs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			am.handleFileEvent(name)
+		}
+		return nil
+	})
+}
+
 // Add starts watching the named file or directory (non-recursively).
 func (am *AssetManager) add(name string) error {
 	if am.isClosed {
@@ -107,75 +282,235 @@ func (am *AssetManager) registerLoader(assetType metadata.ResourceType, loader L
 	am.loaders[assetType] = loader
 }
 
-var imageExtensions = []string{".tga", ".png", ".jpg", ".bmp"}
+// The extensions of each type of asset, in order of preference. Cooked assets come first, they
+// are ready to be used.
+var imageExtensions = []string{".kti", ".tga", ".png", ".jpg", ".bmp"}
 
-// Load an asset using the appropriate loader
-func (am *AssetManager) LoadAsset(filename string, resourceType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	var asset *AssetInfo
+var modelExtensions = []string{".ksm", ".glb", ".gltf", ".obj"}
+
+var shaderExtensions = []string{".ksc", ".shadercfg"}
+
+var bitmapFontExtensions = []string{".kbf", ".fnt"}
+
+// assetPath returns the path of the asset in the virtual file system, and its index entry.
+// Assets are named by file name without extension, or by GUID. The entry is nil when no mount
+// provides the asset, the path is then where it was expected.
+func (am *AssetManager) assetPath(filename string, resourceType metadata.ResourceType) (string, *AssetInfo, error) {
+	if path, ok := am.PathOf(filename); ok {
+		return path, am.assetExists(path), nil
+	}
+	candidates, missing, err := assetCandidates(filename, resourceType)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, candidate := range candidates {
+		if asset := am.assetExists(candidate); asset != nil {
+			return candidate, asset, nil
+		}
+	}
+	return missing, nil, nil
+}
+
+// assetCandidates returns the paths the named asset may be at, in order of preference, and
+// the path reported when none exists: without extension when there are several.
+func assetCandidates(filename string, resourceType metadata.ResourceType) ([]string, string, error) {
+	withExtensions := func(dir string, extensions []string) ([]string, string, error) {
+		base := fmt.Sprintf("%s/%s", dir, filename)
+		candidates := make([]string, len(extensions))
+		for i, extension := range extensions {
+			candidates[i] = base + extension
+		}
+		return candidates, base, nil
+	}
 	var path string
 	switch resourceType {
 	case metadata.ResourceTypeImage:
-		found := false
-		for i := 0; i < len(imageExtensions); i++ {
-			path = fmt.Sprintf("assets/textures/%s%s", filename, imageExtensions[i])
-			asset = am.assetExists(path)
-			if asset != nil {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, fmt.Errorf("asset with name %s not found", filename)
-		}
+		return withExtensions("textures", imageExtensions)
 	case metadata.ResourceTypeShader:
-		path = fmt.Sprintf("assets/shaders/%s.shadercfg", filename)
-		asset = am.assetExists(path)
+		return withExtensions("shaders", shaderExtensions)
 	case metadata.ResourceTypeBinary:
-		path = fmt.Sprintf("assets/%s", filename)
-		params = map[string]string{
-			"name": filename,
-		}
-		asset = am.assetExists(path)
+		path = filename
 	case metadata.ResourceTypeMaterial:
-		path = fmt.Sprintf("assets/materials/%s.amt", filename)
-		asset = am.assetExists(path)
+		path = fmt.Sprintf("materials/%s.amt", filename)
 	case metadata.ResourceTypeSystemFont:
-		path = fmt.Sprintf("assets/fonts/%s.fontcfg", filename)
-		asset = am.assetExists(path)
+		path = fmt.Sprintf("fonts/%s.fontcfg", filename)
 	case metadata.ResourceTypeBitmapFont:
-		path = fmt.Sprintf("assets/fonts/%s.fnt", filename)
-		asset = am.assetExists(path)
+		return withExtensions("fonts", bitmapFontExtensions)
+	case metadata.ResourceTypeMesh, metadata.ResourceTypeModel:
+		return withExtensions("models", modelExtensions)
 	default:
-		err := fmt.Errorf("unknown resource type")
+		return nil, "", fmt.Errorf("unknown resource type")
+	}
+	return []string{path}, path, nil
+}
+
+// findAsset looks for the named asset in the directory with each extension in turn. When
+// missing, the returned path has no extension.
+func (am *AssetManager) findAsset(dir string, filename string, extensions []string) (string, *AssetInfo, error) {
+	for _, extension := range extensions {
+		path := fmt.Sprintf("%s/%s%s", dir, filename, extension)
+		if asset := am.assetExists(path); asset != nil {
+			return path, asset, nil
+		}
+	}
+	return fmt.Sprintf("%s/%s", dir, filename), nil, nil
+}
+
+// Load an asset using the appropriate loader, by name or GUID. The import settings of the
+// asset sidecar override the given parameters.
+func (am *AssetManager) LoadAsset(filename string, resourceType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	path, asset, err := am.assetPath(filename, resourceType)
+	if err != nil {
 		return nil, err
 	}
+	if asset == nil {
+		return nil, fmt.Errorf("asset `%s` not found", path)
+	}
+	if resourceType == metadata.ResourceTypeBinary {
+		params = map[string]string{
+			"name": filename,
+		}
+	}
+	params = importParams(asset, params)
 
 	loader, loaderExists := am.loaders[asset.Type]
 	if !loaderExists {
 		return nil, fmt.Errorf("no loader registered for asset type: %d", asset.Type)
 	}
 
-	return loader.Load(path, resourceType, params)
+	key := cacheKey{path: path, resourceType: resourceType, variant: cacheVariant(params)}
+	am.cacheMutex.Lock()
+	cached := am.cache.acquire(key)
+	am.cacheMutex.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	resource, err := loader.Load(path, resourceType, params)
+	if err != nil {
+		return nil, err
+	}
+	am.recordDependencies(path, resource)
+
+	am.cacheMutex.Lock()
+	cached, evicted := am.cache.add(key, resource, loader)
+	am.cacheMutex.Unlock()
+	// Loaded concurrently by someone else, theirs is shared.
+	if cached != resource {
+		if err := loader.Unload(resource); err != nil {
+			core.LogWarn("failed to unload asset `%s`: %s", path, err.Error())
+		}
+	}
+	unloadEntries(evicted)
+	return cached, nil
 }
 
-func (am *AssetManager) assetExists(path string) *AssetInfo {
+// Asset returns a copy of the index entry of the file at the given path (e.g.
+// textures/foo.png), false when no mount provides it.
+func (am *AssetManager) Asset(assetPath string) (*AssetInfo, bool) {
 	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	asset, exists := am.assets[assetPath]
+	if !exists {
+		return nil, false
+	}
+	info := *asset
+	return &info, true
+}
+
+// isIndexed tells whether a mount provides the file.
+func (am *AssetManager) isIndexed(path string) bool {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	_, exists := am.assets[path]
+	return exists
+}
+
+func (am *AssetManager) assetExists(path string) *AssetInfo {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
 	asset, exists := am.assets[path]
-	am.mutex.RUnlock()
 	if !exists {
 		return nil
 	}
-	// Load or reload asset from disk if necessary
 	asset.LastLoaded = time.Now()
-	am.assets[path] = asset // Update the loaded time
 
 	return asset
 }
 
+// UnloadAsset releases a resource returned by LoadAsset. The resource must not be used
+// afterwards: it stays cached until it is evicted to fit the cache budget, or its file changes.
 func (am *AssetManager) UnloadAsset(asset *metadata.Resource) error {
+	if asset == nil {
+		return nil
+	}
+	am.cacheMutex.Lock()
+	unloaded, ok := am.cache.release(asset)
+	am.cacheMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("asset `%s` was not loaded by the asset manager", asset.FullPath)
+	}
+	unloadEntries(unloaded)
 	return nil
 }
 
+// SetCacheBudget sets the number of bytes of released assets kept in memory. Referenced
+// assets are never evicted, so the cache may exceed it.
+func (am *AssetManager) SetCacheBudget(budget uint64) {
+	am.cacheMutex.Lock()
+	evicted := am.cache.setBudget(budget)
+	am.cacheMutex.Unlock()
+	unloadEntries(evicted)
+}
+
+// CacheStats returns the statistics of the asset cache.
+func (am *AssetManager) CacheStats() CacheStats {
+	am.cacheMutex.Lock()
+	defer am.cacheMutex.Unlock()
+	return am.cache.statistics()
+}
+
+// invalidate drops the cached resources of the file, it changed, and of the assets
+// referencing it.
+func (am *AssetManager) invalidate(path string) {
+	paths := append([]string{path}, am.dependencies.dependentsOf(path, true)...)
+	am.cacheMutex.Lock()
+	unloaded := []*cacheEntry{}
+	for _, p := range paths {
+		unloaded = append(unloaded, am.cache.invalidate(p)...)
+	}
+	am.cacheMutex.Unlock()
+	unloadEntries(unloaded)
+}
+
+// Shutdown stops watching the assets. Pending change notifications are dropped.
+func (am *AssetManager) Shutdown() error {
+	if am.isClosed {
+		return nil
+	}
+	am.isClosed = true
+
+	am.pendingMutex.Lock()
+	for path, change := range am.pending {
+		change.timer.Stop()
+		delete(am.pending, path)
+	}
+	am.pendingMutex.Unlock()
+
+	stats := am.CacheStats()
+	core.LogDebug("asset cache: %d hits, %d misses, %d evictions, %d assets (%d bytes) left", stats.Hits, stats.Misses, stats.Evictions, stats.Entries, stats.Bytes)
+	am.cacheMutex.Lock()
+	unloaded := am.cache.clear()
+	am.cacheMutex.Unlock()
+	unloadEntries(unloaded)
+
+	close(am.done)
+	return am.vfs.Close()
+}
+
 func (am *AssetManager) start() {
 	for {
 		select {
@@ -186,27 +521,39 @@ func (am *AssetManager) start() {
 				if e.Op&fsnotify.Create != 0 {
 					am.watchRecursive(e.Name, false)
 				}
+				continue
+			}
+			// The index is keyed by names in the virtual file system.
+			path, ok := am.vfsName(e.Name)
+			if !ok {
+				continue
+			}
+			if strings.HasSuffix(path, META_EXTENSION) {
+				if e.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+					am.handleMetaEvent(strings.TrimSuffix(path, META_EXTENSION))
+				}
+				continue
 			}
 			// Handle create or modify events
 			if e.Op&(fsnotify.Create|fsnotify.Write) != 0 {
-				am.handleFileEvent(e.Name)
+				am.handleFileEvent(path)
+				am.scheduleChanged(path)
 			}
 			//Can't stat a deleted directory, so just pretend that it's always a directory and
 			//try to remove from the watch list...  we really have no clue if it's a directory or not...
-			if e.Op&fsnotify.Remove != 0 {
-				am.removeAsset(e.Name)
+			if e.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Another mount may still provide the file.
+				if _, err := am.vfs.Stat(path); err != nil {
+					am.removeAsset(path)
+				}
 				am.fsnotify.Remove(e.Name)
 			}
-			am.events <- e
 
 		case e := <-am.fsnotify.Errors:
-			am.errors <- e
 			core.LogError(e.Error())
 
 		case <-am.done:
 			am.fsnotify.Close()
-			close(am.events)
-			close(am.errors)
 			return
 		}
 	}
@@ -215,63 +562,153 @@ func (am *AssetManager) start() {
 // watchRecursive adds all directories under the given one to the watch list.
 // this is probably a very racey process. What if a file is added to a folder before we get the watch added?
 func (am *AssetManager) watchRecursive(path string, unWatch bool) error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-	wd = wd + "/" // add trailing slash
-	err = filepath.Walk(path, func(walkPath string, fi os.FileInfo, err error) error {
+	return filepath.Walk(path, func(walkPath string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if fi.IsDir() {
-			if unWatch {
-				if err = am.fsnotify.Remove(walkPath); err != nil {
-					return err
+		if !fi.IsDir() {
+			// Files created before the watch was added are indexed here.
+			if !unWatch {
+				if name, ok := am.vfsName(walkPath); ok {
+					am.handleFileEvent(name)
 				}
-			} else {
-				am.mutex.RLock()
-				if err = am.fsnotify.Add(walkPath); err != nil {
-					return err
-				}
-				am.mutex.RUnlock()
 			}
-		} else {
-			p := strings.TrimPrefix(walkPath, wd)
-			am.handleFileEvent(p)
+			return nil
 		}
-		return nil
+		if unWatch {
+			return am.fsnotify.Remove(walkPath)
+		}
+		return am.fsnotify.Add(walkPath)
 	})
-	return err
+}
+
+// vfsName maps a path on disk to its name in the virtual file system, using the root of
+// the watched mount it belongs to.
+func (am *AssetManager) vfsName(diskPath string) (string, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	for i := len(am.watchedRoots) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(am.watchedRoots[i], diskPath)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
 }
 
 // Handle the creation or modification of a file
 func (am *AssetManager) handleFileEvent(path string) {
-	am.mutex.Lock()
-	defer am.mutex.Unlock()
-
 	assetType := determineAssetType(path)
 	if assetType == metadata.ResourceTypeNone {
 		return
 	}
-	am.assets[path] = &AssetInfo{
+	am.invalidate(path)
+
+	asset := &AssetInfo{
 		Path:       path,
 		Type:       assetType,
 		LastLoaded: time.Now(),
 	}
+	if info, err := am.vfs.Stat(path); err == nil {
+		asset.size = info.Size()
+		asset.modTime = info.ModTime()
+	}
+	am.indexMeta(asset)
+
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	if old, ok := am.assets[path]; ok && am.guids[old.GUID] == path {
+		delete(am.guids, old.GUID)
+	}
+	am.assets[path] = asset
+	am.guids[asset.GUID] = path
+}
+
+/** @brief A change notification waiting for the file to stop being written to. */
+type pendingChange struct {
+	timer *time.Timer
+	cause string
+}
+
+// scheduleChanged publishes the change of the file once it stopped being written to for
+// ASSET_RELOAD_DEBOUNCE. Editors usually save in several writes, and reloading a half
+// written file would fail. The change cascades to the assets referencing the file.
+func (am *AssetManager) scheduleChanged(path string) {
+	am.schedule(path, "")
+	for _, dependent := range am.dependencies.dependentsOf(path, true) {
+		am.schedule(dependent, path)
+	}
+}
+
+func (am *AssetManager) schedule(path string, cause string) {
+	assetType := determineAssetType(path)
+	if assetType == metadata.ResourceTypeNone {
+		return
+	}
+
+	am.pendingMutex.Lock()
+	defer am.pendingMutex.Unlock()
+
+	if change, ok := am.pending[path]; ok {
+		// The asset changing itself wins over a cascade.
+		if cause == "" {
+			change.cause = ""
+		}
+		change.timer.Reset(ASSET_RELOAD_DEBOUNCE)
+		return
+	}
+	change := &pendingChange{cause: cause}
+	change.timer = time.AfterFunc(ASSET_RELOAD_DEBOUNCE, func() {
+		am.pendingMutex.Lock()
+		delete(am.pending, path)
+		cause := change.cause
+		am.pendingMutex.Unlock()
+
+		// Posted, so that listeners run on the main thread between frames.
+		event := AssetChangedEvent{
+			Path:  path,
+			Name:  assetName(path, assetType),
+			Type:  assetType,
+			Cause: cause,
+		}
+		event.GUID, _ = am.GUID(path)
+		core.Post(core.Events(), event)
+	})
+	am.pending[path] = change
 }
 
 // Remove the asset from the index if it was deleted
 func (am *AssetManager) removeAsset(path string) {
+	am.invalidate(path)
+	am.dependencies.remove(path)
+
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
+	if asset, ok := am.assets[path]; ok {
+		if am.guids[asset.GUID] == path {
+			delete(am.guids, asset.GUID)
+		}
+		am.addOrphan(asset)
+	}
 	delete(am.assets, path)
 }
 
+// assetName returns the name LoadAsset expects for the asset at the given path.
+func assetName(path string, assetType metadata.ResourceType) string {
+	if assetType == metadata.ResourceTypeBinary {
+		return path
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
 func determineAssetType(path string) metadata.ResourceType {
 	switch filepath.Ext(path) {
-	case ".shadercfg":
+	case ".shadercfg", ".ksc":
 		return metadata.ResourceTypeShader
 	case ".fontcfg", ".ksf":
 		return metadata.ResourceTypeSystemFont
@@ -279,9 +716,9 @@ func determineAssetType(path string) metadata.ResourceType {
 		return metadata.ResourceTypeBitmapFont
 	case ".spv":
 		return metadata.ResourceTypeBinary
-	case ".png", ".jpg", ".tga":
+	case ".png", ".jpg", ".tga", ".kti":
 		return metadata.ResourceTypeImage
-	case ".obj", ".ksm":
+	case ".obj", ".ksm", ".gltf", ".glb":
 		return metadata.ResourceTypeModel
 	case ".amt":
 		return metadata.ResourceTypeMaterial
@@ -0,0 +1,328 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The number of assets loaded concurrently by LoadAsync.
+const ASSET_LOAD_WORKERS = 4
+
+/**
+ * @brief An asset being loaded asynchronously. The file is read on a worker goroutine, then
+ * the completion callbacks run on the main thread, between frames. The handle is ready once
+ * they all ran, so systems finishing the asset on the main thread (e.g. uploading textures)
+ * do it in a callback registered right after LoadAsync.
+ * NOTE: the main thread runs the callbacks when the engine loop dispatches the queued events.
+ * If the event system was not initialized before the manager (tools, tests), there is no main
+ * thread: the callbacks run on the goroutine finishing the load, and Wait returns right after.
+ */
+type AssetHandle struct {
+	/** @brief The name the asset was requested by. */
+	Name string
+	/** @brief The type of the asset. */
+	Type metadata.ResourceType
+
+	am        *AssetManager
+	mutex     sync.Mutex
+	resource  *metadata.Resource
+	err       error
+	loaded    bool
+	completed bool
+	// Closed once completed.
+	done      chan struct{}
+	callbacks []func(*AssetHandle)
+	groups    []*LoadGroup
+}
+
+// Posted when an asynchronous load finished, or a callback was added to a finished one.
+type assetLoaded struct {
+	handle *AssetHandle
+}
+
+// Posted when a load group changed, it may be complete already.
+type loadGroupChanged struct {
+	group *LoadGroup
+}
+
+// LoadAsync starts loading the asset on a worker and returns right away. See AssetHandle.
+func (am *AssetManager) LoadAsync(filename string, resourceType metadata.ResourceType, params interface{}) *AssetHandle {
+	h := &AssetHandle{
+		Name: filename,
+		Type: resourceType,
+		am:   am,
+		done: make(chan struct{}),
+	}
+	go func() {
+		am.loadSlots <- struct{}{}
+		resource, err := am.LoadAsset(filename, resourceType, params)
+		<-am.loadSlots
+
+		h.mutex.Lock()
+		h.resource = resource
+		h.err = err
+		h.loaded = true
+		h.mutex.Unlock()
+		am.postLoaded(h)
+	}()
+	return h
+}
+
+// Ready reports whether the asset was loaded successfully and its completion callbacks ran.
+func (h *AssetHandle) Ready() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.completed && h.err == nil
+}
+
+// Completed reports whether the load finished, successfully or not, and the completion
+// callbacks ran.
+func (h *AssetHandle) Completed() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.completed
+}
+
+// Err returns the error the load failed with, nil while loading or on success.
+func (h *AssetHandle) Err() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.err
+}
+
+// Resource returns the loaded resource, nil while loading or on failure.
+func (h *AssetHandle) Resource() *metadata.Resource {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.resource
+}
+
+// Wait blocks until the load completed or the context is done, and returns the error of the
+// load or of the context. The completions are delivered by the main thread, so it must not
+// wait on them: poll Ready or use OnComplete there instead.
+func (h *AssetHandle) Wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OnComplete registers a callback run on the main thread when the load finishes, whether it
+// failed or not. Callbacks run in the order they were registered; the ones registered after
+// the load finished run between the next frames.
+func (h *AssetHandle) OnComplete(callback func(*AssetHandle)) {
+	h.mutex.Lock()
+	h.callbacks = append(h.callbacks, callback)
+	loaded := h.loaded
+	h.mutex.Unlock()
+
+	if loaded {
+		h.am.postLoaded(h)
+	}
+}
+
+// Release releases the loaded resource, see AssetManager.UnloadAsset.
+func (h *AssetHandle) Release() error {
+	h.mutex.Lock()
+	resource := h.resource
+	h.resource = nil
+	h.mutex.Unlock()
+	return h.am.UnloadAsset(resource)
+}
+
+// postLoaded has the pending callbacks of the handle run on the main thread, or right away
+// when there is no event system to deliver them.
+func (am *AssetManager) postLoaded(h *AssetHandle) {
+	if am.events == nil {
+		am.onAssetLoaded(assetLoaded{handle: h})
+		return
+	}
+	core.Post(am.events, assetLoaded{handle: h})
+}
+
+// postGroupChanged has the group checked on the main thread, or right away when there is no
+// event system to deliver it.
+func (am *AssetManager) postGroupChanged(g *LoadGroup) {
+	if am.events == nil {
+		g.check()
+		return
+	}
+	core.Post(am.events, loadGroupChanged{group: g})
+}
+
+// onAssetLoaded runs the pending callbacks of the handle, on the main thread if there is one.
+func (am *AssetManager) onAssetLoaded(event assetLoaded) {
+	h := event.handle
+	h.mutex.Lock()
+	callbacks := h.callbacks
+	h.callbacks = nil
+	h.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(h)
+	}
+
+	h.mutex.Lock()
+	if h.completed {
+		h.mutex.Unlock()
+		return
+	}
+	h.completed = true
+	close(h.done)
+	groups := h.groups
+	h.mutex.Unlock()
+
+	for _, g := range groups {
+		g.check()
+	}
+}
+
+/**
+ * @brief A set of asynchronous loads tracked together, e.g. the assets of a scene behind a
+ * loading screen.
+ */
+type LoadGroup struct {
+	am *AssetManager
+
+	mutex     sync.Mutex
+	handles   []*AssetHandle
+	callbacks []func(*LoadGroup)
+}
+
+// NewLoadGroup creates an empty load group.
+func (am *AssetManager) NewLoadGroup() *LoadGroup {
+	return &LoadGroup{am: am}
+}
+
+// Load starts loading the asset asynchronously as part of the group.
+func (g *LoadGroup) Load(filename string, resourceType metadata.ResourceType, params interface{}) *AssetHandle {
+	h := g.am.LoadAsync(filename, resourceType, params)
+	g.Add(h)
+	return h
+}
+
+// Add tracks the loads with the group.
+func (g *LoadGroup) Add(handles ...*AssetHandle) {
+	g.mutex.Lock()
+	g.handles = append(g.handles, handles...)
+	g.mutex.Unlock()
+
+	for _, h := range handles {
+		h.mutex.Lock()
+		h.groups = append(h.groups, g)
+		h.mutex.Unlock()
+	}
+	// The loads may be complete already.
+	g.am.postGroupChanged(g)
+}
+
+// Handles returns the loads of the group.
+func (g *LoadGroup) Handles() []*AssetHandle {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return append([]*AssetHandle(nil), g.handles...)
+}
+
+// Counts returns the number of completed loads, the number of failed ones among them, and
+// the total number of loads.
+func (g *LoadGroup) Counts() (completed int, failed int, total int) {
+	for _, h := range g.Handles() {
+		h.mutex.Lock()
+		if h.completed {
+			completed++
+			if h.err != nil {
+				failed++
+			}
+		}
+		h.mutex.Unlock()
+		total++
+	}
+	return completed, failed, total
+}
+
+// Progress returns the fraction of the loads completed, between 0 and 1. An empty group is
+// complete.
+func (g *LoadGroup) Progress() float32 {
+	completed, _, total := g.Counts()
+	if total == 0 {
+		return 1.0
+	}
+	return float32(completed) / float32(total)
+}
+
+// Completed reports whether all the loads completed, successfully or not.
+func (g *LoadGroup) Completed() bool {
+	completed, _, total := g.Counts()
+	return completed == total
+}
+
+// Ready reports whether all the loads completed successfully.
+func (g *LoadGroup) Ready() bool {
+	completed, failed, total := g.Counts()
+	return completed == total && failed == 0
+}
+
+// Err returns the errors of the failed loads, joined.
+func (g *LoadGroup) Err() error {
+	errs := []error{}
+	for _, h := range g.Handles() {
+		if err := h.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wait blocks until all the loads completed or the context is done. Like AssetHandle.Wait,
+// it must not be called from the main thread.
+func (g *LoadGroup) Wait(ctx context.Context) error {
+	for _, h := range g.Handles() {
+		select {
+		case <-h.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return g.Err()
+}
+
+// OnComplete registers a callback run once on the main thread when all the loads of the
+// group completed, successfully or not.
+func (g *LoadGroup) OnComplete(callback func(*LoadGroup)) {
+	g.mutex.Lock()
+	g.callbacks = append(g.callbacks, callback)
+	g.mutex.Unlock()
+
+	// The group may be complete already.
+	g.am.postGroupChanged(g)
+}
+
+// Release releases the resources of all the loads.
+func (g *LoadGroup) Release() {
+	for _, h := range g.Handles() {
+		if err := h.Release(); err != nil {
+			core.LogWarn(err.Error())
+		}
+	}
+}
+
+// check runs the callbacks of the group if all its loads completed.
+func (g *LoadGroup) check() {
+	if !g.Completed() {
+		return
+	}
+	g.mutex.Lock()
+	callbacks := g.callbacks
+	g.callbacks = nil
+	g.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(g)
+	}
+}
@@ -0,0 +1,206 @@
+package assets
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// gatedLoader holds the loads until the gate is closed.
+type gatedLoader struct {
+	gate chan struct{}
+}
+
+func (l *gatedLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	<-l.gate
+	return &metadata.Resource{FullPath: path, DataSize: 1}, nil
+}
+
+func (l *gatedLoader) Unload(*metadata.Resource) error {
+	return nil
+}
+
+// newGatedAssetManager returns an asset manager with two materials, a and b, loaded by a
+// gated loader.
+func newGatedAssetManager(t *testing.T) (*AssetManager, *gatedLoader) {
+	t.Helper()
+	am := newMemoryAssetManager(t, map[string]string{
+		"materials/a.amt": "name=a\n",
+		"materials/b.amt": "name=b\n",
+	})
+	loader := &gatedLoader{gate: make(chan struct{})}
+	am.registerLoader(metadata.ResourceTypeMaterial, loader)
+	return am, loader
+}
+
+func waitTimeout(t *testing.T, wait func(ctx context.Context) error) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := wait(ctx)
+	if err == context.DeadlineExceeded {
+		t.Fatal("the load did not complete")
+	}
+	return err
+}
+
+// Without an event system, the callbacks run on the loading goroutine.
+func TestAssetHandleWithoutEvents(t *testing.T) {
+	am, loader := newGatedAssetManager(t)
+
+	h := am.LoadAsync("a", metadata.ResourceTypeMaterial, nil)
+	calls := []string{}
+	var mutex sync.Mutex
+	h.OnComplete(func(h *AssetHandle) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		// The handle completes once its callbacks ran.
+		if h.Completed() {
+			t.Error("the handle completed before its callbacks ran")
+		}
+		calls = append(calls, "first")
+	})
+	h.OnComplete(func(*AssetHandle) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, "second")
+	})
+	if h.Completed() || h.Resource() != nil {
+		t.Fatal("the handle completed before the load")
+	}
+
+	close(loader.gate)
+	if err := waitTimeout(t, h.Wait); err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("got calls %v, want first and second", calls)
+	}
+	mutex.Unlock()
+	if !h.Ready() || h.Resource() == nil || h.Resource().FullPath != "materials/a.amt" {
+		t.Fatalf("got resource %v, ready %v", h.Resource(), h.Ready())
+	}
+
+	// Registered after the completion, the callback runs right away.
+	ran := false
+	h.OnComplete(func(*AssetHandle) { ran = true })
+	if !ran {
+		t.Error("the callback registered after the completion did not run")
+	}
+	if err := h.Release(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAssetHandleFailure(t *testing.T) {
+	am, _ := newGatedAssetManager(t)
+
+	h := am.LoadAsync("missing", metadata.ResourceTypeMaterial, nil)
+	if err := waitTimeout(t, h.Wait); err == nil {
+		t.Fatal("loaded a missing asset")
+	}
+	if !h.Completed() || h.Ready() || h.Err() == nil || h.Resource() != nil {
+		t.Errorf("got completed %v, ready %v, error %v", h.Completed(), h.Ready(), h.Err())
+	}
+}
+
+func TestLoadGroupWithoutEvents(t *testing.T) {
+	am, loader := newGatedAssetManager(t)
+
+	empty := am.NewLoadGroup()
+	emptyCompleted := false
+	empty.OnComplete(func(*LoadGroup) { emptyCompleted = true })
+	if !emptyCompleted || empty.Progress() != 1 || !empty.Ready() {
+		t.Error("the empty group did not complete")
+	}
+
+	g := am.NewLoadGroup()
+	g.Load("a", metadata.ResourceTypeMaterial, nil)
+	g.Load("missing", metadata.ResourceTypeMaterial, nil)
+	g.Add(am.LoadAsync("b", metadata.ResourceTypeMaterial, nil))
+	completions := make(chan *LoadGroup, 2)
+	g.OnComplete(func(g *LoadGroup) { completions <- g })
+
+	// The missing asset fails right away, the others wait for the gate.
+	deadline := time.Now().Add(5 * time.Second)
+	for completed, _, _ := g.Counts(); completed < 1 && time.Now().Before(deadline); completed, _, _ = g.Counts() {
+		time.Sleep(time.Millisecond)
+	}
+	if completed, failed, total := g.Counts(); completed != 1 || failed != 1 || total != 3 || g.Completed() {
+		t.Fatalf("got %d completed, %d failed out of %d", completed, failed, total)
+	}
+	if len(completions) != 0 {
+		t.Fatal("the group completed before its loads")
+	}
+
+	close(loader.gate)
+	if err := waitTimeout(t, g.Wait); err == nil {
+		t.Error("got no error for the missing asset")
+	}
+	select {
+	case <-completions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the group did not complete")
+	}
+	if !g.Completed() || g.Ready() || g.Progress() != 1 {
+		t.Errorf("got completed %v, ready %v, progress %f", g.Completed(), g.Ready(), g.Progress())
+	}
+	// Once.
+	time.Sleep(10 * time.Millisecond)
+	if len(completions) != 0 {
+		t.Error("the group completed twice")
+	}
+	g.Release()
+}
+
+// With an event system, the callbacks run when the main thread dispatches the queued events.
+func TestAssetHandleWithEvents(t *testing.T) {
+	if err := core.EventSystemInitialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer core.EventSystemShutdown()
+	am, loader := newGatedAssetManager(t)
+
+	g := am.NewLoadGroup()
+	h := g.Load("a", metadata.ResourceTypeMaterial, nil)
+	calls := []string{}
+	h.OnComplete(func(*AssetHandle) { calls = append(calls, "handle") })
+	g.OnComplete(func(*LoadGroup) { calls = append(calls, "group") })
+
+	close(loader.gate)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mutex.Lock()
+		loaded := h.loaded
+		h.mutex.Unlock()
+		if loaded || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(calls) != 0 || h.Completed() {
+		t.Fatalf("got calls %v before the events were dispatched", calls)
+	}
+
+	core.EventDispatchQueued()
+	if len(calls) != 2 || calls[0] != "handle" || calls[1] != "group" || !h.Ready() || !g.Ready() {
+		t.Fatalf("got calls %v, ready %v", calls, h.Ready())
+	}
+
+	// Registered after the completion, the callbacks are posted to the next dispatch.
+	h.OnComplete(func(*AssetHandle) { calls = append(calls, "late handle") })
+	g.OnComplete(func(*LoadGroup) { calls = append(calls, "late group") })
+	if len(calls) != 2 {
+		t.Fatalf("got calls %v before the events were dispatched", calls)
+	}
+	core.EventDispatchQueued()
+	if len(calls) != 4 || calls[2] != "late handle" || calls[3] != "late group" {
+		t.Errorf("got calls %v", calls)
+	}
+	g.Release()
+}
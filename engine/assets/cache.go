@@ -0,0 +1,223 @@
+package assets
+
+import (
+	"container/list"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The number of bytes of released assets kept in memory by default.
+const ASSET_CACHE_DEFAULT_BUDGET uint64 = 256 * 1024 * 1024
+
+/** @brief The statistics of the asset cache. */
+type CacheStats struct {
+	/** @brief The number of loads served from the cache. */
+	Hits uint64
+	/** @brief The number of loads that read the file. */
+	Misses uint64
+	/** @brief The number of released assets unloaded to stay within the budget. */
+	Evictions uint64
+	/** @brief The number of assets in the cache, referenced or not. */
+	Entries int
+	/** @brief The number of assets in the cache that are still referenced. */
+	Referenced int
+	/** @brief The size of the assets in the cache, in bytes. */
+	Bytes uint64
+	/** @brief The size the released assets are evicted down to, in bytes. */
+	Budget uint64
+}
+
+type cacheKey struct {
+	path         string
+	resourceType metadata.ResourceType
+	// Tells apart the loads of the same file with different parameters, e.g. flipped images.
+	variant string
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	resource *metadata.Resource
+	loader   Loader
+	refCount uint32
+	// Set when the file changed. The entry is unloaded as soon as it is released.
+	stale bool
+	// The position in the list of released entries, nil while referenced.
+	element *list.Element
+}
+
+/**
+ * @brief The loaded resources, shared by everyone loading the same asset. The resources are
+ * reference counted; released ones stay cached until the total size exceeds the budget, the
+ * least recently used being unloaded first.
+ */
+type assetCache struct {
+	entries map[cacheKey]*cacheEntry
+	handles map[*metadata.Resource]*cacheEntry
+	// The released entries, least recently used at the front.
+	released *list.List
+
+	bytes  uint64
+	budget uint64
+	stats  CacheStats
+}
+
+func newAssetCache(budget uint64) *assetCache {
+	return &assetCache{
+		entries:  make(map[cacheKey]*cacheEntry),
+		handles:  make(map[*metadata.Resource]*cacheEntry),
+		released: list.New(),
+		budget:   budget,
+	}
+}
+
+// acquire returns the cached resource of the key and adds a reference to it, or nil.
+func (c *assetCache) acquire(key cacheKey) *metadata.Resource {
+	entry, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	if entry.element != nil {
+		c.released.Remove(entry.element)
+		entry.element = nil
+	}
+	entry.refCount++
+	return entry.resource
+}
+
+// add caches a freshly loaded resource with one reference. If the same asset was loaded
+// concurrently, the cached one is acquired and returned instead, and the given one must be
+// unloaded. The returned entries were evicted and must be unloaded too.
+func (c *assetCache) add(key cacheKey, resource *metadata.Resource, loader Loader) (*metadata.Resource, []*cacheEntry) {
+	if entry, ok := c.entries[key]; ok {
+		if entry.element != nil {
+			c.released.Remove(entry.element)
+			entry.element = nil
+		}
+		entry.refCount++
+		return entry.resource, nil
+	}
+	entry := &cacheEntry{
+		key:      key,
+		resource: resource,
+		loader:   loader,
+		refCount: 1,
+	}
+	c.entries[key] = entry
+	c.handles[resource] = entry
+	c.bytes += resource.DataSize
+	return resource, c.evict()
+}
+
+// release removes a reference to the resource. The returned entries must be unloaded.
+func (c *assetCache) release(resource *metadata.Resource) ([]*cacheEntry, bool) {
+	entry, ok := c.handles[resource]
+	if !ok {
+		return nil, false
+	}
+	if entry.refCount == 0 {
+		core.LogWarn("asset `%s` released more times than it was loaded", entry.key.path)
+		return nil, true
+	}
+	entry.refCount--
+	if entry.refCount > 0 {
+		return nil, true
+	}
+	if entry.stale {
+		delete(c.handles, resource)
+		return []*cacheEntry{entry}, true
+	}
+	entry.element = c.released.PushBack(entry)
+	return c.evict(), true
+}
+
+// invalidate drops the entries of the file, so the next loads read it again. Referenced
+// entries are kept alive until released. The returned entries must be unloaded.
+func (c *assetCache) invalidate(path string) []*cacheEntry {
+	unloaded := []*cacheEntry{}
+	for key, entry := range c.entries {
+		if key.path != path {
+			continue
+		}
+		delete(c.entries, key)
+		c.bytes -= entry.resource.DataSize
+		if entry.refCount > 0 {
+			entry.stale = true
+			continue
+		}
+		c.released.Remove(entry.element)
+		entry.element = nil
+		delete(c.handles, entry.resource)
+		unloaded = append(unloaded, entry)
+	}
+	return unloaded
+}
+
+// setBudget changes the budget. The returned entries were evicted and must be unloaded.
+func (c *assetCache) setBudget(budget uint64) []*cacheEntry {
+	c.budget = budget
+	return c.evict()
+}
+
+// evict removes the least recently used released entries until the cache fits the budget.
+func (c *assetCache) evict() []*cacheEntry {
+	evicted := []*cacheEntry{}
+	for c.bytes > c.budget && c.released.Len() > 0 {
+		entry := c.released.Remove(c.released.Front()).(*cacheEntry)
+		entry.element = nil
+		delete(c.entries, entry.key)
+		delete(c.handles, entry.resource)
+		c.bytes -= entry.resource.DataSize
+		c.stats.Evictions++
+		evicted = append(evicted, entry)
+	}
+	return evicted
+}
+
+// clear drops every entry, referenced or not. The returned entries must be unloaded.
+func (c *assetCache) clear() []*cacheEntry {
+	entries := make([]*cacheEntry, 0, len(c.handles))
+	for _, entry := range c.handles {
+		entries = append(entries, entry)
+	}
+	c.entries = make(map[cacheKey]*cacheEntry)
+	c.handles = make(map[*metadata.Resource]*cacheEntry)
+	c.released.Init()
+	c.bytes = 0
+	return entries
+}
+
+func (c *assetCache) statistics() CacheStats {
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	for _, entry := range c.entries {
+		if entry.refCount > 0 {
+			stats.Referenced++
+		}
+	}
+	stats.Bytes = c.bytes
+	stats.Budget = c.budget
+	return stats
+}
+
+// unloadEntries hands the resources back to their loaders.
+func unloadEntries(entries []*cacheEntry) {
+	for _, entry := range entries {
+		if err := entry.loader.Unload(entry.resource); err != nil {
+			core.LogWarn("failed to unload asset `%s`: %s", entry.key.path, err.Error())
+		}
+	}
+}
+
+// cacheVariant returns the part of the cache key depending on the load parameters.
+func cacheVariant(params interface{}) string {
+	switch p := params.(type) {
+	case *metadata.ImageResourceParams:
+		if p != nil && p.FlipY {
+			return "flip_y"
+		}
+	}
+	return ""
+}
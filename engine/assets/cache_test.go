@@ -0,0 +1,163 @@
+package assets
+
+import (
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// countingLoader counts the resources handed back to it.
+type countingLoader struct {
+	unloaded int
+}
+
+func (l *countingLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	return &metadata.Resource{FullPath: path, DataSize: 1}, nil
+}
+
+func (l *countingLoader) Unload(*metadata.Resource) error {
+	l.unloaded++
+	return nil
+}
+
+func textKey(path string) cacheKey {
+	return cacheKey{path: path, resourceType: metadata.ResourceTypeText}
+}
+
+func textResource(path string, size uint64) *metadata.Resource {
+	return &metadata.Resource{FullPath: path, DataSize: size}
+}
+
+func TestAssetCacheAcquireRelease(t *testing.T) {
+	c := newAssetCache(0)
+	loader := &countingLoader{}
+
+	if c.acquire(textKey("a.txt")) != nil {
+		t.Fatal("acquire of an empty cache returned a resource")
+	}
+	resource := textResource("a.txt", 10)
+	if cached, evicted := c.add(textKey("a.txt"), resource, loader); cached != resource || len(evicted) != 0 {
+		t.Fatalf("add returned %v and evicted %d entries", cached, len(evicted))
+	}
+	if c.acquire(textKey("a.txt")) != resource {
+		t.Fatal("acquire did not return the cached resource")
+	}
+	if c.acquire(cacheKey{path: "a.txt", resourceType: metadata.ResourceTypeText, variant: "flip_y"}) != nil {
+		t.Fatal("acquire returned the resource of another variant")
+	}
+
+	// The first release keeps the resource referenced, the second one evicts it as the budget is 0.
+	if evicted, ok := c.release(resource); !ok || len(evicted) != 0 {
+		t.Fatalf("first release: ok %v, evicted %d entries", ok, len(evicted))
+	}
+	evicted, ok := c.release(resource)
+	if !ok || len(evicted) != 1 || evicted[0].resource != resource {
+		t.Fatalf("second release: ok %v, evicted %d entries", ok, len(evicted))
+	}
+	unloadEntries(evicted)
+	if loader.unloaded != 1 {
+		t.Fatalf("unloaded %d resources, want 1", loader.unloaded)
+	}
+	if _, ok := c.release(resource); ok {
+		t.Fatal("release of an evicted resource succeeded")
+	}
+
+	stats := c.statistics()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 1 || stats.Entries != 0 || stats.Bytes != 0 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+}
+
+func TestAssetCacheConcurrentAdd(t *testing.T) {
+	c := newAssetCache(ASSET_CACHE_DEFAULT_BUDGET)
+	loader := &countingLoader{}
+
+	first, second := textResource("a.txt", 10), textResource("a.txt", 10)
+	c.add(textKey("a.txt"), first, loader)
+	if cached, _ := c.add(textKey("a.txt"), second, loader); cached != first {
+		t.Fatal("the second add did not return the resource cached first")
+	}
+	if stats := c.statistics(); stats.Entries != 1 || stats.Bytes != 10 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+	// Both adds hold a reference.
+	c.release(first)
+	if stats := c.statistics(); stats.Referenced != 1 {
+		t.Fatalf("%d referenced entries after one release, want 1", stats.Referenced)
+	}
+}
+
+func TestAssetCacheEvict(t *testing.T) {
+	c := newAssetCache(25)
+	loader := &countingLoader{}
+
+	resources := map[string]*metadata.Resource{}
+	for _, path := range []string{"a.txt", "b.txt", "c.txt"} {
+		resources[path] = textResource(path, 10)
+		c.add(textKey(path), resources[path], loader)
+	}
+	// Over budget, but every entry is referenced.
+	if stats := c.statistics(); stats.Bytes != 30 || stats.Evictions != 0 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+
+	// Releasing a and b: a is evicted, the least recently used of the released ones.
+	if evicted, _ := c.release(resources["a.txt"]); len(evicted) != 1 || evicted[0].key.path != "a.txt" {
+		t.Fatalf("release of a evicted %d entries", len(evicted))
+	}
+	if evicted, _ := c.release(resources["b.txt"]); len(evicted) != 0 {
+		t.Fatalf("release of b evicted %d entries", len(evicted))
+	}
+
+	// Acquiring b again takes it out of the released entries, so a lower budget keeps it.
+	if c.acquire(textKey("b.txt")) != resources["b.txt"] {
+		t.Fatal("acquire did not return b")
+	}
+	if evicted := c.setBudget(0); len(evicted) != 0 {
+		t.Fatalf("setBudget evicted %d referenced entries", len(evicted))
+	}
+	evicted, _ := c.release(resources["b.txt"])
+	if len(evicted) != 1 || evicted[0].key.path != "b.txt" {
+		t.Fatalf("release of b under a zero budget evicted %d entries", len(evicted))
+	}
+
+	if entries := c.clear(); len(entries) != 1 || entries[0].key.path != "c.txt" {
+		t.Fatalf("clear returned %d entries", len(entries))
+	}
+	if stats := c.statistics(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Fatalf("unexpected statistics after clear %+v", stats)
+	}
+}
+
+func TestAssetCacheInvalidate(t *testing.T) {
+	c := newAssetCache(ASSET_CACHE_DEFAULT_BUDGET)
+	loader := &countingLoader{}
+
+	released, referenced := textResource("a.txt", 10), textResource("b.txt", 10)
+	c.add(textKey("a.txt"), released, loader)
+	c.add(textKey("b.txt"), referenced, loader)
+	c.release(released)
+
+	// A released entry is unloaded right away.
+	if unloaded := c.invalidate("a.txt"); len(unloaded) != 1 || unloaded[0].resource != released {
+		t.Fatalf("invalidate of a released entry returned %d entries", len(unloaded))
+	}
+	if c.acquire(textKey("a.txt")) != nil {
+		t.Fatal("acquire returned an invalidated resource")
+	}
+
+	// A referenced entry is dropped from the cache, and unloaded once released.
+	if unloaded := c.invalidate("b.txt"); len(unloaded) != 0 {
+		t.Fatalf("invalidate of a referenced entry returned %d entries", len(unloaded))
+	}
+	if c.acquire(textKey("b.txt")) != nil {
+		t.Fatal("acquire returned an invalidated resource")
+	}
+	if stats := c.statistics(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+	unloaded, ok := c.release(referenced)
+	if !ok || len(unloaded) != 1 || unloaded[0].resource != referenced {
+		t.Fatalf("release of a stale entry: ok %v, returned %d entries", ok, len(unloaded))
+	}
+}
@@ -0,0 +1,100 @@
+package assets
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/spaghettifunk/anima/engine/assets/loaders"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The types of the assets Check loads. Images are only checked to exist, decoding them all
+// would take too long.
+var checkedTypes = []metadata.ResourceType{
+	metadata.ResourceTypeMaterial,
+	metadata.ResourceTypeShader,
+	metadata.ResourceTypeSystemFont,
+	metadata.ResourceTypeBitmapFont,
+	metadata.ResourceTypeModel,
+}
+
+// Check loads every material, shader, font and model, the configurations through strict
+// loaders, and returns an error for every problem found: unknown keys, invalid values,
+// duplicate material names and references to missing assets. The references of the assets
+// the strict loaders reject are checked too, as far as the engine would load them.
+func (am *AssetManager) Check() []error {
+	strict := map[metadata.ResourceType]Loader{
+		metadata.ResourceTypeMaterial:   &loaders.MaterialLoader{FS: am.vfs, Strict: true},
+		metadata.ResourceTypeShader:     &loaders.ShaderLoader{FS: am.vfs, Strict: true},
+		metadata.ResourceTypeSystemFont: &loaders.SystemFontLoader{FS: am.vfs, Strict: true},
+	}
+
+	am.mutex.RLock()
+	checked := []*AssetInfo{}
+	for _, asset := range am.assets {
+		if slices.Contains(checkedTypes, asset.Type) {
+			checked = append(checked, asset)
+		}
+	}
+	am.mutex.RUnlock()
+	sort.Slice(checked, func(i, j int) bool {
+		return checked[i].Path < checked[j].Path
+	})
+
+	errs := []error{}
+	// The materials by name, the material system finds them by name only.
+	materials := map[string]string{}
+	for _, asset := range checked {
+		resource, loader, err := am.checkLoad(asset, strict[asset.Type])
+		if err != nil {
+			errs = append(errs, assetErrors(asset.Path, err)...)
+		}
+		if resource == nil {
+			continue
+		}
+		am.recordDependencies(asset.Path, resource)
+
+		if material, ok := resource.Data.(*metadata.MaterialConfig); ok {
+			if other, ok := materials[material.Name]; ok {
+				errs = append(errs, fmt.Errorf("`%s`: material name `%s` already used by `%s`", asset.Path, material.Name, other))
+			} else {
+				materials[material.Name] = asset.Path
+			}
+		}
+		if err := loader.Unload(resource); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return append(errs, am.CheckDependencies()...)
+}
+
+// checkLoad loads the asset with the strict loader if any. When it fails, the asset is loaded
+// like the engine does to still find its references, the error is the one of the strict load.
+func (am *AssetManager) checkLoad(asset *AssetInfo, strict Loader) (*metadata.Resource, Loader, error) {
+	params := importParams(asset, nil)
+	loader := am.loaders[asset.Type]
+	if strict == nil {
+		resource, err := loader.Load(asset.Path, asset.Type, params)
+		return resource, loader, err
+	}
+	resource, err := strict.Load(asset.Path, asset.Type, params)
+	if err == nil {
+		return resource, strict, nil
+	}
+	resource, _ = loader.Load(asset.Path, asset.Type, params)
+	return resource, loader, err
+}
+
+// assetErrors splits the joined errors of the asset, one per problem.
+func assetErrors(assetPath string, err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{fmt.Errorf("`%s`: %w", assetPath, err)}
+	}
+	errs := []error{}
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, assetErrors(assetPath, e)...)
+	}
+	return errs
+}
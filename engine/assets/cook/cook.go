@@ -0,0 +1,259 @@
+/*
+Package cook converts the source assets into the files the engine loads fastest: models into
+.ksm meshes, images into pre-flipped .kti textures with their mips, AngelCode fonts into .kbf
+fonts and shader configs into validated .ksc descriptors. Materials reference their textures
+and shader by GUID. The other runtime files are copied.
+
+The outputs only depend on the content of the sources, so cooking twice gives the same bytes.
+A manifest records the hashes of the files read and written for every source, and sources
+whose files did not change are not cooked again.
+*/
+package cook
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/assets"
+	"github.com/spaghettifunk/anima/engine/core"
+)
+
+// The version of the cooker. Changing it cooks everything again, it must change with the
+// output formats.
+const COOK_VERSION = 2
+
+/** @brief Cooks an assets directory into an output directory. */
+type Cooker struct {
+	/** @brief The directory of the source assets. */
+	Source string
+	/** @brief The directory the cooked assets are written to. */
+	Output string
+	/** @brief Cooks every source, even the unchanged ones. */
+	Force bool
+	/** @brief The number of sources cooked concurrently. */
+	Workers int
+}
+
+/** @brief The outcome of a cook. */
+type Report struct {
+	/** @brief The sources cooked, sorted. */
+	Cooked []string
+	/** @brief The sources left untouched, their outputs being up to date. */
+	UpToDate []string
+	/** @brief The sources not shipped, e.g. GLSL sources, or losing against another source. */
+	Skipped []string
+	/** @brief The outputs deleted, their source being gone. */
+	Removed []string
+	/** @brief The errors of the sources which failed to cook, their outputs are left as they were. */
+	Errors []error
+}
+
+func NewCooker(source string, output string) *Cooker {
+	return &Cooker{
+		Source:  source,
+		Output:  output,
+		Workers: runtime.NumCPU(),
+	}
+}
+
+// Cook cooks the changed sources, writes the manifest and removes the outputs of the sources
+// which are gone. The errors of single sources are reported, the returned error tells the
+// cook itself failed.
+func (c *Cooker) Cook() (*Report, error) {
+	if filepath.Clean(c.Source) == filepath.Clean(c.Output) {
+		return nil, errors.New("the output directory must differ from the source directory")
+	}
+	recorded, err := LoadManifest(c.Output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	previous := recorded
+	if c.Force || recorded.Version != COOK_VERSION {
+		previous = &Manifest{Version: COOK_VERSION}
+	}
+
+	sourceFS := os.DirFS(c.Source)
+	report := &Report{}
+	jobs, err := c.plan(sourceFS, report)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Version: COOK_VERSION}
+	pending := []*job{}
+	for _, j := range jobs {
+		if entry := previous.entry(j.source); entry != nil && entry.upToDate(sourceFS, c.Output) {
+			manifest.Assets = append(manifest.Assets, entry)
+			report.UpToDate = append(report.UpToDate, j.source)
+			continue
+		}
+		pending = append(pending, j)
+	}
+
+	failures := c.run(sourceFS, pending)
+
+	// The outputs of the up to date sources stay theirs, the others are claimed in order.
+	claimed := map[string]string{}
+	for _, entry := range manifest.Assets {
+		for _, output := range entry.Outputs {
+			claimed[output.Path] = entry.Source
+		}
+	}
+	for i, j := range pending {
+		if failures[i] != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", j.source, failures[i]))
+			// Its previous outputs stay, so is its record, to be cooked again next time.
+			if entry := previous.entry(j.source); entry != nil {
+				manifest.Assets = append(manifest.Assets, entry)
+			}
+			continue
+		}
+		entry, err := c.write(j, claimed)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", j.source, err))
+			continue
+		}
+		manifest.Assets = append(manifest.Assets, entry)
+		report.Cooked = append(report.Cooked, j.source)
+	}
+
+	report.Removed = c.removeStale(recorded, manifest)
+	if err := manifest.Save(c.Output); err != nil {
+		return nil, err
+	}
+	sort.Strings(report.Cooked)
+	return report, nil
+}
+
+// plan lists the sources and their recipes, sorted by path.
+func (c *Cooker) plan(sourceFS fs.FS, report *Report) ([]*job, error) {
+	jobs := []*job{}
+	byOutput := map[string]*job{}
+	err := fs.WalkDir(sourceFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		extension := strings.ToLower(path.Ext(name))
+		r, ok := recipes[extension]
+		if !ok {
+			if extension != assets.META_EXTENSION {
+				report.Skipped = append(report.Skipped, name)
+			}
+			return nil
+		}
+		j := newJob(name, r)
+		other, ok := byOutput[j.output]
+		if !ok {
+			byOutput[j.output] = j
+			jobs = append(jobs, j)
+			return nil
+		}
+		winner, loser := other, j
+		if preferred(j, other) {
+			winner, loser = j, other
+			byOutput[j.output] = j
+			jobs[slices.Index(jobs, other)] = j
+		}
+		core.LogWarn("`%s` and `%s` both cook into `%s`, keeping `%s`", winner.source, loser.source, j.output, winner.source)
+		report.Skipped = append(report.Skipped, loser.source)
+		return nil
+	})
+	return jobs, err
+}
+
+// preferred tells whether the source of a wins over the one of b.
+func preferred(a *job, b *job) bool {
+	if (a.recipe == copyRecipe) != (b.recipe == copyRecipe) {
+		return b.recipe == copyRecipe
+	}
+	rank := func(j *job) int {
+		index := slices.Index(sourcePreference, strings.ToLower(path.Ext(j.source)))
+		if index < 0 {
+			return len(sourcePreference)
+		}
+		return index
+	}
+	return rank(a) < rank(b)
+}
+
+// run cooks the jobs on the workers, and returns their errors by index.
+func (c *Cooker) run(sourceFS fs.FS, jobs []*job) []error {
+	failures := make([]error, len(jobs))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, c.Workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				failures[i] = jobs[i].run(sourceFS)
+			}
+		}()
+	}
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return failures
+}
+
+// write writes the outputs of the cooked job, except the ones claimed by another source.
+func (c *Cooker) write(j *job, claimed map[string]string) (*ManifestEntry, error) {
+	entry := &ManifestEntry{
+		Source: j.source,
+		Inputs: j.fsys.inputs(),
+	}
+	for _, name := range j.order {
+		if owner, ok := claimed[name]; ok && owner != j.source {
+			core.LogWarn("`%s` produces `%s`, already produced by `%s`, skipping it", j.source, name, owner)
+			continue
+		}
+		claimed[name] = j.source
+		data := j.outputs[name]
+		if err := writeFile(filepath.Join(c.Output, filepath.FromSlash(name)), data); err != nil {
+			return nil, err
+		}
+		entry.Outputs = append(entry.Outputs, FileHash{Path: name, Hash: hashData(data)})
+	}
+	return entry, nil
+}
+
+// removeStale deletes the outputs recorded by the previous cook that the current one did not
+// produce.
+func (c *Cooker) removeStale(previous *Manifest, current *Manifest) []string {
+	produced := map[string]struct{}{}
+	for _, entry := range current.Assets {
+		for _, output := range entry.Outputs {
+			produced[output.Path] = struct{}{}
+		}
+	}
+	removed := []string{}
+	for _, entry := range previous.Assets {
+		for _, output := range entry.Outputs {
+			if _, ok := produced[output.Path]; ok {
+				continue
+			}
+			err := os.Remove(filepath.Join(c.Output, filepath.FromSlash(output.Path)))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				core.LogWarn("failed to remove `%s`: %s", output.Path, err.Error())
+				continue
+			}
+			removed = append(removed, output.Path)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
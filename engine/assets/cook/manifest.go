@@ -0,0 +1,180 @@
+package cook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// The name of the manifest, at the root of the output directory.
+const MANIFEST_NAME = "cook.manifest"
+
+/**
+ * @brief The record of a cook: for every source, the files read to cook it and the files
+ * written, with the hashes of their contents. A source is cooked again when one of them
+ * changed.
+ */
+type Manifest struct {
+	/** @brief The version of the cooker which wrote the manifest. */
+	Version int `toml:"version"`
+	/** @brief The cooked sources, sorted by path. */
+	Assets []*ManifestEntry `toml:"asset"`
+}
+
+/** @brief The record of a cooked source. */
+type ManifestEntry struct {
+	/** @brief The path of the source, relative to the source directory. */
+	Source string `toml:"source"`
+	/** @brief The files read to cook the source, including the source itself and its sidecar. */
+	Inputs []FileHash `toml:"inputs"`
+	/** @brief The files written, relative to the output directory. */
+	Outputs []FileHash `toml:"outputs"`
+}
+
+/** @brief The hash of a file. */
+type FileHash struct {
+	/** @brief The path of the file. */
+	Path string `toml:"path"`
+	/** @brief The SHA-256 of the content of the file, empty when the file did not exist. */
+	Hash string `toml:"hash"`
+}
+
+// LoadManifest reads the manifest of the output directory. A missing manifest is empty.
+func LoadManifest(outputDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, MANIFEST_NAME))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{Version: COOK_VERSION}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := toml.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest into the output directory.
+func (m *Manifest) Save(outputDir string) error {
+	sort.Slice(m.Assets, func(i, j int) bool {
+		return m.Assets[i].Source < m.Assets[j].Source
+	})
+	data, err := toml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(outputDir, MANIFEST_NAME), data)
+}
+
+// entry returns the record of the source, nil when it was never cooked.
+func (m *Manifest) entry(source string) *ManifestEntry {
+	for _, e := range m.Assets {
+		if e.Source == source {
+			return e
+		}
+	}
+	return nil
+}
+
+// upToDate tells whether none of the inputs nor of the outputs of the entry changed since it
+// was cooked.
+func (e *ManifestEntry) upToDate(sourceFS fs.FS, outputDir string) bool {
+	for _, input := range e.Inputs {
+		if hashFile(sourceFS, input.Path) != input.Hash {
+			return false
+		}
+	}
+	outputFS := os.DirFS(outputDir)
+	for _, output := range e.Outputs {
+		if hashFile(outputFS, output.Path) != output.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// hashFile returns the hash of the file, empty when it does not exist.
+func hashFile(fsys fs.FS, name string) string {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ""
+	}
+	return hashData(data)
+}
+
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+/**
+ * @brief A file system recording the names of the files looked at, existing or not, so that
+ * the cook of a source is redone when any of them changes.
+ */
+type recordingFS struct {
+	fsys fs.FS
+
+	mutex sync.Mutex
+	names map[string]struct{}
+}
+
+func newRecordingFS(fsys fs.FS) *recordingFS {
+	return &recordingFS{fsys: fsys, names: make(map[string]struct{})}
+}
+
+func (r *recordingFS) record(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.names[name] = struct{}{}
+}
+
+func (r *recordingFS) Open(name string) (fs.File, error) {
+	r.record(name)
+	return r.fsys.Open(name)
+}
+
+func (r *recordingFS) ReadFile(name string) ([]byte, error) {
+	r.record(name)
+	return fs.ReadFile(r.fsys, name)
+}
+
+func (r *recordingFS) Stat(name string) (fs.FileInfo, error) {
+	r.record(name)
+	return fs.Stat(r.fsys, name)
+}
+
+// inputs returns the hashes of the recorded files, sorted by name.
+func (r *recordingFS) inputs() []FileHash {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	inputs := make([]FileHash, 0, len(r.names))
+	for name := range r.names {
+		inputs = append(inputs, FileHash{Path: name, Hash: hashFile(r.fsys, name)})
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].Path < inputs[j].Path
+	})
+	return inputs
+}
+
+// writeFile writes the file through a temporary file, so an interrupted cook never leaves a
+// truncated output behind.
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	temp := name + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, name)
+}
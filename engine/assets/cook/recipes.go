@@ -0,0 +1,313 @@
+package cook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spaghettifunk/anima/engine/assets"
+	"github.com/spaghettifunk/anima/engine/assets/loaders"
+	"github.com/spaghettifunk/anima/engine/assets/vfs"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/** @brief How a kind of source is turned into engine-ready files. */
+type recipe struct {
+	/** @brief The name of the recipe, shown in the reports. */
+	name string
+	/** @brief The extension of the main output, replacing the one of the source. Empty keeps it. */
+	extension string
+	/** @brief Converts the source. Copies it when nil. */
+	cook func(job *job) error
+}
+
+var (
+	modelRecipe    = &recipe{name: "model", extension: ".ksm", cook: cookModel}
+	textureRecipe  = &recipe{name: "texture", extension: ".kti", cook: cookTexture}
+	fontRecipe     = &recipe{name: "font", extension: ".kbf", cook: cookFont}
+	shaderRecipe   = &recipe{name: "shader", extension: ".ksc", cook: cookShader}
+	materialRecipe = &recipe{name: "material", cook: cookMaterial}
+	copyRecipe     = &recipe{name: "copy"}
+)
+
+// The recipe of each source extension. Other files (e.g. GLSL sources, MTL libraries or
+// glTF buffers, read while cooking their models) are not shipped.
+var recipes = map[string]*recipe{
+	".obj":       modelRecipe,
+	".gltf":      modelRecipe,
+	".glb":       modelRecipe,
+	".png":       textureRecipe,
+	".jpg":       textureRecipe,
+	".tga":       textureRecipe,
+	".bmp":       textureRecipe,
+	".fnt":       fontRecipe,
+	".shadercfg": shaderRecipe,
+	".amt":       materialRecipe,
+	".spv":       copyRecipe,
+	".fontcfg":   copyRecipe,
+	".ksf":       copyRecipe,
+	".ttf":       copyRecipe,
+	".otf":       copyRecipe,
+	".ksm":       copyRecipe,
+	".kbf":       copyRecipe,
+	".kti":       copyRecipe,
+	".ksc":       copyRecipe,
+}
+
+// When several sources cook into the same file, the one with the extension listed first wins,
+// like the engine prefers them at runtime. Converted sources win over copied files: those are
+// exports of older tools left next to their source.
+var sourcePreference = []string{".glb", ".gltf", ".obj", ".tga", ".png", ".jpg", ".bmp"}
+
+/** @brief A source being cooked. */
+type job struct {
+	/** @brief The path of the source, relative to the source directory. */
+	source string
+	recipe *recipe
+	/** @brief The path of the main output, relative to the output directory. */
+	output string
+
+	fsys *recordingFS
+	meta *metadata.AssetMeta
+	// The files produced, by path relative to the output directory.
+	outputs map[string][]byte
+	// The order the outputs were produced in.
+	order []string
+}
+
+func newJob(source string, r *recipe) *job {
+	output := source
+	if r.extension != "" {
+		output = strings.TrimSuffix(source, path.Ext(source)) + r.extension
+	}
+	return &job{source: source, recipe: r, output: output, outputs: map[string][]byte{}}
+}
+
+// emit adds a produced file with its sidecar.
+func (j *job) emit(name string, data []byte, meta *metadata.AssetMeta) error {
+	if _, ok := j.outputs[name]; ok {
+		return fmt.Errorf("`%s` produced twice", name)
+	}
+	sidecar, err := toml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	j.outputs[name] = data
+	j.outputs[name+assets.META_EXTENSION] = sidecar
+	j.order = append(j.order, name, name+assets.META_EXTENSION)
+	return nil
+}
+
+// readMeta reads the sidecar of the source. Sources without one get the GUID the asset
+// manager gives them.
+func (j *job) readMeta() error {
+	j.meta = &metadata.AssetMeta{GUID: assets.PathGUID(j.source)}
+	data, err := j.fsys.ReadFile(j.source + assets.META_EXTENSION)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := toml.Unmarshal(data, j.meta); err != nil {
+		return fmt.Errorf("invalid sidecar: %w", err)
+	}
+	return nil
+}
+
+// run cooks the source.
+func (j *job) run(sourceFS fs.FS) error {
+	j.fsys = newRecordingFS(sourceFS)
+	if err := j.readMeta(); err != nil {
+		return err
+	}
+	if j.recipe.cook == nil {
+		data, err := j.fsys.ReadFile(j.source)
+		if err != nil {
+			return err
+		}
+		return j.emit(j.output, data, j.meta)
+	}
+	return j.recipe.cook(j)
+}
+
+// cookModel imports the model with its import settings, bakes its nodes and writes it as a
+// .ksm. The materials it defines and the images it embeds are cooked too.
+func cookModel(j *job) error {
+	// The memory mount collects the embedded images, the materials are written as they are.
+	embedded := vfs.NewMemoryFS()
+	embeddedNames := []string{}
+	materials := map[string][]byte{}
+	materialNames := []string{}
+	loader := &loaders.ModelLoader{
+		FS: j.fsys,
+		GUID: func(name string, resourceType metadata.ResourceType) (string, bool) {
+			// The embedded images get the GUID of their cooked texture.
+			for _, embeddedName := range embeddedNames {
+				if resourceType == metadata.ResourceTypeImage && embeddedName == fmt.Sprintf("textures/%s%s", name, path.Ext(embeddedName)) {
+					return assets.PathGUID(strings.TrimSuffix(embeddedName, path.Ext(embeddedName)) + textureRecipe.extension), true
+				}
+			}
+			return assets.ReferenceGUID(j.fsys, name, resourceType)
+		},
+		WriteFile: func(name string, data []byte) error {
+			materials[name] = data
+			materialNames = append(materialNames, name)
+			return nil
+		},
+		EmbedFile: func(name string, data []byte) error {
+			embeddedNames = append(embeddedNames, name)
+			return embedded.WriteFile(name, data)
+		},
+	}
+	var params interface{}
+	if j.meta.Model != nil {
+		params = j.meta.Model
+	}
+	resource, err := loader.Load(j.source, metadata.ResourceTypeMesh, params)
+	if err != nil {
+		return err
+	}
+	defer loader.Unload(resource)
+
+	var buffer bytes.Buffer
+	if err := loaders.WriteKSM(&buffer, resource.Data.([]*metadata.GeometryConfig)); err != nil {
+		return err
+	}
+	// The settings are baked in the vertices.
+	meta := *j.meta
+	meta.Model = nil
+	if err := j.emit(j.output, buffer.Bytes(), &meta); err != nil {
+		return err
+	}
+
+	for _, name := range materialNames {
+		if err := j.emit(name, materials[name], &metadata.AssetMeta{GUID: assets.PathGUID(name)}); err != nil {
+			return err
+		}
+	}
+	for _, name := range embeddedNames {
+		output := strings.TrimSuffix(name, path.Ext(name)) + textureRecipe.extension
+		data, err := encodeTexture(embedded, name, true)
+		if err != nil {
+			return fmt.Errorf("embedded image `%s`: %w", name, err)
+		}
+		if err := j.emit(output, data, &metadata.AssetMeta{GUID: assets.PathGUID(output)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cookTexture decodes the image, flipped unless its import settings tell otherwise like the
+// texture system loads it, and writes it with its mips as a .kti.
+func cookTexture(j *job) error {
+	flip := true
+	if j.meta.Image != nil && j.meta.Image.FlipY != nil {
+		flip = *j.meta.Image.FlipY
+	}
+	data, err := encodeTexture(j.fsys, j.source, flip)
+	if err != nil {
+		return err
+	}
+	return j.emit(j.output, data, j.meta)
+}
+
+func encodeTexture(fsys fs.FS, name string, flip bool) ([]byte, error) {
+	loader := &loaders.ImageLoader{FS: fsys}
+	resource, err := loader.Load(name, metadata.ResourceTypeImage, &metadata.ImageResourceParams{FlipY: flip})
+	if err != nil {
+		return nil, err
+	}
+	defer loader.Unload(resource)
+
+	image := resource.Data.(*metadata.ImageResourceData)
+	loaders.GenerateMips(image)
+	var buffer bytes.Buffer
+	if err := loaders.WriteKTI(&buffer, image, flip); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// cookFont parses the .fnt descriptor and writes it as a .kbf. Its pages must sit next to it,
+// they are cooked as textures and referenced without extension.
+func cookFont(j *job) error {
+	loader := &loaders.BitmapFontLoader{FS: j.fsys}
+	resource, err := loader.Load(j.source, metadata.ResourceTypeBitmapFont, nil)
+	if err != nil {
+		return err
+	}
+	defer loader.Unload(resource)
+
+	font := resource.Data.(*metadata.BitmapFontResourceData)
+	for _, page := range font.Pages {
+		pagePath := path.Join(path.Dir(j.source), page.File)
+		if _, err := j.fsys.Stat(pagePath); err != nil {
+			return fmt.Errorf("page `%s` not found", pagePath)
+		}
+		page.File = strings.TrimSuffix(page.File, path.Ext(page.File))
+	}
+	var buffer bytes.Buffer
+	if err := loaders.WriteKBF(&buffer, font); err != nil {
+		return err
+	}
+	return j.emit(j.output, buffer.Bytes(), j.meta)
+}
+
+// cookMaterial parses the .amt and writes it back referencing its textures and its shader by
+// GUID, so renaming or moving them does not break it.
+func cookMaterial(j *job) error {
+	loader := &loaders.MaterialLoader{FS: j.fsys}
+	resource, err := loader.Load(j.source, metadata.ResourceTypeMaterial, nil)
+	if err != nil {
+		return err
+	}
+	defer loader.Unload(resource)
+
+	material := loaders.ReferenceByGUID(resource.Data.(*metadata.MaterialConfig), func(name string, resourceType metadata.ResourceType) (string, bool) {
+		return assets.ReferenceGUID(j.fsys, name, resourceType)
+	})
+	var buffer bytes.Buffer
+	if err := loaders.WriteAMTFile(&buffer, material); err != nil {
+		return err
+	}
+	return j.emit(j.output, buffer.Bytes(), j.meta)
+}
+
+// cookShader parses and validates the .shadercfg, and writes it as a .ksc.
+func cookShader(j *job) error {
+	loader := &loaders.ShaderLoader{FS: j.fsys, Strict: true}
+	resource, err := loader.Load(j.source, metadata.ResourceTypeShader, nil)
+	if err != nil {
+		return err
+	}
+	defer loader.Unload(resource)
+
+	config := resource.Data.(*metadata.ShaderConfig)
+	errs := []error{}
+	seen := map[string]bool{}
+	for _, c := range append([]*metadata.ShaderConfig{config}, config.Variants()...) {
+		for _, stageFile := range c.StageFilenames {
+			if seen[stageFile] {
+				continue
+			}
+			seen[stageFile] = true
+			if _, err := j.fsys.Stat(stageFile); err != nil {
+				errs = append(errs, fmt.Errorf("stage file `%s` not found", stageFile))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	var buffer bytes.Buffer
+	if err := loaders.WriteKSC(&buffer, config); err != nil {
+		return err
+	}
+	return j.emit(j.output, buffer.Bytes(), j.meta)
+}
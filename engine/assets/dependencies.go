@@ -0,0 +1,358 @@
+package assets
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/** @brief A reference from an asset to another one, e.g. from a material to its diffuse texture. */
+type Dependency struct {
+	/** @brief The name the asset is referenced by (e.g. bricks_diff). */
+	Name string
+	/** @brief The type of the referenced asset. */
+	Type metadata.ResourceType
+	/**
+	 * @brief The path of the referenced asset in the virtual file system. When missing, the
+	 * path it was expected at.
+	 */
+	Path string
+	/** @brief True when no mounted file provides the referenced asset. */
+	Missing bool
+}
+
+/**
+ * @brief The references between the assets, recorded as they are loaded. Edges go from an
+ * asset to the assets it references, by path.
+ */
+type dependencyGraph struct {
+	mutex sync.RWMutex
+	// The references of each asset.
+	references map[string][]Dependency
+	// The assets referencing each path.
+	dependents map[string]map[string]struct{}
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{
+		references: make(map[string][]Dependency),
+		dependents: make(map[string]map[string]struct{}),
+	}
+}
+
+// set replaces the references of the asset.
+func (g *dependencyGraph) set(assetPath string, references []Dependency) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.unlink(assetPath)
+	g.references[assetPath] = references
+	for _, r := range references {
+		if g.dependents[r.Path] == nil {
+			g.dependents[r.Path] = make(map[string]struct{})
+		}
+		g.dependents[r.Path][assetPath] = struct{}{}
+	}
+}
+
+// remove forgets the references of the asset. The assets referencing it keep their edges,
+// so they show up as missing until it comes back.
+func (g *dependencyGraph) remove(assetPath string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.unlink(assetPath)
+	delete(g.references, assetPath)
+}
+
+// move points the references made by GUID to the asset at the old path to its new path.
+// References by name keep the old path, the asset is missing for them.
+func (g *dependencyGraph) move(oldPath string, newPath string, guid string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for dependent := range g.dependents[oldPath] {
+		moved := false
+		references := g.references[dependent]
+		for i := range references {
+			if references[i].Path == oldPath && strings.EqualFold(references[i].Name, guid) {
+				references[i].Path = newPath
+				moved = true
+			}
+		}
+		if !moved {
+			continue
+		}
+		// Still referencing the old path by name otherwise.
+		stillOld := false
+		for _, r := range references {
+			if r.Path == oldPath {
+				stillOld = true
+				break
+			}
+		}
+		if !stillOld {
+			delete(g.dependents[oldPath], dependent)
+		}
+		if g.dependents[newPath] == nil {
+			g.dependents[newPath] = make(map[string]struct{})
+		}
+		g.dependents[newPath][dependent] = struct{}{}
+	}
+	if len(g.dependents[oldPath]) == 0 {
+		delete(g.dependents, oldPath)
+	}
+}
+
+func (g *dependencyGraph) unlink(assetPath string) {
+	for _, r := range g.references[assetPath] {
+		delete(g.dependents[r.Path], assetPath)
+		if len(g.dependents[r.Path]) == 0 {
+			delete(g.dependents, r.Path)
+		}
+	}
+}
+
+func (g *dependencyGraph) referencesOf(assetPath string) []Dependency {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	return append([]Dependency(nil), g.references[assetPath]...)
+}
+
+// dependentsOf returns the assets referencing the path, sorted. With recursive, the assets
+// referencing those are included too, and so on.
+func (g *dependencyGraph) dependentsOf(assetPath string, recursive bool) []string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	seen := map[string]struct{}{assetPath: {}}
+	queue := []string{assetPath}
+	result := []string{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for dependent := range g.dependents[current] {
+			if _, ok := seen[dependent]; ok {
+				continue
+			}
+			seen[dependent] = struct{}{}
+			result = append(result, dependent)
+			if recursive {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// cycles returns the reference cycles, each as the paths along it, the first one repeated
+// at the end.
+func (g *dependencyGraph) cycles() [][]string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, r := range g.references[node] {
+			switch state[r.Path] {
+			case unvisited:
+				visit(r.Path)
+			case visiting:
+				// Back edge, the cycle is the stack from the referenced asset on.
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == r.Path {
+						cycle := append([]string(nil), stack[i:]...)
+						cycles = append(cycles, append(cycle, r.Path))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = visited
+	}
+
+	// Sorted, so the same cycles are reported from the same nodes every time.
+	nodes := make([]string, 0, len(g.references))
+	for node := range g.references {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+	return cycles
+}
+
+// missing returns the references to assets no mount provides, by referencing asset.
+func (g *dependencyGraph) missing(exists func(string) bool) map[string][]Dependency {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	result := map[string][]Dependency{}
+	for assetPath, references := range g.references {
+		for _, r := range references {
+			if !exists(r.Path) {
+				result[assetPath] = append(result[assetPath], r)
+			}
+		}
+	}
+	return result
+}
+
+// Dependencies returns the assets referenced by the asset at the given path (e.g.
+// materials/bricks.amt), as recorded when it was last loaded.
+func (am *AssetManager) Dependencies(assetPath string) []Dependency {
+	references := am.dependencies.referencesOf(assetPath)
+	for i := range references {
+		references[i].Missing = !am.isIndexed(references[i].Path)
+	}
+	return references
+}
+
+// Dependents returns the paths of the loaded assets referencing the asset at the given path
+// (e.g. textures/bricks_diff.tga). With recursive, the assets referencing those are
+// included too, and so on.
+func (am *AssetManager) Dependents(assetPath string, recursive bool) []string {
+	return am.dependencies.dependentsOf(assetPath, recursive)
+}
+
+// CheckDependencies returns an error for every reference to a missing asset and every
+// reference cycle among the loaded assets.
+func (am *AssetManager) CheckDependencies() []error {
+	errs := []error{}
+
+	missing := am.dependencies.missing(am.isIndexed)
+	referencing := make([]string, 0, len(missing))
+	for assetPath := range missing {
+		referencing = append(referencing, assetPath)
+	}
+	sort.Strings(referencing)
+	for _, assetPath := range referencing {
+		for _, r := range missing[assetPath] {
+			errs = append(errs, fmt.Errorf("`%s` references missing asset `%s` (%s)", assetPath, r.Name, r.Path))
+		}
+	}
+
+	for _, cycle := range am.dependencies.cycles() {
+		errs = append(errs, fmt.Errorf("reference cycle: %s", strings.Join(cycle, " -> ")))
+	}
+	return errs
+}
+
+// recordDependencies stores the references found in the freshly loaded resource.
+func (am *AssetManager) recordDependencies(assetPath string, resource *metadata.Resource) {
+	am.dependencies.set(assetPath, am.findReferences(assetPath, resource))
+}
+
+// findReferences returns the assets referenced by the resource data.
+func (am *AssetManager) findReferences(assetPath string, resource *metadata.Resource) []Dependency {
+	references := []Dependency{}
+	add := func(name string, resourceType metadata.ResourceType) {
+		if name == "" {
+			return
+		}
+		p, _, _ := am.assetPath(name, resourceType)
+		for _, r := range references {
+			if r.Path == p {
+				return
+			}
+		}
+		references = append(references, Dependency{Name: name, Type: resourceType, Path: p})
+	}
+
+	switch data := resource.Data.(type) {
+	case *metadata.MaterialConfig:
+		add(data.DiffuseMapName, metadata.ResourceTypeImage)
+		add(data.SpecularMapName, metadata.ResourceTypeImage)
+		add(data.NormalMapName, metadata.ResourceTypeImage)
+		add(data.ShaderName, metadata.ResourceTypeShader)
+	case *metadata.ShaderConfig:
+		for _, config := range append([]*metadata.ShaderConfig{data}, data.Variants()...) {
+			for _, stageFile := range config.StageFilenames {
+				add(stageFile, metadata.ResourceTypeBinary)
+			}
+		}
+	case *metadata.BitmapFontResourceData:
+		// The pages sit next to the font, or with the other textures. Cooked fonts name them
+		// without extension.
+		for _, page := range data.Pages {
+			pagePath := path.Join(path.Dir(assetPath), page.File)
+			if !am.isIndexed(pagePath) {
+				pagePath, _, _ = am.findAsset(path.Dir(assetPath), page.File, imageExtensions)
+			}
+			if am.isIndexed(pagePath) {
+				references = append(references, Dependency{Name: page.File, Type: metadata.ResourceTypeImage, Path: pagePath})
+				continue
+			}
+			add(strings.TrimSuffix(page.File, path.Ext(page.File)), metadata.ResourceTypeImage)
+		}
+	case *metadata.ModelConfig:
+		for _, g := range data.Geometries {
+			add(g.MaterialName, metadata.ResourceTypeMaterial)
+		}
+	case []*metadata.GeometryConfig:
+		for _, g := range data {
+			add(g.MaterialName, metadata.ResourceTypeMaterial)
+		}
+	}
+	return references
+}
+
+// scanDependencies loads the assets referencing others by name, to record their references
+// before anything is loaded, and logs the missing references and the cycles. Models are
+// skipped, importing them takes too long.
+func (am *AssetManager) scanDependencies() []error {
+	am.mutex.RLock()
+	paths := []string{}
+	for assetPath, asset := range am.assets {
+		switch asset.Type {
+		case metadata.ResourceTypeMaterial, metadata.ResourceTypeShader, metadata.ResourceTypeBitmapFont:
+			paths = append(paths, assetPath)
+		}
+	}
+	am.mutex.RUnlock()
+	sort.Strings(paths)
+
+	errs := []error{}
+	for _, assetPath := range paths {
+		assetType := determineAssetType(assetPath)
+		loader, ok := am.loaders[assetType]
+		if !ok {
+			continue
+		}
+		// Only the files LoadAsset reads, e.g. not the .kbf next to the .fnt fonts.
+		if p, _, _ := am.assetPath(assetName(assetPath, assetType), assetType); p != assetPath {
+			continue
+		}
+		resource, err := loader.Load(assetPath, assetType, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to scan `%s`: %w", assetPath, err))
+			continue
+		}
+		am.recordDependencies(assetPath, resource)
+		if err := loader.Unload(resource); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return append(errs, am.CheckDependencies()...)
+}
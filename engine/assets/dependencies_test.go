@@ -0,0 +1,225 @@
+package assets
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+func TestMain(m *testing.M) {
+	if err := core.InitializeLogger(core.WarnLevel); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newMemoryAssetManager returns an asset manager reading the given files from memory, over an
+// empty assets directory. Nothing is written to disk.
+func newMemoryAssetManager(t *testing.T, files map[string]string) *AssetManager {
+	t.Helper()
+	am, err := NewAssetManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	am.SetReadOnly(true)
+	for name, data := range files {
+		if err := am.memory.WriteFile(name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := am.Initialize(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := am.Shutdown(); err != nil {
+			t.Error(err)
+		}
+	})
+	return am
+}
+
+func TestDependencyGraph(t *testing.T) {
+	g := newDependencyGraph()
+	g.set("materials/a.amt", []Dependency{{Name: "diff", Path: "textures/diff.png"}, {Name: "shader", Path: "shaders/s.shadercfg"}})
+	g.set("materials/b.amt", []Dependency{{Name: "diff", Path: "textures/diff.png"}})
+	g.set("shaders/s.shadercfg", []Dependency{{Name: "shaders/s.vert.spv", Path: "shaders/s.vert.spv"}})
+	g.set("models/m.obj", []Dependency{{Name: "a", Path: "materials/a.amt"}})
+
+	tests := []struct {
+		path      string
+		recursive bool
+		want      []string
+	}{
+		{"textures/diff.png", false, []string{"materials/a.amt", "materials/b.amt"}},
+		{"textures/diff.png", true, []string{"materials/a.amt", "materials/b.amt", "models/m.obj"}},
+		{"shaders/s.vert.spv", false, []string{"shaders/s.shadercfg"}},
+		{"shaders/s.vert.spv", true, []string{"materials/a.amt", "models/m.obj", "shaders/s.shadercfg"}},
+		{"models/m.obj", true, []string{}},
+	}
+	for _, test := range tests {
+		if got := g.dependentsOf(test.path, test.recursive); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("dependentsOf(%q, %v) = %v, want %v", test.path, test.recursive, got, test.want)
+		}
+	}
+
+	// Setting the references again replaces the edges.
+	g.set("materials/b.amt", []Dependency{{Name: "other", Path: "textures/other.png"}})
+	if got := g.dependentsOf("textures/diff.png", false); !reflect.DeepEqual(got, []string{"materials/a.amt"}) {
+		t.Errorf("got dependents %v after replacing the references of b", got)
+	}
+
+	// A removed asset stays referenced, as missing.
+	g.remove("shaders/s.shadercfg")
+	if got := g.dependentsOf("shaders/s.vert.spv", false); len(got) != 0 {
+		t.Errorf("got dependents %v of the stage of the removed shader", got)
+	}
+	missing := g.missing(func(path string) bool { return path != "shaders/s.shadercfg" })
+	if want := map[string][]Dependency{"materials/a.amt": {{Name: "shader", Path: "shaders/s.shadercfg"}}}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("got missing %v, want %v", missing, want)
+	}
+}
+
+// Only the references made by GUID follow an asset moved.
+func TestDependencyGraphMove(t *testing.T) {
+	g := newDependencyGraph()
+	g.set("materials/guid.amt", []Dependency{{Name: testGUID, Path: "textures/old.png"}})
+	g.set("materials/name.amt", []Dependency{{Name: "old", Path: "textures/old.png"}})
+
+	g.move("textures/old.png", "textures/new.png", testGUID)
+	if got := g.dependentsOf("textures/new.png", false); !reflect.DeepEqual(got, []string{"materials/guid.amt"}) {
+		t.Errorf("got dependents %v of the new path", got)
+	}
+	if got := g.dependentsOf("textures/old.png", false); !reflect.DeepEqual(got, []string{"materials/name.amt"}) {
+		t.Errorf("got dependents %v of the old path", got)
+	}
+	if got := g.referencesOf("materials/guid.amt"); got[0].Path != "textures/new.png" {
+		t.Errorf("got references %v", got)
+	}
+}
+
+func TestDependencyGraphCycles(t *testing.T) {
+	g := newDependencyGraph()
+	g.set("a", []Dependency{{Path: "b"}})
+	g.set("b", []Dependency{{Path: "c"}, {Path: "leaf"}})
+	g.set("c", []Dependency{{Path: "a"}})
+	g.set("self", []Dependency{{Path: "self"}})
+	g.set("d", []Dependency{{Path: "leaf"}})
+
+	want := [][]string{{"a", "b", "c", "a"}, {"self", "self"}}
+	if got := g.cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got cycles %v, want %v", got, want)
+	}
+}
+
+const dependencyTestMaterial = `version=0.1
+name=bricks
+diffuse_map_name=bricks_diff
+normal_map_name=bricks_norm
+shader=Shader.Builtin.Material
+`
+
+func TestAssetManagerDependencies(t *testing.T) {
+	am := newMemoryAssetManager(t, map[string]string{
+		"materials/bricks.amt":     dependencyTestMaterial,
+		"textures/bricks_diff.png": "not decoded",
+	})
+
+	// The materials are scanned on startup, before anything is loaded.
+	dependencies := am.Dependencies("materials/bricks.amt")
+	if len(dependencies) != 3 {
+		t.Fatalf("got dependencies %+v, want 3", dependencies)
+	}
+	want := []struct {
+		name    string
+		path    string
+		missing bool
+	}{
+		{"bricks_diff", "textures/bricks_diff.png", false},
+		{"bricks_norm", "", true},
+		{"Shader.Builtin.Material", "", true},
+	}
+	for i, w := range want {
+		d := dependencies[i]
+		if d.Name != w.name || d.Missing != w.missing || w.path != "" && d.Path != w.path {
+			t.Errorf("dependency %d: got %+v, want %s at %q missing %v", i, d, w.name, w.path, w.missing)
+		}
+	}
+	if got := am.Dependents("textures/bricks_diff.png", true); !reflect.DeepEqual(got, []string{"materials/bricks.amt"}) {
+		t.Errorf("got dependents %v", got)
+	}
+	if errs := am.CheckDependencies(); len(errs) != 2 {
+		t.Errorf("got errors %v, want the 2 missing references", errs)
+	}
+}
+
+// A changed texture drops the cached material referencing it, and the change cascades to it.
+func TestAssetManagerCascade(t *testing.T) {
+	if err := core.EventSystemInitialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer core.EventSystemShutdown()
+
+	am := newMemoryAssetManager(t, map[string]string{
+		"materials/bricks.amt":     dependencyTestMaterial,
+		"textures/bricks_diff.png": "not decoded",
+	})
+
+	resource, err := am.LoadAsset("bricks", metadata.ResourceTypeMaterial, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := am.UnloadAsset(resource); err != nil {
+		t.Fatal(err)
+	}
+	if stats := am.CacheStats(); stats.Entries != 1 {
+		t.Fatalf("got %d cached assets, want the released material", stats.Entries)
+	}
+
+	changed := []AssetChangedEvent{}
+	core.Subscribe(core.Events(), func(event AssetChangedEvent) {
+		changed = append(changed, event)
+	})
+	if err := am.memory.WriteFile("textures/bricks_diff.png", []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	am.handleFileEvent("textures/bricks_diff.png")
+	am.scheduleChanged("textures/bricks_diff.png")
+	if stats := am.CacheStats(); stats.Entries != 0 {
+		t.Errorf("got %d cached assets, the material should be invalidated", stats.Entries)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(changed) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		core.EventDispatchQueued()
+	}
+	// The debounce timers fire concurrently, the events may come in any order.
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Path < changed[j].Path })
+	want := []AssetChangedEvent{
+		{Path: "materials/bricks.amt", Name: "bricks", Type: metadata.ResourceTypeMaterial, GUID: PathGUID("materials/bricks.amt"), Cause: "textures/bricks_diff.png"},
+		{Path: "textures/bricks_diff.png", Name: "bricks_diff", Type: metadata.ResourceTypeImage, GUID: PathGUID("textures/bricks_diff.png")},
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("got changes %+v, want %+v", changed, want)
+	}
+
+	// The asset changing itself wins over the cascade.
+	changed = changed[:0]
+	am.scheduleChanged("textures/bricks_diff.png")
+	am.scheduleChanged("materials/bricks.amt")
+	deadline = time.Now().Add(5 * time.Second)
+	for len(changed) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		core.EventDispatchQueued()
+	}
+	for _, event := range changed {
+		if event.Cause != "" {
+			t.Errorf("got %s changed because of %s, it changed itself", event.Path, event.Cause)
+		}
+	}
+}
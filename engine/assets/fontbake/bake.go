@@ -0,0 +1,250 @@
+/*
+Package fontbake rasterizes TrueType and OpenType fonts offline into bitmap fonts: an AngelCode
+BMFont descriptor (.fnt) and its atlas (.png), loaded by the BitmapFontLoader. Glyphs are packed
+with stb_truetype, like the font system does for system fonts at runtime, so a baked font looks
+the same as the system font it comes from.
+*/
+package fontbake
+
+/*
+#cgo CFLAGS: -I../../vendors
+#cgo linux LDFLAGS: -lm
+#include <stdlib.h>
+#define STBTT_STATIC
+#define STB_TRUETYPE_IMPLEMENTATION
+#include "../../vendors/stb_truetype.h"
+
+// Packs the codepoints into the pixels, with one range. Returns 0 when some did not fit.
+static int pack(const unsigned char *font, int index, float size, int *codepoints, int count,
+		int padding, unsigned char *pixels, int width, int height, stbtt_packedchar *chars) {
+	stbtt_pack_context context;
+	if (!stbtt_PackBegin(&context, pixels, width, height, 0, padding, NULL)) {
+		return 0;
+	}
+	stbtt_pack_range range = {0};
+	range.font_size = size;
+	range.array_of_unicode_codepoints = codepoints;
+	range.num_chars = count;
+	range.chardata_for_range = chars;
+	int result = stbtt_PackFontRanges(&context, font, index, &range, 1);
+	stbtt_PackEnd(&context);
+	return result;
+}
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The codepoint of the glyph drawn for the characters the font lacks.
+const UNKNOWN_CODEPOINT = -1
+
+// The largest atlas when the settings give none.
+const DEFAULT_MAX_ATLAS_SIZE = 4096
+
+/** @brief A range of codepoints, both ends included. */
+type Range struct {
+	First rune
+	Last  rune
+}
+
+/** @brief The settings of a bake. */
+type Config struct {
+	/** @brief The height of the glyphs in pixels, like the size of the system fonts. */
+	Size int
+	/** @brief The index of the font in a collection (.ttc), 0 otherwise. */
+	Index int
+	/** @brief The name of the face. Empty takes the family name of the font. */
+	Face string
+	/** @brief The codepoints baked. Empty bakes the printable ASCII characters. */
+	Ranges []Range
+	/** @brief The pixels left between the glyphs in the atlas. */
+	Padding int
+	/**
+	 * @brief The largest width and height of the atlas, the smallest one fitting the glyphs is
+	 * used. 0 is DEFAULT_MAX_ATLAS_SIZE.
+	 */
+	MaxAtlasSize int
+}
+
+/** @brief A baked font. */
+type Font struct {
+	/** @brief The metrics, glyphs and kernings of the font. */
+	Data *metadata.FontData
+	/** @brief The atlas of the glyphs, white with the coverage in every channel. */
+	Atlas *image.RGBA
+	/** @brief The codepoints requested that the font has no glyph for, left out. */
+	Missing []rune
+}
+
+// NewConfig returns the settings baking the printable ASCII characters at the given size.
+func NewConfig(size int) *Config {
+	return &Config{
+		Size:         size,
+		Ranges:       []Range{{First: 32, Last: 126}},
+		Padding:      1,
+		MaxAtlasSize: DEFAULT_MAX_ATLAS_SIZE,
+	}
+}
+
+// Bake rasterizes the glyphs of the font into an atlas, the smallest square power of two
+// fitting them.
+func Bake(fontBinary []byte, config *Config) (*Font, error) {
+	if config.Size <= 0 {
+		return nil, fmt.Errorf("invalid size %d", config.Size)
+	}
+	if len(fontBinary) == 0 {
+		return nil, errors.New("empty font")
+	}
+	// stb_truetype keeps pointing at the data, it must not move.
+	data := (*C.uchar)(C.CBytes(fontBinary))
+	defer C.free(unsafe.Pointer(data))
+
+	if count := int(C.stbtt_GetNumberOfFonts(data)); config.Index < 0 || config.Index >= count {
+		return nil, fmt.Errorf("font index %d out of range, the file has %d fonts", config.Index, count)
+	}
+	offset := C.stbtt_GetFontOffsetForIndex(data, C.int(config.Index))
+	var info C.stbtt_fontinfo
+	if C.stbtt_InitFont(&info, data, offset) == 0 {
+		return nil, errors.New("invalid font")
+	}
+
+	font := &Font{
+		Data: &metadata.FontData{
+			FontType: metadata.FONT_TYPE_BITMAP,
+			Face:     config.Face,
+			Size:     uint32(config.Size),
+		},
+	}
+	if font.Data.Face == "" {
+		font.Data.Face = familyName(&info)
+	}
+
+	// The unknown glyph first, like the system fonts have it.
+	codepoints := []C.int{UNKNOWN_CODEPOINT}
+	glyphIndices := []C.int{0}
+	for _, cp := range codepointsOf(config.Ranges) {
+		glyphIndex := C.stbtt_FindGlyphIndex(&info, C.int(cp))
+		if glyphIndex == 0 {
+			font.Missing = append(font.Missing, cp)
+			continue
+		}
+		codepoints = append(codepoints, C.int(cp))
+		glyphIndices = append(glyphIndices, glyphIndex)
+	}
+
+	scale := float32(C.stbtt_ScaleForPixelHeight(&info, C.float(config.Size)))
+	var ascent, descent, lineGap C.int
+	C.stbtt_GetFontVMetrics(&info, &ascent, &descent, &lineGap)
+	baseline := round(float32(ascent) * scale)
+	font.Data.Baseline = baseline
+	font.Data.LineHeight = round(float32(ascent-descent+lineGap) * scale)
+
+	maxSize := config.MaxAtlasSize
+	if maxSize <= 0 {
+		maxSize = DEFAULT_MAX_ATLAS_SIZE
+	}
+	chars := make([]C.stbtt_packedchar, len(codepoints))
+	var pixels []uint8
+	size := 64
+	for {
+		if size > maxSize {
+			return nil, fmt.Errorf("the glyphs do not fit in a %dx%d atlas", maxSize, maxSize)
+		}
+		pixels = make([]uint8, size*size)
+		if C.pack(data, C.int(config.Index), C.float(config.Size), &codepoints[0], C.int(len(codepoints)),
+			C.int(config.Padding), (*C.uchar)(&pixels[0]), C.int(size), C.int(size), &chars[0]) != 0 {
+			break
+		}
+		size *= 2
+	}
+	font.Data.AtlasSizeX = int32(size)
+	font.Data.AtlasSizeY = int32(size)
+
+	font.Atlas = image.NewRGBA(image.Rect(0, 0, size, size))
+	for i, p := range pixels {
+		font.Atlas.Pix[i*4+0] = p
+		font.Atlas.Pix[i*4+1] = p
+		font.Atlas.Pix[i*4+2] = p
+		font.Atlas.Pix[i*4+3] = p
+	}
+
+	font.Data.Glyphs = make([]*metadata.FontGlyph, len(codepoints))
+	for i, pc := range chars {
+		font.Data.Glyphs[i] = &metadata.FontGlyph{
+			Codepoint: int32(codepoints[i]),
+			X:         uint16(pc.x0),
+			Y:         uint16(pc.y0),
+			Width:     uint16(pc.x1 - pc.x0),
+			Height:    uint16(pc.y1 - pc.y0),
+			XOffset:   int16(round(float32(pc.xoff))),
+			// From the top of the line, as in BMFont descriptors, not from the baseline.
+			YOffset:  int16(baseline + round(float32(pc.yoff))),
+			XAdvance: int16(round(float32(pc.xadvance))),
+			PageID:   0,
+		}
+	}
+
+	// Every pair of baked glyphs, the kerning may come from the kern or the GPOS table.
+	for i := 1; i < len(codepoints); i++ {
+		for j := 1; j < len(codepoints); j++ {
+			advance := C.stbtt_GetGlyphKernAdvance(&info, glyphIndices[i], glyphIndices[j])
+			if amount := round(float32(advance) * scale); amount != 0 {
+				font.Data.Kernings = append(font.Data.Kernings, &metadata.FontKerning{
+					Codepoint0: int32(codepoints[i]),
+					Codepoint1: int32(codepoints[j]),
+					Amount:     int16(amount),
+				})
+			}
+		}
+	}
+	return font, nil
+}
+
+// codepointsOf returns the codepoints of the ranges, sorted and without duplicates. No ranges
+// are the printable ASCII characters.
+func codepointsOf(ranges []Range) []rune {
+	if len(ranges) == 0 {
+		ranges = []Range{{First: 32, Last: 126}}
+	}
+	codepoints := []rune{}
+	for _, r := range ranges {
+		for cp := r.First; cp <= r.Last; cp++ {
+			codepoints = append(codepoints, cp)
+		}
+	}
+	slices.Sort(codepoints)
+	return slices.Compact(codepoints)
+}
+
+// familyName returns the family name from the name table of the font, in English when there
+// are several.
+func familyName(info *C.stbtt_fontinfo) string {
+	var length C.int
+	// Windows, Unicode BMP, English (US): big-endian UTF-16.
+	if name := C.stbtt_GetFontNameString(info, &length, C.int(C.STBTT_PLATFORM_ID_MICROSOFT), C.int(C.STBTT_MS_EID_UNICODE_BMP), C.int(C.STBTT_MS_LANG_ENGLISH), 1); name != nil {
+		raw := C.GoBytes(unsafe.Pointer(name), length)
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = uint16(raw[i*2])<<8 | uint16(raw[i*2+1])
+		}
+		return string(utf16.Decode(units))
+	}
+	// Mac, Roman, English.
+	if name := C.stbtt_GetFontNameString(info, &length, C.int(C.STBTT_PLATFORM_ID_MAC), C.int(C.STBTT_MAC_EID_ROMAN), C.int(C.STBTT_MAC_LANG_ENGLISH), 1); name != nil {
+		return string(C.GoBytes(unsafe.Pointer(name), length))
+	}
+	return ""
+}
+
+func round(v float32) int32 {
+	return int32(math.Round(float64(v)))
+}
@@ -0,0 +1,104 @@
+package fontbake
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/assets/loaders"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+func bakeUbuntuMono(t *testing.T, ranges string) *Font {
+	t.Helper()
+	fontBinary, err := os.ReadFile(filepath.Join("..", "..", "..", "assets", "fonts", "UbuntuMono-R.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewConfig(16)
+	if config.Ranges, err = ParseRanges(ranges); err != nil {
+		t.Fatal(err)
+	}
+	font, err := Bake(fontBinary, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestBakeGlyphs(t *testing.T) {
+	// Printable ASCII, Latin-1 and a few CJK ideographs the font lacks. The ranges overlap.
+	font := bakeUbuntuMono(t, "32-126,0xA0-0xFF,0x41-0x5A,0x4E00-0x4E03")
+
+	if want := []rune{0x4E00, 0x4E01, 0x4E02, 0x4E03}; !reflect.DeepEqual(font.Missing, want) {
+		t.Errorf("got missing %U, want %U", font.Missing, want)
+	}
+	// The invalid glyph, then the 95 ASCII and 96 Latin-1 characters once each.
+	glyphs := font.Data.Glyphs
+	if len(glyphs) != 1+95+96 {
+		t.Fatalf("got %d glyphs, want %d", len(glyphs), 1+95+96)
+	}
+	if glyphs[0].Codepoint != UNKNOWN_CODEPOINT {
+		t.Errorf("got codepoint %d for the first glyph, want the invalid glyph %d", glyphs[0].Codepoint, UNKNOWN_CODEPOINT)
+	}
+	codepoints := []int32{}
+	for _, g := range glyphs[1:] {
+		codepoints = append(codepoints, g.Codepoint)
+	}
+	if !slices.IsSorted(codepoints) || codepoints[0] != 32 || codepoints[94] != 126 || codepoints[95] != 0xA0 || codepoints[len(codepoints)-1] != 0xFF {
+		t.Errorf("got codepoints %v", codepoints)
+	}
+
+	for _, g := range glyphs {
+		if int32(g.X)+int32(g.Width) > font.Data.AtlasSizeX || int32(g.Y)+int32(g.Height) > font.Data.AtlasSizeY {
+			t.Errorf("glyph %d at %d,%d of %dx%d is out of the atlas", g.Codepoint, g.X, g.Y, g.Width, g.Height)
+		}
+	}
+	// A monospaced font.
+	if glyphs[1].XAdvance <= 0 || glyphs[len(glyphs)-1].XAdvance != glyphs[1].XAdvance {
+		t.Errorf("got advances %d and %d", glyphs[1].XAdvance, glyphs[len(glyphs)-1].XAdvance)
+	}
+	if font.Data.Face != "Ubuntu Mono" || font.Data.Size != 16 || font.Data.LineHeight <= 0 || font.Data.Baseline <= 0 {
+		t.Errorf("got face %q of size %d, line height %d and baseline %d", font.Data.Face, font.Data.Size, font.Data.LineHeight, font.Data.Baseline)
+	}
+}
+
+// The saved descriptor loads back as it was baked.
+func TestBakeSaveLoad(t *testing.T) {
+	font := bakeUbuntuMono(t, "32-126")
+	dir := t.TempDir()
+	if err := font.Save(filepath.Join(dir, "UbuntuMono16px.fnt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "UbuntuMono16px_0.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &loaders.BitmapFontLoader{FS: os.DirFS(dir)}
+	resource, err := loader.Load("UbuntuMono16px.fnt", metadata.ResourceTypeBitmapFont, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loader.Unload(resource)
+	loaded := resource.Data.(*metadata.BitmapFontResourceData)
+
+	if len(loaded.Pages) != 1 || loaded.Pages[0].ID != 0 || loaded.Pages[0].File != "UbuntuMono16px_0.png" {
+		t.Errorf("got pages %+v", loaded.Pages)
+	}
+	got, want := loaded.Data, font.Data
+	if got.Face != want.Face || got.Size != want.Size || got.LineHeight != want.LineHeight || got.Baseline != want.Baseline ||
+		got.AtlasSizeX != want.AtlasSizeX || got.AtlasSizeY != want.AtlasSizeY {
+		t.Errorf("got %s %d, line height %d, baseline %d, atlas %dx%d, want %s %d, %d, %d, %dx%d",
+			got.Face, got.Size, got.LineHeight, got.Baseline, got.AtlasSizeX, got.AtlasSizeY,
+			want.Face, want.Size, want.LineHeight, want.Baseline, want.AtlasSizeX, want.AtlasSizeY)
+	}
+	if !reflect.DeepEqual(got.Glyphs, want.Glyphs) {
+		t.Errorf("got %d glyphs differing from the %d baked", len(got.Glyphs), len(want.Glyphs))
+	}
+	// Monospaced, the font has no kernings; none are read back either.
+	if len(got.Kernings) != len(want.Kernings) || len(want.Kernings) > 0 && !reflect.DeepEqual(got.Kernings, want.Kernings) {
+		t.Errorf("got %d kernings differing from the %d baked", len(got.Kernings), len(want.Kernings))
+	}
+}
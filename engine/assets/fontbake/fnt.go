@@ -0,0 +1,102 @@
+package fontbake
+
+import (
+	"bufio"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// Save writes the descriptor of the font at the given path (e.g. fonts/UbuntuMono21px.fnt), and
+// its atlas next to it, named after it like BMFont does (e.g. fonts/UbuntuMono21px_0.png).
+func (f *Font) Save(fntPath string) error {
+	pageFile := strings.TrimSuffix(filepath.Base(fntPath), filepath.Ext(fntPath)) + "_0.png"
+
+	atlas, err := os.Create(filepath.Join(filepath.Dir(fntPath), pageFile))
+	if err != nil {
+		return err
+	}
+	defer atlas.Close()
+	if err := png.Encode(atlas, f.Atlas); err != nil {
+		return err
+	}
+	if err := atlas.Close(); err != nil {
+		return err
+	}
+
+	descriptor, err := os.Create(fntPath)
+	if err != nil {
+		return err
+	}
+	defer descriptor.Close()
+	if err := WriteFNT(descriptor, f.Data, pageFile); err != nil {
+		return err
+	}
+	return descriptor.Close()
+}
+
+// WriteFNT writes the font as a BMFont text descriptor with a single page.
+func WriteFNT(w io.Writer, font *metadata.FontData, pageFile string) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "info face=%q size=%d bold=0 italic=0 charset=\"\" unicode=1 stretchH=100 smooth=1 aa=1 padding=0,0,0,0 spacing=1,1 outline=0\n",
+		font.Face, font.Size)
+	// The glyphs are in every channel.
+	fmt.Fprintf(b, "common lineHeight=%d base=%d scaleW=%d scaleH=%d pages=1 packed=0 alphaChnl=0 redChnl=0 greenChnl=0 blueChnl=0\n",
+		font.LineHeight, font.Baseline, font.AtlasSizeX, font.AtlasSizeY)
+	fmt.Fprintf(b, "page id=0 file=%q\n", pageFile)
+	fmt.Fprintf(b, "chars count=%d\n", len(font.Glyphs))
+	for _, g := range font.Glyphs {
+		fmt.Fprintf(b, "char id=%-5d x=%-5d y=%-5d width=%-5d height=%-5d xoffset=%-5d yoffset=%-5d xadvance=%-5d page=%d  chnl=15\n",
+			g.Codepoint, g.X, g.Y, g.Width, g.Height, g.XOffset, g.YOffset, g.XAdvance, g.PageID)
+	}
+	if len(font.Kernings) > 0 {
+		fmt.Fprintf(b, "kernings count=%d\n", len(font.Kernings))
+		for _, k := range font.Kernings {
+			fmt.Fprintf(b, "kerning first=%-5d second=%-5d amount=%d\n", k.Codepoint0, k.Codepoint1, k.Amount)
+		}
+	}
+	return b.Flush()
+}
+
+// ParseRanges parses codepoint ranges separated by commas, each a codepoint or two separated by
+// a dash, in decimal or hexadecimal (e.g. "32-126,0xA0-0xFF,0x20AC").
+func ParseRanges(s string) ([]Range, error) {
+	ranges := []Range{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		r := Range{}
+		var err error
+		if r.First, err = parseCodepoint(first); err != nil {
+			return nil, err
+		}
+		if r.Last, err = parseCodepoint(last); err != nil {
+			return nil, err
+		}
+		if r.First > r.Last {
+			return nil, fmt.Errorf("invalid range `%s`", part)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseCodepoint(s string) (rune, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 0, 32)
+	if err != nil || v < 0 || v > 0x10FFFF {
+		return 0, fmt.Errorf("invalid codepoint `%s`", s)
+	}
+	return rune(v), nil
+}
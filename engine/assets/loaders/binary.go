@@ -2,22 +2,18 @@ package loaders
 
 import (
 	"fmt"
-	"io"
-	"os"
+	"io/fs"
 
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type BinaryLoader struct{}
+type BinaryLoader struct {
+	// The file system the binaries are read from.
+	FS fs.FS
+}
 
 func (bl *BinaryLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	buf, err := io.ReadAll(f)
+	buf, err := fs.ReadFile(bl.FS, path)
 	if err != nil {
 		return nil, err
 	}
@@ -32,12 +28,14 @@ func (bl *BinaryLoader) Load(path string, assetType metadata.ResourceType, param
 	return &metadata.Resource{
 		Name:     p["name"],
 		FullPath: path,
-		DataSize: uint64(len(res)),
+		DataSize: uint64(len(res) * 4),
 		Data:     res,
 	}, nil
 }
 
-func (bl *BinaryLoader) Unload(*metadata.Resource) error {
+func (bl *BinaryLoader) Unload(resource *metadata.Resource) error {
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
 
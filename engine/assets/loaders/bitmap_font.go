@@ -2,7 +2,10 @@ package loaders
 
 import (
 	"fmt"
-	"os"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
 	"unsafe"
 
 	_ "image/png"
@@ -12,7 +15,8 @@ import (
 )
 
 type BitmapFontLoader struct {
-	ResourcePath string
+	// The file system the font descriptors are read from.
+	FS fs.FS
 }
 
 type BitmapFontFileType int
@@ -29,9 +33,15 @@ type SupportedBitmapFontFileType struct {
 	IsBinary   bool
 }
 
+// Load reads the bitmap font at the given path, an AngelCode .fnt descriptor or a cooked .kbf.
 func (fl *BitmapFontLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	// Generate the KBF filename.
-	rd, err := fl.importFNTFile(path)
+	var rd *metadata.BitmapFontResourceData
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".kbf") {
+		rd, err = fl.readKBFFile(path)
+	} else {
+		rd, err = fl.importFNTFile(path)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +49,7 @@ func (fl *BitmapFontLoader) Load(path string, assetType metadata.ResourceType, p
 	res := &metadata.Resource{
 		FullPath: path,
 		Data:     rd,
-		DataSize: uint64(unsafe.Sizeof(&metadata.BitmapFontResourceData{})),
+		DataSize: bitmapFontSize(rd),
 	}
 
 	return res, nil
@@ -59,13 +69,37 @@ func (fl *BitmapFontLoader) Unload(resource *metadata.Resource) error {
 	return nil
 }
 
+// bitmapFontSize returns the approximate size in bytes of the font data.
+func bitmapFontSize(rd *metadata.BitmapFontResourceData) uint64 {
+	size := unsafe.Sizeof(metadata.BitmapFontResourceData{}) + unsafe.Sizeof(metadata.FontData{})
+	size += uintptr(len(rd.Data.Glyphs)) * unsafe.Sizeof(metadata.FontGlyph{})
+	size += uintptr(len(rd.Data.Kernings)) * unsafe.Sizeof(metadata.FontKerning{})
+	size += uintptr(len(rd.Pages)) * unsafe.Sizeof(metadata.BitmapFontPage{})
+	return uint64(size)
+}
+
+func (fl *BitmapFontLoader) readKBFFile(kbfFileName string) (*metadata.BitmapFontResourceData, error) {
+	file, err := fl.FS.Open(kbfFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rd, err := ReadKBF(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bitmap font `%s`: %w", kbfFileName, err)
+	}
+	return rd, nil
+}
+
 func (fl *BitmapFontLoader) importFNTFile(kbf_file_name string) (*metadata.BitmapFontResourceData, error) {
-	wd, err := os.Getwd()
+	file, err := fl.FS.Open(kbf_file_name)
 	if err != nil {
 		return nil, err
 	}
-	file := fmt.Sprintf("%s/%s", wd, kbf_file_name)
-	descriptor, err := bmfont.LoadDescriptor(file)
+	defer file.Close()
+
+	descriptor, err := bmfont.ReadDescriptor(file)
 	if err != nil {
 		return nil, err
 	}
@@ -119,5 +153,20 @@ func (fl *BitmapFontLoader) importFNTFile(kbf_file_name string) (*metadata.Bitma
 		i++
 	}
 
+	// The descriptor keeps them in maps, they are sorted for the font to always read the same.
+	sort.Slice(out_data.Pages, func(a, b int) bool {
+		return out_data.Pages[a].ID < out_data.Pages[b].ID
+	})
+	sort.Slice(out_data.Data.Glyphs, func(a, b int) bool {
+		return out_data.Data.Glyphs[a].Codepoint < out_data.Data.Glyphs[b].Codepoint
+	})
+	sort.Slice(out_data.Data.Kernings, func(a, b int) bool {
+		ka, kb := out_data.Data.Kernings[a], out_data.Data.Kernings[b]
+		if ka.Codepoint0 != kb.Codepoint0 {
+			return ka.Codepoint0 < kb.Codepoint0
+		}
+		return ka.Codepoint1 < kb.Codepoint1
+	})
+
 	return out_data, nil
 }
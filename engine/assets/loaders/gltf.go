@@ -0,0 +1,871 @@
+package loaders
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	gomath "math"
+	"net/url"
+	"path"
+	"strings"
+	"unsafe"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+const (
+	glbMagic     uint32 = 0x46546C67 // "glTF"
+	glbChunkJSON uint32 = 0x4E4F534A // "JSON"
+	glbChunkBIN  uint32 = 0x004E4942 // "BIN\0"
+)
+
+/** @brief The primitive modes, how the indices of a primitive form its faces. */
+const (
+	gltfModeTriangles     = 4
+	gltfModeTriangleStrip = 5
+	gltfModeTriangleFan   = 6
+)
+
+var gltfComponentCounts = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+var gltfComponentSizes = map[int]int{
+	5120: 1, // BYTE
+	5121: 1, // UNSIGNED_BYTE
+	5122: 2, // SHORT
+	5123: 2, // UNSIGNED_SHORT
+	5125: 4, // UNSIGNED_INT
+	5126: 4, // FLOAT
+}
+
+// The file extensions of the image types the texture path can load.
+var gltfImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+type gltfDocument struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	ExtensionsRequired []string `json:"extensionsRequired"`
+	Scene              *int     `json:"scene"`
+	Scenes             []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Textures    []gltfTexture    `json:"textures"`
+	Images      []gltfImage      `json:"images"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Matrix      []float32 `json:"matrix"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfTextureInfo struct {
+	Index int `json:"index"`
+}
+
+type gltfMaterial struct {
+	Name                 string `json:"name"`
+	PbrMetallicRoughness *struct {
+		BaseColorFactor  []float32        `json:"baseColorFactor"`
+		BaseColorTexture *gltfTextureInfo `json:"baseColorTexture"`
+		RoughnessFactor  *float32         `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture *gltfTextureInfo `json:"normalTexture"`
+}
+
+type gltfTexture struct {
+	Source *int `json:"source"`
+}
+
+type gltfImage struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+/** @brief The state of a glTF file being imported. */
+type gltfImporter struct {
+	fsys fs.FS
+	// The directory of the file, relative URIs are resolved against it.
+	dir string
+	// The name of the model, used to name the unnamed parts.
+	name string
+	// Stores the images embedded in the file so the texture path can load them.
+	embed func(name string, data []byte) error
+
+	doc     *gltfDocument
+	buffers [][]byte
+	// The texture name of each image already resolved.
+	textures map[int]string
+}
+
+// ImportGLTF imports the glTF 2.0 file at the given path, either JSON (.gltf) or binary (.glb).
+// Each primitive becomes a geometry and each material a material config. Images embedded in
+// the file (in buffers or data URIs) are given to embed as textures/<name>.<ext>, so they can be
+// acquired by name like any texture; they are skipped when embed is nil.
+func ImportGLTF(fsys fs.FS, filename string, embed func(name string, data []byte) error) (*metadata.ModelConfig, error) {
+	content, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &gltfImporter{
+		fsys:     fsys,
+		dir:      path.Dir(filename),
+		name:     strings.TrimSuffix(path.Base(filename), path.Ext(filename)),
+		embed:    embed,
+		textures: map[int]string{},
+	}
+
+	var binChunk []byte
+	if len(content) >= 4 && binary.LittleEndian.Uint32(content) == glbMagic {
+		if content, binChunk, err = parseGLB(content); err != nil {
+			return nil, fmt.Errorf("invalid glb `%s`: %w", filename, err)
+		}
+	}
+	imp.doc = &gltfDocument{}
+	if err := json.Unmarshal(content, imp.doc); err != nil {
+		return nil, fmt.Errorf("invalid gltf `%s`: %w", filename, err)
+	}
+	if !strings.HasPrefix(imp.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("unsupported gltf version `%s` in `%s`", imp.doc.Asset.Version, filename)
+	}
+	if len(imp.doc.ExtensionsRequired) > 0 {
+		return nil, fmt.Errorf("unsupported gltf extensions %v in `%s`", imp.doc.ExtensionsRequired, filename)
+	}
+
+	if err := imp.loadBuffers(binChunk); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	model, err := imp.buildModel()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return model, nil
+}
+
+// parseGLB returns the JSON and binary chunks of a binary glTF file.
+func parseGLB(content []byte) ([]byte, []byte, error) {
+	if len(content) < 12 {
+		return nil, nil, errors.New("truncated header")
+	}
+	version := binary.LittleEndian.Uint32(content[4:])
+	length := binary.LittleEndian.Uint32(content[8:])
+	if version != 2 {
+		return nil, nil, fmt.Errorf("unsupported version %d", version)
+	}
+	if int(length) > len(content) {
+		return nil, nil, errors.New("truncated file")
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= int(length); {
+		chunkLength := int(binary.LittleEndian.Uint32(content[offset:]))
+		chunkType := binary.LittleEndian.Uint32(content[offset+4:])
+		offset += 8
+		if offset+chunkLength > int(length) {
+			return nil, nil, errors.New("truncated chunk")
+		}
+		switch chunkType {
+		case glbChunkJSON:
+			jsonChunk = content[offset : offset+chunkLength]
+		case glbChunkBIN:
+			if binChunk == nil {
+				binChunk = content[offset : offset+chunkLength]
+			}
+		}
+		// Chunks are 4 bytes aligned.
+		offset += (chunkLength + 3) &^ 3
+	}
+	if jsonChunk == nil {
+		return nil, nil, errors.New("missing JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+func (imp *gltfImporter) loadBuffers(binChunk []byte) error {
+	imp.buffers = make([][]byte, len(imp.doc.Buffers))
+	for i, b := range imp.doc.Buffers {
+		var data []byte
+		var err error
+		switch {
+		case b.URI == "":
+			// The buffer of a .glb, stored in its binary chunk.
+			if i != 0 || binChunk == nil {
+				return fmt.Errorf("buffer %d has no data", i)
+			}
+			data = binChunk
+		default:
+			if data, _, err = imp.readURI(b.URI); err != nil {
+				return fmt.Errorf("buffer %d: %w", i, err)
+			}
+		}
+		if len(data) < b.ByteLength {
+			return fmt.Errorf("buffer %d is %d bytes long, expected %d", i, len(data), b.ByteLength)
+		}
+		imp.buffers[i] = data
+	}
+	return nil
+}
+
+// readURI reads the content of a data URI, or of a file relative to the glTF file. It also
+// returns the mime type of data URIs.
+func (imp *gltfImporter) readURI(uri string) ([]byte, string, error) {
+	if strings.HasPrefix(uri, "data:") {
+		header, payload, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return nil, "", errors.New("only base64 data URIs are supported")
+		}
+		data, err := base64.StdEncoding.DecodeString(payload)
+		return data, strings.TrimSuffix(header, ";base64"), err
+	}
+	name, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := fs.ReadFile(imp.fsys, path.Join(imp.dir, name))
+	return data, "", err
+}
+
+func (imp *gltfImporter) buildModel() (*metadata.ModelConfig, error) {
+	model := &metadata.ModelConfig{
+		Name: imp.name,
+	}
+
+	materialNames := make([]string, len(imp.doc.Materials))
+	for i, m := range imp.doc.Materials {
+		material := imp.buildMaterial(i, m)
+		materialNames[i] = material.Name
+		model.Materials = append(model.Materials, material)
+	}
+
+	meshGeometries := make([][]uint32, len(imp.doc.Meshes))
+	for m, mesh := range imp.doc.Meshes {
+		meshName := mesh.Name
+		if meshName == "" {
+			meshName = fmt.Sprintf("%s_mesh%d", imp.name, m)
+		}
+		for p, primitive := range mesh.Primitives {
+			name := meshName
+			if len(mesh.Primitives) > 1 {
+				name = fmt.Sprintf("%s_%d", meshName, p)
+			}
+			g, err := imp.buildPrimitive(name, primitive)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d: %w", m, p, err)
+			}
+			if g == nil {
+				continue
+			}
+			if primitive.Material != nil {
+				if *primitive.Material < 0 || *primitive.Material >= len(materialNames) {
+					return nil, fmt.Errorf("mesh %d primitive %d: material %d out of range", m, p, *primitive.Material)
+				}
+				g.MaterialName = materialNames[*primitive.Material]
+			}
+			meshGeometries[m] = append(meshGeometries[m], uint32(len(model.Geometries)))
+			model.Geometries = append(model.Geometries, g)
+		}
+	}
+
+	if err := imp.buildNodes(model, meshGeometries); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// buildNodes walks the node tree of the default scene, parents first.
+func (imp *gltfImporter) buildNodes(model *metadata.ModelConfig, meshGeometries [][]uint32) error {
+	var roots []int
+	switch {
+	case len(imp.doc.Scenes) > 0:
+		scene := 0
+		if imp.doc.Scene != nil {
+			scene = *imp.doc.Scene
+		}
+		if scene < 0 || scene >= len(imp.doc.Scenes) {
+			return fmt.Errorf("scene %d out of range", scene)
+		}
+		roots = imp.doc.Scenes[scene].Nodes
+	default:
+		// Without scenes, every node that is not a child is a root.
+		isChild := make([]bool, len(imp.doc.Nodes))
+		for _, n := range imp.doc.Nodes {
+			for _, c := range n.Children {
+				if c >= 0 && c < len(isChild) {
+					isChild[c] = true
+				}
+			}
+		}
+		for i := range imp.doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	// Files with meshes but no nodes still get them drawn.
+	if len(imp.doc.Nodes) == 0 {
+		root := &metadata.ModelNode{Name: imp.name, Transform: math.TransformCreate(), Parent: -1}
+		for i := range model.Geometries {
+			root.Geometries = append(root.Geometries, uint32(i))
+		}
+		model.Nodes = append(model.Nodes, root)
+		return nil
+	}
+
+	visited := make([]bool, len(imp.doc.Nodes))
+	var walk func(index int, parent int32) error
+	walk = func(index int, parent int32) error {
+		if index < 0 || index >= len(imp.doc.Nodes) {
+			return fmt.Errorf("node %d out of range", index)
+		}
+		if visited[index] {
+			return fmt.Errorf("node %d has several parents", index)
+		}
+		visited[index] = true
+
+		n := imp.doc.Nodes[index]
+		node := &metadata.ModelNode{
+			Name:      n.Name,
+			Transform: gltfNodeTransform(n),
+			Parent:    parent,
+		}
+		if node.Name == "" {
+			node.Name = fmt.Sprintf("%s_node%d", imp.name, index)
+		}
+		if parent >= 0 {
+			node.Transform.Parent = model.Nodes[parent].Transform
+		}
+		if n.Mesh != nil {
+			if *n.Mesh < 0 || *n.Mesh >= len(meshGeometries) {
+				return fmt.Errorf("node %d: mesh %d out of range", index, *n.Mesh)
+			}
+			node.Geometries = meshGeometries[*n.Mesh]
+		}
+
+		self := int32(len(model.Nodes))
+		model.Nodes = append(model.Nodes, node)
+		for _, child := range n.Children {
+			if err := walk(child, self); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := walk(root, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gltfNodeTransform creates the local transform of the node. glTF rotations are converted to
+// the convention of Quaternion.ToMat4, which builds the transposed rotation.
+func gltfNodeTransform(n gltfNode) *math.Transform {
+	position := math.NewVec3Zero()
+	rotation := math.NewQuatIdentity()
+	scale := math.NewVec3One()
+
+	if len(n.Matrix) == 16 {
+		position, rotation, scale = decomposeGLTFMatrix(n.Matrix)
+	} else {
+		if len(n.Translation) == 3 {
+			position = math.NewVec3(n.Translation[0], n.Translation[1], n.Translation[2])
+		}
+		if len(n.Rotation) == 4 {
+			rotation = math.Quaternion{X: n.Rotation[0], Y: n.Rotation[1], Z: n.Rotation[2], W: n.Rotation[3]}
+		}
+		if len(n.Scale) == 3 {
+			scale = math.NewVec3(n.Scale[0], n.Scale[1], n.Scale[2])
+		}
+	}
+	return math.TransformFromPositionRotationScale(position, rotation.Conjugate(), scale)
+}
+
+// decomposeGLTFMatrix splits a column major glTF matrix into its translation, rotation and scale.
+func decomposeGLTFMatrix(m []float32) (math.Vec3, math.Quaternion, math.Vec3) {
+	position := math.NewVec3(m[12], m[13], m[14])
+	scale := math.NewVec3(
+		math.NewVec3(m[0], m[1], m[2]).Length(),
+		math.NewVec3(m[4], m[5], m[6]).Length(),
+		math.NewVec3(m[8], m[9], m[10]).Length(),
+	)
+	// A mirroring matrix has a negative determinant, put the mirror in the scale.
+	if math.NewVec3(m[0], m[1], m[2]).Cross(math.NewVec3(m[4], m[5], m[6])).Dot(math.NewVec3(m[8], m[9], m[10])) < 0 {
+		scale.X = -scale.X
+	}
+	if scale.X == 0 || scale.Y == 0 || scale.Z == 0 {
+		return position, math.NewQuatIdentity(), scale
+	}
+
+	// r(i, j) is the element at row i, column j of the rotation.
+	s := [3]float32{scale.X, scale.Y, scale.Z}
+	r := func(i, j int) float64 { return float64(m[j*4+i] / s[j]) }
+
+	var q math.Quaternion
+	trace := r(0, 0) + r(1, 1) + r(2, 2)
+	switch {
+	case trace > 0:
+		f := gomath.Sqrt(trace+1.0) * 2
+		q = math.Quaternion{X: float32((r(2, 1) - r(1, 2)) / f), Y: float32((r(0, 2) - r(2, 0)) / f), Z: float32((r(1, 0) - r(0, 1)) / f), W: float32(0.25 * f)}
+	case r(0, 0) > r(1, 1) && r(0, 0) > r(2, 2):
+		f := gomath.Sqrt(1.0+r(0, 0)-r(1, 1)-r(2, 2)) * 2
+		q = math.Quaternion{X: float32(0.25 * f), Y: float32((r(0, 1) + r(1, 0)) / f), Z: float32((r(0, 2) + r(2, 0)) / f), W: float32((r(2, 1) - r(1, 2)) / f)}
+	case r(1, 1) > r(2, 2):
+		f := gomath.Sqrt(1.0+r(1, 1)-r(0, 0)-r(2, 2)) * 2
+		q = math.Quaternion{X: float32((r(0, 1) + r(1, 0)) / f), Y: float32(0.25 * f), Z: float32((r(1, 2) + r(2, 1)) / f), W: float32((r(0, 2) - r(2, 0)) / f)}
+	default:
+		f := gomath.Sqrt(1.0+r(2, 2)-r(0, 0)-r(1, 1)) * 2
+		q = math.Quaternion{X: float32((r(0, 2) + r(2, 0)) / f), Y: float32((r(1, 2) + r(2, 1)) / f), Z: float32(0.25 * f), W: float32((r(1, 0) - r(0, 1)) / f)}
+	}
+	return position, q.Normalize(), scale
+}
+
+func (imp *gltfImporter) buildMaterial(index int, m gltfMaterial) *metadata.MaterialConfig {
+	material := &metadata.MaterialConfig{
+		Version:       0.1,
+		Name:          m.Name,
+		ShaderName:    ImportedMaterialShaderName,
+		AutoRelease:   true,
+		DiffuseColour: math.NewVec4One(),
+		Shininess:     8.0,
+	}
+	if material.Name == "" {
+		material.Name = fmt.Sprintf("%s_material%d", imp.name, index)
+	}
+
+	if pbr := m.PbrMetallicRoughness; pbr != nil {
+		if len(pbr.BaseColorFactor) == 4 {
+			material.DiffuseColour = math.NewVec4(
+				math.Clamp(pbr.BaseColorFactor[0], 0.0, 1.0),
+				math.Clamp(pbr.BaseColorFactor[1], 0.0, 1.0),
+				math.Clamp(pbr.BaseColorFactor[2], 0.0, 1.0),
+				math.Clamp(pbr.BaseColorFactor[3], 0.0, 1.0),
+			)
+		}
+		// The material system has no roughness, smoother surfaces get sharper highlights.
+		roughness := float32(1.0)
+		if pbr.RoughnessFactor != nil {
+			roughness = math.Clamp(*pbr.RoughnessFactor, 0.0, 1.0)
+		}
+		material.Shininess = max(8.0, (1.0-roughness)*128.0)
+		if pbr.BaseColorTexture != nil {
+			material.DiffuseMapName = imp.textureName(pbr.BaseColorTexture.Index)
+		}
+	}
+	if m.NormalTexture != nil {
+		material.NormalMapName = imp.textureName(m.NormalTexture.Index)
+	}
+	return material
+}
+
+// textureName returns the name the texture is acquired by. Images found in the textures
+// directory are referenced as they are, the others are embedded. Returns an empty name,
+// so the material falls back to the default texture, when the image cannot be used.
+func (imp *gltfImporter) textureName(textureIndex int) string {
+	if textureIndex < 0 || textureIndex >= len(imp.doc.Textures) || imp.doc.Textures[textureIndex].Source == nil {
+		core.LogWarn("%s: texture %d has no image, skipping", imp.name, textureIndex)
+		return ""
+	}
+	source := *imp.doc.Textures[textureIndex].Source
+	if name, ok := imp.textures[source]; ok {
+		return name
+	}
+	name, err := imp.resolveImage(source)
+	if err != nil {
+		core.LogWarn("%s: image %d: %s", imp.name, source, err.Error())
+	}
+	imp.textures[source] = name
+	return name
+}
+
+func (imp *gltfImporter) resolveImage(index int) (string, error) {
+	if index < 0 || index >= len(imp.doc.Images) {
+		return "", fmt.Errorf("out of range")
+	}
+	img := imp.doc.Images[index]
+
+	var data []byte
+	var name, extension string
+	switch {
+	case img.BufferView != nil:
+		view, err := imp.bufferView(*img.BufferView)
+		if err != nil {
+			return "", err
+		}
+		data = view
+		extension = gltfImageExtensions[img.MimeType]
+	case strings.HasPrefix(img.URI, "data:"):
+		content, mimeType, err := imp.readURI(img.URI)
+		if err != nil {
+			return "", err
+		}
+		data = content
+		extension = gltfImageExtensions[mimeType]
+	default:
+		file, err := url.PathUnescape(img.URI)
+		if err != nil {
+			return "", err
+		}
+		extension = strings.ToLower(path.Ext(file))
+		name = strings.TrimSuffix(path.Base(file), path.Ext(file))
+		// Already reachable through the texture path.
+		if _, err := fs.Stat(imp.fsys, fmt.Sprintf("textures/%s%s", name, path.Ext(file))); err == nil {
+			return name, nil
+		}
+		if data, _, err = imp.readURI(img.URI); err != nil {
+			return "", err
+		}
+	}
+
+	if extension != ".png" && extension != ".jpg" && extension != ".jpeg" {
+		return "", fmt.Errorf("unsupported image type `%s%s`", img.MimeType, extension)
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s_image%d", imp.name, index)
+		if img.Name != "" {
+			name = fmt.Sprintf("%s_%s", imp.name, strings.ReplaceAll(img.Name, "/", "_"))
+		}
+	}
+	if imp.embed == nil {
+		return "", fmt.Errorf("cannot embed `%s`", name)
+	}
+	if err := imp.embed(fmt.Sprintf("textures/%s%s", name, extension), data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (imp *gltfImporter) bufferView(index int) ([]byte, error) {
+	if index < 0 || index >= len(imp.doc.BufferViews) {
+		return nil, fmt.Errorf("buffer view %d out of range", index)
+	}
+	view := imp.doc.BufferViews[index]
+	if view.Buffer < 0 || view.Buffer >= len(imp.buffers) {
+		return nil, fmt.Errorf("buffer view %d: buffer %d out of range", index, view.Buffer)
+	}
+	buffer := imp.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset+view.ByteLength > len(buffer) {
+		return nil, fmt.Errorf("buffer view %d out of the bounds of its buffer", index)
+	}
+	return buffer[view.ByteOffset : view.ByteOffset+view.ByteLength], nil
+}
+
+// readAccessor returns the values of the accessor, converted to float64, and the number of
+// components of each element. Normalized integers are mapped to [0, 1] or [-1, 1].
+func (imp *gltfImporter) readAccessor(index int) ([]float64, int, error) {
+	if index < 0 || index >= len(imp.doc.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d out of range", index)
+	}
+	acc := imp.doc.Accessors[index]
+	if len(acc.Sparse) > 0 {
+		return nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	components, ok := gltfComponentCounts[acc.Type]
+	if !ok {
+		return nil, 0, fmt.Errorf("accessor %d: unknown type `%s`", index, acc.Type)
+	}
+	size, ok := gltfComponentSizes[acc.ComponentType]
+	if !ok {
+		return nil, 0, fmt.Errorf("accessor %d: unknown component type %d", index, acc.ComponentType)
+	}
+
+	if acc.Count < 0 {
+		return nil, 0, fmt.Errorf("accessor %d: negative count %d", index, acc.Count)
+	}
+	// Accessors without buffer view are all zeros.
+	if acc.BufferView == nil || acc.Count == 0 {
+		return make([]float64, acc.Count*components), components, nil
+	}
+	view, err := imp.bufferView(*acc.BufferView)
+	if err != nil {
+		return nil, 0, err
+	}
+	stride := imp.doc.BufferViews[*acc.BufferView].ByteStride
+	if stride == 0 {
+		stride = components * size
+	} else if stride < 4 || stride > 252 || stride%4 != 0 {
+		// The spec requires a multiple of 4 between 4 and 252.
+		return nil, 0, fmt.Errorf("buffer view %d: invalid byte stride %d", *acc.BufferView, stride)
+	}
+	// The offset and the count are bounded first, so the end below cannot overflow.
+	if acc.ByteOffset < 0 || acc.ByteOffset > len(view) || acc.Count > len(view) || acc.ByteOffset+(acc.Count-1)*stride+components*size > len(view) {
+		return nil, 0, fmt.Errorf("accessor %d out of the bounds of its buffer view", index)
+	}
+
+	values := make([]float64, acc.Count*components)
+
+	for i := 0; i < acc.Count; i++ {
+		for c := 0; c < components; c++ {
+			data := view[acc.ByteOffset+i*stride+c*size:]
+			values[i*components+c] = readGLTFComponent(data, acc.ComponentType, acc.Normalized)
+		}
+	}
+	return values, components, nil
+}
+
+func readGLTFComponent(data []byte, componentType int, normalized bool) float64 {
+	switch componentType {
+	case 5120:
+		v := float64(int8(data[0]))
+		if normalized {
+			return gomath.Max(v/127.0, -1.0)
+		}
+		return v
+	case 5121:
+		v := float64(data[0])
+		if normalized {
+			return v / 255.0
+		}
+		return v
+	case 5122:
+		v := float64(int16(binary.LittleEndian.Uint16(data)))
+		if normalized {
+			return gomath.Max(v/32767.0, -1.0)
+		}
+		return v
+	case 5123:
+		v := float64(binary.LittleEndian.Uint16(data))
+		if normalized {
+			return v / 65535.0
+		}
+		return v
+	case 5125:
+		return float64(binary.LittleEndian.Uint32(data))
+	default:
+		return float64(gomath.Float32frombits(binary.LittleEndian.Uint32(data)))
+	}
+}
+
+// readAttribute reads a vertex attribute, checking it has one element per vertex and the
+// expected number of components (any of them).
+func (imp *gltfImporter) readAttribute(name string, index int, vertexCount int, components ...int) ([]float64, int, error) {
+	values, count, err := imp.readAccessor(index)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", name, err)
+	}
+	valid := false
+	for _, c := range components {
+		valid = valid || c == count
+	}
+	if !valid || len(values) != vertexCount*count {
+		return nil, 0, fmt.Errorf("%s: unexpected layout", name)
+	}
+	return values, count, nil
+}
+
+// buildPrimitive creates the geometry config of the primitive. Returns nil for primitives
+// that are not made of triangles.
+func (imp *gltfImporter) buildPrimitive(name string, p gltfPrimitive) (*metadata.GeometryConfig, error) {
+	mode := gltfModeTriangles
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode != gltfModeTriangles && mode != gltfModeTriangleStrip && mode != gltfModeTriangleFan {
+		core.LogWarn("%s: primitive `%s` is not made of triangles (mode %d), skipping", imp.name, name, mode)
+		return nil, nil
+	}
+
+	positionIndex, ok := p.Attributes["POSITION"]
+	if !ok {
+		return nil, errors.New("missing POSITION attribute")
+	}
+	if positionIndex < 0 || positionIndex >= len(imp.doc.Accessors) {
+		return nil, fmt.Errorf("POSITION: accessor %d out of range", positionIndex)
+	}
+	vertexCount := imp.doc.Accessors[positionIndex].Count
+	positions, _, err := imp.readAttribute("POSITION", positionIndex, vertexCount, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices := make([]math.Vertex3D, vertexCount)
+	for i := range vertices {
+		vertices[i].Position = math.NewVec3(float32(positions[i*3]), float32(positions[i*3+1]), float32(positions[i*3+2]))
+		vertices[i].Colour = math.NewVec4One()
+	}
+
+	_, hasNormals := p.Attributes["NORMAL"]
+	if hasNormals {
+		normals, _, err := imp.readAttribute("NORMAL", p.Attributes["NORMAL"], vertexCount, 3)
+		if err != nil {
+			return nil, err
+		}
+		for i := range vertices {
+			vertices[i].Normal = math.NewVec3(float32(normals[i*3]), float32(normals[i*3+1]), float32(normals[i*3+2]))
+		}
+	}
+	_, hasTexcoords := p.Attributes["TEXCOORD_0"]
+	if hasTexcoords {
+		texcoords, _, err := imp.readAttribute("TEXCOORD_0", p.Attributes["TEXCOORD_0"], vertexCount, 2)
+		if err != nil {
+			return nil, err
+		}
+		// glTF has its origin at the top left of the images, the engine at the bottom left.
+		for i := range vertices {
+			vertices[i].Texcoord = math.NewVec2(float32(texcoords[i*2]), 1.0-float32(texcoords[i*2+1]))
+		}
+	}
+	_, hasTangents := p.Attributes["TANGENT"]
+	if hasTangents {
+		tangents, _, err := imp.readAttribute("TANGENT", p.Attributes["TANGENT"], vertexCount, 4)
+		if err != nil {
+			return nil, err
+		}
+		// The handedness is stored in the direction, like GeometryGenerateTangents does.
+		for i := range vertices {
+			w := float32(tangents[i*4+3])
+			vertices[i].Tangent = math.NewVec3(float32(tangents[i*4])*w, float32(tangents[i*4+1])*w, float32(tangents[i*4+2])*w)
+		}
+	}
+	if colourIndex, ok := p.Attributes["COLOR_0"]; ok {
+		colours, components, err := imp.readAttribute("COLOR_0", colourIndex, vertexCount, 3, 4)
+		if err != nil {
+			return nil, err
+		}
+		for i := range vertices {
+			c := colours[i*components:]
+			vertices[i].Colour = math.NewVec4(float32(c[0]), float32(c[1]), float32(c[2]), 1.0)
+			if components == 4 {
+				vertices[i].Colour.W = float32(c[3])
+			}
+		}
+	}
+
+	var elements []uint32
+	if p.Indices != nil {
+		values, components, err := imp.readAccessor(*p.Indices)
+		if err != nil {
+			return nil, fmt.Errorf("indices: %w", err)
+		}
+		if components != 1 {
+			return nil, errors.New("indices: unexpected layout")
+		}
+		elements = make([]uint32, len(values))
+		for i, v := range values {
+			if int(v) >= vertexCount {
+				return nil, fmt.Errorf("indices: %d out of range", uint32(v))
+			}
+			elements[i] = uint32(v)
+		}
+	} else {
+		elements = make([]uint32, vertexCount)
+		for i := range elements {
+			elements[i] = uint32(i)
+		}
+	}
+	indices := gltfTriangles(mode, elements)
+
+	// Flat normals need their own vertices per face, duplicates are merged back afterwards.
+	deduplicate := p.Indices == nil
+	if !hasNormals {
+		unwelded := make([]math.Vertex3D, len(indices))
+		for i, index := range indices {
+			unwelded[i] = vertices[index]
+			indices[i] = uint32(i)
+		}
+		vertices = unwelded
+		math.GeometryGenerateNormals(uint32(len(vertices)), vertices, uint32(len(indices)), indices)
+		deduplicate = true
+	}
+	if deduplicate {
+		_, vertices = math.GeometryDeduplicateVertices(uint32(len(vertices)), vertices, uint32(len(indices)), indices)
+	}
+	if !hasTangents && hasTexcoords {
+		vertices = math.GeometryGenerateTangents(uint32(len(vertices)), vertices, uint32(len(indices)), indices)
+	}
+
+	config := &metadata.GeometryConfig{
+		VertexSize:  uint32(unsafe.Sizeof(math.Vertex3D{})),
+		VertexCount: uint32(len(vertices)),
+		Vertices:    vertices,
+		IndexSize:   uint32(unsafe.Sizeof(uint32(0))),
+		IndexCount:  uint32(len(indices)),
+		Indices:     indices,
+		Name:        name,
+	}
+	config.MinExtents, config.MaxExtents, config.Center = computeExtents(vertices)
+	return config, nil
+}
+
+// gltfTriangles converts the elements of a primitive to a triangle list.
+func gltfTriangles(mode int, elements []uint32) []uint32 {
+	switch mode {
+	case gltfModeTriangleStrip:
+		indices := make([]uint32, 0, max(len(elements)-2, 0)*3)
+		for i := 0; i+2 < len(elements); i++ {
+			// Every other triangle is flipped to keep the winding order.
+			if i%2 == 0 {
+				indices = append(indices, elements[i], elements[i+1], elements[i+2])
+			} else {
+				indices = append(indices, elements[i+1], elements[i], elements[i+2])
+			}
+		}
+		return indices
+	case gltfModeTriangleFan:
+		indices := make([]uint32, 0, max(len(elements)-2, 0)*3)
+		for i := 1; i+1 < len(elements); i++ {
+			indices = append(indices, elements[0], elements[i], elements[i+1])
+		}
+		return indices
+	default:
+		return elements[:len(elements)/3*3]
+	}
+}
@@ -0,0 +1,187 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spaghettifunk/anima/engine/math"
+)
+
+// gltfTriangle returns the JSON and the buffer of a triangle. Its positions are interleaved with
+// a padding float, 16 bytes apart, and followed by its 3 u16 indices.
+func gltfTriangle() (map[string]interface{}, []byte) {
+	var buffer bytes.Buffer
+	positions := [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	for _, position := range positions {
+		binary.Write(&buffer, binary.LittleEndian, position)
+		binary.Write(&buffer, binary.LittleEndian, float32(-1))
+	}
+	binary.Write(&buffer, binary.LittleEndian, []uint16{0, 1, 2, 0})
+
+	doc := map[string]interface{}{
+		"asset":  map[string]interface{}{"version": "2.0"},
+		"meshes": []interface{}{map[string]interface{}{"name": "triangle", "primitives": []interface{}{map[string]interface{}{"attributes": map[string]interface{}{"POSITION": 0}, "indices": 1}}}},
+		"accessors": []interface{}{
+			map[string]interface{}{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+			map[string]interface{}{"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"},
+		},
+		"bufferViews": []interface{}{
+			map[string]interface{}{"buffer": 0, "byteOffset": 0, "byteLength": 48, "byteStride": 16},
+			map[string]interface{}{"buffer": 0, "byteOffset": 48, "byteLength": 6},
+		},
+		"buffers": []interface{}{map[string]interface{}{"byteLength": buffer.Len()}},
+	}
+	return doc, buffer.Bytes()
+}
+
+// glb packs the JSON and the buffer in a binary glTF file.
+func glb(t *testing.T, doc map[string]interface{}, bin []byte) []byte {
+	t.Helper()
+	content, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(content)%4 != 0 {
+		content = append(content, ' ')
+	}
+	var file bytes.Buffer
+	binary.Write(&file, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(content) + 8 + len(bin))})
+	binary.Write(&file, binary.LittleEndian, []uint32{uint32(len(content)), glbChunkJSON})
+	file.Write(content)
+	binary.Write(&file, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN})
+	file.Write(bin)
+	return file.Bytes()
+}
+
+func importTestGLB(t *testing.T, content []byte) error {
+	t.Helper()
+	model, err := ImportGLTF(fstest.MapFS{"models/test.glb": {Data: content}}, "models/test.glb", nil)
+	if err == nil && len(model.Geometries) != 1 {
+		t.Fatalf("got %d geometries, want 1", len(model.Geometries))
+	}
+	return err
+}
+
+func TestImportGLBStride(t *testing.T) {
+	doc, bin := gltfTriangle()
+	model, err := ImportGLTF(fstest.MapFS{"models/test.glb": {Data: glb(t, doc, bin)}}, "models/test.glb", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Geometries) != 1 {
+		t.Fatalf("got %d geometries, want 1", len(model.Geometries))
+	}
+	// The padding between the positions is skipped.
+	want := []math.Vec3{math.NewVec3(0, 0, 0), math.NewVec3(1, 0, 0), math.NewVec3(0, 1, 0)}
+	got := trianglePositions(model.Geometries[0])
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestImportGLBInvalidAccessors(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(accessors []interface{}, views []interface{})
+		err    string
+	}{
+		{
+			"negative vertex count",
+			func(accessors, views []interface{}) { accessors[0].(map[string]interface{})["count"] = -1 },
+			"accessor 0: negative count -1",
+		},
+		{
+			"negative index count",
+			func(accessors, views []interface{}) { accessors[1].(map[string]interface{})["count"] = -3 },
+			"accessor 1: negative count -3",
+		},
+		{
+			// Large enough for the end of the accessor to overflow if it were computed first.
+			"huge count",
+			func(accessors, views []interface{}) { accessors[0].(map[string]interface{})["count"] = 1 << 61 },
+			"out of the bounds of its buffer view",
+		},
+		{
+			"count past the view",
+			func(accessors, views []interface{}) { accessors[0].(map[string]interface{})["count"] = 4 },
+			"out of the bounds of its buffer view",
+		},
+		{
+			"negative offset",
+			func(accessors, views []interface{}) { accessors[0].(map[string]interface{})["byteOffset"] = -16 },
+			"out of the bounds of its buffer view",
+		},
+		{
+			"stride under 4",
+			func(accessors, views []interface{}) { views[0].(map[string]interface{})["byteStride"] = 2 },
+			"invalid byte stride 2",
+		},
+		{
+			"stride not a multiple of 4",
+			func(accessors, views []interface{}) { views[0].(map[string]interface{})["byteStride"] = 14 },
+			"invalid byte stride 14",
+		},
+		{
+			"stride over 252",
+			func(accessors, views []interface{}) { views[0].(map[string]interface{})["byteStride"] = 256 },
+			"invalid byte stride 256",
+		},
+		{
+			"negative stride",
+			func(accessors, views []interface{}) { views[0].(map[string]interface{})["byteStride"] = -16 },
+			"invalid byte stride -16",
+		},
+		{
+			"stride past the view",
+			func(accessors, views []interface{}) { views[0].(map[string]interface{})["byteStride"] = 20 },
+			"out of the bounds of its buffer view",
+		},
+		{
+			"view past the buffer",
+			func(accessors, views []interface{}) { views[1].(map[string]interface{})["byteLength"] = 64 },
+			"buffer view 1 out of the bounds of its buffer",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, bin := gltfTriangle()
+			test.mutate(doc["accessors"].([]interface{}), doc["bufferViews"].([]interface{}))
+			err := importTestGLB(t, glb(t, doc, bin))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestImportGLBInvalidContainer(t *testing.T) {
+	doc, bin := gltfTriangle()
+	valid := glb(t, doc, bin)
+	tests := []struct {
+		name    string
+		content []byte
+		err     string
+	}{
+		{"truncated header", valid[:8], "truncated header"},
+		{"truncated file", valid[:len(valid)-4], "truncated file"},
+		{"wrong version", append(append([]byte{}, valid[:4]...), append([]byte{1, 0, 0, 0}, valid[8:]...)...), "unsupported version 1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := importTestGLB(t, test.content)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
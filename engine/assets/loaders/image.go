@@ -2,35 +2,46 @@ package loaders
 
 /*
 #cgo CFLAGS: -I../../vendors
+#cgo LDFLAGS: -lm
 #define STB_IMAGE_IMPLEMENTATION
 #include "../../vendors/stb_image.h"
 */
 import "C"
 import (
 	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
 	"unsafe"
 
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type ImageLoader struct{}
+type ImageLoader struct {
+	// The file system the images are read from.
+	FS fs.FS
+}
 
-// loadImage loads an image from a specified path.
-func stbLoadImage(path string, flip bool) ([]uint8, int, int, int) {
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+// stbLoadImage decodes an image from the content of its file.
+func stbLoadImage(content []byte, flip bool) ([]uint8, int, int, int) {
+	if len(content) == 0 {
+		return nil, 0, 0, 0
+	}
 
 	flipY := 0
 	if flip {
 		flipY = 1
 	}
 
+	// The flip setting is per thread, the goroutine must not move in between.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
 	C.stbi_set_flip_vertically_on_load_thread(C.int(flipY))
 
 	var width, height, channels C.int
-	data := C.stbi_load(cPath, &width, &height, &channels, 0)
+	data := C.stbi_load_from_memory((*C.stbi_uc)(unsafe.Pointer(&content[0])), C.int(len(content)), &width, &height, &channels, 0)
 	if data == nil {
-		fmt.Println("Failed to load image")
 		return nil, 0, 0, 0
 	}
 	defer C.stbi_image_free(unsafe.Pointer(data))
@@ -41,10 +52,26 @@ func stbLoadImage(path string, flip bool) ([]uint8, int, int, int) {
 	return goData, int(width), int(height), int(channels)
 }
 
+// Load decodes the image at the given path, or reads it when cooked (.kti). The params are
+// *metadata.ImageResourceParams.
 func (il *ImageLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	typedParams := params.(*metadata.ImageResourceParams)
+	typedParams, _ := params.(*metadata.ImageResourceParams)
+	if typedParams == nil {
+		typedParams = &metadata.ImageResourceParams{}
+	}
+	if isCookedImage(path) {
+		return il.loadCooked(path, typedParams)
+	}
 
-	goData, width, height, channels := stbLoadImage(path, typedParams.FlipY)
+	content, err := fs.ReadFile(il.FS, path)
+	if err != nil {
+		return nil, err
+	}
+
+	goData, width, height, channels := stbLoadImage(content, typedParams.FlipY)
+	if goData == nil {
+		return nil, fmt.Errorf("failed to decode image `%s`", path)
+	}
 
 	return &metadata.Resource{
 		Name:     "image",
@@ -59,6 +86,43 @@ func (il *ImageLoader) Load(path string, assetType metadata.ResourceType, params
 	}, nil
 }
 
-func (il *ImageLoader) Unload(*metadata.Resource) error {
+// loadCooked reads a .kti texture, flipping it when it was cooked the other way up.
+func (il *ImageLoader) loadCooked(filename string, params *metadata.ImageResourceParams) (*metadata.Resource, error) {
+	file, err := il.FS.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	image, flipped, err := ReadKTI(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid texture `%s`: %w", filename, err)
+	}
+	if flipped != params.FlipY {
+		flipImage(image)
+	}
+	size := uint64(len(image.Pixels))
+	for _, mip := range image.Mips {
+		size += uint64(len(mip))
+	}
+	return &metadata.Resource{
+		Name:     "image",
+		FullPath: filename,
+		DataSize: size,
+		Data:     image,
+	}, nil
+}
+
+func isCookedImage(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".kti")
+}
+
+func (il *ImageLoader) Unload(resource *metadata.Resource) error {
+	if data, ok := resource.Data.(*metadata.ImageResourceData); ok {
+		data.Pixels = nil
+		data.Mips = nil
+	}
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
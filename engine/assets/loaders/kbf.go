@@ -0,0 +1,236 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/*
+ * Layout of a .kbf bitmap font, little endian:
+ *
+ *   header    u32 magic (metadata.ResourceMagic), u8 resource type, u8 version, u16 reserved
+ *   u32       face length, face
+ *   u32       size, i32 line height, i32 baseline, i32 atlas width, i32 atlas height
+ *   u32       page count
+ *   pages     i8 id, u32 file length, file
+ *   u32       glyph count
+ *   glyphs    i32 codepoint, u16 x, u16 y, u16 width, u16 height,
+ *             i16 x offset, i16 y offset, i16 x advance, u8 page
+ *   u32       kerning count
+ *   kernings  i32 codepoint 0, i32 codepoint 1, i16 amount
+ *
+ * Files written by Kohi start with its own magic number and store the strings null terminated
+ * (the length excludes the terminator) and the glyphs and kernings as padded C structs. Both
+ * are read.
+ */
+
+// The version of the .kbf format written by WriteKBF.
+const KBF_VERSION uint8 = 1
+
+// The magic number of the binary resources written by Kohi.
+const kohiResourceMagic uint32 = 0xcafebabe
+
+// WriteKBF writes the bitmap font as a .kbf file.
+func WriteKBF(w io.Writer, font *metadata.BitmapFontResourceData) error {
+	bw := bufio.NewWriter(w)
+	if err := writeHeader(bw, metadata.ResourceTypeBitmapFont, KBF_VERSION); err != nil {
+		return err
+	}
+	data := font.Data
+	if err := writeString(bw, data.Face); err != nil {
+		return err
+	}
+	if err := writeValues(bw, data.Size, data.LineHeight, data.Baseline, data.AtlasSizeX, data.AtlasSizeY); err != nil {
+		return err
+	}
+
+	if err := writeValues(bw, uint32(len(font.Pages))); err != nil {
+		return err
+	}
+	for _, page := range font.Pages {
+		if err := writeValues(bw, page.ID); err != nil {
+			return err
+		}
+		if err := writeString(bw, page.File); err != nil {
+			return err
+		}
+	}
+
+	if err := writeValues(bw, uint32(len(data.Glyphs))); err != nil {
+		return err
+	}
+	for _, g := range data.Glyphs {
+		if err := writeValues(bw, *g); err != nil {
+			return err
+		}
+	}
+
+	if err := writeValues(bw, uint32(len(data.Kernings))); err != nil {
+		return err
+	}
+	for _, k := range data.Kernings {
+		if err := writeValues(bw, *k); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadKBF reads a .kbf bitmap font.
+func ReadKBF(r io.Reader) (*metadata.BitmapFontResourceData, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(start) == kohiResourceMagic {
+		return readLegacyKBF(br)
+	}
+	if _, err := readHeader(br, metadata.ResourceTypeBitmapFont, KBF_VERSION); err != nil {
+		return nil, err
+	}
+	data := &metadata.FontData{
+		FontType: metadata.FONT_TYPE_BITMAP,
+	}
+	if data.Face, err = readString(br); err != nil {
+		return nil, err
+	}
+	if err := readValues(br, &data.Size, &data.LineHeight, &data.Baseline, &data.AtlasSizeX, &data.AtlasSizeY); err != nil {
+		return nil, err
+	}
+	font := &metadata.BitmapFontResourceData{Data: data}
+
+	var count uint32
+	if err := readValues(br, &count); err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		page := &metadata.BitmapFontPage{}
+		if err := readValues(br, &page.ID); err != nil {
+			return nil, err
+		}
+		if page.File, err = readString(br); err != nil {
+			return nil, err
+		}
+		font.Pages = append(font.Pages, page)
+	}
+
+	if err := readValues(br, &count); err != nil {
+		return nil, err
+	}
+	data.Glyphs = make([]*metadata.FontGlyph, count)
+	for i := range data.Glyphs {
+		data.Glyphs[i] = &metadata.FontGlyph{}
+		if err := readValues(br, data.Glyphs[i]); err != nil {
+			return nil, err
+		}
+		if int(data.Glyphs[i].PageID) >= len(font.Pages) {
+			return nil, fmt.Errorf("glyph %d is on missing page %d", data.Glyphs[i].Codepoint, data.Glyphs[i].PageID)
+		}
+	}
+
+	if err := readValues(br, &count); err != nil {
+		return nil, err
+	}
+	data.Kernings = make([]*metadata.FontKerning, count)
+	for i := range data.Kernings {
+		data.Kernings[i] = &metadata.FontKerning{}
+		if err := readValues(br, data.Kernings[i]); err != nil {
+			return nil, err
+		}
+	}
+	return font, nil
+}
+
+func readLegacyKBF(r io.Reader) (*metadata.BitmapFontResourceData, error) {
+	var header struct {
+		MagicNumber  uint32
+		ResourceType uint8
+		Version      uint8
+		Reserved     uint16
+	}
+	if err := readValues(r, &header); err != nil {
+		return nil, err
+	}
+	if header.Version != 1 {
+		return nil, fmt.Errorf("unsupported Kohi .kbf version %d", header.Version)
+	}
+	data := &metadata.FontData{
+		FontType: metadata.FONT_TYPE_BITMAP,
+	}
+	var err error
+	if data.Face, err = readLegacyKBFString(r); err != nil {
+		return nil, err
+	}
+	if err := readValues(r, &data.Size, &data.LineHeight, &data.Baseline, &data.AtlasSizeX, &data.AtlasSizeY); err != nil {
+		return nil, err
+	}
+	font := &metadata.BitmapFontResourceData{Data: data}
+
+	var count uint32
+	if err := readValues(r, &count); err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		page := &metadata.BitmapFontPage{}
+		if err := readValues(r, &page.ID); err != nil {
+			return nil, err
+		}
+		if page.File, err = readLegacyKBFString(r); err != nil {
+			return nil, err
+		}
+		font.Pages = append(font.Pages, page)
+	}
+
+	if err := readValues(r, &count); err != nil {
+		return nil, err
+	}
+	data.Glyphs = make([]*metadata.FontGlyph, count)
+	for i := range data.Glyphs {
+		var glyph struct {
+			metadata.FontGlyph
+			Padding uint8
+		}
+		if err := readValues(r, &glyph); err != nil {
+			return nil, err
+		}
+		data.Glyphs[i] = &glyph.FontGlyph
+	}
+
+	if err := readValues(r, &count); err != nil {
+		return nil, err
+	}
+	data.Kernings = make([]*metadata.FontKerning, count)
+	for i := range data.Kernings {
+		var kerning struct {
+			metadata.FontKerning
+			Padding uint16
+		}
+		if err := readValues(r, &kerning); err != nil {
+			return nil, err
+		}
+		data.Kernings[i] = &kerning.FontKerning
+	}
+	return font, nil
+}
+
+// readLegacyKBFString reads a string followed by its null terminator, not counted in its length.
+func readLegacyKBFString(r io.Reader) (string, error) {
+	var length uint32
+	if err := readValues(r, &length); err != nil {
+		return "", err
+	}
+	if length > 0xFFFF {
+		return "", fmt.Errorf("string too long: %d", length)
+	}
+	value := make([]byte, length+1)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value[:length]), "\x00"), nil
+}
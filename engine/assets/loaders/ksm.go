@@ -0,0 +1,222 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/*
+ * Layout of a .ksm static mesh, little endian:
+ *
+ *   header      u32 magic (metadata.ResourceMagic), u8 resource type, u8 version, u16 reserved
+ *   u32         geometry count
+ *   geometries  u32 vertex size, u32 vertex count, vertices
+ *               u32 index size, u32 index count, indices
+ *               u32 name length, name
+ *               u32 material name length, material name
+ *               vec3 center, vec3 min extents, vec3 max extents
+ *
+ * Files written by Kohi have no header: they start with a u16 version (1) and a null terminated
+ * mesh name, and their strings are null terminated too. Both are read.
+ */
+
+// The version of the .ksm format written by WriteKSM.
+const KSM_VERSION uint8 = 1
+
+// The version of the legacy headerless .ksm format.
+const ksmLegacyVersion uint16 = 1
+
+// WriteKSM writes the geometry configs as a .ksm static mesh.
+func WriteKSM(w io.Writer, configs []*metadata.GeometryConfig) error {
+	bw := bufio.NewWriter(w)
+	if err := writeHeader(bw, metadata.ResourceTypeMesh, KSM_VERSION); err != nil {
+		return err
+	}
+	if err := writeValues(bw, uint32(len(configs))); err != nil {
+		return err
+	}
+
+	vertexSize := uint32(unsafe.Sizeof(math.Vertex3D{}))
+	for _, g := range configs {
+		if g.VertexCount > uint32(len(g.Vertices)) || g.IndexCount > uint32(len(g.Indices)) {
+			return fmt.Errorf("geometry `%s` has fewer vertices or indices than its counts", g.Name)
+		}
+		values := []interface{}{
+			vertexSize, g.VertexCount, g.Vertices[:g.VertexCount],
+			uint32(4), g.IndexCount, g.Indices[:g.IndexCount],
+			uint32(len(g.Name)), []byte(g.Name),
+			uint32(len(g.MaterialName)), []byte(g.MaterialName),
+			g.Center, g.MinExtents, g.MaxExtents,
+		}
+		if err := writeValues(bw, values...); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadKSM reads the geometry configs of a .ksm static mesh. The geometries are ready to be
+// uploaded: they were deduplicated and had their tangents generated when cooked.
+func ReadKSM(r io.Reader) ([]*metadata.GeometryConfig, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+
+	if binary.LittleEndian.Uint32(start) != uint32(metadata.ResourceMagic) {
+		return readLegacyKSM(br)
+	}
+
+	if _, err := readHeader(br, metadata.ResourceTypeMesh, KSM_VERSION); err != nil {
+		return nil, err
+	}
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	return readKSMGeometries(br, count)
+}
+
+func readLegacyKSM(r io.Reader) ([]*metadata.GeometryConfig, error) {
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != ksmLegacyVersion {
+		return nil, errors.New("not a .ksm file")
+	}
+	// The mesh name is not used, geometries have their own.
+	if _, err := readString(r); err != nil {
+		return nil, err
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	return readKSMGeometries(r, count)
+}
+
+func readKSMGeometries(r io.Reader, count uint32) ([]*metadata.GeometryConfig, error) {
+	vertexSize := uint32(unsafe.Sizeof(math.Vertex3D{}))
+	configs := make([]*metadata.GeometryConfig, 0, count)
+	for i := uint32(0); i < count; i++ {
+		g := &metadata.GeometryConfig{}
+
+		if err := readValues(r, &g.VertexSize, &g.VertexCount); err != nil {
+			return nil, err
+		}
+		if g.VertexSize != vertexSize {
+			return nil, fmt.Errorf("geometry %d has a vertex size of %d, expected %d", i, g.VertexSize, vertexSize)
+		}
+		g.Vertices = make([]math.Vertex3D, g.VertexCount)
+		if err := binary.Read(r, binary.LittleEndian, g.Vertices); err != nil {
+			return nil, err
+		}
+
+		if err := readValues(r, &g.IndexSize, &g.IndexCount); err != nil {
+			return nil, err
+		}
+		if g.IndexSize != 4 {
+			return nil, fmt.Errorf("geometry %d has an index size of %d, expected 4", i, g.IndexSize)
+		}
+		g.Indices = make([]uint32, g.IndexCount)
+		if err := binary.Read(r, binary.LittleEndian, g.Indices); err != nil {
+			return nil, err
+		}
+		for _, index := range g.Indices {
+			if index >= g.VertexCount {
+				return nil, fmt.Errorf("geometry %d has an index out of range: %d", i, index)
+			}
+		}
+
+		var err error
+		if g.Name, err = readString(r); err != nil {
+			return nil, err
+		}
+		if g.MaterialName, err = readString(r); err != nil {
+			return nil, err
+		}
+		if err := readValues(r, &g.Center, &g.MinExtents, &g.MaxExtents); err != nil {
+			return nil, err
+		}
+		configs = append(configs, g)
+	}
+	return configs, nil
+}
+
+// writeHeader writes the header of an anima binary resource.
+func writeHeader(w io.Writer, resourceType metadata.ResourceType, version uint8) error {
+	return writeValues(w, uint32(metadata.ResourceMagic), uint8(resourceType), version, uint16(0))
+}
+
+// readHeader reads the header of an anima binary resource of the given type, and returns the
+// version of its format.
+func readHeader(r io.Reader, resourceType metadata.ResourceType, maxVersion uint8) (uint8, error) {
+	var header struct {
+		MagicNumber  uint32
+		ResourceType uint8
+		Version      uint8
+		Reserved     uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+	if header.MagicNumber != uint32(metadata.ResourceMagic) {
+		return 0, errors.New("not an anima binary resource")
+	}
+	if metadata.ResourceType(header.ResourceType) != resourceType {
+		return 0, fmt.Errorf("unexpected resource type %d, expected %d", header.ResourceType, resourceType)
+	}
+	if header.Version == 0 || header.Version > maxVersion {
+		return 0, fmt.Errorf("unsupported format version %d", header.Version)
+	}
+	return header.Version, nil
+}
+
+// writeString writes a length prefixed string.
+func writeString(w io.Writer, value string) error {
+	return writeValues(w, uint32(len(value)), []byte(value))
+}
+
+// readString reads a length prefixed string, dropping the null terminator of legacy .ksm files.
+func readString(r io.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if length > 0xFFFF {
+		return "", fmt.Errorf("string too long: %d", length)
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\x00"), nil
+}
+
+func writeValues(w io.Writer, values ...interface{}) error {
+	for _, value := range values {
+		if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readValues(r io.Reader, values ...interface{}) error {
+	for _, value := range values {
+		if err := binary.Read(r, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
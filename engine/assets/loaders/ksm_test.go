@@ -0,0 +1,99 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+func ksmTriangle(name, material string) *metadata.GeometryConfig {
+	vertices := []math.Vertex3D{
+		{Position: math.NewVec3(0, 0, 0), Normal: math.NewVec3(0, 0, 1), Texcoord: math.NewVec2(0, 0), Colour: math.NewVec4(1, 1, 1, 1), Tangent: math.NewVec3(1, 0, 0)},
+		{Position: math.NewVec3(1, 0, 0), Normal: math.NewVec3(0, 0, 1), Texcoord: math.NewVec2(1, 0), Colour: math.NewVec4(1, 1, 1, 1), Tangent: math.NewVec3(1, 0, 0)},
+		{Position: math.NewVec3(0, 1, 0), Normal: math.NewVec3(0, 0, 1), Texcoord: math.NewVec2(0, 1), Colour: math.NewVec4(1, 1, 1, 1), Tangent: math.NewVec3(1, 0, 0)},
+	}
+	return &metadata.GeometryConfig{
+		VertexSize:   uint32(unsafe.Sizeof(math.Vertex3D{})),
+		VertexCount:  uint32(len(vertices)),
+		Vertices:     vertices,
+		IndexSize:    4,
+		IndexCount:   3,
+		Indices:      []uint32{0, 1, 2},
+		Center:       math.NewVec3(0.5, 0.5, 0),
+		MinExtents:   math.NewVec3(0, 0, 0),
+		MaxExtents:   math.NewVec3(1, 1, 0),
+		Name:         name,
+		MaterialName: material,
+	}
+}
+
+func TestKSMRoundTrip(t *testing.T) {
+	configs := []*metadata.GeometryConfig{
+		ksmTriangle("first", "Material.Default"),
+		ksmTriangle("second", ""),
+	}
+	var buffer bytes.Buffer
+	if err := WriteKSM(&buffer, configs); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadKSM(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, configs) {
+		t.Fatalf("read %+v, wrote %+v", read, configs)
+	}
+}
+
+func TestKSMReadLegacy(t *testing.T) {
+	config := ksmTriangle("legacy", "Material.Default")
+
+	// The headerless format written by Kohi, with null terminated strings.
+	var buffer bytes.Buffer
+	values := []interface{}{
+		ksmLegacyVersion, uint32(5), []byte("mesh\x00"), uint32(1),
+		config.VertexSize, config.VertexCount, config.Vertices,
+		config.IndexSize, config.IndexCount, config.Indices,
+		uint32(len(config.Name) + 1), []byte(config.Name + "\x00"),
+		uint32(len(config.MaterialName) + 1), []byte(config.MaterialName + "\x00"),
+		config.Center, config.MinExtents, config.MaxExtents,
+	}
+	for _, value := range values {
+		if err := binary.Write(&buffer, binary.LittleEndian, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read, err := ReadKSM(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || !reflect.DeepEqual(read[0], config) {
+		t.Fatalf("read %+v, want %+v", read, config)
+	}
+}
+
+func TestKSMRejectsCorruptFiles(t *testing.T) {
+	config := ksmTriangle("corrupt", "")
+	config.Indices = []uint32{0, 1, 3}
+	var buffer bytes.Buffer
+	if err := WriteKSM(&buffer, []*metadata.GeometryConfig{config}); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	if _, err := ReadKSM(bytes.NewReader(data)); err == nil {
+		t.Error("ReadKSM accepted an index out of range")
+	}
+	if _, err := ReadKSM(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Error("ReadKSM accepted a truncated file")
+	}
+
+	config.VertexCount = 4
+	if err := WriteKSM(&buffer, []*metadata.GeometryConfig{config}); err == nil {
+		t.Error("WriteKSM accepted a vertex count larger than the vertices")
+	}
+}
@@ -0,0 +1,144 @@
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/*
+ * Layout of a .kti cooked texture, little endian:
+ *
+ *   header  u32 magic (metadata.ResourceMagic), u8 resource type, u8 version, u16 reserved
+ *   u32     width, u32 height
+ *   u8      channel count, u8 flags (KTI_FLAG_FLIPPED_Y), u8 level count, u8 reserved
+ *   levels  u32 size, pixels; the base image first, then the mips down to 1x1
+ */
+
+// The version of the .kti format written by WriteKTI.
+const KTI_VERSION uint8 = 1
+
+// Set when the rows of the texture are stored bottom to top.
+const KTI_FLAG_FLIPPED_Y uint8 = 0x1
+
+// WriteKTI writes the image and its mips as a .kti texture. flipped tells whether the pixels
+// were flipped on the y-axis when decoded.
+func WriteKTI(w io.Writer, image *metadata.ImageResourceData, flipped bool) error {
+	levels := append([][]uint8{image.Pixels}, image.Mips...)
+	if len(levels) > 0xFF {
+		return fmt.Errorf("too many mip levels: %d", len(levels))
+	}
+	flags := uint8(0)
+	if flipped {
+		flags |= KTI_FLAG_FLIPPED_Y
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeHeader(bw, metadata.ResourceTypeImage, KTI_VERSION); err != nil {
+		return err
+	}
+	if err := writeValues(bw, image.Width, image.Height, image.ChannelCount, flags, uint8(len(levels)), uint8(0)); err != nil {
+		return err
+	}
+	width, height := image.Width, image.Height
+	for i, level := range levels {
+		if len(level) != int(width*height*uint32(image.ChannelCount)) {
+			return fmt.Errorf("level %d has %d bytes, expected %dx%dx%d", i, len(level), width, height, image.ChannelCount)
+		}
+		if err := writeValues(bw, uint32(len(level)), level); err != nil {
+			return err
+		}
+		width, height = mipSize(width), mipSize(height)
+	}
+	return bw.Flush()
+}
+
+// ReadKTI reads a .kti texture, and tells whether its pixels are flipped on the y-axis.
+func ReadKTI(r io.Reader) (*metadata.ImageResourceData, bool, error) {
+	br := bufio.NewReader(r)
+	if _, err := readHeader(br, metadata.ResourceTypeImage, KTI_VERSION); err != nil {
+		return nil, false, err
+	}
+	image := &metadata.ImageResourceData{}
+	var flags, levelCount, reserved uint8
+	if err := readValues(br, &image.Width, &image.Height, &image.ChannelCount, &flags, &levelCount, &reserved); err != nil {
+		return nil, false, err
+	}
+	if levelCount == 0 {
+		return nil, false, fmt.Errorf("texture without pixels")
+	}
+
+	width, height := image.Width, image.Height
+	for i := uint8(0); i < levelCount; i++ {
+		var size uint32
+		if err := readValues(br, &size); err != nil {
+			return nil, false, err
+		}
+		if size != width*height*uint32(image.ChannelCount) {
+			return nil, false, fmt.Errorf("level %d has %d bytes, expected %dx%dx%d", i, size, width, height, image.ChannelCount)
+		}
+		level := make([]uint8, size)
+		if _, err := io.ReadFull(br, level); err != nil {
+			return nil, false, err
+		}
+		if i == 0 {
+			image.Pixels = level
+		} else {
+			image.Mips = append(image.Mips, level)
+		}
+		width, height = mipSize(width), mipSize(height)
+	}
+	return image, flags&KTI_FLAG_FLIPPED_Y != 0, nil
+}
+
+// GenerateMips computes the mip levels of the image, averaging 2x2 blocks of pixels of the
+// previous level.
+func GenerateMips(image *metadata.ImageResourceData) {
+	channels := uint32(image.ChannelCount)
+	image.Mips = nil
+	source := image.Pixels
+	width, height := image.Width, image.Height
+	for width > 1 || height > 1 {
+		mipWidth, mipHeight := mipSize(width), mipSize(height)
+		mip := make([]uint8, mipWidth*mipHeight*channels)
+		for y := uint32(0); y < mipHeight; y++ {
+			// Odd sizes repeat the last row or column.
+			y0, y1 := min(2*y, height-1), min(2*y+1, height-1)
+			for x := uint32(0); x < mipWidth; x++ {
+				x0, x1 := min(2*x, width-1), min(2*x+1, width-1)
+				for c := uint32(0); c < channels; c++ {
+					sum := uint32(source[(y0*width+x0)*channels+c]) + uint32(source[(y0*width+x1)*channels+c]) +
+						uint32(source[(y1*width+x0)*channels+c]) + uint32(source[(y1*width+x1)*channels+c])
+					mip[(y*mipWidth+x)*channels+c] = uint8((sum + 2) / 4)
+				}
+			}
+		}
+		image.Mips = append(image.Mips, mip)
+		source = mip
+		width, height = mipWidth, mipHeight
+	}
+}
+
+// flipImage flips the image and its mips on the y-axis, in place.
+func flipImage(image *metadata.ImageResourceData) {
+	width, height := image.Width, image.Height
+	for _, level := range append([][]uint8{image.Pixels}, image.Mips...) {
+		stride := width * uint32(image.ChannelCount)
+		row := make([]uint8, stride)
+		for top, bottom := uint32(0), height-1; top < bottom; top, bottom = top+1, bottom-1 {
+			copy(row, level[top*stride:(top+1)*stride])
+			copy(level[top*stride:(top+1)*stride], level[bottom*stride:(bottom+1)*stride])
+			copy(level[bottom*stride:(bottom+1)*stride], row)
+		}
+		width, height = mipSize(width), mipSize(height)
+	}
+}
+
+func mipSize(size uint32) uint32 {
+	if size <= 1 {
+		return 1
+	}
+	return size / 2
+}
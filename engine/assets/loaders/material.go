@@ -2,8 +2,10 @@ package loaders
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -13,10 +15,15 @@ import (
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type MaterialLoader struct{}
+type MaterialLoader struct {
+	// The file system the materials are read from.
+	FS fs.FS
+	// Reports the unknown keys and the malformed lines as errors, instead of skipping them.
+	Strict bool
+}
 
 func (ml *MaterialLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	mCfg, err := parseAMTFile(path)
+	mCfg, err := parseAMTFile(ml.FS, path, ml.Strict)
 	if err != nil {
 		return nil, err
 	}
@@ -28,8 +35,8 @@ func (ml *MaterialLoader) Load(path string, assetType metadata.ResourceType, par
 	}, nil
 }
 
-func parseAMTFile(filename string) (*metadata.MaterialConfig, error) {
-	file, err := os.Open(filename)
+func parseAMTFile(fsys fs.FS, filename string, strict bool) (*metadata.MaterialConfig, error) {
+	file, err := fsys.Open(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +44,13 @@ func parseAMTFile(filename string) (*metadata.MaterialConfig, error) {
 
 	scanner := bufio.NewScanner(file)
 	materialConfig := &metadata.MaterialConfig{}
+	// The lines skipped, reported all at once in strict mode.
+	skipped := []error{}
+	lineNumber := 0
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
 
 		// Skip comments and empty lines
 		if strings.HasPrefix(line, "#") || line == "" {
@@ -49,6 +60,10 @@ func parseAMTFile(filename string) (*metadata.MaterialConfig, error) {
 		// Split key-value pairs by the first "=" sign
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
+			if strict {
+				skipped = append(skipped, fmt.Errorf("line %d: invalid line: %s", lineNumber, line))
+				continue
+			}
 			fmt.Printf("Skipping invalid line: %s\n", line)
 			continue
 		}
@@ -113,12 +128,19 @@ func parseAMTFile(filename string) (*metadata.MaterialConfig, error) {
 			}
 			materialConfig.AutoRelease = autoRelease
 		default:
+			if strict {
+				skipped = append(skipped, fmt.Errorf("line %d: unknown key '%s'", lineNumber, key))
+				continue
+			}
 			core.LogError("Unknown key '%s' found in file. Skipping...", key)
 		}
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if len(skipped) > 0 {
+		return nil, errors.Join(skipped...)
+	}
 	// Perform validation
 	if err := validateMaterial(materialConfig); err != nil {
 		return nil, err
@@ -126,6 +148,56 @@ func parseAMTFile(filename string) (*metadata.MaterialConfig, error) {
 	return materialConfig, nil
 }
 
+// WriteAMTFile writes the material config in the .amt format read by the MaterialLoader.
+func WriteAMTFile(w io.Writer, material *metadata.MaterialConfig) error {
+	if err := validateMaterial(material); err != nil {
+		return err
+	}
+	lines := []string{
+		"#material file",
+		"",
+		fmt.Sprintf("version=%.1f", material.Version),
+		fmt.Sprintf("name=%s", material.Name),
+		fmt.Sprintf("diffuse_colour=%f %f %f %f", material.DiffuseColour.X, material.DiffuseColour.Y, material.DiffuseColour.Z, material.DiffuseColour.W),
+		fmt.Sprintf("shininess=%f", material.Shininess),
+	}
+	if material.DiffuseMapName != "" {
+		lines = append(lines, fmt.Sprintf("diffuse_map_name=%s", material.DiffuseMapName))
+	}
+	if material.SpecularMapName != "" {
+		lines = append(lines, fmt.Sprintf("specular_map_name=%s", material.SpecularMapName))
+	}
+	if material.NormalMapName != "" {
+		lines = append(lines, fmt.Sprintf("normal_map_name=%s", material.NormalMapName))
+	}
+	lines = append(lines,
+		fmt.Sprintf("autorelease=%t", material.AutoRelease),
+		fmt.Sprintf("shader=%s", material.ShaderName),
+	)
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// ReferenceByGUID returns a copy of the material referencing its textures and its shader by
+// the GUIDs guid returns for their names. The names guid does not know are kept.
+func ReferenceByGUID(material *metadata.MaterialConfig, guid func(name string, resourceType metadata.ResourceType) (string, bool)) *metadata.MaterialConfig {
+	reference := func(name string, resourceType metadata.ResourceType) string {
+		if name == "" {
+			return name
+		}
+		if id, ok := guid(name, resourceType); ok {
+			return id
+		}
+		return name
+	}
+	referenced := *material
+	referenced.DiffuseMapName = reference(material.DiffuseMapName, metadata.ResourceTypeImage)
+	referenced.SpecularMapName = reference(material.SpecularMapName, metadata.ResourceTypeImage)
+	referenced.NormalMapName = reference(material.NormalMapName, metadata.ResourceTypeImage)
+	referenced.ShaderName = reference(material.ShaderName, metadata.ResourceTypeShader)
+	return &referenced
+}
+
 func validateMaterial(material *metadata.MaterialConfig) error {
 	if material.Name == "" {
 		return fmt.Errorf("material name is required")
@@ -177,6 +249,8 @@ func isValidTextureName(name string) bool {
 	return len(name) > 0
 }
 
-func (ml *MaterialLoader) Unload(*metadata.Resource) error {
+func (ml *MaterialLoader) Unload(resource *metadata.Resource) error {
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
@@ -1,28 +1,237 @@
 package loaders
 
 import (
-	"os"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"unsafe"
 
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type ModelLoader struct{}
+type ModelLoader struct {
+	// The file system the models are read from.
+	FS fs.FS
+	// Writes a file next to the assets, used to save the materials found in model files as
+	// materials/<name>.amt. Materials that already exist are kept. Nil disables it.
+	WriteFile func(name string, data []byte) error
+	// Stores a file in memory, used for the images embedded in model files. Nil disables it.
+	EmbedFile func(name string, data []byte) error
+	// Returns the GUID of the asset referenced by name, so the materials written reference
+	// their textures and shader by GUID. Nil, or a name it does not know, keeps the name.
+	GUID func(name string, resourceType metadata.ResourceType) (string, bool)
+}
+
+// Load imports the model at the given path. Models (ResourceTypeModel) are loaded as a
+// *metadata.ModelConfig keeping the node hierarchy. Meshes (ResourceTypeMesh) are loaded as a
+// []*metadata.GeometryConfig, with the transforms of the nodes baked into the vertices.
+// The params may be the *metadata.ModelImportSettings of the model.
+// The resource size is the size of the vertices and indices, in bytes.
+func (ml *ModelLoader) Load(filename string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+
+	var model *metadata.ModelConfig
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".obj":
+		geometries, materials, err := ImportOBJ(ml.FS, filename)
+		if err != nil {
+			return nil, err
+		}
+		model = singleNodeModel(name, geometries, materials)
+	case ".ksm":
+		file, err := ml.FS.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		geometries, err := ReadKSM(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mesh `%s`: %w", filename, err)
+		}
+		model = singleNodeModel(name, geometries, nil)
+	case ".gltf", ".glb":
+		imported, err := ImportGLTF(ml.FS, filename, ml.EmbedFile)
+		if err != nil {
+			return nil, err
+		}
+		model = imported
+	default:
+		return nil, fmt.Errorf("unsupported model format `%s`", filename)
+	}
+	if settings, ok := params.(*metadata.ModelImportSettings); ok && settings != nil {
+		if err := applyImportSettings(model, settings); err != nil {
+			return nil, fmt.Errorf("invalid import settings for `%s`: %w", filename, err)
+		}
+	}
+	ml.writeMaterials(model.Materials)
+
+	resource := &metadata.Resource{
+		Name:     name,
+		FullPath: filename,
+		DataSize: geometriesSize(model.Geometries),
+		Data:     model,
+	}
+	if assetType != metadata.ResourceTypeModel {
+		geometries := BakeModel(model)
+		resource.DataSize = geometriesSize(geometries)
+		resource.Data = geometries
+	}
+	return resource, nil
+}
 
-func (ml *ModelLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	// Read and parse the model file (e.g., OBJ, FBX)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// BakeModel flattens the model into a list of geometries, transforming the vertices of every
+// geometry by the world transform of the nodes drawing it.
+func BakeModel(model *metadata.ModelConfig) []*metadata.GeometryConfig {
+	identity := math.NewMat4Identity()
+	geometries := []*metadata.GeometryConfig{}
+	for _, node := range model.Nodes {
+		world := node.Transform.GetWorld()
+		for _, index := range node.Geometries {
+			g := model.Geometries[index]
+			if world == identity {
+				geometries = append(geometries, g)
+				continue
+			}
+			geometries = append(geometries, transformGeometry(g, world))
+		}
 	}
-	// Implement parsing based on file format and return a model struct
-	model := ml.parseModelData(data)
-	return model, nil
+	return geometries
 }
 
-func (ml *ModelLoader) parseModelData(data []byte) *metadata.Resource {
+// transformGeometry returns a copy of the geometry with its vertices transformed.
+func transformGeometry(g *metadata.GeometryConfig, m math.Mat4) *metadata.GeometryConfig {
+	// Directions are not translated.
+	direction := m
+	direction.Data[12], direction.Data[13], direction.Data[14] = 0, 0, 0
+
+	baked := *g
+	baked.Vertices = make([]math.Vertex3D, len(g.Vertices))
+	for i, v := range g.Vertices {
+		v.Position = v.Position.Transform(m)
+		v.Normal = v.Normal.Transform(direction).Normalized()
+		v.Tangent = v.Tangent.Transform(direction).Normalized()
+		baked.Vertices[i] = v
+	}
+	baked.Indices = append([]uint32(nil), g.Indices...)
+	baked.MinExtents, baked.MaxExtents, baked.Center = computeExtents(baked.Vertices)
+	return &baked
+}
+
+// applyImportSettings converts the model to the engine conventions by placing its root nodes
+// under a new root, scaling and rotating them.
+func applyImportSettings(model *metadata.ModelConfig, settings *metadata.ModelImportSettings) error {
+	rotation := math.NewQuatIdentity()
+	switch strings.ToLower(settings.UpAxis) {
+	case "", "y":
+	case "z":
+		// A quarter turn around X brings Z up to Y up. Converted like the glTF rotations,
+		// Quaternion.ToMat4 builds the transposed rotation.
+		rotation = math.NewQuatFromAxisAngle(math.NewVec3(1.0, 0.0, 0.0), -math.K_HALF_PI, true).Conjugate()
+	default:
+		return fmt.Errorf("unknown up axis `%s`", settings.UpAxis)
+	}
+	scale := settings.Scale
+	if scale == 0 {
+		scale = 1.0
+	}
+	if scale < 0 {
+		return fmt.Errorf("negative scale %f", scale)
+	}
+	if scale == 1.0 && rotation == math.NewQuatIdentity() {
+		return nil
+	}
+
+	root := &metadata.ModelNode{
+		Name:      model.Name,
+		Transform: math.TransformFromPositionRotationScale(math.NewVec3Zero(), rotation, math.NewVec3(scale, scale, scale)),
+		Parent:    -1,
+	}
+	for _, node := range model.Nodes {
+		// The indices shift by one, the former roots now point to the new one.
+		node.Parent++
+		if node.Parent == 0 {
+			node.Transform.Parent = root.Transform
+		}
+	}
+	model.Nodes = append([]*metadata.ModelNode{root}, model.Nodes...)
 	return nil
 }
 
-func (ml *ModelLoader) Unload(*metadata.Resource) error {
+// singleNodeModel creates a model drawing all the geometries at its origin.
+func singleNodeModel(name string, geometries []*metadata.GeometryConfig, materials []*metadata.MaterialConfig) *metadata.ModelConfig {
+	root := &metadata.ModelNode{
+		Name:      name,
+		Transform: math.TransformCreate(),
+		Parent:    -1,
+	}
+	for i := range geometries {
+		root.Geometries = append(root.Geometries, uint32(i))
+	}
+	return &metadata.ModelConfig{
+		Name:       name,
+		Geometries: geometries,
+		Materials:  materials,
+		Nodes:      []*metadata.ModelNode{root},
+	}
+}
+
+// writeMaterials saves the imported materials that do not exist yet as .amt files, so the
+// material system can acquire them by name. Their references are written as GUIDs if known.
+func (ml *ModelLoader) writeMaterials(materials []*metadata.MaterialConfig) {
+	if ml.WriteFile == nil {
+		return
+	}
+	for _, material := range materials {
+		name := fmt.Sprintf("materials/%s.amt", material.Name)
+		if _, err := fs.Stat(ml.FS, name); err == nil {
+			continue
+		}
+		if ml.GUID != nil {
+			material = ReferenceByGUID(material, ml.GUID)
+		}
+		var buffer bytes.Buffer
+		if err := WriteAMTFile(&buffer, material); err != nil {
+			core.LogWarn("failed to write material `%s`: %s", material.Name, err.Error())
+			continue
+		}
+		if err := ml.WriteFile(name, buffer.Bytes()); err != nil {
+			core.LogWarn("failed to write material `%s`: %s", material.Name, err.Error())
+		}
+	}
+}
+
+func (ml *ModelLoader) Unload(resource *metadata.Resource) error {
+	switch data := resource.Data.(type) {
+	case *metadata.ModelConfig:
+		disposeGeometries(data.Geometries)
+		data.Geometries = nil
+		data.Materials = nil
+		data.Nodes = nil
+	case []*metadata.GeometryConfig:
+		disposeGeometries(data)
+	}
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
+
+// geometriesSize returns the size of the vertices and indices of the geometries, in bytes.
+func geometriesSize(geometries []*metadata.GeometryConfig) uint64 {
+	size := uint64(0)
+	for _, g := range geometries {
+		size += uint64(len(g.Vertices))*uint64(unsafe.Sizeof(math.Vertex3D{})) + uint64(len(g.Indices))*4
+	}
+	return size
+}
+
+func disposeGeometries(geometries []*metadata.GeometryConfig) {
+	for _, g := range geometries {
+		g.Vertices = nil
+		g.Indices = nil
+	}
+}
@@ -0,0 +1,117 @@
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/** @brief The shader used by the materials imported from model files. */
+const ImportedMaterialShaderName string = "Shader.Builtin.Material"
+
+// ParseMTL parses the Wavefront MTL file at the given path into material configs. Texture maps
+// are referenced by their file name without extension, like in .amt files.
+func ParseMTL(fsys fs.FS, filename string) ([]*metadata.MaterialConfig, error) {
+	file, err := fsys.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	materials := []*metadata.MaterialConfig{}
+	var current *metadata.MaterialConfig
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		// Skip comments and empty lines
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		args := fields[1:]
+
+		if fields[0] == "newmtl" {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s:%d: newmtl without a name", filename, lineNumber)
+			}
+			current = &metadata.MaterialConfig{
+				Version:       0.1,
+				Name:          args[0],
+				ShaderName:    ImportedMaterialShaderName,
+				AutoRelease:   true,
+				DiffuseColour: math.NewVec4One(),
+				Shininess:     8.0,
+			}
+			materials = append(materials, current)
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("%s:%d: '%s' before any newmtl", filename, lineNumber, fields[0])
+		}
+
+		switch fields[0] {
+		case "Kd":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+			}
+			current.DiffuseColour.X = math.Clamp(v[0], 0.0, 1.0)
+			current.DiffuseColour.Y = math.Clamp(v[1], 0.0, 1.0)
+			current.DiffuseColour.Z = math.Clamp(v[2], 0.0, 1.0)
+		case "d", "Tr":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+			}
+			// Tr is the transparency, d the opacity.
+			alpha := v[0]
+			if fields[0] == "Tr" {
+				alpha = 1.0 - alpha
+			}
+			current.DiffuseColour.W = math.Clamp(alpha, 0.0, 1.0)
+		case "Ns":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+			}
+			// A shininess of 0 renders as fully lit, so keep the default.
+			if v[0] > 0 {
+				current.Shininess = v[0]
+			}
+		case "map_Kd":
+			current.DiffuseMapName = mtlMapName(args)
+		case "map_Ks":
+			current.SpecularMapName = mtlMapName(args)
+		case "map_bump", "map_Bump", "bump", "norm":
+			current.NormalMapName = mtlMapName(args)
+		case "Ka", "Ks", "Ke", "Ni", "Tf", "illum", "map_Ka", "map_d", "map_Ke", "map_Ns", "refl", "disp":
+			// Not supported by the material system.
+		default:
+			core.LogDebug("unknown MTL statement '%s', skipping", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// mtlMapName returns the texture name of a map statement: the file name without directory nor
+// extension. Options preceding the file name (e.g. -bm 1.0) are skipped.
+func mtlMapName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	file := strings.ReplaceAll(args[len(args)-1], "\\", "/")
+	base := path.Base(file)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
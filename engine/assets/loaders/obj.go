@@ -0,0 +1,283 @@
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/** @brief A corner of an OBJ face, as 0-based indices into the vertex data. -1 when missing. */
+type objCorner struct {
+	position int
+	texcoord int
+	normal   int
+}
+
+/** @brief The faces sharing the same object/group name and material. */
+type objGroup struct {
+	name         string
+	materialName string
+	// Triangulated faces, 3 corners each.
+	corners []objCorner
+}
+
+/** @brief The state of an OBJ file being parsed. */
+type objParser struct {
+	positions []math.Vec3
+	normals   []math.Vec3
+	texcoords []math.Vec2
+
+	groups  []*objGroup
+	current *objGroup
+
+	materials []*metadata.MaterialConfig
+}
+
+// ImportOBJ parses the Wavefront OBJ file at the given path, and the MTL libraries it references.
+// Every object, group and material change starts a new geometry. Faces with more than 3 vertices
+// are triangulated as fans.
+func ImportOBJ(fsys fs.FS, filename string) ([]*metadata.GeometryConfig, []*metadata.MaterialConfig, error) {
+	file, err := fsys.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	p := &objParser{
+		current: &objGroup{name: name},
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		// Skip comments and empty lines
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := p.parseLine(fsys, path.Dir(filename), line); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	p.flush(p.current.name)
+
+	configs := make([]*metadata.GeometryConfig, 0, len(p.groups))
+	for _, g := range p.groups {
+		configs = append(configs, p.buildGeometry(g))
+	}
+	return configs, p.materials, nil
+}
+
+func (p *objParser) parseLine(fsys fs.FS, dir string, line string) error {
+	fields := strings.Fields(line)
+	args := fields[1:]
+
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, math.NewVec3(v[0], v[1], v[2]))
+	case "vn":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		p.normals = append(p.normals, math.NewVec3(v[0], v[1], v[2]))
+	case "vt":
+		// The optional w component is ignored.
+		v, err := parseFloats(args, 2)
+		if err != nil {
+			return err
+		}
+		p.texcoords = append(p.texcoords, math.NewVec2(v[0], v[1]))
+	case "f":
+		return p.parseFace(args)
+	case "o", "g":
+		if len(args) == 0 {
+			return nil
+		}
+		p.flush(strings.Join(args, " "))
+	case "usemtl":
+		if len(args) == 0 {
+			return fmt.Errorf("usemtl without a material name")
+		}
+		p.flush(p.current.name)
+		p.current.materialName = args[0]
+	case "mtllib":
+		for _, lib := range args {
+			materials, err := ParseMTL(fsys, path.Join(dir, lib))
+			if err != nil {
+				return err
+			}
+			p.materials = append(p.materials, materials...)
+		}
+	case "s", "l", "p":
+		// Smoothing groups, lines and points are not supported.
+	default:
+		core.LogDebug("unknown OBJ statement '%s', skipping", fields[0])
+	}
+	return nil
+}
+
+// flush closes the current group if it has faces, and starts a new one with the given name
+// and the same material.
+func (p *objParser) flush(name string) {
+	if len(p.current.corners) > 0 {
+		p.groups = append(p.groups, p.current)
+		p.current = &objGroup{materialName: p.current.materialName}
+	}
+	p.current.name = name
+}
+
+func (p *objParser) parseFace(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face with %d vertices", len(args))
+	}
+	corners := make([]objCorner, len(args))
+	for i, arg := range args {
+		c, err := p.parseCorner(arg)
+		if err != nil {
+			return err
+		}
+		corners[i] = c
+	}
+	// Triangulate as a fan around the first vertex.
+	for i := 1; i < len(corners)-1; i++ {
+		p.current.corners = append(p.current.corners, corners[0], corners[i], corners[i+1])
+	}
+	return nil
+}
+
+// parseCorner parses v, v/vt, v//vn or v/vt/vn. Indices are 1-based, or relative to the end
+// of the data read so far when negative.
+func (p *objParser) parseCorner(arg string) (objCorner, error) {
+	c := objCorner{position: -1, texcoord: -1, normal: -1}
+	parts := strings.Split(arg, "/")
+	if len(parts) > 3 {
+		return c, fmt.Errorf("invalid face vertex '%s'", arg)
+	}
+	counts := []int{len(p.positions), len(p.texcoords), len(p.normals)}
+	targets := []*int{&c.position, &c.texcoord, &c.normal}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return c, fmt.Errorf("face vertex without position '%s'", arg)
+			}
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return c, fmt.Errorf("invalid face vertex '%s'", arg)
+		}
+		if index < 0 {
+			index += counts[i]
+		} else {
+			index--
+		}
+		if index < 0 || index >= counts[i] {
+			return c, fmt.Errorf("face vertex '%s' out of range", arg)
+		}
+		*targets[i] = index
+	}
+	return c, nil
+}
+
+// buildGeometry creates the geometry config of the group, removing the duplicated vertices and
+// generating normals when the file has none and tangents when it has texture coordinates.
+func (p *objParser) buildGeometry(g *objGroup) *metadata.GeometryConfig {
+	vertexCount := uint32(len(g.corners))
+	vertices := make([]math.Vertex3D, vertexCount)
+	indices := make([]uint32, vertexCount)
+
+	hasNormals := true
+	hasTexcoords := true
+	for i, c := range g.corners {
+		v := &vertices[i]
+		v.Position = p.positions[c.position]
+		v.Colour = math.Vec4{X: 1.0, Y: 1.0, Z: 1.0, W: 1.0}
+		if c.normal >= 0 {
+			v.Normal = p.normals[c.normal]
+		} else {
+			hasNormals = false
+		}
+		if c.texcoord >= 0 {
+			v.Texcoord = p.texcoords[c.texcoord]
+		} else {
+			hasTexcoords = false
+		}
+		indices[i] = uint32(i)
+	}
+
+	if !hasNormals {
+		math.GeometryGenerateNormals(vertexCount, vertices, vertexCount, indices)
+	}
+	vertexCount, vertices = math.GeometryDeduplicateVertices(vertexCount, vertices, vertexCount, indices)
+	// Generated after the deduplication, so the tangents of the triangles sharing a vertex are averaged.
+	if hasTexcoords {
+		vertices = math.GeometryGenerateTangents(vertexCount, vertices, uint32(len(indices)), indices)
+	}
+
+	config := &metadata.GeometryConfig{
+		VertexSize:   uint32(unsafe.Sizeof(math.Vertex3D{})),
+		VertexCount:  vertexCount,
+		Vertices:     vertices,
+		IndexSize:    uint32(unsafe.Sizeof(uint32(0))),
+		IndexCount:   uint32(len(indices)),
+		Indices:      indices,
+		Name:         g.name,
+		MaterialName: g.materialName,
+	}
+	config.MinExtents, config.MaxExtents, config.Center = computeExtents(vertices)
+	return config
+}
+
+// computeExtents returns the bounding box of the vertices and its center.
+func computeExtents(vertices []math.Vertex3D) (math.Vec3, math.Vec3, math.Vec3) {
+	if len(vertices) == 0 {
+		return math.NewVec3Zero(), math.NewVec3Zero(), math.NewVec3Zero()
+	}
+	minExtents := vertices[0].Position
+	maxExtents := vertices[0].Position
+	for _, v := range vertices[1:] {
+		minExtents.X = min(minExtents.X, v.Position.X)
+		minExtents.Y = min(minExtents.Y, v.Position.Y)
+		minExtents.Z = min(minExtents.Z, v.Position.Z)
+		maxExtents.X = max(maxExtents.X, v.Position.X)
+		maxExtents.Y = max(maxExtents.Y, v.Position.Y)
+		maxExtents.Z = max(maxExtents.Z, v.Position.Z)
+	}
+	center := minExtents.Add(maxExtents).MulScalar(0.5)
+	return minExtents, maxExtents, center
+}
+
+// parseFloats parses at least count floats out of the fields.
+func parseFloats(fields []string, count int) ([]float32, error) {
+	if len(fields) < count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+	}
+	values := make([]float32, count)
+	for i := 0; i < count; i++ {
+		v, err := strconv.ParseFloat(fields[i], 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s'", fields[i])
+		}
+		values[i] = float32(v)
+	}
+	return values, nil
+}
@@ -0,0 +1,205 @@
+package loaders
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+func TestMain(m *testing.M) {
+	if err := core.InitializeLogger(core.WarnLevel); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// importTestOBJ imports the OBJ source as models/test.obj, next to the given MTL library.
+func importTestOBJ(t *testing.T, source string, mtl string) ([]*metadata.GeometryConfig, []*metadata.MaterialConfig, error) {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"models/test.obj": {Data: []byte(source)},
+		"models/test.mtl": {Data: []byte(mtl)},
+	}
+	return ImportOBJ(fsys, "models/test.obj")
+}
+
+// trianglePositions returns the positions of the corners of the triangles, in index order.
+func trianglePositions(config *metadata.GeometryConfig) []math.Vec3 {
+	positions := make([]math.Vec3, len(config.Indices))
+	for i, index := range config.Indices {
+		positions[i] = config.Vertices[index].Position
+	}
+	return positions
+}
+
+const objSquare = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0.5 1.5 0
+`
+
+func TestImportOBJFan(t *testing.T) {
+	configs, _, err := importTestOBJ(t, objSquare+"f 1 2 3 4\nf 1 2 3 5 4\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("got %d geometries, want 1", len(configs))
+	}
+	// The quad and the pentagon are fans around their first vertex.
+	order := []int{0, 1, 2, 0, 2, 3, 0, 1, 2, 0, 2, 4, 0, 4, 3}
+	corners := []math.Vec3{
+		math.NewVec3(0, 0, 0), math.NewVec3(1, 0, 0), math.NewVec3(1, 1, 0), math.NewVec3(0, 1, 0), math.NewVec3(0.5, 1.5, 0),
+	}
+	got := trianglePositions(configs[0])
+	if len(got) != len(order) {
+		t.Fatalf("got %d corners, want %d", len(got), len(order))
+	}
+	for i, corner := range order {
+		if got[i] != corners[corner] {
+			t.Errorf("corner %d: got %v, want %v", i, got[i], corners[corner])
+		}
+	}
+	// The corners shared by the triangles are deduplicated.
+	if configs[0].VertexCount != 5 {
+		t.Errorf("got %d vertices, want 5", configs[0].VertexCount)
+	}
+}
+
+func TestImportOBJIndices(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []math.Vec3
+	}{
+		{
+			"negative",
+			objSquare + "f -5 -4 -3\n",
+			[]math.Vec3{math.NewVec3(0, 0, 0), math.NewVec3(1, 0, 0), math.NewVec3(1, 1, 0)},
+		},
+		{
+			// Relative to the vertices read before the face, not to the whole file.
+			"relative to the face",
+			"v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\nv 5 5 5\n",
+			[]math.Vec3{math.NewVec3(0, 0, 0), math.NewVec3(1, 0, 0), math.NewVec3(0, 1, 0)},
+		},
+		{
+			"mixed",
+			objSquare + "vt 0 0\nvt 1 0\nvn 0 0 1\nf 1/1/1 -4/-1/-1 3//1\n",
+			[]math.Vec3{math.NewVec3(0, 0, 0), math.NewVec3(1, 0, 0), math.NewVec3(1, 1, 0)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configs, _, err := importTestOBJ(t, test.source, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := trianglePositions(configs[0])
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("got %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestImportOBJInvalidFaces(t *testing.T) {
+	tests := []struct {
+		name string
+		face string
+		err  string
+	}{
+		{"zero index", "f 0 1 2", "out of range"},
+		{"past the positions", "f 1 2 6", "out of range"},
+		{"before the positions", "f -6 1 2", "out of range"},
+		{"past the texture coordinates", "f 1/2 2/1 3/1", "out of range"},
+		{"past the normals", "f 1//2 2//1 3//1", "out of range"},
+		{"two vertices", "f 1 2", "face with 2 vertices"},
+		{"no position", "f /1 2 3", "without position"},
+		{"too many parts", "f 1/1/1/1 2 3", "invalid face vertex"},
+		{"not a number", "f 1 two 3", "invalid face vertex"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := objSquare + "vt 0 0\nvn 0 0 1\n" + test.face + "\n"
+			_, _, err := importTestOBJ(t, source, "")
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want one containing %q", err, test.err)
+			}
+			// The error tells the line of the face.
+			if !strings.Contains(err.Error(), "models/test.obj:9:") {
+				t.Errorf("got error %v without the line of the face", err)
+			}
+		})
+	}
+}
+
+func TestImportOBJGroups(t *testing.T) {
+	source := objSquare + `
+mtllib test.mtl
+f 1 2 3
+o first
+usemtl red
+usemtl green
+f 1 2 3
+f 1 3 4
+g second
+f 1 2 3
+usemtl red
+f 1 3 4
+o empty
+o third
+f 2 3 4
+`
+	mtl := `
+newmtl red
+Kd 1 0 0
+newmtl green
+Kd 0 1 0
+map_Kd textures\grass.png
+`
+	configs, materials, err := importTestOBJ(t, source, mtl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every object, group and material change starts a geometry, the empty ones are skipped.
+	want := []struct {
+		name, material string
+		indices        uint32
+	}{
+		{"test", "", 3},
+		{"first", "green", 6},
+		{"second", "green", 3},
+		{"second", "red", 3},
+		{"third", "red", 3},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("got %d geometries, want %d", len(configs), len(want))
+	}
+	for i, w := range want {
+		if configs[i].Name != w.name || configs[i].MaterialName != w.material || configs[i].IndexCount != w.indices {
+			t.Errorf("geometry %d: got %s with %s and %d indices, want %s with %s and %d",
+				i, configs[i].Name, configs[i].MaterialName, configs[i].IndexCount, w.name, w.material, w.indices)
+		}
+	}
+
+	if len(materials) != 2 || materials[0].Name != "red" || materials[1].Name != "green" {
+		t.Fatalf("got materials %+v, want red and green", materials)
+	}
+	if materials[0].DiffuseColour != math.NewVec4(1, 0, 0, 1) || materials[1].DiffuseMapName != "grass" {
+		t.Errorf("got materials %+v and %+v", *materials[0], *materials[1])
+	}
+}
@@ -1,15 +1,33 @@
 package loaders
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type ShaderLoader struct{}
+type ShaderLoader struct {
+	// The file system the shader configurations are read from.
+	FS fs.FS
+	// Rejects the unknown keys, and the configs the renderer would fail to create a shader from.
+	Strict bool
+}
+
+// The version of the .ksc format written by WriteKSC.
+const KSC_VERSION uint8 = 1
 
 type tmpShaderConfig struct {
 	Version    string      `toml:"version"`
@@ -22,6 +40,11 @@ type tmpShaderConfig struct {
 	DepthWrite int         `toml:"depth_write"`
 	Attributes []attribute `toml:"attribute"`
 	Uniforms   []uniform   `toml:"uniform"`
+	Structs    []structure `toml:"struct"`
+	// The local uniforms declared as a block, visible to the given stages.
+	PushConstants *pushConstants `toml:"push_constants"`
+	Storage       []storage      `toml:"storage"`
+	Keywords      []keyword      `toml:"keyword"`
 }
 
 // attribute represents a single attribute entry
@@ -37,7 +60,37 @@ type uniform struct {
 	Name  string `toml:"name"`
 }
 
-// Validate checks for duplicate names in Attributes and Uniforms
+// structure represents a single struct entry, its members are in order
+type structure struct {
+	Name    string   `toml:"name"`
+	Members []member `toml:"member"`
+}
+
+// member represents a single member of a struct or of the push constants
+type member struct {
+	Type string `toml:"type"`
+	Name string `toml:"name"`
+}
+
+// pushConstants represents the push constant block
+type pushConstants struct {
+	Stages  []string `toml:"stages"`
+	Members []member `toml:"member"`
+}
+
+// storage represents a single read-only storage buffer entry
+type storage struct {
+	Type string `toml:"type"`
+	Name string `toml:"name"`
+}
+
+// keyword represents a single keyword entry
+type keyword struct {
+	Name   string   `toml:"name"`
+	Stages []string `toml:"stages"`
+}
+
+// Validate checks for duplicate names in Attributes, Uniforms and Keywords
 func (config *tmpShaderConfig) Validate() error {
 	attrNames := make(map[string]bool)
 	for _, attr := range config.Attributes {
@@ -46,16 +99,54 @@ func (config *tmpShaderConfig) Validate() error {
 		}
 		attrNames[attr.Name] = true
 	}
+	// The push constants and the storage buffers are set by name like the uniforms.
 	uniformNames := make(map[string]bool)
+	names := []string{}
 	for _, uniform := range config.Uniforms {
-		if uniformNames[uniform.Name] {
-			return fmt.Errorf("duplicate uniform name found: %s", uniform.Name)
+		names = append(names, uniform.Name)
+		if uniform.Scope == int(metadata.ShaderScopeLocal) && config.PushConstants != nil {
+			return fmt.Errorf("local uniform `%s` declared besides the push_constants block", uniform.Name)
 		}
-		uniformNames[uniform.Name] = true
+	}
+	if config.PushConstants != nil {
+		for _, member := range config.PushConstants.Members {
+			names = append(names, member.Name)
+		}
+	}
+	for _, storage := range config.Storage {
+		names = append(names, storage.Name)
+	}
+	for _, name := range names {
+		if uniformNames[name] {
+			return fmt.Errorf("duplicate uniform name found: %s", name)
+		}
+		uniformNames[name] = true
+	}
+	structNames := make(map[string]bool)
+	for _, structure := range config.Structs {
+		if structNames[structure.Name] {
+			return fmt.Errorf("duplicate struct name found: %s", structure.Name)
+		}
+		structNames[structure.Name] = true
+		memberNames := make(map[string]bool)
+		for _, member := range structure.Members {
+			if memberNames[member.Name] {
+				return fmt.Errorf("duplicate member name found in struct %s: %s", structure.Name, member.Name)
+			}
+			memberNames[member.Name] = true
+		}
+	}
+	keywordNames := make(map[string]bool)
+	for _, keyword := range config.Keywords {
+		if keywordNames[keyword.Name] {
+			return fmt.Errorf("duplicate keyword name found: %s", keyword.Name)
+		}
+		keywordNames[keyword.Name] = true
 	}
 	return nil
 }
 
+// TransformToShaderConfig converts the parsed config, reporting all its invalid types at once.
 func (config *tmpShaderConfig) TransformToShaderConfig() (*metadata.ShaderConfig, error) {
 	shaderCfg := &metadata.ShaderConfig{
 		Name:           config.Name,
@@ -66,11 +157,13 @@ func (config *tmpShaderConfig) TransformToShaderConfig() (*metadata.ShaderConfig
 		DepthWrite:     config.DepthWrite != 0,
 	}
 
+	errs := []error{}
 	stages := make([]metadata.ShaderStage, len(config.Stages))
 	for i, st := range config.Stages {
 		s, err := metadata.ShaderStageFromString(st)
 		if err != nil {
-			return nil, err
+			errs = append(errs, err)
+			continue
 		}
 		stages[i] = s
 	}
@@ -80,7 +173,8 @@ func (config *tmpShaderConfig) TransformToShaderConfig() (*metadata.ShaderConfig
 	for i, att := range config.Attributes {
 		t, size, err := metadata.ShaderAttributeTypeFromString(att.Type)
 		if err != nil {
-			return nil, err
+			errs = append(errs, fmt.Errorf("attribute `%s`: %w", att.Name, err))
+			continue
 		}
 		attributes[i] = &metadata.ShaderAttributeConfig{
 			Name:                att.Name,
@@ -90,45 +184,180 @@ func (config *tmpShaderConfig) TransformToShaderConfig() (*metadata.ShaderConfig
 	}
 	shaderCfg.Attributes = attributes
 
+	// A struct holds the structs declared before it only.
+	for _, st := range config.Structs {
+		structCfg := &metadata.ShaderStructConfig{Name: st.Name}
+		for _, mem := range st.Members {
+			m, err := uniformConfig(mem.Name, mem.Type, 0, shaderCfg.Structs)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("struct `%s`: member `%s`: %w", st.Name, mem.Name, err))
+				continue
+			}
+			if m.ShaderUniformType == metadata.ShaderUniformTypeSampler {
+				errs = append(errs, fmt.Errorf("struct `%s`: member `%s` is a sampler, structs cannot hold samplers", st.Name, mem.Name))
+				continue
+			}
+			structCfg.Members = append(structCfg.Members, m)
+		}
+		shaderCfg.Structs = append(shaderCfg.Structs, structCfg)
+	}
+
 	uniforms := make([]*metadata.ShaderUniformConfig, len(config.Uniforms))
 	for i, unif := range config.Uniforms {
-		t, size, err := metadata.ShaderUniformTypeFromString(unif.Type)
+		u, err := uniformConfig(unif.Name, unif.Type, metadata.ShaderScope(unif.Scope), shaderCfg.Structs)
 		if err != nil {
-			return nil, err
+			errs = append(errs, fmt.Errorf("uniform `%s`: %w", unif.Name, err))
+			continue
 		}
-		uniforms[i] = &metadata.ShaderUniformConfig{
-			Name:              unif.Name,
-			Size:              size,
-			ShaderUniformType: t,
-			Location:          0,
-			Scope:             metadata.ShaderScope(unif.Scope),
+		uniforms[i] = u
+	}
+	if config.PushConstants != nil {
+		for _, st := range config.PushConstants.Stages {
+			s, err := metadata.ShaderStageFromString(st)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("push_constants: %w", err))
+				continue
+			}
+			shaderCfg.PushConstantStages = append(shaderCfg.PushConstantStages, s)
 		}
+		for _, mem := range config.PushConstants.Members {
+			u, err := uniformConfig(mem.Name, mem.Type, metadata.ShaderScopeLocal, shaderCfg.Structs)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("push constant `%s`: %w", mem.Name, err))
+				continue
+			}
+			uniforms = append(uniforms, u)
+		}
+	}
+	for _, sto := range config.Storage {
+		u, err := uniformConfig(sto.Name, sto.Type, metadata.ShaderScopeGlobal, shaderCfg.Structs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("storage buffer `%s`: %w", sto.Name, err))
+			continue
+		}
+		if u.ShaderUniformType == metadata.ShaderUniformTypeSampler {
+			errs = append(errs, fmt.Errorf("storage buffer `%s` cannot be a sampler", sto.Name))
+			continue
+		}
+		u.Storage = true
+		uniforms = append(uniforms, u)
 	}
 	shaderCfg.Uniforms = uniforms
 
+	keywords := make([]*metadata.ShaderKeywordConfig, len(config.Keywords))
+	for i, kw := range config.Keywords {
+		keywords[i] = &metadata.ShaderKeywordConfig{Name: kw.Name}
+		for _, st := range kw.Stages {
+			s, err := metadata.ShaderStageFromString(st)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("keyword `%s`: %w", kw.Name, err))
+				continue
+			}
+			keywords[i].Stages = append(keywords[i].Stages, s)
+		}
+	}
+	shaderCfg.Keywords = keywords
+
 	if config.CullMode != "" {
 		cm, err := metadata.CullModeFromString(config.CullMode)
 		if err != nil {
-			return nil, err
+			errs = append(errs, err)
 		}
 		shaderCfg.CullMode = cm
 	}
 
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return shaderCfg, nil
 }
 
-func (sl *ShaderLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	tmpShaderConfig := tmpShaderConfig{}
-	cfg, err := os.ReadFile(path)
+// uniformConfig returns the uniform of the given type: a uniform type, or one of the structs, with
+// an optional array length, e.g. vec4[8] or point_light[4].
+func uniformConfig(name, typeName string, scope metadata.ShaderScope, structs []*metadata.ShaderStructConfig) (*metadata.ShaderUniformConfig, error) {
+	uniform := &metadata.ShaderUniformConfig{
+		Name:  name,
+		Scope: scope,
+	}
+	if i := strings.IndexByte(typeName, '['); i >= 0 && strings.HasSuffix(typeName, "]") {
+		length, err := strconv.ParseUint(typeName[i+1:len(typeName)-1], 10, 32)
+		if err != nil || length == 0 {
+			return nil, fmt.Errorf("invalid array length in type %s", typeName)
+		}
+		uniform.Length = uint32(length)
+		typeName = typeName[:i]
+	}
+	if slices.ContainsFunc(structs, func(s *metadata.ShaderStructConfig) bool { return s.Name == typeName }) {
+		uniform.ShaderUniformType = metadata.ShaderUniformTypeStruct
+		uniform.StructName = typeName
+		return uniform, nil
+	}
+	t, size, err := metadata.ShaderUniformTypeFromString(typeName)
+	if err != nil {
+		return nil, err
+	}
+	if t == metadata.ShaderUniformTypeSampler && uniform.Length > 0 {
+		return nil, errors.New("samplers cannot be arrays, declare one per texture")
+	}
+	uniform.ShaderUniformType = t
+	uniform.Size = size
+	return uniform, nil
+}
+
+// Load reads the shader config at the given path, a .shadercfg or a cooked .ksc.
+func (sl *ShaderLoader) Load(filename string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	var shaderCfg *metadata.ShaderConfig
+	var err error
+	if strings.EqualFold(path.Ext(filename), ".ksc") {
+		shaderCfg, err = sl.readKSC(filename)
+	} else {
+		shaderCfg, err = parseShaderConfig(sl.FS, filename, sl.Strict)
+	}
 	if err != nil {
 		return nil, err
 	}
+	// A config drifting from its compiled stages, or those of its variants, makes the renderer
+	// write the uniforms and the vertices where the shader does not read them.
+	if err := checkShaderStages(sl.FS, shaderCfg); err != nil {
+		if sl.Strict {
+			return nil, err
+		}
+		core.LogError("shader `%s` does not match its stages: %s", shaderCfg.Name, err.Error())
+	}
+
+	return &metadata.Resource{
+		Name:     shaderCfg.Name,
+		FullPath: filename,
+		DataSize: uint64(unsafe.Sizeof(*shaderCfg)),
+		Data:     shaderCfg,
+	}, nil
+}
 
-	err = toml.Unmarshal([]byte(cfg), &tmpShaderConfig)
+// parseShaderConfig parses the .shadercfg file at the given path.
+func parseShaderConfig(fsys fs.FS, filename string, strict bool) (*metadata.ShaderConfig, error) {
+	tmpShaderConfig := tmpShaderConfig{}
+	cfg, err := fs.ReadFile(fsys, filename)
 	if err != nil {
 		return nil, err
 	}
 
+	decoder := toml.NewDecoder(bytes.NewReader(cfg))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&tmpShaderConfig); err != nil {
+		var strictErr *toml.StrictMissingError
+		if errors.As(err, &strictErr) {
+			unknown := make([]error, len(strictErr.Errors))
+			for i := range strictErr.Errors {
+				row, _ := strictErr.Errors[i].Position()
+				unknown[i] = fmt.Errorf("line %d: unknown key '%s'", row, strings.Join(strictErr.Errors[i].Key(), "."))
+			}
+			return nil, errors.Join(unknown...)
+		}
+		return nil, err
+	}
+
 	if err := tmpShaderConfig.Validate(); err != nil {
 		return nil, err
 	}
@@ -137,15 +366,156 @@ func (sl *ShaderLoader) Load(path string, assetType metadata.ResourceType, param
 	if err != nil {
 		return nil, err
 	}
+	if strict {
+		if err := validateShaderConfig(shaderCfg); err != nil {
+			return nil, err
+		}
+	}
+	return shaderCfg, nil
+}
 
-	return &metadata.Resource{
-		Name:     shaderCfg.Name,
-		FullPath: path,
-		DataSize: uint64(unsafe.Sizeof(shaderCfg)),
-		Data:     shaderCfg,
-	}, nil
+// validateShaderConfig checks what the renderer would otherwise only find out when creating
+// the shader. The stage files are references, their existence is checked by the asset manager.
+func validateShaderConfig(config *metadata.ShaderConfig) error {
+	errs := []error{}
+	if config.Name == "" {
+		errs = append(errs, errors.New("the shader has no name"))
+	}
+	if config.RenderpassName == "" {
+		errs = append(errs, errors.New("the shader has no renderpass"))
+	}
+	if len(config.Stages) == 0 {
+		errs = append(errs, errors.New("the shader has no stages"))
+	}
+	if len(config.Stages) != len(config.StageFilenames) {
+		errs = append(errs, fmt.Errorf("%d stages but %d stage files", len(config.Stages), len(config.StageFilenames)))
+	}
+	seen := map[metadata.ShaderStage]bool{}
+	for _, stage := range config.Stages {
+		if seen[stage] {
+			errs = append(errs, fmt.Errorf("stage %d declared twice", stage))
+		}
+		seen[stage] = true
+	}
+	for _, attribute := range config.Attributes {
+		if attribute.Name == "" {
+			errs = append(errs, errors.New("attribute without a name"))
+		}
+	}
+	pushConstants := []*metadata.ShaderBlockType{}
+	for _, uniform := range config.Uniforms {
+		if uniform.Name == "" {
+			errs = append(errs, errors.New("uniform without a name"))
+		}
+		switch uniform.Scope {
+		case metadata.ShaderScopeGlobal, metadata.ShaderScopeInstance, metadata.ShaderScopeLocal:
+		default:
+			errs = append(errs, fmt.Errorf("uniform `%s` has an invalid scope %d", uniform.Name, uniform.Scope))
+		}
+		if uniform.Storage && uniform.Scope != metadata.ShaderScopeGlobal {
+			errs = append(errs, fmt.Errorf("storage buffer `%s` is of scope %d, storage buffers are global", uniform.Name, uniform.Scope))
+		}
+		if uniform.ShaderUniformType == metadata.ShaderUniformTypeSampler {
+			continue
+		}
+		t, err := config.UniformBlockType(uniform)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if uniform.Scope == metadata.ShaderScopeLocal && !uniform.Storage {
+			pushConstants = append(pushConstants, t)
+		}
+	}
+	// The push constants the Vulkan spec guarantees, see Shader.PushConstantStride.
+	if _, end := metadata.ShaderBlockLayoutStd430.Offsets(pushConstants); end > 128 {
+		errs = append(errs, fmt.Errorf("the push constants take %d bytes, more than the 128 guaranteed", end))
+	}
+	for _, stage := range config.PushConstantStages {
+		if !slices.Contains(config.Stages, stage) {
+			errs = append(errs, fmt.Errorf("the push constants are visible to stage %d, which the shader does not have", stage))
+		}
+	}
+	for _, structure := range config.Structs {
+		if structure.Name == "" {
+			errs = append(errs, errors.New("struct without a name"))
+		}
+		if _, _, err := metadata.ShaderUniformTypeFromString(structure.Name); err == nil {
+			errs = append(errs, fmt.Errorf("struct `%s` is named after a uniform type", structure.Name))
+		}
+		if len(structure.Members) == 0 {
+			errs = append(errs, fmt.Errorf("struct `%s` has no members", structure.Name))
+		}
+	}
+	for _, keyword := range config.Keywords {
+		// The keywords name the stage files of the variants, and are defined in the stages.
+		if !isKeywordName(keyword.Name) {
+			errs = append(errs, fmt.Errorf("invalid keyword name `%s`, expected letters, digits and underscores", keyword.Name))
+		}
+		for _, stage := range keyword.Stages {
+			if !slices.Contains(config.Stages, stage) {
+				errs = append(errs, fmt.Errorf("keyword `%s` affects stage %d, which the shader does not have", keyword.Name, stage))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isKeywordName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (sl *ShaderLoader) readKSC(filename string) (*metadata.ShaderConfig, error) {
+	file, err := sl.FS.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := ReadKSC(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shader descriptor `%s`: %w", filename, err)
+	}
+	return config, nil
+}
+
+// WriteKSC writes the shader config as a .ksc binary descriptor: the resource header, then the
+// config encoded with encoding/gob, so that the descriptor follows the config as it grows.
+// KSC_VERSION changes when fields are renamed or change type.
+func WriteKSC(w io.Writer, config *metadata.ShaderConfig) error {
+	bw := bufio.NewWriter(w)
+	if err := writeHeader(bw, metadata.ResourceTypeShader, KSC_VERSION); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(bw).Encode(config); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadKSC reads a .ksc binary shader descriptor.
+func ReadKSC(r io.Reader) (*metadata.ShaderConfig, error) {
+	br := bufio.NewReader(r)
+	if _, err := readHeader(br, metadata.ResourceTypeShader, KSC_VERSION); err != nil {
+		return nil, err
+	}
+	config := &metadata.ShaderConfig{}
+	if err := gob.NewDecoder(br).Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
 func (sl *ShaderLoader) Unload(resource *metadata.Resource) error {
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
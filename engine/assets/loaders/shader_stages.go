@@ -0,0 +1,708 @@
+package loaders
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/spaghettifunk/anima/engine/assets/spirv"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The execution models of the compiled stages, by the stage they are declared as.
+var stageExecutionModels = map[metadata.ShaderStage]spirv.ExecutionModel{
+	metadata.ShaderStageVertex:   spirv.ExecutionModelVertex,
+	metadata.ShaderStageGeometry: spirv.ExecutionModelGeometry,
+	metadata.ShaderStageFragment: spirv.ExecutionModelFragment,
+	metadata.ShaderStageCompute:  spirv.ExecutionModelGLCompute,
+}
+
+/**
+ * @brief A descriptor set as the renderer creates it from a shader config: the uniform buffer at
+ * binding 0, if the scope has uniforms, then the samplers as an array, then the storage buffers,
+ * one per binding.
+ */
+type shaderSetLayout struct {
+	scope    metadata.ShaderScope
+	uniforms []*metadata.ShaderUniformConfig
+	samplers []*metadata.ShaderUniformConfig
+	storage  []*metadata.ShaderUniformConfig
+}
+
+// shaderSetLayouts returns the descriptor sets of the config, by set index: the global set
+// first, then the instance set, each left out when its scope has no uniforms.
+func shaderSetLayouts(config *metadata.ShaderConfig) []*shaderSetLayout {
+	layouts := []*shaderSetLayout{}
+	for _, scope := range []metadata.ShaderScope{metadata.ShaderScopeGlobal, metadata.ShaderScopeInstance} {
+		layout := &shaderSetLayout{scope: scope}
+		for _, uniform := range config.Uniforms {
+			if uniform == nil || uniform.Scope != scope {
+				continue
+			}
+			switch {
+			case uniform.Storage:
+				layout.storage = append(layout.storage, uniform)
+			case uniform.ShaderUniformType == metadata.ShaderUniformTypeSampler:
+				layout.samplers = append(layout.samplers, uniform)
+			default:
+				layout.uniforms = append(layout.uniforms, uniform)
+			}
+		}
+		if len(layout.uniforms) > 0 || len(layout.samplers) > 0 || len(layout.storage) > 0 {
+			layouts = append(layouts, layout)
+		}
+	}
+	return layouts
+}
+
+// samplerBinding returns the binding of the samplers of the set.
+func (l *shaderSetLayout) samplerBinding() uint32 {
+	if len(l.uniforms) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// storageBinding returns the binding of the first storage buffer of the set.
+func (l *shaderSetLayout) storageBinding() uint32 {
+	if len(l.samplers) > 0 {
+		return l.samplerBinding() + 1
+	}
+	return l.samplerBinding()
+}
+
+// uniformTypes returns the types of the uniforms as laid out in their block.
+func uniformTypes(config *metadata.ShaderConfig, uniforms []*metadata.ShaderUniformConfig) []*metadata.ShaderBlockType {
+	types := make([]*metadata.ShaderBlockType, len(uniforms))
+	for i, uniform := range uniforms {
+		var err error
+		if types[i], err = config.UniformBlockType(uniform); err != nil {
+			// Reported when parsing the config, taken as bytes here.
+			types[i] = metadata.NewShaderBlockVector(metadata.ShaderUniformTypeUint8, uint32(uniform.Size))
+		}
+	}
+	return types
+}
+
+// checkShaderStages compares the attributes and uniforms of the config with what its compiled
+// stages, and those of its variants, declare, reporting every mismatch. The stage files not
+// found are skipped, their existence is checked by the asset manager.
+func checkShaderStages(fsys fs.FS, config *metadata.ShaderConfig) error {
+	errs := []error{}
+	// The variants share the uniforms of the shader, a stage file shared by several of them is
+	// checked once.
+	checked := map[string]bool{}
+	for _, c := range append([]*metadata.ShaderConfig{config}, config.Variants()...) {
+		if err := checkStageFiles(fsys, c, checked); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkStageFiles(fsys fs.FS, config *metadata.ShaderConfig, checked map[string]bool) error {
+	errs := []error{}
+	layouts := shaderSetLayouts(config)
+	locals := []*metadata.ShaderUniformConfig{}
+	for _, uniform := range config.Uniforms {
+		if uniform != nil && uniform.Scope == metadata.ShaderScopeLocal && !uniform.Storage {
+			locals = append(locals, uniform)
+		}
+	}
+	bound := map[[2]uint32]bool{}
+	pushConstantsChecked := false
+	for i, stageFile := range config.StageFilenames {
+		if checked[stageFile] {
+			continue
+		}
+		checked[stageFile] = true
+		code, err := fs.ReadFile(fsys, stageFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reflection, err := spirv.Reflect(code)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stage file `%s`: %w", stageFile, err))
+			continue
+		}
+
+		stageErrs := []error{}
+		if i < len(config.Stages) {
+			if model, ok := stageExecutionModels[config.Stages[i]]; ok && model != reflection.ExecutionModel {
+				stageErrs = append(stageErrs, fmt.Errorf("is a %s shader, declared as a %s one", reflection.ExecutionModel, model))
+			}
+		}
+		if reflection.ExecutionModel == spirv.ExecutionModelVertex {
+			stageErrs = append(stageErrs, checkShaderInputs(config.Attributes, reflection.Inputs)...)
+		}
+		for _, resource := range reflection.Resources {
+			// The stages share the bindings, the first one declaring a binding is checked.
+			key := [2]uint32{resource.Set, resource.Binding}
+			if bound[key] {
+				continue
+			}
+			bound[key] = true
+			stageErrs = append(stageErrs, checkShaderResource(config, layouts, resource)...)
+		}
+		if reflection.PushConstants != nil {
+			if i < len(config.Stages) && config.Stages[i]&config.PushConstantStageMask() == 0 {
+				stageErrs = append(stageErrs, fmt.Errorf("declares push constants, which are not visible to its stage"))
+			}
+			if !pushConstantsChecked {
+				pushConstantsChecked = true
+				stageErrs = append(stageErrs, checkShaderBlock(config, locals, metadata.ShaderBlockLayoutStd430, reflection.PushConstants)...)
+			}
+		}
+
+		for _, err := range stageErrs {
+			errs = append(errs, fmt.Errorf("stage file `%s`: %w", stageFile, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkShaderInputs compares the attributes with the inputs of the vertex stage. The locations
+// of the attributes follow their order in the config.
+func checkShaderInputs(attributes []*metadata.ShaderAttributeConfig, inputs []*spirv.Variable) []error {
+	errs := []error{}
+	for _, input := range inputs {
+		if int(input.Location) >= len(attributes) || attributes[input.Location] == nil {
+			errs = append(errs, fmt.Errorf("input `%s` at location %d has no attribute", input.Name, input.Location))
+			continue
+		}
+		attribute := attributes[input.Location]
+		if !attributeMatches(attribute.ShaderAttributeType, input.Type) {
+			errs = append(errs, fmt.Errorf("attribute `%s` is %s, input `%s` at location %d is %s",
+				attribute.Name, attribute.ShaderAttributeType, input.Name, input.Location, input.Type))
+		}
+	}
+	return errs
+}
+
+// checkShaderResource compares a resource of a stage with the descriptor set of the config
+// at its set and binding.
+func checkShaderResource(config *metadata.ShaderConfig, layouts []*shaderSetLayout, resource *spirv.Resource) []error {
+	if int(resource.Set) >= len(layouts) {
+		return []error{fmt.Errorf("%s `%s` at set %d, binding %d is not in the config",
+			resource.Kind, resource.Name, resource.Set, resource.Binding)}
+	}
+	layout := layouts[resource.Set]
+	switch {
+	case len(layout.uniforms) > 0 && resource.Binding == 0:
+		if resource.Kind != spirv.ResourceUniformBuffer || resource.Count != 1 {
+			return []error{fmt.Errorf("%s `%s` at set %d, binding 0 is not a uniform buffer",
+				resource.Kind, resource.Name, resource.Set)}
+		}
+		return checkShaderBlock(config, layout.uniforms, metadata.ShaderBlockLayoutStd140, resource)
+	case len(layout.samplers) > 0 && resource.Binding == layout.samplerBinding():
+		if resource.Kind != spirv.ResourceCombinedImageSampler {
+			return []error{fmt.Errorf("%s `%s` at set %d, binding %d is not a sampler",
+				resource.Kind, resource.Name, resource.Set, resource.Binding)}
+		}
+		if int(resource.Count) != len(layout.samplers) {
+			return []error{fmt.Errorf("`%s` has %d samplers, the config has %d in scope %d",
+				resource.Name, resource.Count, len(layout.samplers), layout.scope)}
+		}
+		return nil
+	case resource.Binding >= layout.storageBinding() && resource.Binding < layout.storageBinding()+uint32(len(layout.storage)):
+		storage := layout.storage[resource.Binding-layout.storageBinding()]
+		if resource.Kind != spirv.ResourceStorageBuffer || resource.Count != 1 {
+			return []error{fmt.Errorf("%s `%s` at set %d, binding %d is not a storage buffer, the config has `%s` there",
+				resource.Kind, resource.Name, resource.Set, resource.Binding, storage.Name)}
+		}
+		// A storage buffer holds its uniform as its only member.
+		return checkShaderBlock(config, []*metadata.ShaderUniformConfig{storage}, metadata.ShaderBlockLayoutStd430, resource)
+	}
+	return []error{fmt.Errorf("%s `%s` at set %d, binding %d is not in the config",
+		resource.Kind, resource.Name, resource.Set, resource.Binding)}
+}
+
+// checkShaderBlock compares the uniforms, laid out as the shader system writes them, with the
+// members of the block.
+func checkShaderBlock(config *metadata.ShaderConfig, uniforms []*metadata.ShaderUniformConfig, layout metadata.ShaderBlockLayout, block *spirv.Resource) []error {
+	errs := []error{}
+	types := uniformTypes(config, uniforms)
+	offsets, _ := layout.Offsets(types)
+	for i, member := range block.Type.Members {
+		if i >= len(uniforms) {
+			errs = append(errs, fmt.Errorf("member `%s` of `%s` has no uniform", member.Name, block.Name))
+			continue
+		}
+		uniform := uniforms[i]
+		if !blockTypeMatches(types[i], member.Type) {
+			errs = append(errs, fmt.Errorf("uniform `%s` is %s, member `%s` of `%s` is %s",
+				uniform.Name, uniform.TypeName(), member.Name, block.Name, member.Type))
+			continue
+		}
+		if offsets[i] != member.Offset {
+			errs = append(errs, fmt.Errorf("uniform `%s` is written at offset %d, member `%s` of `%s` is at offset %d",
+				uniform.Name, offsets[i], member.Name, block.Name, member.Offset))
+			continue
+		}
+		if err := checkMemberLayout(layout, types[i], member.Type); err != nil {
+			errs = append(errs, fmt.Errorf("uniform `%s`, member `%s` of `%s`: %w", uniform.Name, member.Name, block.Name, err))
+		}
+	}
+	for _, uniform := range uniforms[min(len(uniforms), len(block.Type.Members)):] {
+		errs = append(errs, fmt.Errorf("uniform `%s` is not in `%s`", uniform.Name, block.Name))
+	}
+	return errs
+}
+
+// attributeMatches tells whether the vertex format of the attribute type feeds the input.
+func attributeMatches(attributeType metadata.ShaderAttributeType, t *spirv.Type) bool {
+	switch attributeType {
+	case metadata.ShaderAttribTypeFloat32:
+		return isScalar(t, spirv.TypeFloat, 32)
+	case metadata.ShaderAttribTypeFloat32_2:
+		return isVector(t, spirv.TypeFloat, 2)
+	case metadata.ShaderAttribTypeFloat32_3:
+		return isVector(t, spirv.TypeFloat, 3)
+	case metadata.ShaderAttribTypeFloat32_4:
+		return isVector(t, spirv.TypeFloat, 4)
+	case metadata.ShaderAttribTypeInt8, metadata.ShaderAttribTypeInt16, metadata.ShaderAttribTypeInt32:
+		// Integer formats are widened, only the sign must match.
+		return isScalar(t, spirv.TypeInt, 0) && t.Signed
+	case metadata.ShaderAttribTypeUint8, metadata.ShaderAttribTypeUint16, metadata.ShaderAttribTypeUint32:
+		return isScalar(t, spirv.TypeInt, 0) && !t.Signed
+	}
+	return false
+}
+
+// uniformMatches tells whether the uniform type has the layout of the member.
+func uniformMatches(uniformType metadata.ShaderUniformType, t *spirv.Type) bool {
+	blockType := uniformType.BlockType()
+	return blockType != nil && blockTypeMatches(blockType, t)
+}
+
+// blockTypeMatches tells whether the type of a uniform is the type of the member, arrays and
+// structs included. Signed and unsigned integers of the same size are interchangeable.
+func blockTypeMatches(blockType *metadata.ShaderBlockType, t *spirv.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch {
+	case blockType.Length > 0:
+		return t.Kind == spirv.TypeArray && t.Count == blockType.Length && blockTypeMatches(blockType.Element(), t.Elem)
+	case blockType.Members != nil:
+		if t.Kind != spirv.TypeStruct || len(t.Members) != len(blockType.Members) {
+			return false
+		}
+		for i, member := range blockType.Members {
+			if !blockTypeMatches(member, t.Members[i].Type) {
+				return false
+			}
+		}
+		return true
+	case blockType.Columns > 1:
+		return t.Kind == spirv.TypeMatrix && t.Count == blockType.Columns &&
+			blockTypeMatches(metadata.NewShaderBlockVector(blockType.Component, blockType.Components), t.Elem)
+	case blockType.Components > 1:
+		return t.Kind == spirv.TypeVector && t.Count == blockType.Components &&
+			blockTypeMatches(metadata.NewShaderBlockScalar(blockType.Component), t.Elem)
+	}
+	switch blockType.Component {
+	case metadata.ShaderUniformTypeFloat32:
+		return isScalar(t, spirv.TypeFloat, 32)
+	case metadata.ShaderUniformTypeInt8, metadata.ShaderUniformTypeUint8:
+		return isScalar(t, spirv.TypeInt, 8)
+	case metadata.ShaderUniformTypeInt16, metadata.ShaderUniformTypeUint16:
+		return isScalar(t, spirv.TypeInt, 16)
+	case metadata.ShaderUniformTypeInt32, metadata.ShaderUniformTypeUint32:
+		return isScalar(t, spirv.TypeInt, 32)
+	}
+	return false
+}
+
+// checkMemberLayout compares the array strides and the offsets of the struct members inside a
+// member with where the shader system writes them, its type matching the member.
+func checkMemberLayout(layout metadata.ShaderBlockLayout, blockType *metadata.ShaderBlockType, t *spirv.Type) error {
+	switch {
+	case blockType.Length > 0:
+		if stride := layout.ArrayStride(blockType); t.ArrayStride != 0 && t.ArrayStride != stride {
+			return fmt.Errorf("the elements of %s are written %d bytes apart, %d in the stage", t, stride, t.ArrayStride)
+		}
+		return checkMemberLayout(layout, blockType.Element(), t.Elem)
+	case blockType.Members != nil:
+		offsets, _ := layout.Offsets(blockType.Members)
+		for i, member := range t.Members {
+			if offsets[i] != member.Offset {
+				return fmt.Errorf("`%s` of %s is written at offset %d, it is at offset %d in the stage", member.Name, t, offsets[i], member.Offset)
+			}
+			if err := checkMemberLayout(layout, blockType.Members[i], member.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isScalar tells whether the type is a scalar of the kind and width, any width when 0.
+func isScalar(t *spirv.Type, kind spirv.TypeKind, width uint32) bool {
+	return t != nil && t.Kind == kind && (width == 0 || t.Width == width)
+}
+
+func isVector(t *spirv.Type, kind spirv.TypeKind, count uint32) bool {
+	return t != nil && t.Kind == spirv.TypeVector && t.Count == count && isScalar(t.Elem, kind, 32)
+}
+
+// ShaderConfigFromStages reflects the compiled stages into the stages, attributes and uniforms
+// of a shader config. The attributes are the inputs of the vertex stage. The uniforms of set 0
+// are global and those of set 1 per instance, the members of the uniform buffer first and then
+// the samplers, named after their array; the storage buffers of set 0 follow, each holding a
+// single member; the push constants are local. The structs of the members are declared after
+// their GLSL names. Layouts the shader system cannot write, like padded members, are reported.
+func ShaderConfigFromStages(fsys fs.FS, stageFiles []string) (*metadata.ShaderConfig, error) {
+	config := &metadata.ShaderConfig{
+		StageNames:     stageFiles,
+		StageFilenames: stageFiles,
+	}
+	errs := []error{}
+	// The resources of all the stages, sorted by set and binding.
+	resources := []*spirv.Resource{}
+	var pushConstants *spirv.Resource
+	for _, stageFile := range stageFiles {
+		code, err := fs.ReadFile(fsys, stageFile)
+		if err != nil {
+			return nil, err
+		}
+		reflection, err := spirv.Reflect(code)
+		if err != nil {
+			return nil, fmt.Errorf("stage file `%s`: %w", stageFile, err)
+		}
+		stage := metadata.ShaderStage(0)
+		for s, model := range stageExecutionModels {
+			if model == reflection.ExecutionModel {
+				stage = s
+			}
+		}
+		if stage == 0 {
+			return nil, fmt.Errorf("stage file `%s`: %s shaders are not supported", stageFile, reflection.ExecutionModel)
+		}
+		config.Stages = append(config.Stages, stage)
+		if reflection.PushConstants != nil {
+			config.PushConstantStages = append(config.PushConstantStages, stage)
+		}
+
+		if reflection.ExecutionModel == spirv.ExecutionModelVertex {
+			for i, input := range reflection.Inputs {
+				if input.Location != uint32(i) {
+					errs = append(errs, fmt.Errorf("input `%s` at location %d, the attributes are at consecutive locations from 0", input.Name, input.Location))
+					continue
+				}
+				attributeType, ok := attributeTypeOf(input.Type)
+				if !ok {
+					errs = append(errs, fmt.Errorf("input `%s` is %s, no attribute type matches it", input.Name, input.Type))
+					continue
+				}
+				_, size, _ := metadata.ShaderAttributeTypeFromString(attributeType.String())
+				config.Attributes = append(config.Attributes, &metadata.ShaderAttributeConfig{
+					Name:                input.Name,
+					ShaderAttributeType: attributeType,
+					Size:                size,
+				})
+			}
+		}
+		for _, resource := range reflection.Resources {
+			if !slices.ContainsFunc(resources, func(r *spirv.Resource) bool {
+				return r.Set == resource.Set && r.Binding == resource.Binding
+			}) {
+				resources = append(resources, resource)
+			}
+		}
+		if pushConstants == nil {
+			pushConstants = reflection.PushConstants
+		}
+	}
+	slices.SortFunc(resources, func(a, b *spirv.Resource) int {
+		if a.Set != b.Set {
+			return cmp.Compare(a.Set, b.Set)
+		}
+		return cmp.Compare(a.Binding, b.Binding)
+	})
+
+	for set := uint32(0); set < 2; set++ {
+		scope := metadata.ShaderScope(set)
+		var block, samplers *spirv.Resource
+		storage := []*spirv.Resource{}
+		for _, resource := range resources {
+			if resource.Set != set {
+				continue
+			}
+			// The bindings are sorted, the storage buffers follow the uniform buffer and the samplers.
+			storageBinding := uint32(len(storage))
+			if block != nil {
+				storageBinding++
+			}
+			if samplers != nil {
+				storageBinding++
+			}
+			switch {
+			case resource.Binding == 0 && resource.Kind == spirv.ResourceUniformBuffer && resource.Count == 1:
+				block = resource
+			case resource.Kind == spirv.ResourceCombinedImageSampler && samplers == nil &&
+				(block == nil && resource.Binding == 0 || block != nil && resource.Binding == 1):
+				samplers = resource
+			case scope == metadata.ShaderScopeGlobal && resource.Kind == spirv.ResourceStorageBuffer && resource.Count == 1 &&
+				resource.Binding == storageBinding:
+				storage = append(storage, resource)
+			default:
+				errs = append(errs, fmt.Errorf("%s `%s` at set %d, binding %d cannot be bound by the renderer",
+					resource.Kind, resource.Name, resource.Set, resource.Binding))
+			}
+		}
+		if block != nil {
+			uniforms, err := uniformsOf(config, block, scope, metadata.ShaderBlockLayoutStd140)
+			errs = append(errs, err...)
+			config.Uniforms = append(config.Uniforms, uniforms...)
+		}
+		if samplers != nil {
+			for i := uint32(0); i < samplers.Count; i++ {
+				name := samplers.Name
+				if samplers.Count > 1 {
+					name = fmt.Sprintf("%s_%d", samplers.Name, i)
+				}
+				config.Uniforms = append(config.Uniforms, &metadata.ShaderUniformConfig{
+					Name:              name,
+					ShaderUniformType: metadata.ShaderUniformTypeSampler,
+					Scope:             scope,
+				})
+			}
+		}
+		for _, buffer := range storage {
+			if len(buffer.Type.Members) != 1 {
+				errs = append(errs, fmt.Errorf("storage buffer `%s` has %d members, the renderer binds storage buffers of a single member",
+					buffer.Name, len(buffer.Type.Members)))
+				continue
+			}
+			uniforms, err := uniformsOf(config, buffer, scope, metadata.ShaderBlockLayoutStd430)
+			errs = append(errs, err...)
+			for _, uniform := range uniforms {
+				uniform.Storage = true
+			}
+			config.Uniforms = append(config.Uniforms, uniforms...)
+		}
+	}
+	for _, resource := range resources {
+		if resource.Set >= 2 {
+			errs = append(errs, fmt.Errorf("%s `%s` at set %d cannot be bound by the renderer", resource.Kind, resource.Name, resource.Set))
+		}
+	}
+	if pushConstants != nil {
+		uniforms, err := uniformsOf(config, pushConstants, metadata.ShaderScopeLocal, metadata.ShaderBlockLayoutStd430)
+		errs = append(errs, err...)
+		config.Uniforms = append(config.Uniforms, uniforms...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return config, nil
+}
+
+// uniformsOf returns the uniforms of the members of the block, declaring their structs in the
+// config, and checking that the shader system writes each of them where the stage reads it.
+func uniformsOf(config *metadata.ShaderConfig, block *spirv.Resource, scope metadata.ShaderScope, layout metadata.ShaderBlockLayout) ([]*metadata.ShaderUniformConfig, []error) {
+	errs := []error{}
+	uniforms := []*metadata.ShaderUniformConfig{}
+	for _, member := range block.Type.Members {
+		uniform, err := uniformOf(config, member.Name, member.Type, scope)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("member `%s` of `%s` %w", member.Name, block.Name, err))
+			continue
+		}
+		uniforms = append(uniforms, uniform)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	types := uniformTypes(config, uniforms)
+	offsets, _ := layout.Offsets(types)
+	for i, member := range block.Type.Members {
+		if offsets[i] != member.Offset {
+			errs = append(errs, fmt.Errorf("member `%s` of `%s` is at offset %d, the shader system would write it at %d",
+				member.Name, block.Name, member.Offset, offsets[i]))
+			continue
+		}
+		if err := checkMemberLayout(layout, types[i], member.Type); err != nil {
+			errs = append(errs, fmt.Errorf("member `%s` of `%s`: %w", member.Name, block.Name, err))
+		}
+	}
+	return uniforms, errs
+}
+
+// uniformOf returns the uniform of a member of the given type, declaring its struct in the
+// config. The errors complete a sentence starting with the member.
+func uniformOf(config *metadata.ShaderConfig, name string, t *spirv.Type, scope metadata.ShaderScope) (*metadata.ShaderUniformConfig, error) {
+	uniform := &metadata.ShaderUniformConfig{
+		Name:  name,
+		Scope: scope,
+	}
+	switch t.Kind {
+	case spirv.TypeRuntimeArray:
+		return nil, fmt.Errorf("is %s, the renderer binds arrays of a fixed length only", t)
+	case spirv.TypeArray:
+		if t.Elem.Kind == spirv.TypeArray || t.Elem.Kind == spirv.TypeRuntimeArray {
+			return nil, fmt.Errorf("is %s, no uniform type matches arrays of arrays", t)
+		}
+		uniform.Length = t.Count
+		t = t.Elem
+	}
+	if t.Kind == spirv.TypeStruct {
+		structName, err := declareStruct(config, t)
+		if err != nil {
+			return nil, err
+		}
+		uniform.ShaderUniformType = metadata.ShaderUniformTypeStruct
+		uniform.StructName = structName
+		return uniform, nil
+	}
+	uniformType, ok := uniformTypeOf(t)
+	if !ok {
+		return nil, fmt.Errorf("is %s, no uniform type matches it", t)
+	}
+	_, uniform.Size, _ = metadata.ShaderUniformTypeFromString(uniformType.String())
+	uniform.ShaderUniformType = uniformType
+	return uniform, nil
+}
+
+// declareStruct declares the struct type in the config, after the structs it holds, unless it
+// is declared already, and returns its name.
+func declareStruct(config *metadata.ShaderConfig, t *spirv.Type) (string, error) {
+	if t.Name == "" {
+		return "", fmt.Errorf("is a struct without a name")
+	}
+	if config.Struct(t.Name) != nil {
+		return t.Name, nil
+	}
+	structCfg := &metadata.ShaderStructConfig{Name: t.Name}
+	for _, member := range t.Members {
+		m, err := uniformOf(config, member.Name, member.Type, 0)
+		if err != nil {
+			return "", fmt.Errorf("is struct `%s`, whose member `%s` %w", t.Name, member.Name, err)
+		}
+		structCfg.Members = append(structCfg.Members, m)
+	}
+	config.Structs = append(config.Structs, structCfg)
+	return t.Name, nil
+}
+
+func attributeTypeOf(t *spirv.Type) (metadata.ShaderAttributeType, bool) {
+	for _, attributeType := range []metadata.ShaderAttributeType{
+		metadata.ShaderAttribTypeFloat32, metadata.ShaderAttribTypeFloat32_2, metadata.ShaderAttribTypeFloat32_3,
+		metadata.ShaderAttribTypeFloat32_4, metadata.ShaderAttribTypeInt32, metadata.ShaderAttribTypeUint32,
+	} {
+		if attributeMatches(attributeType, t) {
+			return attributeType, true
+		}
+	}
+	return 0, false
+}
+
+func uniformTypeOf(t *spirv.Type) (metadata.ShaderUniformType, bool) {
+	if isScalar(t, spirv.TypeInt, 0) {
+		switch {
+		case t.Signed && t.Width == 8:
+			return metadata.ShaderUniformTypeInt8, true
+		case !t.Signed && t.Width == 8:
+			return metadata.ShaderUniformTypeUint8, true
+		case t.Signed && t.Width == 16:
+			return metadata.ShaderUniformTypeInt16, true
+		case !t.Signed && t.Width == 16:
+			return metadata.ShaderUniformTypeUint16, true
+		case t.Signed && t.Width == 32:
+			return metadata.ShaderUniformTypeInt32, true
+		case !t.Signed && t.Width == 32:
+			return metadata.ShaderUniformTypeUint32, true
+		}
+		return 0, false
+	}
+	for _, uniformType := range []metadata.ShaderUniformType{
+		metadata.ShaderUniformTypeFloat32, metadata.ShaderUniformTypeFloat32_2, metadata.ShaderUniformTypeFloat32_3,
+		metadata.ShaderUniformTypeFloat32_4, metadata.ShaderUniformTypeMatrix4,
+	} {
+		if uniformMatches(uniformType, t) {
+			return uniformType, true
+		}
+	}
+	return 0, false
+}
+
+// WriteShaderConfigSections writes the stages, the attributes and the uniforms of the config in
+// the .shadercfg format, for a config generated by ShaderConfigFromStages.
+func WriteShaderConfigSections(w io.Writer, config *metadata.ShaderConfig) error {
+	var b strings.Builder
+	stageNames := map[metadata.ShaderStage]string{
+		metadata.ShaderStageVertex:   "vertex",
+		metadata.ShaderStageGeometry: "geometry",
+		metadata.ShaderStageFragment: "fragment",
+		metadata.ShaderStageCompute:  "compute",
+	}
+	quoted := func(values []string) string {
+		q := make([]string, len(values))
+		for i, v := range values {
+			q[i] = fmt.Sprintf("%q", v)
+		}
+		return strings.Join(q, ", ")
+	}
+	stages := make([]string, len(config.Stages))
+	for i, stage := range config.Stages {
+		stages[i] = stageNames[stage]
+	}
+	fmt.Fprintf(&b, "stages = [%s]\n", quoted(stages))
+	fmt.Fprintf(&b, "stagefiles = [%s]\n", quoted(config.StageFilenames))
+
+	if len(config.Structs) > 0 {
+		b.WriteString("\n# Structs\n")
+	}
+	for _, structCfg := range config.Structs {
+		fmt.Fprintf(&b, "[[struct]]\nname = %q\n\n", structCfg.Name)
+		for _, member := range structCfg.Members {
+			fmt.Fprintf(&b, "[[struct.member]]\ntype = %q\nname = %q\n\n", member.TypeName(), member.Name)
+		}
+	}
+
+	b.WriteString("\n# Attributes\n")
+	for _, attribute := range config.Attributes {
+		fmt.Fprintf(&b, "[[attribute]]\ntype = %q\nname = %q\n\n", attribute.ShaderAttributeType, attribute.Name)
+	}
+	// The local uniforms are written as the push constant block when it has stages of its own.
+	pushConstants, storage := []*metadata.ShaderUniformConfig{}, []*metadata.ShaderUniformConfig{}
+	b.WriteString("# Uniforms\n# Scope: 0=global, 1=instance, 2=local\n")
+	for _, uniform := range config.Uniforms {
+		switch {
+		case uniform.Storage:
+			storage = append(storage, uniform)
+		case uniform.Scope == metadata.ShaderScopeLocal && len(config.PushConstantStages) > 0:
+			pushConstants = append(pushConstants, uniform)
+		default:
+			fmt.Fprintf(&b, "[[uniform]]\ntype = %q\nscope = %d\nname = %q\n\n", uniform.TypeName(), uniform.Scope, uniform.Name)
+		}
+	}
+	if len(pushConstants) > 0 {
+		pushConstantStages := make([]string, len(config.PushConstantStages))
+		for i, stage := range config.PushConstantStages {
+			pushConstantStages[i] = stageNames[stage]
+		}
+		fmt.Fprintf(&b, "# Push constants\n[push_constants]\nstages = [%s]\n\n", quoted(pushConstantStages))
+		for _, uniform := range pushConstants {
+			fmt.Fprintf(&b, "[[push_constants.member]]\ntype = %q\nname = %q\n\n", uniform.TypeName(), uniform.Name)
+		}
+	}
+	if len(storage) > 0 {
+		b.WriteString("# Storage buffers, global\n")
+	}
+	for _, uniform := range storage {
+		fmt.Fprintf(&b, "[[storage]]\ntype = %q\nname = %q\n\n", uniform.TypeName(), uniform.Name)
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
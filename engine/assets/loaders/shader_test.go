@@ -0,0 +1,283 @@
+package loaders
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The header of the configs below, a shader with a vertex and a fragment stage.
+const shaderTestHeader = `
+name = "Shader.Test"
+renderpass = "Renderpass.Builtin.World"
+stages = ["vertex", "fragment"]
+stagefiles = ["shaders/Test.vert.spv", "shaders/Test.frag.spv"]
+`
+
+func parseTestShaderConfig(t *testing.T, config string) (*metadata.ShaderConfig, error) {
+	t.Helper()
+	fsys := fstest.MapFS{"shaders/Shader.Test.shadercfg": {Data: []byte(shaderTestHeader + config)}}
+	return parseShaderConfig(fsys, "shaders/Shader.Test.shadercfg", true)
+}
+
+func TestShaderConfigBlocks(t *testing.T) {
+	config, err := parseTestShaderConfig(t, `
+[[struct]]
+name = "attenuation"
+[[struct.member]]
+type = "f32"
+name = "constant_f"
+[[struct.member]]
+type = "f32"
+name = "linear"
+
+[[struct]]
+name = "light"
+[[struct.member]]
+type = "vec4"
+name = "colour"
+[[struct.member]]
+type = "attenuation"
+name = "attenuation"
+[[struct.member]]
+type = "f32[2]"
+name = "weights"
+
+[[uniform]]
+type = "light[4]"
+scope = 0
+name = "lights"
+
+[[uniform]]
+type = "vec4[8]"
+scope = 1
+name = "tints"
+
+[[uniform]]
+type = "samp"
+scope = 1
+name = "diffuse_texture"
+
+[push_constants]
+stages = ["vertex"]
+[[push_constants.member]]
+type = "mat4"
+name = "model"
+[[push_constants.member]]
+type = "u32"
+name = "id"
+
+[[storage]]
+type = "mat4[16]"
+name = "bones"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStructs := []*metadata.ShaderStructConfig{
+		{Name: "attenuation", Members: []*metadata.ShaderUniformConfig{
+			{Name: "constant_f", Size: 4, ShaderUniformType: metadata.ShaderUniformTypeFloat32},
+			{Name: "linear", Size: 4, ShaderUniformType: metadata.ShaderUniformTypeFloat32},
+		}},
+		{Name: "light", Members: []*metadata.ShaderUniformConfig{
+			{Name: "colour", Size: 16, ShaderUniformType: metadata.ShaderUniformTypeFloat32_4},
+			{Name: "attenuation", ShaderUniformType: metadata.ShaderUniformTypeStruct, StructName: "attenuation"},
+			{Name: "weights", Size: 4, ShaderUniformType: metadata.ShaderUniformTypeFloat32, Length: 2},
+		}},
+	}
+	if !reflect.DeepEqual(config.Structs, wantStructs) {
+		t.Errorf("got structs %+v, want %+v", config.Structs, wantStructs)
+	}
+
+	// The push constants and the storage buffers come after the uniforms, in order.
+	wantUniforms := []*metadata.ShaderUniformConfig{
+		{Name: "lights", ShaderUniformType: metadata.ShaderUniformTypeStruct, StructName: "light", Length: 4, Scope: metadata.ShaderScopeGlobal},
+		{Name: "tints", Size: 16, ShaderUniformType: metadata.ShaderUniformTypeFloat32_4, Length: 8, Scope: metadata.ShaderScopeInstance},
+		{Name: "diffuse_texture", ShaderUniformType: metadata.ShaderUniformTypeSampler, Scope: metadata.ShaderScopeInstance},
+		{Name: "model", Size: 64, ShaderUniformType: metadata.ShaderUniformTypeMatrix4, Scope: metadata.ShaderScopeLocal},
+		{Name: "id", Size: 4, ShaderUniformType: metadata.ShaderUniformTypeUint32, Scope: metadata.ShaderScopeLocal},
+		{Name: "bones", Size: 64, ShaderUniformType: metadata.ShaderUniformTypeMatrix4, Length: 16, Scope: metadata.ShaderScopeGlobal, Storage: true},
+	}
+	if !reflect.DeepEqual(config.Uniforms, wantUniforms) {
+		for _, u := range config.Uniforms {
+			t.Logf("%+v", *u)
+		}
+		t.Errorf("got uniforms above, want %+v", wantUniforms)
+	}
+	if want := []metadata.ShaderStage{metadata.ShaderStageVertex}; !reflect.DeepEqual(config.PushConstantStages, want) {
+		t.Errorf("got push constant stages %v, want %v", config.PushConstantStages, want)
+	}
+
+	// The nested struct is laid out inside the array of lights.
+	lights, err := config.UniformBlockType(config.Uniforms[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := metadata.ShaderBlockLayoutStd140.Size(lights); size != 4*64 {
+		t.Errorf("got a light array of %d bytes in std140, want %d", size, 4*64)
+	}
+}
+
+func TestShaderConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			"push constants over 128 bytes",
+			`
+[push_constants]
+stages = ["vertex"]
+[[push_constants.member]]
+type = "mat4"
+name = "model"
+[[push_constants.member]]
+type = "mat4"
+name = "view"
+[[push_constants.member]]
+type = "u32"
+name = "id"
+`,
+			"the push constants take 132 bytes",
+		},
+		{
+			"push constants of a missing stage",
+			`
+[push_constants]
+stages = ["geometry"]
+[[push_constants.member]]
+type = "u32"
+name = "id"
+`,
+			"which the shader does not have",
+		},
+		{
+			"local uniform besides the push constants",
+			`
+[[uniform]]
+type = "mat4"
+scope = 2
+name = "model"
+
+[push_constants]
+stages = ["vertex"]
+[[push_constants.member]]
+type = "u32"
+name = "id"
+`,
+			"declared besides the push_constants block",
+		},
+		{
+			"push constant named like a uniform",
+			`
+[[uniform]]
+type = "u32"
+scope = 0
+name = "id"
+
+[push_constants]
+stages = ["vertex"]
+[[push_constants.member]]
+type = "u32"
+name = "id"
+`,
+			"duplicate uniform name found: id",
+		},
+		{
+			"sampler storage buffer",
+			`
+[[storage]]
+type = "samp"
+name = "texture"
+`,
+			"storage buffer `texture` cannot be a sampler",
+		},
+		{
+			"storage buffer of an unknown type",
+			`
+[[storage]]
+type = "bone[4]"
+name = "bones"
+`,
+			"storage buffer `bones`",
+		},
+		{
+			"sampler array",
+			`
+[[uniform]]
+type = "samp[4]"
+scope = 1
+name = "textures"
+`,
+			"samplers cannot be arrays",
+		},
+		{
+			"empty array",
+			`
+[[uniform]]
+type = "vec4[0]"
+scope = 0
+name = "tints"
+`,
+			"invalid array length",
+		},
+		{
+			"struct holding a struct declared after it",
+			`
+[[struct]]
+name = "outer"
+[[struct.member]]
+type = "inner"
+name = "inner"
+
+[[struct]]
+name = "inner"
+[[struct.member]]
+type = "f32"
+name = "value"
+`,
+			"struct `outer`: member `inner`",
+		},
+		{
+			"struct holding a sampler",
+			`
+[[struct]]
+name = "material"
+[[struct.member]]
+type = "samp"
+name = "texture"
+`,
+			"structs cannot hold samplers",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseTestShaderConfig(t, test.config)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+// The push constants may take the 128 bytes the Vulkan spec guarantees.
+func TestShaderConfigPushConstantsLimit(t *testing.T) {
+	_, err := parseTestShaderConfig(t, `
+[push_constants]
+stages = ["vertex", "fragment"]
+[[push_constants.member]]
+type = "mat4"
+name = "model"
+[[push_constants.member]]
+type = "mat4"
+name = "normal_matrix"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
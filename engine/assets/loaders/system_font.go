@@ -2,38 +2,42 @@ package loaders
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
 	"strings"
-	"unsafe"
 
 	"golang.org/x/image/font/opentype"
-	"golang.org/x/image/font/sfnt"
 
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
-type SystemFontLoader struct{}
+type SystemFontLoader struct {
+	// The file system the font configurations and font files are read from.
+	FS fs.FS
+	// Reports the unknown keys, and configurations without font file or face, as errors.
+	Strict bool
+}
 
-func (fl *SystemFontLoader) Load(path string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
-	file, err := os.Open(path)
+func (fl *SystemFontLoader) Load(fontPath string, assetType metadata.ResourceType, params interface{}) (*metadata.Resource, error) {
+	file, err := fl.FS.Open(fontPath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-
 	rd := &metadata.SystemFontResourceData{
 		Fonts: []*metadata.SystemFontFace{},
 	}
 	scanner := bufio.NewScanner(file)
+	// The lines skipped, reported all at once in strict mode.
+	skipped := []error{}
+	lineNumber := 0
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
 
 		// Skip comments and empty lines
 		if len(line) == 0 || strings.HasPrefix(line, "#") {
@@ -43,39 +47,61 @@ func (fl *SystemFontLoader) Load(path string, assetType metadata.ResourceType, p
 		// Parse the file and face keys
 		if strings.HasPrefix(line, "file=") {
 			filename := strings.TrimPrefix(line, "file=")
-			fullPath := fmt.Sprintf("%s/assets/fonts/%s", wd, filename)
+			// The font file sits next to its configuration.
+			fullPath := path.Join(path.Dir(fontPath), filename)
 			// Read the font data.
-			fontBytes, err := os.ReadFile(fullPath)
+			fontBytes, err := fs.ReadFile(fl.FS, fullPath)
 			if err != nil {
 				return nil, err
 			}
-			f, err := opentype.ParseCollection(fontBytes)
-			if err != nil {
+			// Only to validate it, the raw data is what gets rasterized.
+			if _, err := opentype.ParseCollection(fontBytes); err != nil {
 				return nil, err
 			}
-			rd.FontBinary = f
-			rd.BinarySize = uint64(unsafe.Sizeof(&sfnt.Collection{}))
+			rd.FontBinary = fontBytes
+			rd.BinarySize = uint64(len(fontBytes))
 		} else if strings.HasPrefix(line, "face=") {
 			face := strings.TrimPrefix(line, "face=")
 			rd.Fonts = append(rd.Fonts, &metadata.SystemFontFace{
 				Name: face,
 			})
+		} else if fl.Strict {
+			key, _, _ := strings.Cut(line, "=")
+			skipped = append(skipped, fmt.Errorf("line %d: unknown key '%s'", lineNumber, key))
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if fl.Strict {
+		if rd.FontBinary == nil {
+			skipped = append(skipped, errors.New("no font file"))
+		}
+		if len(rd.Fonts) == 0 {
+			skipped = append(skipped, errors.New("no face"))
+		}
+		if len(skipped) > 0 {
+			return nil, errors.Join(skipped...)
+		}
+	}
 
 	res := &metadata.Resource{
-		FullPath: path,
+		FullPath: fontPath,
 		Data:     rd,
-		DataSize: uint64(unsafe.Sizeof(&metadata.SystemFontResourceData{})),
+		DataSize: rd.BinarySize,
 	}
 
 	return res, nil
 }
 
-func (fl *SystemFontLoader) Unload(*metadata.Resource) error {
+func (fl *SystemFontLoader) Unload(resource *metadata.Resource) error {
+	if data, ok := resource.Data.(*metadata.SystemFontResourceData); ok {
+		data.Fonts = nil
+		data.FontBinary = nil
+		data.BinarySize = 0
+	}
+	resource.Data = nil
+	resource.DataSize = 0
 	return nil
 }
@@ -0,0 +1,292 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The extension of the sidecars, appended to the file name of the asset (e.g. bricks.png.meta).
+const META_EXTENSION = ".meta"
+
+// How long a removed asset waits for a file with the same content to show up, to be
+// considered renamed or moved and keep its GUID.
+const ASSET_RENAME_WINDOW = 5 * time.Second
+
+/** @brief An asset removed recently, which may show up again under another name. */
+type orphanAsset struct {
+	asset   *AssetInfo
+	removed time.Time
+}
+
+// GUID returns the GUID of the asset at the given path (e.g. textures/bricks_diff.png).
+func (am *AssetManager) GUID(assetPath string) (string, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	asset, ok := am.assets[assetPath]
+	if !ok {
+		return "", false
+	}
+	return asset.GUID, true
+}
+
+// PathOf returns the path of the asset with the given GUID.
+func (am *AssetManager) PathOf(guid string) (string, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	assetPath, ok := am.guids[strings.ToLower(guid)]
+	return assetPath, ok
+}
+
+// ReferenceName returns the name the asset with the given GUID is loaded by (e.g. bricks_diff
+// for textures/bricks_diff.png). Other references are returned as they are.
+func (am *AssetManager) ReferenceName(reference string) string {
+	assetPath, ok := am.PathOf(reference)
+	if !ok {
+		return reference
+	}
+	if determineAssetType(assetPath) == metadata.ResourceTypeBinary {
+		return assetPath
+	}
+	// The names are relative to the directory of the type of asset.
+	name := strings.TrimSuffix(assetPath, path.Ext(assetPath))
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// referenceGUID returns the GUID of the asset referenced by name, false when it is missing.
+func (am *AssetManager) referenceGUID(name string, resourceType metadata.ResourceType) (string, bool) {
+	_, asset, err := am.assetPath(name, resourceType)
+	if err != nil || asset == nil {
+		return "", false
+	}
+	return asset.GUID, true
+}
+
+// ReferenceGUID returns the GUID of the asset referenced by name in the file system, like the
+// asset manager gives it: read from its sidecar, or derived from its path when it has none.
+// False when no file provides the asset or its sidecar is invalid.
+func ReferenceGUID(fsys fs.FS, name string, resourceType metadata.ResourceType) (string, bool) {
+	candidates, _, err := assetCandidates(name, resourceType)
+	if err != nil {
+		return "", false
+	}
+	for _, candidate := range candidates {
+		if _, err := fs.Stat(fsys, candidate); err != nil {
+			continue
+		}
+		meta, err := readMeta(fsys, candidate)
+		if err != nil {
+			return "", false
+		}
+		if meta == nil {
+			return PathGUID(candidate), true
+		}
+		return strings.ToLower(meta.GUID), true
+	}
+	return "", false
+}
+
+// Meta returns the sidecar of the asset, requested by name or GUID like with LoadAsset. The
+// sidecar must not be modified.
+func (am *AssetManager) Meta(filename string, resourceType metadata.ResourceType) (*metadata.AssetMeta, error) {
+	assetPath, asset, err := am.assetPath(filename, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("asset `%s` not found", assetPath)
+	}
+	return asset.Meta, nil
+}
+
+// importParams applies the import settings of the asset on top of the load parameters.
+func importParams(asset *AssetInfo, params interface{}) interface{} {
+	if asset.Meta == nil {
+		return params
+	}
+	switch asset.Type {
+	case metadata.ResourceTypeImage:
+		if asset.Meta.Image == nil || asset.Meta.Image.FlipY == nil {
+			return params
+		}
+		imageParams := &metadata.ImageResourceParams{}
+		if p, ok := params.(*metadata.ImageResourceParams); ok && p != nil {
+			*imageParams = *p
+		}
+		imageParams.FlipY = *asset.Meta.Image.FlipY
+		return imageParams
+	case metadata.ResourceTypeModel:
+		if asset.Meta.Model != nil {
+			return asset.Meta.Model
+		}
+	}
+	return params
+}
+
+// PathGUID returns the GUID given to the asset at the given path when it has no sidecar. It
+// is derived from the path, so that every checkout and the cooked assets agree on it.
+func PathGUID(assetPath string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(assetPath)).String()
+}
+
+// indexMeta reads the sidecar of the asset into its index entry. Assets without one get the
+// GUID of the file they were renamed from if any, PathGUID otherwise, and their sidecar is
+// written next to them.
+func (am *AssetManager) indexMeta(asset *AssetInfo) {
+	am.metaMutex.Lock()
+	defer am.metaMutex.Unlock()
+
+	meta, err := readMeta(am.vfs, asset.Path)
+	if err != nil {
+		core.LogWarn(err.Error())
+	}
+	_, diskPath, _ := am.vfs.Resolve(asset.Path)
+
+	switch {
+	case meta != nil:
+		if other, ok := am.PathOf(meta.GUID); ok && other != asset.Path && am.isIndexed(other) {
+			// Copied along with its sidecar, the copy is another asset.
+			core.LogWarn("asset `%s` has the GUID of `%s`, assigning it a new one", asset.Path, other)
+			meta.GUID = uuid.NewString()
+			if diskPath != "" {
+				am.writeMeta(diskPath, meta)
+			}
+		}
+	case err != nil || diskPath == "":
+		// Broken sidecars are left for the user to fix.
+		meta = &metadata.AssetMeta{GUID: PathGUID(asset.Path)}
+	default:
+		if orphan := am.claimOrphan(asset); orphan != nil {
+			core.LogInfo("asset `%s` was renamed to `%s`", orphan.Path, asset.Path)
+			meta = orphan.Meta
+			am.dependencies.move(orphan.Path, asset.Path, orphan.GUID)
+			// The sidecar left behind would duplicate the GUID.
+			if _, oldMeta, _ := am.vfs.Resolve(orphan.Path + META_EXTENSION); oldMeta != "" && !am.readOnly {
+				if err := os.Remove(oldMeta); err != nil {
+					core.LogWarn("failed to remove sidecar `%s`: %s", oldMeta, err.Error())
+				}
+			}
+		} else {
+			meta = &metadata.AssetMeta{GUID: PathGUID(asset.Path)}
+		}
+		am.writeMeta(diskPath, meta)
+	}
+	asset.Meta = meta
+	asset.GUID = strings.ToLower(meta.GUID)
+}
+
+// readMeta reads the sidecar of the asset, nil when it has none.
+func readMeta(fsys fs.FS, assetPath string) (*metadata.AssetMeta, error) {
+	data, err := fs.ReadFile(fsys, assetPath+META_EXTENSION)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := &metadata.AssetMeta{}
+	if err := toml.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("invalid sidecar `%s%s`: %w", assetPath, META_EXTENSION, err)
+	}
+	if _, err := uuid.Parse(meta.GUID); err != nil {
+		return nil, fmt.Errorf("invalid GUID in sidecar `%s%s`: %w", assetPath, META_EXTENSION, err)
+	}
+	return meta, nil
+}
+
+// writeMeta writes the sidecar next to the asset file on disk, unless read only.
+func (am *AssetManager) writeMeta(diskPath string, meta *metadata.AssetMeta) {
+	if am.readOnly {
+		return
+	}
+	data, err := toml.Marshal(meta)
+	if err == nil {
+		err = os.WriteFile(diskPath+META_EXTENSION, data, 0o644)
+	}
+	if err != nil {
+		core.LogWarn("failed to write the sidecar of `%s`: %s", filepath.ToSlash(diskPath), err.Error())
+	}
+}
+
+// handleMetaEvent applies the changes of the sidecar of the asset, a change of its import
+// settings reloads it.
+func (am *AssetManager) handleMetaEvent(assetPath string) {
+	meta, err := readMeta(am.vfs, assetPath)
+	if err != nil {
+		// Possibly half written, the next write event tells.
+		core.LogDebug(err.Error())
+		return
+	}
+	if meta == nil {
+		return
+	}
+
+	am.mutex.Lock()
+	asset, ok := am.assets[assetPath]
+	if !ok || reflect.DeepEqual(asset.Meta, meta) {
+		am.mutex.Unlock()
+		return
+	}
+	// Replaced, the entries may be in use outside the lock.
+	updated := *asset
+	updated.Meta = meta
+	updated.GUID = strings.ToLower(meta.GUID)
+	if am.guids[asset.GUID] == assetPath {
+		delete(am.guids, asset.GUID)
+	}
+	am.guids[updated.GUID] = assetPath
+	am.assets[assetPath] = &updated
+	am.mutex.Unlock()
+
+	am.invalidate(assetPath)
+	am.scheduleChanged(assetPath)
+}
+
+// addOrphan remembers the removed asset for ASSET_RENAME_WINDOW. Must be called with the
+// index locked.
+func (am *AssetManager) addOrphan(asset *AssetInfo) {
+	if asset.size == 0 {
+		return
+	}
+	am.orphans = append(am.orphans, &orphanAsset{asset: asset, removed: time.Now()})
+}
+
+// claimOrphan returns the asset removed recently with the same extension, size and
+// modification time as the given one, which is then that asset renamed or moved.
+func (am *AssetManager) claimOrphan(asset *AssetInfo) *AssetInfo {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	var claimed *AssetInfo
+	orphans := am.orphans[:0]
+	for _, orphan := range am.orphans {
+		if time.Since(orphan.removed) > ASSET_RENAME_WINDOW {
+			continue
+		}
+		if claimed == nil && orphan.asset.Meta != nil &&
+			strings.EqualFold(filepath.Ext(orphan.asset.Path), filepath.Ext(asset.Path)) &&
+			orphan.asset.size == asset.size && orphan.asset.modTime.Equal(asset.modTime) {
+			claimed = orphan.asset
+			continue
+		}
+		orphans = append(orphans, orphan)
+	}
+	am.orphans = orphans
+	return claimed
+}
@@ -0,0 +1,65 @@
+package assets
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+const testGUID = "0c6f0d6e-6f8a-4f0e-9a57-3c1b0d1e2f3a"
+
+func TestReferenceGUID(t *testing.T) {
+	fsys := fstest.MapFS{
+		"textures/bricks.png":                   {Data: []byte("png")},
+		"textures/bricks.png.meta":              {Data: []byte("GUID = '" + "0C6F0D6E-6F8A-4F0E-9A57-3C1B0D1E2F3A" + "'\n")},
+		"textures/stone.png":                    {Data: []byte("png")},
+		"textures/stone.tga":                    {Data: []byte("tga")},
+		"textures/broken.png":                   {Data: []byte("png")},
+		"textures/broken.png.meta":              {Data: []byte("GUID = 'not a guid'\n")},
+		"shaders/Shader.Builtin.Material.ksc":   {Data: []byte("ksc")},
+		"shaders/Shader.Builtin.Material.ksc.x": {Data: []byte("other")},
+	}
+	tests := []struct {
+		name         string
+		resourceType metadata.ResourceType
+		guid         string
+		ok           bool
+	}{
+		{"bricks", metadata.ResourceTypeImage, testGUID, true},
+		// The .tga wins over the .png, like when loading.
+		{"stone", metadata.ResourceTypeImage, PathGUID("textures/stone.tga"), true},
+		{"Shader.Builtin.Material", metadata.ResourceTypeShader, PathGUID("shaders/Shader.Builtin.Material.ksc"), true},
+		{"broken", metadata.ResourceTypeImage, "", false},
+		{"missing", metadata.ResourceTypeImage, "", false},
+		{testGUID, metadata.ResourceTypeImage, "", false},
+	}
+	for _, test := range tests {
+		guid, ok := ReferenceGUID(fsys, test.name, test.resourceType)
+		if guid != test.guid || ok != test.ok {
+			t.Errorf("ReferenceGUID(%q) = %q, %v, want %q, %v", test.name, guid, ok, test.guid, test.ok)
+		}
+	}
+}
+
+func TestReferenceName(t *testing.T) {
+	am := &AssetManager{guids: map[string]string{
+		testGUID:                  "textures/bricks.kti",
+		PathGUID("shaders/a.ksc"): "shaders/Shader.Builtin.Material.ksc",
+		PathGUID("textures/sub"):  "textures/sub/moss.png",
+	}}
+	tests := map[string]string{
+		testGUID:                               "bricks",
+		"0C6F0D6E-6F8A-4F0E-9A57-3C1B0D1E2F3A": "bricks",
+		PathGUID("shaders/a.ksc"):              "Shader.Builtin.Material",
+		PathGUID("textures/sub"):               "sub/moss",
+		// Names and unknown GUIDs are kept.
+		"bricks":                  "bricks",
+		PathGUID("textures/gone"): PathGUID("textures/gone"),
+	}
+	for reference, want := range tests {
+		if got := am.ReferenceName(reference); got != want {
+			t.Errorf("ReferenceName(%q) = %q, want %q", reference, got, want)
+		}
+	}
+}
@@ -0,0 +1,715 @@
+/*
+Package spirv reflects compiled SPIR-V shader modules: the inputs and outputs of their entry
+point, the resources bound through descriptor sets, with the members and offsets of the uniform
+blocks, and the push constant block. Only what the engine binds is decoded, the instructions of
+the functions are skipped.
+*/
+package spirv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// The first word of every SPIR-V module.
+const MAGIC uint32 = 0x07230203
+
+/** @brief The execution model of an entry point, which stage of the pipeline it runs in. */
+type ExecutionModel uint32
+
+const (
+	ExecutionModelVertex                 ExecutionModel = 0
+	ExecutionModelTessellationControl    ExecutionModel = 1
+	ExecutionModelTessellationEvaluation ExecutionModel = 2
+	ExecutionModelGeometry               ExecutionModel = 3
+	ExecutionModelFragment               ExecutionModel = 4
+	ExecutionModelGLCompute              ExecutionModel = 5
+)
+
+func (m ExecutionModel) String() string {
+	switch m {
+	case ExecutionModelVertex:
+		return "vertex"
+	case ExecutionModelTessellationControl:
+		return "tessellation control"
+	case ExecutionModelTessellationEvaluation:
+		return "tessellation evaluation"
+	case ExecutionModelGeometry:
+		return "geometry"
+	case ExecutionModelFragment:
+		return "fragment"
+	case ExecutionModelGLCompute:
+		return "compute"
+	}
+	return fmt.Sprintf("execution model %d", uint32(m))
+}
+
+/** @brief The kinds of types reflected. */
+type TypeKind int
+
+const (
+	TypeVoid TypeKind = iota
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeVector
+	TypeMatrix
+	TypeArray
+	TypeRuntimeArray
+	TypeStruct
+	TypeImage
+	TypeSampler
+	TypeSampledImage
+	TypePointer
+	TypeOther
+)
+
+/** @brief The dimensionality of an image type. */
+type Dim uint32
+
+const (
+	Dim1D          Dim = 0
+	Dim2D          Dim = 1
+	Dim3D          Dim = 2
+	DimCube        Dim = 3
+	DimRect        Dim = 4
+	DimBuffer      Dim = 5
+	DimSubpassData Dim = 6
+)
+
+/** @brief A type of the module. */
+type Type struct {
+	Kind TypeKind
+	/** @brief The name of a struct, the block name for uniform blocks. */
+	Name string
+	/** @brief The bits of an int or a float. */
+	Width uint32
+	/** @brief Whether an int is signed. */
+	Signed bool
+	/**
+	 * @brief The components of a vector, the columns of a matrix, the length of an array. 0 for
+	 * runtime arrays.
+	 */
+	Count uint32
+	/**
+	 * @brief The component type of a vector, the column type of a matrix, the element type of an
+	 * array, the image type of a sampled image, the pointee of a pointer.
+	 */
+	Elem *Type
+	/** @brief The members of a struct. */
+	Members []*Member
+	/** @brief The dimensionality of an image. */
+	Dim Dim
+	/** @brief Whether an image is an array of layers. */
+	Arrayed bool
+	/** @brief The bytes between the elements of an array, when decorated. */
+	ArrayStride uint32
+
+	// Storage buffers were structs decorated BufferBlock in the Uniform storage class before
+	// SPIR-V 1.3.
+	bufferBlock bool
+}
+
+/** @brief A member of a struct. */
+type Member struct {
+	Name string
+	Type *Type
+	/** @brief The offset of the member in bytes from the start of the struct. */
+	Offset uint32
+	/** @brief The bytes between the columns of a matrix member. */
+	MatrixStride uint32
+	/** @brief Whether the member is a built-in variable, like gl_Position. */
+	BuiltIn bool
+}
+
+/** @brief An input or output of an entry point. */
+type Variable struct {
+	Name     string
+	Type     *Type
+	Location uint32
+}
+
+/** @brief The kinds of resources bound through descriptor sets. */
+type ResourceKind int
+
+const (
+	ResourceUniformBuffer ResourceKind = iota
+	ResourceStorageBuffer
+	ResourceCombinedImageSampler
+	ResourceSampledImage
+	ResourceStorageImage
+	ResourceSampler
+	ResourceInputAttachment
+)
+
+func (k ResourceKind) String() string {
+	switch k {
+	case ResourceUniformBuffer:
+		return "uniform buffer"
+	case ResourceStorageBuffer:
+		return "storage buffer"
+	case ResourceCombinedImageSampler:
+		return "combined image sampler"
+	case ResourceSampledImage:
+		return "sampled image"
+	case ResourceStorageImage:
+		return "storage image"
+	case ResourceSampler:
+		return "sampler"
+	case ResourceInputAttachment:
+		return "input attachment"
+	}
+	return fmt.Sprintf("resource kind %d", int(k))
+}
+
+/** @brief A resource bound through a descriptor set. */
+type Resource struct {
+	/** @brief The name of the variable, the block name when the variable has none. */
+	Name string
+	Kind ResourceKind
+	Set  uint32
+	/** @brief The binding of the resource in its set. */
+	Binding uint32
+	/** @brief The descriptors of the binding, the length of an array of resources, 1 otherwise. */
+	Count uint32
+	/** @brief The type of one descriptor: the block struct of buffers, the image of samplers. */
+	Type *Type
+}
+
+/** @brief What a shader module declares. */
+type Reflection struct {
+	/** @brief The stage of the first entry point. */
+	ExecutionModel ExecutionModel
+	/** @brief The name of the first entry point, usually main. */
+	EntryPoint string
+	/** @brief The inputs with a location, sorted by location. Built-in inputs are left out. */
+	Inputs []*Variable
+	/** @brief The outputs with a location, sorted by location. Built-in outputs are left out. */
+	Outputs []*Variable
+	/** @brief The resources of the descriptor sets, sorted by set and binding. */
+	Resources []*Resource
+	/** @brief The push constant block, nil when there is none. */
+	PushConstants *Resource
+}
+
+// Opcodes of the instructions decoded.
+const (
+	opName             = 5
+	opMemberName       = 6
+	opEntryPoint       = 15
+	opTypeVoid         = 19
+	opTypeBool         = 20
+	opTypeInt          = 21
+	opTypeFloat        = 22
+	opTypeVector       = 23
+	opTypeMatrix       = 24
+	opTypeImage        = 25
+	opTypeSampler      = 26
+	opTypeSampledImage = 27
+	opTypeArray        = 28
+	opTypeRuntimeArray = 29
+	opTypeStruct       = 30
+	opTypePointer      = 32
+	opConstant         = 43
+	opSpecConstant     = 50
+	opVariable         = 59
+	opDecorate         = 71
+	opMemberDecorate   = 72
+)
+
+// Decorations decoded.
+const (
+	decorationBufferBlock   = 3
+	decorationArrayStride   = 6
+	decorationMatrixStride  = 7
+	decorationBuiltIn       = 11
+	decorationLocation      = 30
+	decorationBinding       = 33
+	decorationDescriptorSet = 34
+	decorationOffset        = 35
+)
+
+// Storage classes of the variables reflected.
+const (
+	storageUniformConstant = 0
+	storageInput           = 1
+	storageUniform         = 2
+	storageOutput          = 3
+	storagePushConstant    = 9
+	storageStorageBuffer   = 12
+)
+
+type decorations struct {
+	values map[uint32][]uint32
+}
+
+func (d *decorations) set(decoration uint32, operands []uint32) {
+	if d.values == nil {
+		d.values = map[uint32][]uint32{}
+	}
+	d.values[decoration] = operands
+}
+
+func (d *decorations) has(decoration uint32) bool {
+	_, ok := d.values[decoration]
+	return ok
+}
+
+func (d *decorations) get(decoration uint32) uint32 {
+	if v := d.values[decoration]; len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+type parser struct {
+	names             map[uint32]string
+	memberNames       map[uint32]map[uint32]string
+	decorations       map[uint32]*decorations
+	memberDecorations map[uint32]map[uint32]*decorations
+	types             map[uint32]*Type
+	constants         map[uint32]uint32
+}
+
+// Reflect decodes the SPIR-V module.
+func Reflect(code []byte) (*Reflection, error) {
+	if len(code) < 20 || len(code)%4 != 0 {
+		return nil, errors.New("not a SPIR-V module")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(code) != MAGIC {
+		order = binary.BigEndian
+		if order.Uint32(code) != MAGIC {
+			return nil, errors.New("not a SPIR-V module")
+		}
+	}
+	words := make([]uint32, len(code)/4)
+	for i := range words {
+		words[i] = order.Uint32(code[i*4:])
+	}
+
+	p := &parser{
+		names:             map[uint32]string{},
+		memberNames:       map[uint32]map[uint32]string{},
+		decorations:       map[uint32]*decorations{},
+		memberDecorations: map[uint32]map[uint32]*decorations{},
+		types:             map[uint32]*Type{},
+		constants:         map[uint32]uint32{},
+	}
+	reflection := &Reflection{}
+	hasEntryPoint := false
+	type variable struct {
+		id, typeID, storage uint32
+	}
+	variables := []variable{}
+
+	// The header is five words: magic, version, generator, bound and schema.
+	for i := 5; i < len(words); {
+		count := int(words[i] >> 16)
+		opcode := words[i] & 0xFFFF
+		if count == 0 || i+count > len(words) {
+			return nil, fmt.Errorf("truncated instruction at word %d", i)
+		}
+		operands := words[i+1 : i+count]
+		i += count
+
+		switch opcode {
+		case opName:
+			if len(operands) >= 1 {
+				p.names[operands[0]] = literalString(operands[1:])
+			}
+		case opMemberName:
+			if len(operands) >= 2 {
+				if p.memberNames[operands[0]] == nil {
+					p.memberNames[operands[0]] = map[uint32]string{}
+				}
+				p.memberNames[operands[0]][operands[1]] = literalString(operands[2:])
+			}
+		case opEntryPoint:
+			if len(operands) >= 2 && !hasEntryPoint {
+				hasEntryPoint = true
+				reflection.ExecutionModel = ExecutionModel(operands[0])
+				reflection.EntryPoint = literalString(operands[2:])
+			}
+		case opDecorate:
+			if len(operands) >= 2 {
+				d := p.decorations[operands[0]]
+				if d == nil {
+					d = &decorations{}
+					p.decorations[operands[0]] = d
+				}
+				d.set(operands[1], operands[2:])
+			}
+		case opMemberDecorate:
+			if len(operands) >= 3 {
+				members := p.memberDecorations[operands[0]]
+				if members == nil {
+					members = map[uint32]*decorations{}
+					p.memberDecorations[operands[0]] = members
+				}
+				d := members[operands[1]]
+				if d == nil {
+					d = &decorations{}
+					members[operands[1]] = d
+				}
+				d.set(operands[2], operands[3:])
+			}
+		case opConstant, opSpecConstant:
+			// Only the 32-bit integers matter, as the lengths of the arrays.
+			if len(operands) >= 3 {
+				p.constants[operands[1]] = operands[2]
+			}
+		case opVariable:
+			if len(operands) >= 3 {
+				variables = append(variables, variable{id: operands[1], typeID: operands[0], storage: operands[2]})
+			}
+		default:
+			if opcode >= opTypeVoid && opcode <= opTypePointer {
+				if err := p.declareType(opcode, operands); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if !hasEntryPoint {
+		return nil, errors.New("the module has no entry point")
+	}
+
+	for _, v := range variables {
+		pointer := p.types[v.typeID]
+		if pointer == nil || pointer.Kind != TypePointer || pointer.Elem == nil {
+			continue
+		}
+		t := pointer.Elem
+		d := p.decorations[v.id]
+		if d == nil {
+			d = &decorations{}
+		}
+		name := p.names[v.id]
+
+		switch v.storage {
+		case storageInput, storageOutput:
+			// Built-ins, alone or in a block like gl_PerVertex, are not bound by the pipeline.
+			if d.has(decorationBuiltIn) || isBuiltInBlock(t) || !d.has(decorationLocation) {
+				continue
+			}
+			variable := &Variable{Name: name, Type: t, Location: d.get(decorationLocation)}
+			if v.storage == storageInput {
+				reflection.Inputs = append(reflection.Inputs, variable)
+			} else {
+				reflection.Outputs = append(reflection.Outputs, variable)
+			}
+		case storagePushConstant:
+			reflection.PushConstants = &Resource{Name: nameOr(name, t.Name), Kind: ResourceUniformBuffer, Count: 1, Type: t}
+		case storageUniform, storageUniformConstant, storageStorageBuffer:
+			resource := &Resource{
+				Name:    nameOr(name, t.Name),
+				Set:     d.get(decorationDescriptorSet),
+				Binding: d.get(decorationBinding),
+				Count:   1,
+			}
+			element := t
+			if element.Kind == TypeArray || element.Kind == TypeRuntimeArray {
+				resource.Count = element.Count
+				element = element.Elem
+			}
+			resource.Type = element
+			kind, ok := resourceKind(element, v.storage)
+			if !ok {
+				continue
+			}
+			resource.Kind = kind
+			reflection.Resources = append(reflection.Resources, resource)
+		}
+	}
+
+	sort.SliceStable(reflection.Inputs, func(i, j int) bool {
+		return reflection.Inputs[i].Location < reflection.Inputs[j].Location
+	})
+	sort.SliceStable(reflection.Outputs, func(i, j int) bool {
+		return reflection.Outputs[i].Location < reflection.Outputs[j].Location
+	})
+	sort.SliceStable(reflection.Resources, func(i, j int) bool {
+		a, b := reflection.Resources[i], reflection.Resources[j]
+		if a.Set != b.Set {
+			return a.Set < b.Set
+		}
+		return a.Binding < b.Binding
+	})
+	return reflection, nil
+}
+
+// declareType records the type declared by the instruction. SPIR-V declares the types before
+// they are used, and decorates them before declaring them.
+func (p *parser) declareType(opcode uint32, operands []uint32) error {
+	if len(operands) < 1 {
+		return errors.New("type without a result")
+	}
+	id := operands[0]
+	operands = operands[1:]
+	operand := func(i int) uint32 {
+		if i < len(operands) {
+			return operands[i]
+		}
+		return 0
+	}
+	elem := func(i int) *Type {
+		return p.types[operand(i)]
+	}
+
+	t := &Type{Kind: TypeOther}
+	switch opcode {
+	case opTypeVoid:
+		t.Kind = TypeVoid
+	case opTypeBool:
+		t.Kind = TypeBool
+	case opTypeInt:
+		t.Kind = TypeInt
+		t.Width = operand(0)
+		t.Signed = operand(1) != 0
+	case opTypeFloat:
+		t.Kind = TypeFloat
+		t.Width = operand(0)
+	case opTypeVector:
+		t.Kind = TypeVector
+		t.Elem = elem(0)
+		t.Count = operand(1)
+	case opTypeMatrix:
+		t.Kind = TypeMatrix
+		t.Elem = elem(0)
+		t.Count = operand(1)
+	case opTypeImage:
+		t.Kind = TypeImage
+		t.Elem = elem(0)
+		t.Dim = Dim(operand(1))
+		t.Arrayed = operand(3) != 0
+		// Sampled 2 marks the storage images, 1 the sampled ones.
+		t.Count = operand(5)
+	case opTypeSampler:
+		t.Kind = TypeSampler
+	case opTypeSampledImage:
+		t.Kind = TypeSampledImage
+		t.Elem = elem(0)
+	case opTypeArray:
+		t.Kind = TypeArray
+		t.Elem = elem(0)
+		length, ok := p.constants[operand(1)]
+		if !ok {
+			return fmt.Errorf("array %%%d has no constant length", id)
+		}
+		t.Count = length
+	case opTypeRuntimeArray:
+		t.Kind = TypeRuntimeArray
+		t.Elem = elem(0)
+	case opTypeStruct:
+		t.Kind = TypeStruct
+		t.Name = p.names[id]
+		t.Members = make([]*Member, len(operands))
+		for i, memberType := range operands {
+			member := &Member{Name: p.memberNames[id][uint32(i)], Type: p.types[memberType]}
+			if d := p.memberDecorations[id][uint32(i)]; d != nil {
+				member.Offset = d.get(decorationOffset)
+				member.MatrixStride = d.get(decorationMatrixStride)
+				member.BuiltIn = d.has(decorationBuiltIn)
+			}
+			if member.Type == nil {
+				return fmt.Errorf("member %d of struct %%%d has an undeclared type", i, id)
+			}
+			t.Members[i] = member
+		}
+	case opTypePointer:
+		t.Kind = TypePointer
+		t.Elem = elem(1)
+	}
+	if d := p.decorations[id]; d != nil {
+		t.ArrayStride = d.get(decorationArrayStride)
+		t.bufferBlock = d.has(decorationBufferBlock)
+	}
+	p.types[id] = t
+	return nil
+}
+
+func resourceKind(t *Type, storage uint32) (ResourceKind, bool) {
+	switch t.Kind {
+	case TypeStruct:
+		if storage == storageStorageBuffer || t.bufferBlock {
+			return ResourceStorageBuffer, true
+		}
+		return ResourceUniformBuffer, true
+	case TypeSampledImage:
+		return ResourceCombinedImageSampler, true
+	case TypeSampler:
+		return ResourceSampler, true
+	case TypeImage:
+		if t.Dim == DimSubpassData {
+			return ResourceInputAttachment, true
+		}
+		if t.Count == 2 {
+			return ResourceStorageImage, true
+		}
+		return ResourceSampledImage, true
+	}
+	return 0, false
+}
+
+func isBuiltInBlock(t *Type) bool {
+	if t.Kind == TypeArray {
+		t = t.Elem
+	}
+	if t == nil || t.Kind != TypeStruct {
+		return false
+	}
+	for _, m := range t.Members {
+		if m.BuiltIn {
+			return true
+		}
+	}
+	return false
+}
+
+func nameOr(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	return fallback
+}
+
+// literalString decodes a nul-terminated UTF-8 string packed in words, first byte lowest.
+func literalString(words []uint32) string {
+	b := make([]byte, 0, len(words)*4)
+	for _, w := range words {
+		for shift := 0; shift < 32; shift += 8 {
+			c := byte(w >> shift)
+			if c == 0 {
+				return string(b)
+			}
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// Size returns the bytes the type takes in a block: the offset of the last member plus its size
+// for structs, the stride times the length for arrays. Runtime arrays, images and samplers take
+// none.
+func (t *Type) Size() uint32 {
+	switch t.Kind {
+	case TypeBool:
+		return 4
+	case TypeInt, TypeFloat:
+		return t.Width / 8
+	case TypeVector:
+		return t.Count * t.Elem.Size()
+	case TypeMatrix:
+		return t.Count * t.Elem.Size()
+	case TypeArray:
+		stride := t.ArrayStride
+		if stride == 0 {
+			stride = t.Elem.Size()
+		}
+		return t.Count * stride
+	case TypeStruct:
+		size := uint32(0)
+		for _, m := range t.Members {
+			end := m.Offset + m.Type.Size()
+			if m.Type.Kind == TypeMatrix && m.MatrixStride != 0 {
+				end = m.Offset + m.Type.Count*m.MatrixStride
+			}
+			if end > size {
+				size = end
+			}
+		}
+		return size
+	}
+	return 0
+}
+
+// String returns the GLSL name of the type, e.g. vec3, mat4, sampler2D or float[4].
+func (t *Type) String() string {
+	switch t.Kind {
+	case TypeVoid:
+		return "void"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		name := "int"
+		if !t.Signed {
+			name = "uint"
+		}
+		if t.Width != 32 {
+			name = fmt.Sprintf("%s%d_t", name, t.Width)
+		}
+		return name
+	case TypeFloat:
+		switch t.Width {
+		case 32:
+			return "float"
+		case 64:
+			return "double"
+		}
+		return fmt.Sprintf("float%d_t", t.Width)
+	case TypeVector:
+		return fmt.Sprintf("%svec%d", scalarPrefix(t.Elem), t.Count)
+	case TypeMatrix:
+		if t.Elem != nil && t.Elem.Count == t.Count {
+			return fmt.Sprintf("%smat%d", scalarPrefix(t.Elem.Elem), t.Count)
+		}
+		rows := uint32(0)
+		if t.Elem != nil {
+			rows = t.Elem.Count
+		}
+		return fmt.Sprintf("%smat%dx%d", scalarPrefix(t.Elem.Elem), t.Count, rows)
+	case TypeArray:
+		return fmt.Sprintf("%s[%d]", t.Elem, t.Count)
+	case TypeRuntimeArray:
+		return fmt.Sprintf("%s[]", t.Elem)
+	case TypeStruct:
+		if t.Name != "" {
+			return t.Name
+		}
+		return "struct"
+	case TypeImage:
+		return imageName("texture", t)
+	case TypeSampler:
+		return "sampler"
+	case TypeSampledImage:
+		if t.Elem != nil {
+			return imageName("sampler", t.Elem)
+		}
+		return "sampler"
+	case TypePointer:
+		return fmt.Sprintf("%s*", t.Elem)
+	}
+	return "unknown"
+}
+
+func scalarPrefix(t *Type) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case TypeInt:
+		if t.Signed {
+			return "i"
+		}
+		return "u"
+	case TypeBool:
+		return "b"
+	case TypeFloat:
+		if t.Width == 64 {
+			return "d"
+		}
+	}
+	return ""
+}
+
+func imageName(prefix string, image *Type) string {
+	dims := map[Dim]string{Dim1D: "1D", Dim2D: "2D", Dim3D: "3D", DimCube: "Cube", DimRect: "2DRect", DimBuffer: "Buffer", DimSubpassData: "SubpassInput"}
+	name := scalarPrefix(image.Elem) + prefix + dims[image.Dim]
+	if image.Arrayed {
+		name += "Array"
+	}
+	return name
+}
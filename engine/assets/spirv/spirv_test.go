@@ -0,0 +1,235 @@
+package spirv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The shaders shipped with the engine.
+const shaderDirectory = "../../../assets/shaders"
+
+func reflectShader(t *testing.T, name string) *Reflection {
+	t.Helper()
+	code, err := os.ReadFile(filepath.Join(shaderDirectory, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reflection, err := Reflect(code)
+	if err != nil {
+		t.Fatalf("Reflect(%s): %v", name, err)
+	}
+	return reflection
+}
+
+func variableNames(variables []*Variable) map[uint32]string {
+	names := map[uint32]string{}
+	for _, v := range variables {
+		names[v.Location] = v.Name
+	}
+	return names
+}
+
+func TestReflectShippedShaders(t *testing.T) {
+	type resource struct {
+		set, binding, count uint32
+		kind                ResourceKind
+		size                uint32
+	}
+	tests := []struct {
+		name          string
+		model         ExecutionModel
+		inputs        map[uint32]string
+		outputs       map[uint32]string
+		resources     []resource
+		pushConstants uint32
+	}{
+		{
+			name:          "Builtin.MaterialShader.vert.spv",
+			model:         ExecutionModelVertex,
+			inputs:        map[uint32]string{0: "in_position", 1: "in_normal", 2: "in_texcoord", 3: "in_colour", 4: "in_tangent"},
+			outputs:       map[uint32]string{0: "out_mode", 1: "out_dto"},
+			resources:     []resource{{0, 0, 1, ResourceUniformBuffer, 1328}},
+			pushConstants: 64,
+		},
+		{
+			name:      "Builtin.MaterialShader.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{0: "in_mode", 1: "in_dto"},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{0, 0, 1, ResourceUniformBuffer, 1328}, {1, 0, 1, ResourceUniformBuffer, 20}, {1, 1, 3, ResourceCombinedImageSampler, 0}},
+		},
+		{
+			name:      "Builtin.MaterialShader.NORMAL_MAP.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{0: "in_mode", 1: "in_dto"},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{0, 0, 1, ResourceUniformBuffer, 1328}, {1, 0, 1, ResourceUniformBuffer, 20}, {1, 1, 3, ResourceCombinedImageSampler, 0}},
+		},
+		{
+			name:      "Builtin.SkyboxShader.vert.spv",
+			model:     ExecutionModelVertex,
+			inputs:    map[uint32]string{0: "in_position", 1: "in_normal", 2: "in_texcoord", 3: "in_colour", 4: "in_tangent"},
+			outputs:   map[uint32]string{0: "tex_coord"},
+			resources: []resource{{0, 0, 1, ResourceUniformBuffer, 128}},
+		},
+		{
+			name:      "Builtin.SkyboxShader.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{0: "tex_coord"},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{1, 0, 1, ResourceCombinedImageSampler, 0}},
+		},
+		{
+			name:          "Builtin.UIShader.vert.spv",
+			model:         ExecutionModelVertex,
+			inputs:        map[uint32]string{0: "in_position", 1: "in_texcoord"},
+			outputs:       map[uint32]string{0: "out_mode", 1: "out_dto"},
+			resources:     []resource{{0, 0, 1, ResourceUniformBuffer, 128}},
+			pushConstants: 64,
+		},
+		{
+			name:      "Builtin.UIShader.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{1: "in_dto"},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{1, 0, 1, ResourceUniformBuffer, 16}, {1, 1, 1, ResourceCombinedImageSampler, 0}},
+		},
+		{
+			name:          "Builtin.WorldPickShader.vert.spv",
+			model:         ExecutionModelVertex,
+			inputs:        map[uint32]string{0: "in_position", 1: "in_normal", 2: "in_texcoord", 3: "in_colour", 4: "in_tangent"},
+			outputs:       map[uint32]string{},
+			resources:     []resource{{0, 0, 1, ResourceUniformBuffer, 128}},
+			pushConstants: 64,
+		},
+		{
+			name:      "Builtin.WorldPickShader.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{1, 0, 1, ResourceUniformBuffer, 12}},
+		},
+		{
+			name:          "Builtin.UIPickShader.vert.spv",
+			model:         ExecutionModelVertex,
+			inputs:        map[uint32]string{0: "in_position", 1: "in_texcoord"},
+			outputs:       map[uint32]string{},
+			resources:     []resource{{0, 0, 1, ResourceUniformBuffer, 128}},
+			pushConstants: 64,
+		},
+		{
+			name:      "Builtin.UIPickShader.frag.spv",
+			model:     ExecutionModelFragment,
+			inputs:    map[uint32]string{},
+			outputs:   map[uint32]string{0: "out_colour"},
+			resources: []resource{{1, 0, 1, ResourceUniformBuffer, 12}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := reflectShader(t, test.name)
+			if r.ExecutionModel != test.model || r.EntryPoint != "main" {
+				t.Errorf("got a %s stage with entry point %q", r.ExecutionModel, r.EntryPoint)
+			}
+			if inputs := variableNames(r.Inputs); len(inputs) != len(test.inputs) {
+				t.Errorf("got inputs %v, want %v", inputs, test.inputs)
+			} else {
+				for location, name := range test.inputs {
+					if inputs[location] != name {
+						t.Errorf("got input %q at location %d, want %q", inputs[location], location, name)
+					}
+				}
+			}
+			if outputs := variableNames(r.Outputs); len(outputs) != len(test.outputs) {
+				t.Errorf("got outputs %v, want %v", outputs, test.outputs)
+			} else {
+				for location, name := range test.outputs {
+					if outputs[location] != name {
+						t.Errorf("got output %q at location %d, want %q", outputs[location], location, name)
+					}
+				}
+			}
+			if len(r.Resources) != len(test.resources) {
+				t.Fatalf("got %d resources, want %d", len(r.Resources), len(test.resources))
+			}
+			for i, want := range test.resources {
+				got := r.Resources[i]
+				if got.Set != want.set || got.Binding != want.binding || got.Count != want.count || got.Kind != want.kind {
+					t.Errorf("got resource %q at set %d binding %d, count %d, kind %s", got.Name, got.Set, got.Binding, got.Count, got.Kind)
+				}
+				if want.kind == ResourceUniformBuffer && got.Type.Size() != want.size {
+					t.Errorf("got block %q of %d bytes, want %d", got.Name, got.Type.Size(), want.size)
+				}
+			}
+			switch {
+			case test.pushConstants == 0 && r.PushConstants != nil:
+				t.Errorf("got unexpected push constants %q", r.PushConstants.Name)
+			case test.pushConstants != 0 && (r.PushConstants == nil || r.PushConstants.Type.Size() != test.pushConstants):
+				t.Errorf("got push constants %v, want %d bytes", r.PushConstants, test.pushConstants)
+			}
+		})
+	}
+}
+
+// The global block of the material shader must match the std140 layout uploaded by the
+// material system, lights included.
+func TestReflectMaterialGlobals(t *testing.T) {
+	for _, name := range []string{"Builtin.MaterialShader.vert.spv", "Builtin.MaterialShader.frag.spv", "Builtin.MaterialShader.NORMAL_MAP.frag.spv"} {
+		t.Run(name, func(t *testing.T) {
+			r := reflectShader(t, name)
+			if len(r.Resources) == 0 || r.Resources[0].Type.Kind != TypeStruct {
+				t.Fatal("no global uniform block")
+			}
+			members := map[string]*Member{}
+			for _, m := range r.Resources[0].Type.Members {
+				members[m.Name] = m
+			}
+			offsets := map[string]uint32{
+				"projection": 0, "view": 64, "ambient_colour": 128, "view_position": 144, "mode": 156,
+				"dir_light": 160, "point_light_count": 192, "spot_light_count": 196,
+				"point_lights": 208, "spot_lights": 688,
+			}
+			for member, offset := range offsets {
+				m, ok := members[member]
+				if !ok {
+					t.Errorf("missing member %q", member)
+					continue
+				}
+				if m.Offset != offset {
+					t.Errorf("got %q at offset %d, want %d", member, m.Offset, offset)
+				}
+			}
+			arrays := []struct {
+				member        string
+				count, stride uint32
+			}{
+				{"point_lights", 10, 48},
+				{"spot_lights", 8, 80},
+			}
+			for _, array := range arrays {
+				m, ok := members[array.member]
+				if !ok {
+					continue
+				}
+				if m.Type.Kind != TypeArray || m.Type.Count != array.count || m.Type.ArrayStride != array.stride {
+					t.Errorf("got %q of type %s with a stride of %d, want %d elements with a stride of %d",
+						array.member, m.Type, m.Type.ArrayStride, array.count, array.stride)
+				}
+			}
+		})
+	}
+}
+
+func TestReflectRejectsInvalidModules(t *testing.T) {
+	for name, code := range map[string][]byte{
+		"empty":     nil,
+		"too short": make([]byte, 16),
+		"unaligned": make([]byte, 21),
+		"bad magic": make([]byte, 20),
+	} {
+		if _, err := Reflect(code); err == nil {
+			t.Errorf("Reflect accepted a module that is %s", name)
+		}
+	}
+}
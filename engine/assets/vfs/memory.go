@@ -0,0 +1,134 @@
+package vfs
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+/**
+ * @brief A file system held in memory, for the files produced at runtime
+ * (e.g. the images embedded in models). Files can be added but not removed.
+ */
+type MemoryFS struct {
+	mutex   sync.RWMutex
+	files   map[string]*memoryEntry
+	dirs    map[string][]string
+	created time.Time
+}
+
+type memoryEntry struct {
+	data    []byte
+	modTime time.Time
+}
+
+var (
+	_ fs.FS         = (*MemoryFS)(nil)
+	_ fs.ReadDirFS  = (*MemoryFS)(nil)
+	_ fs.ReadFileFS = (*MemoryFS)(nil)
+	_ fs.StatFS     = (*MemoryFS)(nil)
+)
+
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{
+		files:   map[string]*memoryEntry{},
+		dirs:    map[string][]string{".": {}},
+		created: time.Now(),
+	}
+}
+
+// WriteFile adds the file, or replaces its content if it exists. The data is not copied.
+func (m *MemoryFS) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, isDir := m.dirs[name]; isDir {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	if _, exists := m.files[name]; !exists {
+		addToDirs(m.dirs, name)
+	}
+	m.files[name] = &memoryEntry{data: data, modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := m.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		entries, err := m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dirFile{info: info.(*fileInfo), entries: entries}, nil
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return &memoryFile{Reader: bytes.NewReader(m.files[name].data), info: info.(*fileInfo)}, nil
+}
+
+func (m *MemoryFS) ReadFile(name string) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	e, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(e.data), nil
+}
+
+func (m *MemoryFS) Stat(name string) (fs.FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if e, ok := m.files[name]; ok {
+		return newFileInfo(name, int64(len(e.data)), false, e.modTime), nil
+	}
+	if _, ok := m.dirs[name]; ok {
+		return newFileInfo(name, 0, true, m.created), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemoryFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mutex.RLock()
+	children, ok := m.dirs[name]
+	children = append([]string(nil), children...)
+	m.mutex.RUnlock()
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Strings(children)
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		info, err := m.Stat(path.Join(name, child))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+	info *fileInfo
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memoryFile) Close() error               { return nil }
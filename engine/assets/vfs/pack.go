@@ -0,0 +1,275 @@
+package vfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/*
+ * Layout of a pack archive, little endian:
+ *
+ *   header   u32 magic (metadata.ResourceMagic), u8 resource type, u8 version, u16 reserved
+ *   u32      entry count
+ *   entries  u16 name length, name bytes, u64 offset, u64 size
+ *   data     the contents of the files, at the offsets of their entries
+ */
+
+// The version of the pack format written by WritePack.
+const PACK_VERSION uint8 = 1
+
+type packEntry struct {
+	name   string
+	offset int64
+	size   int64
+}
+
+/**
+ * @brief A read-only pack archive. Implements fs.FS over the files it contains;
+ * directories are derived from the file names.
+ */
+type Pack struct {
+	file    *os.File
+	modTime time.Time
+	entries map[string]*packEntry
+	// Children of each directory, sorted by name.
+	dirs map[string][]string
+}
+
+var (
+	_ fs.FS         = (*Pack)(nil)
+	_ fs.ReadDirFS  = (*Pack)(nil)
+	_ fs.ReadFileFS = (*Pack)(nil)
+	_ fs.StatFS     = (*Pack)(nil)
+)
+
+// OpenPack opens the pack archive at the given path. Close it when done.
+func OpenPack(path string) (*Pack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	p := &Pack{
+		file:    f,
+		modTime: info.ModTime(),
+		entries: map[string]*packEntry{},
+		dirs:    map[string][]string{".": {}},
+	}
+	if err := p.readIndex(info.Size()); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid pack `%s`: %w", path, err)
+	}
+	return p, nil
+}
+
+func (p *Pack) Close() error {
+	return p.file.Close()
+}
+
+func (p *Pack) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if e, ok := p.entries[name]; ok {
+		return &packFile{
+			SectionReader: io.NewSectionReader(p.file, e.offset, e.size),
+			info:          newFileInfo(name, e.size, false, p.modTime),
+		}, nil
+	}
+	if _, ok := p.dirs[name]; ok {
+		entries, _ := p.ReadDir(name)
+		return &dirFile{info: newFileInfo(name, 0, true, p.modTime), entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (p *Pack) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := p.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	data := make([]byte, e.size)
+	if _, err := p.file.ReadAt(data, e.offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (p *Pack) Stat(name string) (fs.FileInfo, error) {
+	if e, ok := p.entries[name]; ok {
+		return newFileInfo(name, e.size, false, p.modTime), nil
+	}
+	if _, ok := p.dirs[name]; ok {
+		return newFileInfo(name, 0, true, p.modTime), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (p *Pack) ReadDir(name string) ([]fs.DirEntry, error) {
+	children, ok := p.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		full := path.Join(name, child)
+		info, err := p.Stat(full)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+func (p *Pack) readIndex(fileSize int64) error {
+	var header struct {
+		MagicNumber  uint32
+		ResourceType uint8
+		Version      uint8
+		Reserved     uint16
+	}
+	if err := binary.Read(p.file, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.MagicNumber != uint32(metadata.ResourceMagic) {
+		return errors.New("wrong magic number")
+	}
+	if metadata.ResourceType(header.ResourceType) != metadata.ResourceTypePack {
+		return fmt.Errorf("not a pack resource (type %d)", header.ResourceType)
+	}
+	if header.Version > PACK_VERSION {
+		return fmt.Errorf("unsupported version %d", header.Version)
+	}
+
+	var count uint32
+	if err := binary.Read(p.file, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		var nameLength uint16
+		if err := binary.Read(p.file, binary.LittleEndian, &nameLength); err != nil {
+			return err
+		}
+		name := make([]byte, nameLength)
+		if _, err := io.ReadFull(p.file, name); err != nil {
+			return err
+		}
+		var location struct {
+			Offset uint64
+			Size   uint64
+		}
+		if err := binary.Read(p.file, binary.LittleEndian, &location); err != nil {
+			return err
+		}
+		if !fs.ValidPath(string(name)) || location.Offset+location.Size > uint64(fileSize) {
+			return fmt.Errorf("corrupted entry `%s`", name)
+		}
+		p.entries[string(name)] = &packEntry{
+			name:   string(name),
+			offset: int64(location.Offset),
+			size:   int64(location.Size),
+		}
+		addToDirs(p.dirs, string(name))
+	}
+
+	for dir := range p.dirs {
+		sort.Strings(p.dirs[dir])
+	}
+	return nil
+}
+
+// WritePack writes all the files of fsys to w as a pack archive.
+func WritePack(w io.Writer, fsys fs.FS) error {
+	names := []string{}
+	sizes := map[string]int64{}
+	if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		sizes[name] = info.Size()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// The data starts right after the index.
+	offset := int64(8 + 4)
+	for _, name := range names {
+		offset += int64(2 + len(name) + 8 + 8)
+	}
+
+	header := []interface{}{
+		uint32(metadata.ResourceMagic),
+		uint8(metadata.ResourceTypePack),
+		PACK_VERSION,
+		uint16(0),
+		uint32(len(names)),
+	}
+	for _, value := range header {
+		if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if len(name) > 0xFFFF {
+			return fmt.Errorf("name too long for a pack entry: `%s`", name)
+		}
+		entry := []interface{}{uint16(len(name)), []byte(name), uint64(offset), uint64(sizes[name])}
+		for _, value := range entry {
+			if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+				return err
+			}
+		}
+		offset += sizes[name]
+	}
+
+	for _, name := range names {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if written != sizes[name] {
+			return fmt.Errorf("file `%s` changed while being packed", name)
+		}
+	}
+	return nil
+}
+
+type packFile struct {
+	*io.SectionReader
+	info *fileInfo
+}
+
+func (f *packFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *packFile) Close() error               { return nil }
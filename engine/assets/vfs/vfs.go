@@ -0,0 +1,304 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+/**
+ * @brief A mounted source of files. Directories are read from disk, packs
+ * from a single archive file, and other file systems (e.g. a MemoryFS) as is.
+ */
+type Mount struct {
+	/** @brief The directory or pack file on disk the mount reads from. */
+	Source string
+	/** @brief Indicates if the mount is a directory on disk. */
+	IsDirectory bool
+	/** @brief Indicates if the mount is a pack archive. Packs are read-only. */
+	IsPack bool
+
+	fsys fs.FS
+}
+
+/**
+ * @brief A virtual file system made of ordered mounts. Names are resolved
+ * against the mounts from the last mounted to the first, so mounting patch
+ * or mod directories after the base directory makes them override its files.
+ * Names are slash-separated and relative, like for fs.FS.
+ */
+type FileSystem struct {
+	mutex  sync.RWMutex
+	mounts []*Mount
+}
+
+var (
+	_ fs.FS         = (*FileSystem)(nil)
+	_ fs.ReadFileFS = (*FileSystem)(nil)
+	_ fs.ReadDirFS  = (*FileSystem)(nil)
+	_ fs.StatFS     = (*FileSystem)(nil)
+)
+
+func New() *FileSystem {
+	return &FileSystem{
+		mounts: []*Mount{},
+	}
+}
+
+// MountDirectory mounts the directory on top of the current mounts.
+func (v *FileSystem) MountDirectory(dir string) (*Mount, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cannot mount `%s`: not a directory", dir)
+	}
+	return v.mount(&Mount{
+		Source:      dir,
+		IsDirectory: true,
+		fsys:        os.DirFS(dir),
+	}), nil
+}
+
+// MountPack opens the pack archive and mounts it on top of the current mounts.
+func (v *FileSystem) MountPack(path string) (*Mount, error) {
+	pack, err := OpenPack(path)
+	if err != nil {
+		return nil, err
+	}
+	return v.mount(&Mount{
+		Source: path,
+		IsPack: true,
+		fsys:   pack,
+	}), nil
+}
+
+// MountFS mounts any file system on top of the current mounts, e.g. a MemoryFS. The source
+// only names the mount.
+func (v *FileSystem) MountFS(source string, fsys fs.FS) *Mount {
+	return v.mount(&Mount{
+		Source: source,
+		fsys:   fsys,
+	})
+}
+
+// Mount mounts either a directory or a pack archive, depending on what the path points to.
+func (v *FileSystem) Mount(path string) (*Mount, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return v.MountDirectory(path)
+	}
+	return v.MountPack(path)
+}
+
+// Unmount removes the mount, closing its pack if it is one.
+func (v *FileSystem) Unmount(m *Mount) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for i, mounted := range v.mounts {
+		if mounted == m {
+			v.mounts = append(v.mounts[:i], v.mounts[i+1:]...)
+			return closeMount(m)
+		}
+	}
+	return fmt.Errorf("cannot unmount `%s`: not mounted", m.Source)
+}
+
+// Mounts returns the current mounts, from the first mounted to the last.
+func (v *FileSystem) Mounts() []*Mount {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	mounts := make([]*Mount, len(v.mounts))
+	copy(mounts, v.mounts)
+	return mounts
+}
+
+// Close unmounts everything.
+func (v *FileSystem) Close() error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	var errs []error
+	for _, m := range v.mounts {
+		if err := closeMount(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	v.mounts = []*Mount{}
+	return errors.Join(errs...)
+}
+
+// Open opens the named file from the last mount that has it. Directories list the
+// entries merged across all the mounts.
+func (v *FileSystem) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, m := range v.lookupOrder() {
+		f, err := m.fsys.Open(name)
+		if err == nil {
+			return v.wrapDir(name, f)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile reads the named file from the last mount that has it.
+func (v *FileSystem) ReadFile(name string) ([]byte, error) {
+	f, err := v.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// Stat returns the info of the named file from the last mount that has it.
+func (v *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, m := range v.lookupOrder() {
+		info, err := fs.Stat(m.fsys, name)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the entries of the named directory across all the mounts.
+// When several mounts have an entry with the same name, the last mounted wins.
+func (v *FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	found := false
+	entries := map[string]fs.DirEntry{}
+	for _, m := range v.lookupOrder() {
+		dir, err := fs.ReadDir(m.fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, entry := range dir {
+			if _, ok := entries[entry.Name()]; !ok {
+				entries[entry.Name()] = entry
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+	return merged, nil
+}
+
+// Resolve returns the mount the named file is read from, and the path of the file on disk
+// when that mount is a directory (empty otherwise).
+func (v *FileSystem) Resolve(name string) (*Mount, string, error) {
+	for _, m := range v.lookupOrder() {
+		if _, err := fs.Stat(m.fsys, name); err == nil {
+			if !m.IsDirectory {
+				return m, "", nil
+			}
+			return m, filepath.Join(m.Source, filepath.FromSlash(name)), nil
+		}
+	}
+	return nil, "", &fs.PathError{Op: "resolve", Path: name, Err: fs.ErrNotExist}
+}
+
+func (v *FileSystem) mount(m *Mount) *Mount {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.mounts = append(v.mounts, m)
+	return m
+}
+
+// lookupOrder returns the mounts from the last mounted to the first.
+func (v *FileSystem) lookupOrder() []*Mount {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	order := make([]*Mount, len(v.mounts))
+	for i, m := range v.mounts {
+		order[len(v.mounts)-1-i] = m
+	}
+	return order
+}
+
+// wrapDir makes directories list the merged entries instead of the ones of a single mount.
+func (v *FileSystem) wrapDir(name string, f fs.File) (fs.File, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f, nil
+	}
+	entries, err := v.ReadDir(name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &mergedDir{File: f, entries: entries}, nil
+}
+
+type mergedDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *mergedDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func closeMount(m *Mount) error {
+	if closer, ok := m.fsys.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePack writes the files to a pack archive in a temporary directory and returns its path.
+func writePack(t *testing.T, files map[string]string) string {
+	t.Helper()
+	source := NewMemoryFS()
+	for name, data := range files {
+		if err := source.WriteFile(name, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "assets.pack")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := WritePack(f, source); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readString(t *testing.T, v *FileSystem, name string) string {
+	t.Helper()
+	data, err := v.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", name, err)
+	}
+	return string(data)
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	path := writePack(t, map[string]string{
+		"shaders/a.shadercfg": "name=a",
+		"textures/b.png":      "png",
+	})
+	pack, err := OpenPack(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pack.Close()
+
+	data, err := pack.ReadFile("shaders/a.shadercfg")
+	if err != nil || string(data) != "name=a" {
+		t.Fatalf("ReadFile returned %q, %v", data, err)
+	}
+	entries, err := pack.ReadDir(".")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "shaders" || !entries[0].IsDir() {
+		t.Fatalf("ReadDir returned %v, %v", entries, err)
+	}
+	if _, err := pack.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat of a missing file returned %v", err)
+	}
+}
+
+func TestFileSystemPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shaders"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"shaders/a.shadercfg": "directory",
+		"shaders/b.shadercfg": "directory",
+		"base.txt":            "directory",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pack := writePack(t, map[string]string{
+		"shaders/b.shadercfg": "pack",
+		"shaders/c.shadercfg": "pack",
+	})
+	overlay := NewMemoryFS()
+	if err := overlay.WriteFile("shaders/c.shadercfg", []byte("overlay")); err != nil {
+		t.Fatal(err)
+	}
+
+	v := New()
+	defer v.Close()
+	base, err := v.Mount(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packMount, err := v.Mount(pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !base.IsDirectory || !packMount.IsPack {
+		t.Fatal("Mount did not tell the directory and the pack apart")
+	}
+	overlayMount := v.MountFS("overlay", overlay)
+
+	// The last mounted wins.
+	for name, want := range map[string]string{
+		"base.txt":            "directory",
+		"shaders/a.shadercfg": "directory",
+		"shaders/b.shadercfg": "pack",
+		"shaders/c.shadercfg": "overlay",
+	} {
+		if got := readString(t, v, name); got != want {
+			t.Errorf("%s read from the %s, want the %s", name, got, want)
+		}
+	}
+
+	m, diskPath, err := v.Resolve("shaders/a.shadercfg")
+	if err != nil || m != base || diskPath != filepath.Join(dir, "shaders", "a.shadercfg") {
+		t.Errorf("Resolve of a directory file returned %v, %q, %v", m, diskPath, err)
+	}
+	if m, diskPath, err := v.Resolve("shaders/b.shadercfg"); err != nil || m != packMount || diskPath != "" {
+		t.Errorf("Resolve of a pack file returned %v, %q, %v", m, diskPath, err)
+	}
+
+	entries, err := v.ReadDir("shaders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 3 || names[0] != "a.shadercfg" || names[1] != "b.shadercfg" || names[2] != "c.shadercfg" {
+		t.Errorf("ReadDir merged %v", names)
+	}
+
+	// Unmounting the overlay and the pack uncovers the files below them.
+	if err := v.Unmount(overlayMount); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, v, "shaders/c.shadercfg"); got != "pack" {
+		t.Errorf("shaders/c.shadercfg read from the %s after unmounting the overlay", got)
+	}
+	if err := v.Unmount(packMount); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, v, "shaders/b.shadercfg"); got != "directory" {
+		t.Errorf("shaders/b.shadercfg read from the %s after unmounting the pack", got)
+	}
+	if _, err := v.ReadFile("shaders/c.shadercfg"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile of an unmounted file returned %v", err)
+	}
+	if err := v.Unmount(packMount); err == nil {
+		t.Error("Unmount of an unmounted pack succeeded")
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+/**
+ * @brief A type-safe event bus. The Go type of an event identifies the
+ * channel it travels on, so games can declare their own events simply
+ * by declaring a type, with no codes to reserve. Dispatching follows
+ * the same rules as the code-based events: listeners run by descending
+ * priority, and a listener can mark an event as handled to stop it.
+ */
+type EventBus struct {
+	mutex    sync.RWMutex
+	channels map[reflect.Type][]busListener
+	nextID   uint64
+
+	queueMutex sync.Mutex
+	// Events posted to be dispatched at the next DispatchQueued.
+	queue []func()
+}
+
+type busListener struct {
+	id       uint64
+	priority int32
+	callback func(event interface{}) bool
+}
+
+/** @brief Identifies a subscription to a bus. Used to unsubscribe. */
+type Subscription struct {
+	bus         *EventBus
+	channel     reflect.Type
+	id          uint64
+	initialized bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		channels: map[reflect.Type][]busListener{},
+		nextID:   1,
+		queue:    []func(){},
+	}
+}
+
+// Valid reports whether the subscription refers to a registration.
+func (s Subscription) Valid() bool {
+	return s.initialized
+}
+
+// Unsubscribe removes the listener. Returns false if it was not subscribed anymore.
+func (s Subscription) Unsubscribe() bool {
+	if !s.initialized || s.bus == nil {
+		return false
+	}
+	return s.bus.remove(s.channel, s.id)
+}
+
+// Subscribe registers the handler for the events of type T with the default priority.
+func Subscribe[T any](bus *EventBus, handler func(event T)) Subscription {
+	return SubscribeWithPriority(bus, EVENT_PRIORITY_DEFAULT, func(event T) bool {
+		handler(event)
+		return false
+	})
+}
+
+// SubscribeWithPriority registers the handler for the events of type T. Handlers with a
+// higher priority are called first, and returning true marks the event as handled,
+// which stops it from reaching the remaining handlers.
+func SubscribeWithPriority[T any](bus *EventBus, priority int32, handler func(event T) bool) Subscription {
+	if bus == nil || handler == nil {
+		LogError("cannot subscribe to events of type %s without a bus and a handler", channelOf[T]())
+		return Subscription{}
+	}
+	return bus.add(channelOf[T](), priority, func(event interface{}) bool {
+		typed, _ := event.(T)
+		return handler(typed)
+	})
+}
+
+// Publish dispatches the event immediately, on the calling goroutine, to the handlers
+// subscribed to its type. Returns true if one of them handled it.
+func Publish[T any](bus *EventBus, event T) bool {
+	if bus == nil {
+		return false
+	}
+	return bus.dispatch(channelOf[T](), event)
+}
+
+// Post queues the event to be dispatched at the next DispatchQueued of the bus.
+// Safe to call from any goroutine.
+func Post[T any](bus *EventBus, event T) {
+	if bus == nil {
+		return
+	}
+	bus.post(func() {
+		Publish(bus, event)
+	})
+}
+
+// DispatchQueued dispatches the posted events in the order they were posted.
+// Events posted while dispatching are delivered on the next call.
+func (b *EventBus) DispatchQueued() {
+	b.queueMutex.Lock()
+	queue := b.queue
+	b.queue = []func(){}
+	b.queueMutex.Unlock()
+
+	for _, publish := range queue {
+		publish()
+	}
+}
+
+// post queues the dispatch to run at the next DispatchQueued.
+func (b *EventBus) post(dispatch func()) {
+	b.queueMutex.Lock()
+	defer b.queueMutex.Unlock()
+
+	b.queue = append(b.queue, dispatch)
+}
+
+func (b *EventBus) add(channel reflect.Type, priority int32, callback func(event interface{}) bool) Subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	// Copy on write, so dispatches in progress keep their own snapshot.
+	listeners := b.channels[channel]
+	updated := make([]busListener, len(listeners), len(listeners)+1)
+	copy(updated, listeners)
+	updated = append(updated, busListener{id: id, priority: priority, callback: callback})
+	sort.SliceStable(updated, func(i, j int) bool {
+		return updated[i].priority > updated[j].priority
+	})
+	b.channels[channel] = updated
+
+	return Subscription{bus: b, channel: channel, id: id, initialized: true}
+}
+
+func (b *EventBus) remove(channel reflect.Type, id uint64) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	listeners := b.channels[channel]
+	for i, l := range listeners {
+		if l.id == id {
+			updated := make([]busListener, 0, len(listeners)-1)
+			updated = append(updated, listeners[:i]...)
+			updated = append(updated, listeners[i+1:]...)
+			b.channels[channel] = updated
+			return true
+		}
+	}
+	return false
+}
+
+func (b *EventBus) dispatch(channel reflect.Type, event interface{}) bool {
+	b.mutex.RLock()
+	listeners := b.channels[channel]
+	b.mutex.RUnlock()
+
+	for _, l := range listeners {
+		if l.callback(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// channelOf returns the channel identifier of the events of type T.
+func channelOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
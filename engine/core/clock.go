@@ -2,34 +2,52 @@ package core
 
 import "time"
 
+// TimeSource returns the current time in seconds.
+type TimeSource func() float64
+
 type Clock struct {
 	startTime float64
 	elapsed   float64
+	started   bool
+	source    TimeSource
 }
 
+// NewClock creates a clock measuring wall time.
 func NewClock() *Clock {
-	return &Clock{}
+	return NewClockWithSource(func() float64 {
+		return float64(time.Now().UnixNano()) / float64(time.Second)
+	})
+}
+
+// NewClockWithSource creates a clock reading the time from the given source,
+// i.e. the platform, which may be virtual.
+func NewClockWithSource(source TimeSource) *Clock {
+	return &Clock{
+		source: source,
+	}
 }
 
 // Updates the provided clock. Should be called just before checking elapsed time.
 // Has no effect on non-started clocks.
 func (c *Clock) Update() {
-	if c.startTime != 0 {
-		c.elapsed = float64(time.Now().UnixNano()) - c.startTime
+	if c.started {
+		c.elapsed = c.source() - c.startTime
 	}
 }
 
 // Starts the provided clock. Resets elapsed time.
 func (c *Clock) Start() {
-	c.startTime = float64(time.Now().UnixNano())
+	c.startTime = c.source()
 	c.elapsed = 0
+	c.started = true
 }
 
 // Stops the provided clock. Does not reset elapsed time.
 func (c *Clock) Stop() {
-	c.startTime = 0
+	c.started = false
 }
 
+// Elapsed returns the seconds elapsed since the clock was started, as of the last Update.
 func (c *Clock) Elapsed() float64 {
 	return c.elapsed
 }
@@ -1,53 +1,36 @@
 package core
 
 import (
+	"sort"
 	"sync"
 )
 
-// System internal event codes. Application should use codes beyond 255.
+// System internal event codes. Application should allocate its own with EventCodeAllocate,
+// or better, declare their own event types and use the typed EventBus.
 type EventCode int
 
+// NOTE: codes are allocated with iota so that they can never collide. Append new ones at the end.
 const (
 	// Shuts the application down on the next frame.
-	EVENT_CODE_APPLICATION_QUIT EventCode = 0x01
-
-	// Keyboard key pressed.
-	EVENT_CODE_KEY_PRESSED EventCode = 0x02
-
-	// Keyboard key released.
-	EVENT_CODE_KEY_RELEASED EventCode = 0x03
-
-	// Mouse button pressed.
-	EVENT_CODE_BUTTON_PRESSED EventCode = 0x04
-
-	// Mouse button released.
-	EVENT_CODE_BUTTON_RELEASED EventCode = 0x05
-
-	// Mouse moved.
-	EVENT_CODE_MOUSE_MOVED EventCode = 0x06
-
-	// Mouse moved.
-	EVENT_CODE_MOUSE_WHEEL EventCode = 0x07
-
-	// Resized/resolution changed from the OS.
-	EVENT_CODE_RESIZED EventCode = 0x08
+	EVENT_CODE_APPLICATION_QUIT EventCode = iota + 1
 
 	// Change the render mode for debugging purposes.
-	EVENT_CODE_SET_RENDER_MODE EventCode = 0x0A
+	EVENT_CODE_SET_RENDER_MODE
 
 	/** @brief The hovered-over object id, if there is one.
 	 */
-	EVENT_CODE_OBJECT_HOVER_ID_CHANGED EventCode = 0x15
+	EVENT_CODE_OBJECT_HOVER_ID_CHANGED
 
-	EVENT_CODE_DEBUG0 EventCode = 0x16
-	EVENT_CODE_DEBUG1 EventCode = 0x17
+	EVENT_CODE_DEBUG0
+	EVENT_CODE_DEBUG1
 
 	/**
 	 * @brief An event fired by the renderer backend to indicate when any render targets
 	 * associated with the default window resources need to be refreshed (i.e. a window resize)
 	 */
-	EVENT_CODE_DEFAULT_RENDERTARGET_REFRESH_REQUIRED EventCode = 0x16
+	EVENT_CODE_DEFAULT_RENDERTARGET_REFRESH_REQUIRED
 
+	// The last code reserved for the engine.
 	MAX_EVENT_CODE EventCode = 0xFF
 )
 
@@ -56,67 +39,217 @@ type EventContext struct {
 	Data interface{}
 }
 
-type MouseEvent struct {
-	PosX   uint16
-	PosY   uint16
-	Button Button
-	Scroll int8
-}
-
+/** @brief Published on the event bus when a keyboard key is pressed or released. */
 type KeyEvent struct {
 	KeyCode KeyCode
+	Pressed bool
 }
 
-type SystemEvent struct {
-	WindowWidth  uint32
-	WindowHeight uint32
+/** @brief Published on the event bus when a mouse button is pressed or released. */
+type MouseButtonEvent struct {
+	Button  Button
+	Pressed bool
 }
 
-type EventCallback func(event EventContext)
+/** @brief Published on the event bus when the mouse moves. */
+type MouseMovedEvent struct {
+	PosX uint16
+	PosY uint16
+}
 
-type EventSystem struct {
-	subscribers map[EventCode][]EventCallback
-	eventChan   chan EventContext // Channel for event communication
+/** @brief Published on the event bus when the mouse wheel scrolls. The delta is either -1 or 1. */
+type MouseWheelEvent struct {
+	ZDelta int8
+}
+
+/** @brief Published on the event bus when the OS resized the window, or changed its resolution. */
+type WindowResizedEvent struct {
+	Width  uint32
+	Height uint32
 }
 
-const MAX_EVENTS_BUFFER_SIZE = 100
+// EventCallback handles an event. Returning true marks the event as handled,
+// which stops it from being passed to the remaining listeners.
+type EventCallback func(event EventContext) bool
+
+// The priority listeners get when registered with EventRegister.
+const EVENT_PRIORITY_DEFAULT int32 = 0
+
+/** @brief Identifies a registered listener. Used to unregister it. */
+type EventHandle struct {
+	Code EventCode
+	id   uint64
+}
+
+// Valid reports whether the handle refers to a registration.
+func (h EventHandle) Valid() bool {
+	return h.id != 0
+}
+
+// Unregister removes the listener the handle refers to. See EventUnregister.
+func (h EventHandle) Unregister() bool {
+	return EventUnregister(h)
+}
+
+type eventListener struct {
+	id       uint64
+	priority int32
+	callback EventCallback
+}
+
+type EventSystem struct {
+	mutex sync.RWMutex
+
+	// Listeners by code, sorted by descending priority and then by registration order.
+	subscribers map[EventCode][]eventListener
+	nextID      uint64
+	nextCode    EventCode
+
+	// The typed bus engine and game events are published on. Its queue also holds the
+	// posted code-based events, so both are dispatched in the order they were posted.
+	bus *EventBus
+}
 
-var onceEventInit sync.Once
 var eventSystem *EventSystem
 
-// Initialize the event system with a buffered channel
+// EventSystemInitialize initializes the event system. Calling it again is a no-op.
 func EventSystemInitialize() error {
-	onceEventInit.Do(func() {
-		eventSystem = &EventSystem{
-			subscribers: make(map[EventCode][]EventCallback),
-			eventChan:   make(chan EventContext, MAX_EVENTS_BUFFER_SIZE), // Buffered channel
-		}
-	})
+	if eventSystem != nil {
+		return nil
+	}
+	eventSystem = &EventSystem{
+		subscribers: make(map[EventCode][]eventListener),
+		nextID:      1,
+		nextCode:    MAX_EVENT_CODE + 1,
+		bus:         NewEventBus(),
+	}
 	return nil
 }
 
-// Subscribe to an event type
-func EventRegister(code EventCode, callback EventCallback) {
-	eventSystem.subscribers[code] = append(eventSystem.subscribers[code], callback)
+// Events returns the typed event bus of the engine, or nil if the event system is not initialized.
+func Events() *EventBus {
+	if eventSystem == nil {
+		return nil
+	}
+	return eventSystem.bus
+}
+
+// EventCodeAllocate returns a new event code, guaranteed not to collide with the
+// system ones or with the ones previously allocated.
+func EventCodeAllocate() EventCode {
+	if eventSystem == nil {
+		LogError("event system is not initialized, cannot allocate an event code")
+		return 0
+	}
+	eventSystem.mutex.Lock()
+	defer eventSystem.mutex.Unlock()
+
+	code := eventSystem.nextCode
+	eventSystem.nextCode++
+	return code
+}
+
+// EventRegister registers the callback for the given code with the default priority.
+func EventRegister(code EventCode, callback EventCallback) EventHandle {
+	return EventRegisterWithPriority(code, EVENT_PRIORITY_DEFAULT, callback)
+}
+
+// EventRegisterWithPriority registers the callback for the given code. Listeners with a
+// higher priority are called first; listeners with the same priority are called in
+// registration order.
+func EventRegisterWithPriority(code EventCode, priority int32, callback EventCallback) EventHandle {
+	if eventSystem == nil {
+		LogError("event system is not initialized, cannot register for event code %d", code)
+		return EventHandle{}
+	}
+	if callback == nil {
+		LogError("cannot register a nil callback for event code %d", code)
+		return EventHandle{}
+	}
+
+	eventSystem.mutex.Lock()
+	defer eventSystem.mutex.Unlock()
+
+	id := eventSystem.nextID
+	eventSystem.nextID++
+
+	// Copy on write, so dispatches in progress keep their own snapshot.
+	listeners := eventSystem.subscribers[code]
+	updated := make([]eventListener, len(listeners), len(listeners)+1)
+	copy(updated, listeners)
+	updated = append(updated, eventListener{id: id, priority: priority, callback: callback})
+	sort.SliceStable(updated, func(i, j int) bool {
+		return updated[i].priority > updated[j].priority
+	})
+	eventSystem.subscribers[code] = updated
+
+	return EventHandle{Code: code, id: id}
 }
 
-func EventFire(context EventContext) {
-	eventSystem.eventChan <- context
+// EventUnregister removes the listener the handle refers to.
+// Returns false if no such listener is registered.
+func EventUnregister(handle EventHandle) bool {
+	if eventSystem == nil || !handle.Valid() {
+		return false
+	}
+
+	eventSystem.mutex.Lock()
+	defer eventSystem.mutex.Unlock()
+
+	listeners := eventSystem.subscribers[handle.Code]
+	for i, l := range listeners {
+		if l.id == handle.id {
+			updated := make([]eventListener, 0, len(listeners)-1)
+			updated = append(updated, listeners[:i]...)
+			updated = append(updated, listeners[i+1:]...)
+			eventSystem.subscribers[handle.Code] = updated
+			return true
+		}
+	}
+	return false
 }
 
-// Process events in a separate goroutine or in the game loop
-func ProcessEvents() {
-	for event := range eventSystem.eventChan {
-		if callbacks, ok := eventSystem.subscribers[event.Type]; ok {
-			for _, callback := range callbacks {
-				callback(event)
-			}
+// EventFire dispatches the event immediately, on the calling goroutine.
+// Returns true if one of the listeners handled it.
+func EventFire(context EventContext) bool {
+	if eventSystem == nil {
+		return false
+	}
+
+	eventSystem.mutex.RLock()
+	listeners := eventSystem.subscribers[context.Type]
+	eventSystem.mutex.RUnlock()
+
+	for _, l := range listeners {
+		if l.callback(context) {
+			return true
 		}
 	}
+	return false
+}
+
+// EventPost queues the event to be dispatched at the next EventDispatchQueued,
+// which the engine calls once per frame on the main thread. Safe to call from any goroutine.
+func EventPost(context EventContext) {
+	if eventSystem == nil {
+		return
+	}
+	eventSystem.bus.post(func() {
+		EventFire(context)
+	})
+}
+
+// EventDispatchQueued dispatches the posted events in the order they were posted,
+// including the ones posted on the typed bus. Events posted while dispatching are
+// delivered on the next call.
+func EventDispatchQueued() {
+	if eventSystem == nil {
+		return
+	}
+	eventSystem.bus.DispatchQueued()
 }
 
 func EventSystemShutdown() error {
-	close(eventSystem.eventChan)
 	eventSystem = nil
 	return nil
 }
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testBusEvent struct {
+	name string
+}
+
+// Code-based and typed events posted in turns are dispatched in the order they were posted.
+func TestEventDispatchQueuedOrder(t *testing.T) {
+	if err := EventSystemInitialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer EventSystemShutdown()
+
+	var got []string
+	EventRegister(EVENT_CODE_APPLICATION_QUIT, func(context EventContext) bool {
+		got = append(got, context.Data.(string))
+		return false
+	})
+	Subscribe(Events(), func(event testBusEvent) {
+		got = append(got, event.name)
+		// Posted while dispatching, so delivered on the next call.
+		if event.name == "bus 1" {
+			EventPost(EventContext{Type: EVENT_CODE_APPLICATION_QUIT, Data: "code 3"})
+		}
+	})
+
+	EventPost(EventContext{Type: EVENT_CODE_APPLICATION_QUIT, Data: "code 1"})
+	Post(Events(), testBusEvent{name: "bus 1"})
+	EventPost(EventContext{Type: EVENT_CODE_APPLICATION_QUIT, Data: "code 2"})
+	Post(Events(), testBusEvent{name: "bus 2"})
+
+	EventDispatchQueued()
+	if want := []string{"code 1", "bus 1", "code 2", "bus 2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	EventDispatchQueued()
+	if want := []string{"code 1", "bus 1", "code 2", "bus 2", "code 3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -217,19 +217,10 @@ func InputProcessKey(key KeyCode, pressed bool) error {
 		// Update internal state.
 		inputState.KeyboardCurrent.Keys[key] = pressed
 
-		var code EventCode = 0
-		if pressed {
-			code = EVENT_CODE_KEY_PRESSED
-		} else {
-			code = EVENT_CODE_KEY_RELEASED
-		}
-
 		// Fire off an event for immediate processing.
-		EventFire(EventContext{
-			Type: code,
-			Data: &KeyEvent{
-				KeyCode: key,
-			},
+		Publish(Events(), KeyEvent{
+			KeyCode: key,
+			Pressed: pressed,
 		})
 	}
 	return nil
@@ -284,17 +275,9 @@ func InputProcessButton(button Button, pressed bool) error {
 		inputState.MouseCurrent.Buttons[button] = pressed
 
 		// Fire the event.
-		var code EventCode = 0
-		if pressed {
-			code = EVENT_CODE_BUTTON_PRESSED
-		} else {
-			code = EVENT_CODE_BUTTON_RELEASED
-		}
-		EventFire(EventContext{
-			Type: code,
-			Data: &MouseEvent{
-				Button: button,
-			},
+		Publish(Events(), MouseButtonEvent{
+			Button:  button,
+			Pressed: pressed,
 		})
 	}
 	return nil
@@ -311,12 +294,9 @@ func InputProcessMouseMove(x uint16, y uint16) error {
 		inputState.MouseCurrent.Y = y
 
 		// Fire the event.
-		EventFire(EventContext{
-			Type: EVENT_CODE_MOUSE_MOVED,
-			Data: &MouseEvent{
-				PosX: x,
-				PosY: y,
-			},
+		Publish(Events(), MouseMovedEvent{
+			PosX: x,
+			PosY: y,
 		})
 	}
 	return nil
@@ -324,11 +304,8 @@ func InputProcessMouseMove(x uint16, y uint16) error {
 
 func InputProcessMouseWheel(zDelta int8) error {
 	// Fire the event.
-	EventFire(EventContext{
-		Type: EVENT_CODE_MOUSE_WHEEL,
-		Data: &MouseEvent{
-			Scroll: zDelta,
-		},
+	Publish(Events(), MouseWheelEvent{
+		ZDelta: zDelta,
 	})
 	return nil
 }
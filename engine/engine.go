@@ -36,13 +36,14 @@ type Engine struct {
 	gameInstance  *Game
 	isRunning     bool
 	isSuspended   bool
-	platform      *platform.Platform
+	platform      platform.Platform
 	assetManager  *assets.AssetManager
 	systemManager *systems.SystemManager
 	width         uint32
 	height        uint32
 	clock         *core.Clock
 	lastTime      float64
+	stepper       *fixedStepper
 }
 
 func init() {
@@ -53,14 +54,17 @@ func New(g *Game) (*Engine, error) {
 	// initialize the logger immediately
 	core.InitializeLogger(g.ApplicationConfig.LogLevel)
 
-	p := platform.New()
+	p := g.ApplicationConfig.Platform
+	if p == nil {
+		return nil, fmt.Errorf("no platform configured, set ApplicationConfig.Platform (e.g. window.NewGLFWPlatform() or platform.NewHeadlessPlatform())")
+	}
 
 	am, err := assets.NewAssetManager()
 	if err != nil {
 		return nil, err
 	}
 
-	sm, err := systems.NewSystemManager(g.ApplicationConfig.Name, g.ApplicationConfig.StartWidth, g.ApplicationConfig.StartHeight, p, am)
+	sm, err := systems.NewSystemManager(g.ApplicationConfig.Name, g.ApplicationConfig.StartWidth, g.ApplicationConfig.StartHeight, g.ApplicationConfig.RendererBackendType, p, am)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +78,7 @@ func New(g *Game) (*Engine, error) {
 	return &Engine{
 		currentStage:  EngineStageUninitialized,
 		gameInstance:  g,
-		clock:         core.NewClock(),
+		clock:         core.NewClockWithSource(p.GetAbsoluteTime),
 		platform:      p,
 		assetManager:  am,
 		systemManager: sm,
@@ -83,6 +87,7 @@ func New(g *Game) (*Engine, error) {
 		width:         g.ApplicationConfig.StartWidth,
 		height:        g.ApplicationConfig.StartHeight,
 		lastTime:      0,
+		stepper:       newFixedStepper(g.ApplicationConfig.FixedTimestep, g.ApplicationConfig.MaxStepsPerFrame),
 	}, nil
 }
 
@@ -104,9 +109,8 @@ func (e *Engine) Initialize() error {
 
 	// register some events
 	core.EventRegister(core.EVENT_CODE_APPLICATION_QUIT, e.onEvent)
-	core.EventRegister(core.EVENT_CODE_KEY_PRESSED, e.onKey)
-	core.EventRegister(core.EVENT_CODE_KEY_RELEASED, e.onKey)
-	core.EventRegister(core.EVENT_CODE_RESIZED, e.onResized)
+	core.SubscribeWithPriority(core.Events(), core.EVENT_PRIORITY_DEFAULT, e.onKey)
+	core.Subscribe(core.Events(), e.onResized)
 
 	if err := e.platform.Startup(e.gameInstance.ApplicationConfig.Name,
 		e.gameInstance.ApplicationConfig.StartPosX,
@@ -121,9 +125,13 @@ func (e *Engine) Initialize() error {
 	if err != nil {
 		return err
 	}
-	if err := e.assetManager.Initialize(fmt.Sprintf("%s/assets", wd)); err != nil {
+	assetsDir := fmt.Sprintf("%s/assets", wd)
+	if err := e.assetManager.Initialize(assetsDir, e.gameInstance.ApplicationConfig.AssetMounts...); err != nil {
 		return err
 	}
+	if budget := e.gameInstance.ApplicationConfig.AssetCacheBudget; budget > 0 {
+		e.assetManager.SetCacheBudget(budget)
+	}
 
 	// initialize all the managers (including the rendering system)
 	if err := e.systemManager.Initialize(); err != nil {
@@ -160,12 +168,10 @@ func (e *Engine) Run() error {
 
 	e.lastTime = e.clock.Elapsed()
 
-	// start goroutine to process all the events around the engine
-	go core.ProcessEvents()
-
-	// var runningTime float64 = 0.0
-	var frameCount uint8 = 0
-	var targetFrameSeconds float64 = 1.0 / 60.0
+	var targetFrameSeconds float64 = 0
+	if e.gameInstance.ApplicationConfig.TargetFPS > 0 {
+		targetFrameSeconds = 1.0 / float64(e.gameInstance.ApplicationConfig.TargetFPS)
+	}
 	var frameElapsedTime float64 = 0
 
 	for e.isRunning {
@@ -173,16 +179,39 @@ func (e *Engine) Run() error {
 			e.isRunning = false
 		}
 
+		// Deliver the events posted since the last frame, on the main thread.
+		core.EventDispatchQueued()
+		// Same for the callbacks of the jobs finished since the last frame.
+		e.systemManager.JobSystem.Update()
+
 		if !e.isSuspended {
 			// Update clock and get delta time.
 			e.clock.Update()
 
 			var currentTime float64 = e.clock.Elapsed()
 			var delta float64 = (currentTime - e.lastTime)
-			var frameStartTime float64 = platform.GetAbsoluteTime()
+			var frameStartTime float64 = e.platform.GetAbsoluteTime()
 
 			core.MetricsUpdate(frameElapsedTime)
 
+			alpha := 1.0
+			if e.gameInstance.ApplicationConfig.LoopMode == LoopModeFixed {
+				steps := e.stepper.advance(delta)
+				if e.gameInstance.FnFixedUpdate != nil {
+					for i := uint32(0); i < steps; i++ {
+						if err := e.gameInstance.FnFixedUpdate(e.stepper.step); err != nil {
+							core.LogFatal("Game fixed update failed, shutting down.")
+							e.isRunning = false
+							break
+						}
+					}
+					if !e.isRunning {
+						break
+					}
+				}
+				alpha = e.stepper.alpha()
+			}
+
 			if err := e.gameInstance.FnUpdate(delta); err != nil {
 				core.LogFatal("Game update failed, shutting down.")
 				e.isRunning = false
@@ -195,7 +224,7 @@ func (e *Engine) Run() error {
 			}
 
 			// Call the game's render routine.
-			if err := e.gameInstance.FnRender(packet, delta); err != nil {
+			if err := e.gameInstance.FnRender(packet, delta, alpha); err != nil {
 				core.LogFatal("Game render failed, shutting down.")
 				e.isRunning = false
 				break
@@ -215,20 +244,14 @@ func (e *Engine) Run() error {
 				}
 			}
 
-			// Figure out how long the frame took and, if below
-			var frameEndTime float64 = platform.GetAbsoluteTime()
+			// Figure out how long the frame took and, if below the target, give the rest back to the OS.
+			var frameEndTime float64 = e.platform.GetAbsoluteTime()
 			frameElapsedTime = frameEndTime - frameStartTime
-			// runningTime += frameElapsedTime
-			var remainingSeconds float64 = targetFrameSeconds - frameElapsedTime
-
-			if remainingSeconds > 0 {
-				remainingMS := (remainingSeconds * 1000)
-				// If there is time left, give it back to the OS.
-				limitFrames := false
-				if remainingMS > 0 && limitFrames {
-					e.platform.Sleep(remainingMS - 1)
+			if targetFrameSeconds > 0 {
+				var remainingSeconds float64 = targetFrameSeconds - frameElapsedTime
+				if remainingSeconds > 0 {
+					e.platform.Sleep(remainingSeconds * 1000)
 				}
-				frameCount++
 			}
 
 			// NOTE: Input update/state copying should always be handled
@@ -252,6 +275,9 @@ func (e *Engine) Shutdown() error {
 	if err := core.InputShutdown(); err != nil {
 		return err
 	}
+	if err := e.assetManager.Shutdown(); err != nil {
+		return err
+	}
 	if err := e.systemManager.Shutdown(); err != nil {
 		return err
 	}
@@ -261,32 +287,33 @@ func (e *Engine) Shutdown() error {
 	return nil
 }
 
+// Platform returns the platform the engine runs on.
+func (e *Engine) Platform() platform.Platform {
+	return e.platform
+}
+
 // ApplicationGetFramebufferSize returns the width and height (in this order)
 // of the application Framebuffer
 func (e *Engine) GetFramebufferSize() (uint32, uint32) {
 	return e.width, e.height
 }
 
-func (e *Engine) onEvent(context core.EventContext) {
+func (e *Engine) onEvent(context core.EventContext) bool {
 	switch context.Type {
 	case core.EVENT_CODE_APPLICATION_QUIT:
 		{
 			core.LogInfo("EVENT_CODE_APPLICATION_QUIT recieved, shutting down.\n")
 			e.isRunning = false
+			return true
 		}
 	}
+	return false
 }
 
-func (e *Engine) onKey(context core.EventContext) {
-	ke, ok := context.Data.(*core.KeyEvent)
-	if !ok {
-		core.LogError("wrong event associated with the event type `%d`", context.Type)
-		return
-	}
-
-	keyCode := ke.KeyCode
+func (e *Engine) onKey(event core.KeyEvent) bool {
+	keyCode := event.KeyCode
 
-	if context.Type == core.EVENT_CODE_KEY_PRESSED {
+	if event.Pressed {
 		if keyCode == core.KEY_ESCAPE {
 			// NOTE: Technically firing an event to itself, but there may be other listeners.
 			data := core.EventContext{
@@ -294,14 +321,14 @@ func (e *Engine) onKey(context core.EventContext) {
 			}
 			core.EventFire(data)
 			// Block anything else from processing this.
-			return
+			return true
 		} else if keyCode == core.KEY_A {
 			// Example on checking for a key
 			core.LogInfo("Explicit - A key pressed!")
 		} else {
 			core.LogInfo("'%c' key pressed in window.", keyCode)
 		}
-	} else if context.Type == core.EVENT_CODE_KEY_RELEASED {
+	} else {
 		if keyCode == core.KEY_B {
 			// Example on checking for a key
 			core.LogInfo("Explicit - B key released!")
@@ -309,41 +336,33 @@ func (e *Engine) onKey(context core.EventContext) {
 			core.LogInfo("'%c' key released in window.", keyCode)
 		}
 	}
+	return false
 }
 
-func (e *Engine) onResized(context core.EventContext) {
-	if context.Type == core.EVENT_CODE_RESIZED {
-		se, ok := context.Data.(*core.SystemEvent)
-		if !ok {
-			core.LogError("wrong event associated with the event type `%d`", context.Type)
+func (e *Engine) onResized(event core.WindowResizedEvent) {
+	width := event.Width
+	height := event.Height
+
+	// Check if different. If so, trigger a resize event.
+	if width != e.width || height != e.height {
+		e.width = width
+		e.height = height
+
+		core.LogDebug("Window resize: %d, %d", width, height)
+
+		// Handle minimization
+		if width == 0 || height == 0 {
+			core.LogInfo("Window minimized, suspending application.")
+			e.isSuspended = true
 			return
 		}
-
-		width := se.WindowWidth
-		height := se.WindowHeight
-
-		// Check if different. If so, trigger a resize event.
-		if width != e.width || height != e.height {
-			e.width = width
-			e.height = height
-
-			core.LogDebug("Window resize: %d, %d", width, height)
-
-			// Handle minimization
-			if width == 0 || height == 0 {
-				core.LogInfo("Window minimized, suspending application.")
-				e.isSuspended = true
-				return
-			} else {
-				if e.isSuspended {
-					core.LogInfo("Window restored, resuming application.")
-					e.isSuspended = false
-				}
-				e.gameInstance.FnOnResize(uint32(width), uint32(height))
-				if err := e.systemManager.OnResize(uint16(width), uint16(height)); err != nil {
-					core.LogError(err.Error())
-				}
-			}
+		if e.isSuspended {
+			core.LogInfo("Window restored, resuming application.")
+			e.isSuspended = false
+		}
+		e.gameInstance.FnOnResize(uint32(width), uint32(height))
+		if err := e.systemManager.OnResize(uint16(width), uint16(height)); err != nil {
+			core.LogError(err.Error())
 		}
 	}
 }
@@ -12,6 +12,7 @@ type Game struct {
 	FnBoot            Boot
 	FnInitialize      Initialize
 	FnUpdate          Update
+	FnFixedUpdate     FixedUpdate
 	FnRender          Render
 	FnOnResize        OnResize
 	FnShutdown        Shutdown
@@ -20,6 +21,10 @@ type Game struct {
 type Boot func() error
 type Initialize func() error
 type Update func(deltaTime float64) error
-type Render func(packer *metadata.RenderPacket, deltaTime float64) error
+type FixedUpdate func(fixedDeltaTime float64) error
+
+// Render receives the interpolation alpha between the last two fixed updates,
+// which is always 1 when not running with LoopModeFixed.
+type Render func(packer *metadata.RenderPacket, deltaTime float64, alpha float64) error
 type OnResize func(width uint32, height uint32) error
 type Shutdown func() error
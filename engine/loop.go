@@ -0,0 +1,69 @@
+package engine
+
+import "math"
+
+/** @brief Determines how the engine steps the game simulation. */
+type LoopMode uint8
+
+const (
+	// FnUpdate is called once per frame with the variable frame delta. This is the default.
+	LoopModeVariable LoopMode = iota
+	// On top of the per-frame FnUpdate, FnFixedUpdate is called with a constant
+	// delta as many times as needed to catch up with the elapsed time.
+	LoopModeFixed
+)
+
+const (
+	// The fixed timestep used when none is configured: 60 updates per second.
+	DEFAULT_FIXED_TIMESTEP float64 = 1.0 / 60.0
+	// The maximum number of fixed updates per frame used when none is configured.
+	DEFAULT_MAX_STEPS_PER_FRAME uint32 = 5
+)
+
+/**
+ * @brief Accumulates frame time and splits it in fixed steps.
+ * The time left over after the steps is reported as an interpolation
+ * alpha in the [0, 1) range, to blend between the last two simulated states.
+ */
+type fixedStepper struct {
+	step        float64
+	maxSteps    uint32
+	accumulator float64
+}
+
+func newFixedStepper(step float64, maxSteps uint32) *fixedStepper {
+	if step <= 0 {
+		step = DEFAULT_FIXED_TIMESTEP
+	}
+	if maxSteps == 0 {
+		maxSteps = DEFAULT_MAX_STEPS_PER_FRAME
+	}
+	return &fixedStepper{
+		step:     step,
+		maxSteps: maxSteps,
+	}
+}
+
+// advance adds the frame delta to the accumulator and returns the number of fixed steps
+// to simulate this frame. When more than maxSteps would be needed, the excess time is
+// dropped so a slow frame cannot make the following ones even slower.
+func (s *fixedStepper) advance(delta float64) uint32 {
+	if delta > 0 {
+		s.accumulator += delta
+	}
+	steps := uint32(s.accumulator / s.step)
+	if steps > s.maxSteps {
+		steps = s.maxSteps
+		s.accumulator = math.Mod(s.accumulator, s.step) + float64(steps)*s.step
+	}
+	s.accumulator -= float64(steps) * s.step
+	if s.accumulator < 0 {
+		s.accumulator = 0
+	}
+	return steps
+}
+
+// alpha returns how far the current time is between the last fixed step and the next one.
+func (s *fixedStepper) alpha() float64 {
+	return s.accumulator / s.step
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/platform"
+)
+
+func TestFixedStepper(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     float64
+		maxSteps uint32
+		// The time each frame takes on the virtual clock.
+		frames []float64
+		steps  []uint32
+		// The accumulator left after the last frame.
+		leftover float64
+	}{
+		{"one step per frame", 0.25, 5, []float64{0.25, 0.25, 0.25}, []uint32{1, 1, 1}, 0},
+		{"several steps in a frame", 0.25, 5, []float64{0.75, 0.5}, []uint32{3, 2}, 0},
+		{"short frames carry over", 0.25, 5, []float64{0.125, 0.125, 0.0625, 0.25}, []uint32{0, 1, 0, 1}, 0.0625},
+		{"leftover adds to the next frame", 0.25, 5, []float64{0.375, 0.375}, []uint32{1, 2}, 0},
+		{"clamped to the maximum", 0.25, 4, []float64{2.125}, []uint32{4}, 0.125},
+		// The dropped time does not make the next frames catch up.
+		{"recovers after a clamp", 0.25, 2, []float64{5, 0.25, 0.25}, []uint32{2, 1, 1}, 0},
+		{"defaults", 0, 0, []float64{10}, []uint32{DEFAULT_MAX_STEPS_PER_FRAME}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := platform.NewHeadlessPlatform()
+			stepper := newFixedStepper(test.step, test.maxSteps)
+			steps := []uint32{}
+			last := p.GetAbsoluteTime()
+			for _, frame := range test.frames {
+				p.Advance(frame)
+				now := p.GetAbsoluteTime()
+				steps = append(steps, stepper.advance(now-last))
+				last = now
+				if alpha := stepper.alpha(); alpha < 0 || alpha >= 1 {
+					t.Errorf("got alpha %f, want it in [0, 1)", alpha)
+				}
+			}
+			if !slices.Equal(steps, test.steps) {
+				t.Errorf("got steps %v, want %v", steps, test.steps)
+			}
+			if math.Abs(stepper.accumulator-test.leftover) > 1e-9 {
+				t.Errorf("got %f left over, want %f", stepper.accumulator, test.leftover)
+			}
+		})
+	}
+}
+
+// At 60 updates per second, the steps of frames at other rates add up to the elapsed time.
+func TestFixedStepperRates(t *testing.T) {
+	for _, frameTime := range []float64{1.0 / 30.0, 1.0 / 60.0, 1.0 / 144.0, 1.0 / 45.0} {
+		p := platform.NewHeadlessPlatform()
+		p.FrameTime = frameTime
+		stepper := newFixedStepper(DEFAULT_FIXED_TIMESTEP, DEFAULT_MAX_STEPS_PER_FRAME)
+		total := uint32(0)
+		last := p.GetAbsoluteTime()
+		for i := 0; i < 600; i++ {
+			p.PumpMessages()
+			now := p.GetAbsoluteTime()
+			total += stepper.advance(now - last)
+			last = now
+			if alpha := stepper.alpha(); alpha < 0 || alpha >= 1 {
+				t.Fatalf("frame time %f: got alpha %f at frame %d, want it in [0, 1)", frameTime, alpha, i)
+			}
+		}
+		// Rounding may leave the last step in the accumulator.
+		if want := last / DEFAULT_FIXED_TIMESTEP; float64(total) < want-1 || float64(total) > want {
+			t.Errorf("frame time %f: got %d steps in %fs, want %f", frameTime, total, last, want)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package math
 
 import (
 	m "math"
+	"time"
 
-	"github.com/spaghettifunk/anima/engine/platform"
 	"golang.org/x/exp/rand"
 )
 
@@ -74,7 +74,7 @@ func kabs(x float32) float32 {
 
 func krandom() int32 {
 	if !rand_seeded {
-		rand.Seed(uint64(platform.GetAbsoluteTime()))
+		rand.Seed(uint64(time.Now().UnixNano()))
 		rand_seeded = true
 	}
 	return rand.Int31()
@@ -82,7 +82,7 @@ func krandom() int32 {
 
 func krandom_in_range(min, max int32) int32 {
 	if !rand_seeded {
-		rand.Seed(uint64(platform.GetAbsoluteTime()))
+		rand.Seed(uint64(time.Now().UnixNano()))
 		rand_seeded = true
 	}
 	return (rand.Int31() % (max - min + 1)) + min
@@ -964,7 +964,7 @@ func (mt Mat4) Inverse() Mat4 {
 	t23 := m[4] * m[1]
 
 	out_matrix := Mat4{}
-	o := out_matrix.Data
+	o := &out_matrix.Data
 
 	o[0] = (t0*m[5] + t3*m[9] + t4*m[13]) - (t1*m[5] + t2*m[9] + t5*m[13])
 	o[1] = (t1*m[1] + t6*m[9] + t9*m[13]) - (t0*m[1] + t7*m[9] + t8*m[13])
@@ -1334,7 +1334,7 @@ func (q Quaternion) ToMat4() Mat4 {
 func (q Quaternion) ToRotationMatrix(center Vec3) Mat4 {
 	out_matrix := Mat4{}
 
-	o := out_matrix.Data
+	o := &out_matrix.Data
 	o[0] = (q.X * q.X) - (q.Y * q.Y) - (q.Z * q.Z) + (q.W * q.W)
 	o[1] = 2. * ((q.X * q.Y) + (q.Z * q.W))
 	o[2] = 2. * ((q.X * q.Z) - (q.Y * q.W))
@@ -1508,7 +1508,7 @@ func UInt32ToRGB(rgbu uint32) (uint32, uint32, uint32) {
  * @param out_v A pointer to hold the vector of floating-point values.
  */
 func RGBUInt32ToVec3(r, g, b uint32) Vec3 {
-	return NewVec3(float32(r/255.0), float32(g/255.0), float32(b/255.0))
+	return NewVec3(float32(r)/255.0, float32(g)/255.0, float32(b)/255.0)
 }
 
 /**
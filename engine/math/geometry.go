@@ -21,7 +21,14 @@ func GeometryGenerateNormals(vertexCount uint32, vertices []Vertex3D, indexCount
 	}
 }
 
+// GeometryGenerateTangents generates the tangent of every vertex from the texture coordinates of
+// the triangles it belongs to. The tangents of the triangles sharing a vertex are averaged and made
+// orthogonal to its normal (Gram-Schmidt), so the normals must be set first. The handedness is
+// stored in the direction of the tangent. Triangles with collapsed texture coordinates have no
+// tangent and are skipped; a vertex left without one gets any tangent orthogonal to its normal.
 func GeometryGenerateTangents(vertexCount uint32, vertices []Vertex3D, indexCount uint32, indices []uint32) []Vertex3D {
+	tangents := make([]Vec3, vertexCount)
+	bitangents := make([]Vec3, vertexCount)
 	for i := uint32(0); i < indexCount; i += 3 {
 		i0 := indices[i+0]
 		i1 := indices[i+1]
@@ -37,33 +44,63 @@ func GeometryGenerateTangents(vertexCount uint32, vertices []Vertex3D, indexCoun
 		deltaV2 := vertices[i2].Texcoord.Y - vertices[i0].Texcoord.Y
 
 		dividend := (deltaU1*deltaV2 - deltaU2*deltaV1)
+		if kabs(dividend) < K_FLOAT_EPSILON {
+			continue
+		}
 		fc := 1.0 / dividend
 
 		tangent := Vec3{
 			(fc * (deltaV2*edge1.X - deltaV1*edge2.X)),
 			(fc * (deltaV2*edge1.Y - deltaV1*edge2.Y)),
 			(fc * (deltaV2*edge1.Z - deltaV1*edge2.Z))}
-
+		bitangent := Vec3{
+			(fc * (deltaU1*edge2.X - deltaU2*edge1.X)),
+			(fc * (deltaU1*edge2.Y - deltaU2*edge1.Y)),
+			(fc * (deltaU1*edge2.Z - deltaU2*edge1.Z))}
+		if tangent.Length() < K_FLOAT_EPSILON || bitangent.Length() < K_FLOAT_EPSILON {
+			continue
+		}
 		tangent = tangent.Normalized()
+		bitangent = bitangent.Normalized()
 
-		sx := deltaU1
-		sy := deltaU2
-		tx := deltaV1
-		ty := deltaV2
+		for _, index := range []uint32{i0, i1, i2} {
+			tangents[index] = tangents[index].Add(tangent)
+			bitangents[index] = bitangents[index].Add(bitangent)
+		}
+	}
 
-		handedness := 1.0
-		if (tx*sy - ty*sx) < 0.0 {
-			handedness = -1.0
+	for v := uint32(0); v < vertexCount; v++ {
+		normal := vertices[v].Normal
+		// Gram-Schmidt: remove the part of the tangent along the normal.
+		tangent := tangents[v].Sub(normal.MulScalar(normal.Dot(tangents[v])))
+		if tangent.Length() < K_FLOAT_EPSILON {
+			tangent = orthogonalTo(normal)
 		}
+		tangent = tangent.Normalized()
 
-		t4 := tangent.MulScalar(float32(handedness))
-		vertices[i0].Tangent = t4
-		vertices[i1].Tangent = t4
-		vertices[i2].Tangent = t4
+		// NOTE: negative when the bitangent agrees with cross(normal, tangent), the convention the
+		// shaders rebuild the bitangent with.
+		handedness := float32(1.0)
+		if normal.Cross(tangent).Dot(bitangents[v]) >= 0.0 {
+			handedness = -1.0
+		}
+		vertices[v].Tangent = tangent.MulScalar(handedness)
 	}
 	return vertices
 }
 
+// orthogonalTo returns a vector orthogonal to the given one, along the axis it is least aligned with.
+func orthogonalTo(v Vec3) Vec3 {
+	if v.Length() < K_FLOAT_EPSILON {
+		return NewVec3(1.0, 0.0, 0.0)
+	}
+	axis := NewVec3(1.0, 0.0, 0.0)
+	if kabs(v.X) > kabs(v.Y) && kabs(v.X) > kabs(v.Z) {
+		axis = NewVec3(0.0, 1.0, 0.0)
+	}
+	return v.Cross(axis)
+}
+
 func Vertex3dEqual(vert0 Vertex3D, vert1 Vertex3D) bool {
 	return vert0.Position.Compare(vert1.Position, K_FLOAT_EPSILON) &&
 		vert0.Normal.Compare(vert1.Normal, K_FLOAT_EPSILON) &&
@@ -72,49 +109,29 @@ func Vertex3dEqual(vert0 Vertex3D, vert1 Vertex3D) bool {
 		vert0.Tangent.Compare(vert1.Tangent, K_FLOAT_EPSILON)
 }
 
-func reassignIndex(indexCount uint32, indices []uint32, from uint32, to uint32) {
-	for i := uint32(0); i < indexCount; i++ {
-		if indices[i] == from {
-			indices[i] = to
-		} else if indices[i] > from {
-			// Pull in all indicies higher than 'from' by 1.
-			indices[i]--
-		}
-	}
-}
-
-// Go version of the geometry_deduplicate_vertices function
+// GeometryDeduplicateVertices removes the duplicated vertices and points the indices to the
+// remaining ones. Vertices are hashed, so importing large meshes stays linear.
 func GeometryDeduplicateVertices(vertexCount uint32, vertices []Vertex3D, indexCount uint32, indices []uint32) (uint32, []Vertex3D) {
-	// Create a new slice for unique vertices
-	uniqueVerts := make([]Vertex3D, vertexCount)
-	outVertexCount := uint32(0)
-
-	foundCount := uint32(0)
+	unique := make(map[Vertex3D]uint32, vertexCount)
+	remap := make([]uint32, vertexCount)
+	outVertices := make([]Vertex3D, 0, vertexCount)
 
 	for v := uint32(0); v < vertexCount; v++ {
-		found := false
-		for u := uint32(0); u < outVertexCount; u++ {
-			if vertices[v] == uniqueVerts[u] {
-				// Reassign indices, do not copy
-				reassignIndex(indexCount, indices, v-foundCount, u)
-				found = true
-				foundCount++
-				break
-			}
-		}
-
-		if !found {
-			// Copy over to unique
-			uniqueVerts[outVertexCount] = vertices[v]
-			outVertexCount++
+		if u, found := unique[vertices[v]]; found {
+			remap[v] = u
+			continue
 		}
+		u := uint32(len(outVertices))
+		unique[vertices[v]] = u
+		remap[v] = u
+		outVertices = append(outVertices, vertices[v])
 	}
 
-	// Allocate new vertices array for the final result
-	// outVertices := make([]Vertex3D, outVertexCount)
-	// Copy over unique vertices
-	outVertices := uniqueVerts[:outVertexCount]
+	for i := uint32(0); i < indexCount; i++ {
+		indices[i] = remap[indices[i]]
+	}
 
+	outVertexCount := uint32(len(outVertices))
 	removedCount := vertexCount - outVertexCount
 	core.LogDebug("geometry_deduplicate_vertices: removed %d vertices, orig/now %d/%d.\n", removedCount, vertexCount, outVertexCount)
 
@@ -0,0 +1,182 @@
+package platform
+
+import (
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/core"
+)
+
+/**
+ * @brief A platform without a window. Time is virtual and only moves
+ * forward when told to (or when sleeping), and input is injected by
+ * the caller. Injected events are queued and delivered on the next
+ * PumpMessages, the same way a windowing system would deliver them.
+ */
+type HeadlessPlatform struct {
+	mutex sync.Mutex
+
+	width  uint32
+	height uint32
+
+	// The virtual clock, in seconds.
+	time float64
+	// The amount of seconds the clock moves forward on every PumpMessages. 0 means the clock is only moved explicitly.
+	FrameTime float64
+
+	pending       []func()
+	quitRequested bool
+}
+
+var _ Platform = (*HeadlessPlatform)(nil)
+
+func NewHeadlessPlatform() *HeadlessPlatform {
+	return &HeadlessPlatform{
+		pending: []func(){},
+	}
+}
+
+func (p *HeadlessPlatform) Startup(applicationName string, x uint32, y uint32, width uint32, height uint32) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.width = width
+	p.height = height
+	p.time = 0
+	p.quitRequested = false
+
+	core.LogInfo("Headless platform started for '%s' (%dx%d).", applicationName, width, height)
+	return nil
+}
+
+func (p *HeadlessPlatform) Shutdown() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pending = []func(){}
+	return nil
+}
+
+func (p *HeadlessPlatform) PumpMessages() bool {
+	p.mutex.Lock()
+	pending := p.pending
+	p.pending = []func(){}
+	p.time += p.FrameTime
+	quit := p.quitRequested
+	p.mutex.Unlock()
+
+	// Deliver outside of the lock, as handlers may inject new events.
+	for _, deliver := range pending {
+		deliver()
+	}
+	return !quit
+}
+
+func (p *HeadlessPlatform) GetAbsoluteTime() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.time
+}
+
+// Sleep moves the virtual clock forward instead of blocking. Timeout is in milliseconds.
+func (p *HeadlessPlatform) Sleep(timeout float64) {
+	p.Advance(timeout / 1000.0)
+}
+
+func (p *HeadlessPlatform) GetFramebufferSize() (uint32, uint32) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.width, p.height
+}
+
+// Advance moves the virtual clock forward by the given amount of seconds.
+func (p *HeadlessPlatform) Advance(seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.time += seconds
+}
+
+// SetTime sets the virtual clock to the given amount of seconds.
+func (p *HeadlessPlatform) SetTime(seconds float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.time = seconds
+}
+
+// RequestClose makes the next PumpMessages report that the application should close,
+// as if the window had been closed.
+func (p *HeadlessPlatform) RequestClose() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.quitRequested = true
+}
+
+// InjectKey queues a key press or release.
+func (p *HeadlessPlatform) InjectKey(key core.KeyCode, pressed bool) {
+	p.enqueue(func() {
+		if err := core.InputProcessKey(key, pressed); err != nil {
+			core.LogError("input process key returned an err: %s", err)
+		}
+	})
+}
+
+// InjectButton queues a mouse button press or release.
+func (p *HeadlessPlatform) InjectButton(button core.Button, pressed bool) {
+	p.enqueue(func() {
+		if err := core.InputProcessButton(button, pressed); err != nil {
+			core.LogError("input process button returned an err: %s", err)
+		}
+	})
+}
+
+// InjectMouseMove queues a move of the mouse to the given position in window coordinates.
+func (p *HeadlessPlatform) InjectMouseMove(x, y uint16) {
+	p.enqueue(func() {
+		if err := core.InputProcessMouseMove(x, y); err != nil {
+			core.LogError("input process mouse move returned an err: %s", err)
+		}
+	})
+}
+
+// InjectScroll queues a vertical scroll. Like on the other platforms, the delta is flattened to -1/+1.
+func (p *HeadlessPlatform) InjectScroll(zDelta int8) {
+	if zDelta < 0 {
+		zDelta = -1
+	} else if zDelta > 0 {
+		zDelta = 1
+	}
+	p.enqueue(func() {
+		if err := core.InputProcessMouseWheel(zDelta); err != nil {
+			core.LogError("input process mouse wheel returned an err: %s", err)
+		}
+	})
+}
+
+// InjectResize queues a resize of the virtual framebuffer.
+func (p *HeadlessPlatform) InjectResize(width, height uint32) {
+	p.enqueue(func() {
+		p.mutex.Lock()
+		p.width = width
+		p.height = height
+		p.mutex.Unlock()
+
+		core.Publish(core.Events(), core.WindowResizedEvent{
+			Width:  width,
+			Height: height,
+		})
+	})
+}
+
+func (p *HeadlessPlatform) enqueue(deliver func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pending = append(p.pending, deliver)
+}
@@ -0,0 +1,40 @@
+package metadata
+
+import "strings"
+
+/**
+ * @brief The sidecar of an asset, stored next to it as <file>.meta. It identifies the asset
+ * independently of its file name, and holds the settings it is imported with.
+ */
+type AssetMeta struct {
+	/** @brief The unique identifier of the asset, kept when the file is renamed or moved. */
+	GUID string `toml:"guid"`
+	/** @brief The import settings of images. */
+	Image *ImageImportSettings `toml:"image,omitempty"`
+	/** @brief The import settings of models. */
+	Model *ModelImportSettings `toml:"model,omitempty"`
+}
+
+/** @brief The settings images are imported with. */
+type ImageImportSettings struct {
+	/** @brief Overrides whether the image is flipped on the y-axis when loaded. */
+	FlipY *bool `toml:"flip_y,omitempty"`
+	/** @brief The filtering of the textures using the image: linear (default) or nearest. */
+	Filter string `toml:"filter,omitempty"`
+}
+
+/** @brief The settings models are imported with. */
+type ModelImportSettings struct {
+	/** @brief The uniform scale applied to the model, e.g. 0.01 for models authored in centimeters. 0 means 1. */
+	Scale float32 `toml:"scale,omitempty"`
+	/** @brief The up axis of the model file: y (default) or z. Z-up models are rotated to Y-up. */
+	UpAxis string `toml:"up_axis,omitempty"`
+}
+
+// TextureFilter returns the filtering mode of the textures using the image.
+func (s *ImageImportSettings) TextureFilter() TextureFilter {
+	if s != nil && strings.EqualFold(s.Filter, "nearest") {
+		return TextureFilterModeNearest
+	}
+	return TextureFilterModeLinear
+}
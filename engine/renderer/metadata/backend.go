@@ -0,0 +1,167 @@
+package metadata
+
+import "fmt"
+
+/** @brief Represents the supported renderer backend types. */
+type RendererBackendType int
+
+const (
+	/** @brief The Vulkan renderer backend. This is the default. */
+	RendererBackendTypeVulkan RendererBackendType = iota
+	/** @brief A backend which does not use the GPU and records every call. Useful for tests and CI. */
+	RendererBackendTypeNull
+	/** @brief A backend which rasterizes on the CPU. Useful to get images on machines without a GPU. */
+	RendererBackendTypeSoftware
+)
+
+func (t RendererBackendType) String() string {
+	switch t {
+	case RendererBackendTypeVulkan:
+		return "vulkan"
+	case RendererBackendTypeNull:
+		return "null"
+	case RendererBackendTypeSoftware:
+		return "software"
+	}
+	return "unknown"
+}
+
+func RendererBackendTypeFromString(s string) (RendererBackendType, error) {
+	if s == "vulkan" {
+		return RendererBackendTypeVulkan, nil
+	}
+	if s == "null" {
+		return RendererBackendTypeNull, nil
+	}
+	if s == "software" {
+		return RendererBackendTypeSoftware, nil
+	}
+	return 0, fmt.Errorf("string %s is not a valid RendererBackendType", s)
+}
+
+/**
+ * @brief The generic interface a renderer backend has to satisfy. The
+ * renderer system only talks to the backend through this interface, so
+ * the actual graphics API (or a mock of it) can be swapped freely.
+ */
+type RendererBackend interface {
+	/**
+	 * @brief Initializes the backend.
+	 *
+	 * @param config A pointer to configuration to be used when initializing the backend.
+	 * @param windowRenderTargetCount A pointer to hold how many render targets are needed for renderpasses targeting the window.
+	 */
+	Initialize(config *RendererBackendConfig, windowRenderTargetCount *uint8) error
+	/** @brief Shuts the backend down and releases all its resources. */
+	Shutdown() error
+
+	/** @brief Handles window resizes. */
+	Resized(width, height uint32) error
+	/** @brief Performs setup routines required at the start of a frame. */
+	BeginFrame(deltaTime float64) error
+	/** @brief Performs routines required to draw a frame, such as presentation. */
+	EndFrame(deltaTime float64) error
+
+	/** @brief Creates a new texture from the given pixel data. */
+	TextureCreate(pixels []uint8, texture *Texture) error
+	/** @brief Destroys the given texture, releasing internal resources from the GPU. */
+	TextureDestroy(texture *Texture) error
+	/** @brief Creates a new writeable texture with no data written to it. */
+	TextureCreateWriteable(texture *Texture) error
+	/** @brief Resizes a texture. Internal data is discarded. */
+	TextureResize(texture *Texture, newWidth, newHeight uint32) error
+	/** @brief Writes the given data to the provided texture. */
+	TextureWriteData(texture *Texture, offset, size uint32, pixels []uint8) error
+	/** @brief Reads the given data from the provided texture. */
+	TextureReadData(texture *Texture, offset, size uint32) (interface{}, error)
+	/** @brief Reads a pixel from the provided texture at the given x/y coordinate. */
+	TextureReadPixel(texture *Texture, x, y uint32) ([]uint8, error)
+
+	/** @brief Acquires internal resources for the given texture map. */
+	TextureMapAcquireResources(textureMap *TextureMap) error
+	/** @brief Releases internal resources for the given texture map. */
+	TextureMapReleaseResources(textureMap *TextureMap) error
+
+	/** @brief Acquires GPU resources and uploads geometry data. */
+	CreateGeometry(geometry *Geometry, vertexSize, vertexCount uint32, vertices interface{}, indexSize uint32, indexCount uint32, indices []uint32) error
+	/** @brief Destroys the given geometry, releasing GPU resources. */
+	DestroyGeometry(geometry *Geometry) error
+	/** @brief Draws the given geometry. */
+	DrawGeometry(data *GeometryRenderData) error
+
+	/** @brief Creates a new renderpass. */
+	RenderPassCreate(config *RenderPassConfig) (*RenderPass, error)
+	/** @brief Destroys the given renderpass. */
+	RenderPassDestroy(pass *RenderPass) error
+	/** @brief Begins a renderpass with the given target. */
+	RenderPassBegin(pass *RenderPass, target *RenderTarget) error
+	/** @brief Ends a renderpass. */
+	RenderPassEnd(pass *RenderPass) error
+
+	/** @brief Creates a new render target using the provided data. */
+	RenderTargetCreate(attachmentCount uint8, attachments []*RenderTargetAttachment, pass *RenderPass, width, height uint32) (*RenderTarget, error)
+	/** @brief Destroys the provided render target. */
+	RenderTargetDestroy(target *RenderTarget, freeInternalMemory bool) error
+
+	/** @brief Creates internal shader resources using the provided parameters. */
+	ShaderCreate(shader *Shader, config *ShaderConfig, pass *RenderPass, stageCount uint8, stageFilenames []string, stages []ShaderStage) error
+	/** @brief Destroys the given shader and releases any resources held by it. */
+	ShaderDestroy(shader *Shader) error
+	/** @brief Initializes a configured shader. */
+	ShaderInitialize(shader *Shader) error
+	/** @brief Uses the given shader, activating it for updates to attributes, uniforms and such. */
+	ShaderUse(shader *Shader) error
+	/** @brief Binds global resources for use and updating. */
+	ShaderBindGlobals(shader *Shader) error
+	/** @brief Binds instance resources for use and updating. */
+	ShaderBindInstance(shader *Shader, instanceID uint32) error
+	/** @brief Applies global data to the uniform buffer. */
+	ShaderApplyGlobals(shader *Shader) error
+	/** @brief Applies data for the currently bound instance. */
+	ShaderApplyInstance(shader *Shader, needsUpdate bool) error
+	/** @brief Acquires internal instance-level resources and provides an instance id. */
+	ShaderAcquireInstanceResources(shader *Shader, maps []*TextureMap) (uint32, error)
+	/** @brief Releases internal instance-level resources for the given instance id. */
+	ShaderReleaseInstanceResources(shader *Shader, instanceID uint32) error
+	/** @brief Sets the uniform of the given shader to the provided value. */
+	SetUniform(shader *Shader, uniform ShaderUniform, value interface{}) error
+
+	/** @brief Creates and assigns the renderer-backend-specific buffer. */
+	RenderBufferCreate(renderbufferType RenderBufferType, totalSize uint64) (*RenderBuffer, error)
+	/** @brief Destroys the given buffer. */
+	RenderBufferDestroy(buffer *RenderBuffer)
+	/** @brief Binds the given buffer at the provided offset. */
+	RenderBufferBind(buffer *RenderBuffer, offset uint64) error
+	/** @brief Unbinds the given buffer. */
+	RenderBufferUnbind(buffer *RenderBuffer) bool
+	/** @brief Maps memory from the given buffer in the provided range to a block of memory and returns it. */
+	RenderBufferMapMemory(buffer *RenderBuffer, offset, size uint64) (interface{}, error)
+	/** @brief Unmaps memory from the given buffer in the provided range. */
+	RenderBufferUnmapMemory(buffer *RenderBuffer, offset, size uint64) error
+	/** @brief Flushes buffer memory at the given range. */
+	RenderBufferFlush(buffer *RenderBuffer, offset, size uint64) error
+	/** @brief Reads memory from the provided buffer at the given range. */
+	RenderBufferRead(buffer *RenderBuffer, offset, size uint64) (interface{}, error)
+	/** @brief Resizes the given buffer to newTotalSize. */
+	RenderBufferResize(buffer *RenderBuffer, newTotalSize uint64) error
+	/** @brief Frees the given range of the provided buffer. */
+	RenderBufferFree(buffer *RenderBuffer, size, offset uint64) error
+	/** @brief Loads provided data into the specified range of the given buffer. */
+	RenderBufferLoadRange(buffer *RenderBuffer, offset, size uint64, data interface{}) error
+	/** @brief Copies data in the specified range from the source to the destination buffer. */
+	RenderBufferCopyRange(source *RenderBuffer, sourceOffset uint64, dest *RenderBuffer, destOffset uint64, size uint64) error
+	/** @brief Attempts to draw the contents of the provided buffer at the given offset and element count. */
+	RenderBufferDraw(buffer *RenderBuffer, offset uint64, elementCount uint32, bindOnly bool) error
+
+	/** @brief Obtains a pointer to the window attachment (swapchain image) at the given index. */
+	WindowAttachmentGet(index uint8) *Texture
+	/** @brief Returns the index of the current window attachment. */
+	WindowAttachmentIndexGet() uint64
+	/** @brief Obtains a pointer to the depth attachment at the given index. */
+	DepthAttachmentGet(index uint8) *Texture
+	/** @brief Returns the number of attachments required for window-based render targets. */
+	GetWindowAttachmentCount() uint8
+
+	/** @brief Indicates if the renderer is capable of multi-threading. */
+	IsMultithreaded() bool
+}
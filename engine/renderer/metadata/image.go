@@ -12,6 +12,11 @@ type ImageResourceData struct {
 	Height uint32
 	/** @brief The pixel data of the image. */
 	Pixels []uint8
+	/**
+	 * @brief The pixel data of the smaller mip levels, each half the size of the previous one
+	 * down to 1x1. Only cooked images have them.
+	 */
+	Mips [][]uint8
 }
 
 /** @brief Parameters used when loading an image. */
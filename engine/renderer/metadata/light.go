@@ -0,0 +1,73 @@
+package metadata
+
+import "github.com/spaghettifunk/anima/engine/math"
+
+/** @brief The number of point lights the material shader holds, the length of its `point_lights` uniform. */
+const MAX_POINT_LIGHTS int = 10
+
+/** @brief The number of spot lights the material shader holds, the length of its `spot_lights` uniform. */
+const MAX_SPOT_LIGHTS int = 8
+
+/**
+ * @brief How the light of a point or a spot light falls off over distance d:
+ * 1 / (constant + linear * d + quadratic * d * d).
+ */
+type LightAttenuation struct {
+	/** @brief Usually 1, makes sure the denominator never gets smaller than 1. */
+	Constant float32
+	/** @brief Reduces the light intensity linearly. */
+	Linear float32
+	/** @brief Makes the light fall off slower at longer distances. */
+	Quadratic float32
+}
+
+/**
+ * @brief A light coming from infinitely far away in one direction, like the sun.
+ * NOTE: laid out as the `directional_light` struct of the material shader, the exported fields
+ * are its members in order.
+ */
+type DirectionalLight struct {
+	/** @brief The colour of the light. */
+	Colour math.Vec4
+	/** @brief The direction the light travels in, normalized. */
+	Direction math.Vec3
+	/** @brief The factor the colour is scaled by. */
+	Intensity float32
+}
+
+/**
+ * @brief A light shining in every direction from a position.
+ * NOTE: laid out as the `point_light` struct of the material shader.
+ */
+type PointLight struct {
+	/** @brief The colour of the light. */
+	Colour math.Vec4
+	/** @brief The position of the light, in world space. */
+	Position math.Vec3
+	/** @brief The factor the colour is scaled by. */
+	Intensity float32
+	/** @brief The falloff of the light over distance. */
+	Attenuation LightAttenuation
+}
+
+/**
+ * @brief A light shining in a cone from a position, fading out between its inner and outer
+ * cutoffs.
+ * NOTE: laid out as the `spot_light` struct of the material shader.
+ */
+type SpotLight struct {
+	/** @brief The colour of the light. */
+	Colour math.Vec4
+	/** @brief The position of the light, in world space. */
+	Position math.Vec3
+	/** @brief The factor the colour is scaled by. */
+	Intensity float32
+	/** @brief The direction the cone points to, normalized. */
+	Direction math.Vec3
+	/** @brief The cosine of the angle the light is at full intensity within. */
+	InnerCutoff float32
+	/** @brief The cosine of the angle past which there is no light. */
+	OuterCutoff float32
+	/** @brief The falloff of the light over distance. */
+	Attenuation LightAttenuation
+}
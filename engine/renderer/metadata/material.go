@@ -5,6 +5,9 @@ import "github.com/spaghettifunk/anima/engine/math"
 /** @brief The name of the default material. */
 const DefaultMaterialName string = "default"
 
+/** @brief The shader keyword enabled for the materials with a normal map. */
+const ShaderKeywordNormalMap string = "NORMAL_MAP"
+
 type MaterialShaderUniformLocations struct {
 	Projection      uint16
 	View            uint16
@@ -0,0 +1,34 @@
+package metadata
+
+import (
+	"github.com/spaghettifunk/anima/engine/math"
+)
+
+/**
+ * @brief A node of a model, placing geometries in the model hierarchy.
+ */
+type ModelNode struct {
+	/** @brief The name of the node. */
+	Name string
+	/** @brief The local transform of the node. Its parent is the transform of the parent node. */
+	Transform *math.Transform
+	/** @brief The index of the parent node in ModelConfig.Nodes, or -1 for root nodes. */
+	Parent int32
+	/** @brief The indices in ModelConfig.Geometries of the geometries drawn at this node. */
+	Geometries []uint32
+}
+
+/**
+ * @brief Represents the configuration of a model loaded from a file: its geometries
+ * in local space, the materials they use and the nodes placing them.
+ */
+type ModelConfig struct {
+	/** @brief The name of the model. */
+	Name string
+	/** @brief The geometries of the model, in the local space of the nodes using them. */
+	Geometries []*GeometryConfig
+	/** @brief The materials defined by the model file, if any. */
+	Materials []*MaterialConfig
+	/** @brief The nodes of the model. Parents come before their children. */
+	Nodes []*ModelNode
+}
@@ -1,9 +1,8 @@
 package metadata
 
 import (
+	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/math"
-
-	vk "github.com/goki/vulkan"
 )
 
 type RendererBackendConfig struct {
@@ -26,7 +25,7 @@ type RenderTarget struct {
 	/** @brief An array of Attachments (pointers to textures). */
 	Attachments []*RenderTargetAttachment
 	/** @brief The renderer API internal framebuffer object. */
-	InternalFramebuffer vk.Framebuffer //interface{}
+	InternalFramebuffer interface{}
 }
 
 type RenderTargetAttachmentType uint32
@@ -93,7 +92,7 @@ const (
 	/** @brief Clear the depth buffer. */
 	RENDERPASS_CLEAR_DEPTH_BUFFER_FLAG RenderpassClearFlag = 0x2
 	/** @brief Clear the stencil buffer. */
-	RENDERPASS_CLEAR_STENCIL_BUFFER_FLAG RenderpassClearFlag = 0x3
+	RENDERPASS_CLEAR_STENCIL_BUFFER_FLAG RenderpassClearFlag = 0x4
 )
 
 type RenderPassConfig struct {
@@ -119,6 +118,8 @@ type RenderPassConfig struct {
 type RenderPass struct {
 	/** @brief The id of the renderpass */
 	ID uint16
+	/** @brief The Name of the renderpass. */
+	Name string
 	/** @brief The current render area of the renderpass. */
 	RenderArea math.Vec4
 	/** @brief The clear colour used for this renderpass. */
@@ -309,6 +310,10 @@ type RenderView struct {
 	CustomShaderName string
 	/** @brief The internal, view-specific data for this view. */
 	InternalData interface{}
+	/** @brief The event listeners registered for this view, unregistered when it is destroyed. */
+	EventHandles []core.EventHandle
+	/** @brief The subscriptions of the view to the event bus, unsubscribed when it is destroyed. */
+	Subscriptions []core.Subscription
 	// ViewConfig *RenderViewConfig
 }
 
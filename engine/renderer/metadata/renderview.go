@@ -56,15 +56,10 @@ type RenderViewPick struct {
 	// u32 render_mode;
 }
 
-func (vp *RenderViewPick) OnMouseMoved(event_data core.EventContext) {
-	if event_data.Type == core.EVENT_CODE_MOUSE_MOVED {
-		// Update position and regenerate the projection matrix.
-		x := event_data.Data.(*core.MouseEvent).PosX
-		y := event_data.Data.(*core.MouseEvent).PosY
-
-		vp.MouseX = int16(x)
-		vp.MouseY = int16(y)
-	}
+func (vp *RenderViewPick) OnMouseMoved(event core.MouseMovedEvent) {
+	// Update position and regenerate the projection matrix.
+	vp.MouseX = int16(event.PosX)
+	vp.MouseY = int16(event.PosY)
 }
 
 type RenderViewUI struct {
@@ -100,7 +95,7 @@ type GeometryDistance struct {
 	Distance           float32
 }
 
-func (vw *RenderViewWorld) OnSetRenderMode(context core.EventContext) {
+func (vw *RenderViewWorld) OnSetRenderMode(context core.EventContext) bool {
 	switch context.Type {
 	case core.EVENT_CODE_SET_RENDER_MODE:
 		{
@@ -120,4 +115,6 @@ func (vw *RenderViewWorld) OnSetRenderMode(context core.EventContext) {
 			}
 		}
 	}
+	// Other world views may be listening too.
+	return false
 }
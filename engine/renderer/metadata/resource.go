@@ -24,6 +24,8 @@ const (
 	ResourceTypeSystemFont
 	/** @brief Custom resource type. Used by loaders outside the core engine. */
 	ResourceTypeCustom
+	/** @brief Pack archive resource type, holding other resources. */
+	ResourceTypePack
 	ResourceTypeNone
 )
 
@@ -2,6 +2,9 @@ package metadata
 
 import (
 	"fmt"
+	"path"
+	"slices"
+	"strings"
 )
 
 /**
@@ -32,13 +35,20 @@ type ShaderUniform struct {
 	/** @brief Index into the internal uniform array. */
 	Index uint16
 	/** @brief The Size of the uniform, or 0 for samplers. */
-	Size uint16
+	Size uint32
 	/** @brief The index of the descriptor set the uniform belongs to (0=global, 1=instance, INVALID_ID=local). */
 	SetIndex uint8
 	/** @brief The Scope of the uniform. */
 	Scope ShaderScope
 	/** @brief The type of uniform. */
 	ShaderUniformType ShaderUniformType
+	/** @brief The type of the uniform as laid out in its block, arrays and structs included. nil for samplers. */
+	Type *ShaderBlockType
+	/**
+	 * @brief Whether the uniform is a storage buffer of its own, rather than a member of a uniform
+	 * buffer or of the push constants. The Offset is then in the storage buffer of the shader.
+	 */
+	Storage bool
 }
 
 /**
@@ -102,6 +112,11 @@ type Shader struct {
 	PushConstantSize uint64
 	/** @brief The push constant stride, aligned to 4 bytes as required by Vulkan. */
 	PushConstantStride uint64
+	/** @brief The stages the push constants are visible to, a mask of ShaderStage. */
+	PushConstantStages ShaderStage
+
+	/** @brief The size of the storage buffers combined, each aligned to STORAGE_BUFFER_ALIGNMENT. */
+	StorageSize uint64
 
 	/** @brief An array of global texture map pointers */
 	GlobalTextureMaps []*TextureMap
@@ -224,6 +239,35 @@ func ShaderAttributeTypeFromString(s string) (ShaderAttributeType, uint8, error)
 	return 0, 0, fmt.Errorf("string %s is not a valid ShaderAttribType", s)
 }
 
+// String returns the name of the type in shader configs.
+func (t ShaderAttributeType) String() string {
+	switch t {
+	case ShaderAttribTypeFloat32:
+		return "f32"
+	case ShaderAttribTypeFloat32_2:
+		return "vec2"
+	case ShaderAttribTypeFloat32_3:
+		return "vec3"
+	case ShaderAttribTypeFloat32_4:
+		return "vec4"
+	case ShaderAttribTypeMatrix4:
+		return "mat4"
+	case ShaderAttribTypeInt8:
+		return "i8"
+	case ShaderAttribTypeUint8:
+		return "u8"
+	case ShaderAttribTypeInt16:
+		return "i16"
+	case ShaderAttribTypeUint16:
+		return "u16"
+	case ShaderAttribTypeInt32:
+		return "i32"
+	case ShaderAttribTypeUint32:
+		return "u32"
+	}
+	return fmt.Sprintf("ShaderAttributeType(%d)", uint(t))
+}
+
 /** @brief Available uniform types. */
 type ShaderUniformType uint
 
@@ -240,9 +284,16 @@ const (
 	ShaderUniformTypeUint32    ShaderUniformType = 9
 	ShaderUniformTypeMatrix4   ShaderUniformType = 10
 	ShaderUniformTypeSampler   ShaderUniformType = 11
+	ShaderUniformTypeStruct    ShaderUniformType = 12
 	ShaderUniformTypeCustom    ShaderUniformType = 255
 )
 
+/**
+ * @brief The alignment of the storage buffers of a shader in the buffer holding them all. This is
+ * the largest minStorageBufferOffsetAlignment the Vulkan spec allows, so it suits any device.
+ */
+const STORAGE_BUFFER_ALIGNMENT uint64 = 256
+
 func ShaderUniformTypeFromString(s string) (ShaderUniformType, uint8, error) {
 	if s == "f32" {
 		return ShaderUniformTypeFloat32, 4, nil
@@ -283,6 +334,41 @@ func ShaderUniformTypeFromString(s string) (ShaderUniformType, uint8, error) {
 	return 0, 0, fmt.Errorf("string %s is not a valid ShaderUniformType", s)
 }
 
+// String returns the name of the type in shader configs.
+func (t ShaderUniformType) String() string {
+	switch t {
+	case ShaderUniformTypeFloat32:
+		return "f32"
+	case ShaderUniformTypeFloat32_2:
+		return "vec2"
+	case ShaderUniformTypeFloat32_3:
+		return "vec3"
+	case ShaderUniformTypeFloat32_4:
+		return "vec4"
+	case ShaderUniformTypeInt8:
+		return "i8"
+	case ShaderUniformTypeUint8:
+		return "u8"
+	case ShaderUniformTypeInt16:
+		return "i16"
+	case ShaderUniformTypeUint16:
+		return "u16"
+	case ShaderUniformTypeInt32:
+		return "i32"
+	case ShaderUniformTypeUint32:
+		return "u32"
+	case ShaderUniformTypeMatrix4:
+		return "mat4"
+	case ShaderUniformTypeSampler:
+		return "samp"
+	case ShaderUniformTypeStruct:
+		return "struct"
+	case ShaderUniformTypeCustom:
+		return "custom"
+	}
+	return fmt.Sprintf("ShaderUniformType(%d)", uint(t))
+}
+
 /**
  * @brief Defines shader scope, which indicates how
  * often it gets updated.
@@ -312,14 +398,28 @@ type ShaderAttributeConfig struct {
 type ShaderUniformConfig struct {
 	/** @brief The name of the uniform. */
 	Name string
-	/** @brief The size of the uniform. */
+	/** @brief The size of the uniform, of one element for arrays, 0 for structs. */
 	Size uint8
 	/** @brief The location of the uniform. */
 	Location uint32
-	/** @brief The type of the uniform. */
+	/** @brief The type of the uniform, of its elements for arrays. */
 	ShaderUniformType ShaderUniformType
 	/** @brief The scope of the uniform. */
 	Scope ShaderScope
+	/** @brief The elements of the array, e.g. 8 for vec4[8]. 0 when the uniform is not an array. */
+	Length uint32
+	/** @brief The name of the struct of the uniform, when its type is ShaderUniformTypeStruct. */
+	StructName string
+	/** @brief Whether the uniform is a read-only storage buffer. Storage buffers are global. */
+	Storage bool
+}
+
+/** @brief Configuration for a struct the uniforms can be of. */
+type ShaderStructConfig struct {
+	/** @brief The name of the struct, used as the type of the uniforms. */
+	Name string
+	/** @brief The members of the struct, in order. Their scope is not used. */
+	Members []*ShaderUniformConfig
 }
 
 /**
@@ -336,6 +436,10 @@ type ShaderConfig struct {
 	Attributes []*ShaderAttributeConfig
 	/** @brief The collection of uniforms. */
 	Uniforms []*ShaderUniformConfig
+	/** @brief The structs the uniforms can be of, each declared after the structs it holds. */
+	Structs []*ShaderStructConfig
+	/** @brief The stages the push constants (the local uniforms) are visible to. Empty is vertex and fragment. */
+	PushConstantStages []ShaderStage
 	/** @brief The name of the renderpass used by this shader. */
 	RenderpassName string
 	/** @brief The collection of stages. */
@@ -351,4 +455,136 @@ type ShaderConfig struct {
 	 * NOTE: This is ignored if depth_test is false.
 	 */
 	DepthWrite bool
+	/** @brief The keywords the variants of the shader are compiled with, in the order they are declared. */
+	Keywords []*ShaderKeywordConfig
+	/** @brief The keywords enabled in this variant of the shader. Empty for the shader itself. */
+	VariantKeywords []string
+}
+
+/**
+ * @brief A keyword of a shader, e.g. NORMAL_MAP. The variants enabling it are compiled with it
+ * defined, to stage files named after it: Builtin.MaterialShader.NORMAL_MAP.frag.spv.
+ */
+type ShaderKeywordConfig struct {
+	/** @brief The name of the keyword, as defined in the stages. */
+	Name string
+	/** @brief The stages compiled differently when the keyword is enabled. Empty is all of them. */
+	Stages []ShaderStage
+}
+
+// affects tells whether the keyword changes the given stage.
+func (k *ShaderKeywordConfig) affects(stage ShaderStage) bool {
+	return len(k.Stages) == 0 || slices.Contains(k.Stages, stage)
+}
+
+// EnabledKeywords returns the given keywords the shader declares, in the order it declares them
+// and without duplicates. The others are ignored.
+func (c *ShaderConfig) EnabledKeywords(keywords []string) []string {
+	enabled := []string{}
+	for _, k := range c.Keywords {
+		if slices.Contains(keywords, k.Name) {
+			enabled = append(enabled, k.Name)
+		}
+	}
+	return enabled
+}
+
+// ShaderVariantName returns the name of the variant of the shader with the given keywords
+// enabled, e.g. Shader.Builtin.Material.NORMAL_MAP. The keywords must be in the order the
+// shader declares them.
+func ShaderVariantName(shaderName string, keywords []string) string {
+	if len(keywords) == 0 {
+		return shaderName
+	}
+	return shaderName + "." + strings.Join(keywords, ".")
+}
+
+// ShaderVariantStageFile returns the stage file compiled with the given keywords, named after
+// them before the stage extension: shaders/Builtin.MaterialShader.frag.spv becomes
+// shaders/Builtin.MaterialShader.NORMAL_MAP.frag.spv.
+func ShaderVariantStageFile(filename string, keywords []string) string {
+	if len(keywords) == 0 {
+		return filename
+	}
+	dir, file := path.Split(filename)
+	base := strings.TrimSuffix(file, path.Ext(file))
+	extensions := file[len(base):]
+	if stageExtension := path.Ext(base); stageExtension != "" {
+		base = strings.TrimSuffix(base, stageExtension)
+		extensions = stageExtension + extensions
+	}
+	return dir + base + "." + strings.Join(keywords, ".") + extensions
+}
+
+// Variant returns the config of the variant of the shader with the given keywords enabled: its
+// name and stage files are the variant ones, the rest is shared with the shader. The keywords
+// the shader does not declare are ignored.
+func (c *ShaderConfig) Variant(keywords []string) *ShaderConfig {
+	variant := *c
+	variant.VariantKeywords = c.EnabledKeywords(keywords)
+	variant.Name = ShaderVariantName(c.Name, variant.VariantKeywords)
+	variant.StageNames = make([]string, len(c.StageFilenames))
+	variant.StageFilenames = make([]string, len(c.StageFilenames))
+	for i, filename := range c.StageFilenames {
+		stageKeywords := []string{}
+		for _, k := range c.Keywords {
+			if i < len(c.Stages) && k.affects(c.Stages[i]) && slices.Contains(variant.VariantKeywords, k.Name) {
+				stageKeywords = append(stageKeywords, k.Name)
+			}
+		}
+		variant.StageNames[i] = ShaderVariantStageFile(filename, stageKeywords)
+		variant.StageFilenames[i] = variant.StageNames[i]
+	}
+	return &variant
+}
+
+// Variants returns the configs of every variant of the shader, one per combination of its
+// keywords, the shader itself left out.
+func (c *ShaderConfig) Variants() []*ShaderConfig {
+	variants := []*ShaderConfig{}
+	for mask := 1; mask < 1<<len(c.Keywords); mask++ {
+		keywords := []string{}
+		for i, k := range c.Keywords {
+			if mask&(1<<i) != 0 {
+				keywords = append(keywords, k.Name)
+			}
+		}
+		variants = append(variants, c.Variant(keywords))
+	}
+	return variants
+}
+
+// TypeName returns the type of the uniform as written in shader configs, e.g. vec4[8] or the
+// name of its struct.
+func (u *ShaderUniformConfig) TypeName() string {
+	name := u.ShaderUniformType.String()
+	if u.ShaderUniformType == ShaderUniformTypeStruct {
+		name = u.StructName
+	}
+	if u.Length > 0 {
+		name = fmt.Sprintf("%s[%d]", name, u.Length)
+	}
+	return name
+}
+
+// Struct returns the struct with the given name, nil if the shader declares none.
+func (c *ShaderConfig) Struct(name string) *ShaderStructConfig {
+	for _, s := range c.Structs {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// PushConstantStageMask returns the stages the push constants are visible to, as a mask.
+func (c *ShaderConfig) PushConstantStageMask() ShaderStage {
+	if len(c.PushConstantStages) == 0 {
+		return ShaderStageVertex | ShaderStageFragment
+	}
+	mask := ShaderStage(0)
+	for _, stage := range c.PushConstantStages {
+		mask |= stage
+	}
+	return mask
 }
@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	m "math"
+	"reflect"
+)
+
+/**
+ * @brief Lays a value out as a member of the given type, as the shader reads it: the padding
+ * of the layout between the array elements, the matrix columns and the struct members is left
+ * zeroed.
+ *
+ * Scalars are Go numbers, vectors and matrices Go arrays or structs of their components (e.g.
+ * math.Vec3, or math.Mat4 for a column-major mat4), structs Go structs whose exported fields are
+ * their members in order, and arrays Go arrays or slices of at most their length. A []byte is
+ * taken as already laid out.
+ *
+ * @param t The type of the member.
+ * @param value The value of the member.
+ * @return The bytes of the member, the size of the type.
+ */
+func (l ShaderBlockLayout) Encode(t *ShaderBlockType, value interface{}) ([]byte, error) {
+	data := make([]byte, l.Size(t))
+	if raw, ok := value.([]byte); ok {
+		if len(raw) > len(data) {
+			return nil, fmt.Errorf("%d bytes for %s, which takes %d in %s", len(raw), t, len(data), l)
+		}
+		copy(data, raw)
+		return data, nil
+	}
+	if err := l.encode(data, t, reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (l ShaderBlockLayout) encode(dst []byte, t *ShaderBlockType, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return fmt.Errorf("nil value for %s", t)
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return fmt.Errorf("no value for %s", t)
+	}
+
+	switch {
+	case t.Length > 0:
+		if v.Kind() != reflect.Array && v.Kind() != reflect.Slice {
+			return fmt.Errorf("%s is not an array, the uniform is %s", v.Type(), t)
+		}
+		if uint32(v.Len()) > t.Length {
+			return fmt.Errorf("%d elements, the uniform is %s", v.Len(), t)
+		}
+		element, stride := t.Element(), l.ArrayStride(t)
+		for i := 0; i < v.Len(); i++ {
+			if err := l.encode(dst[uint32(i)*stride:], element, v.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	case t.Members != nil:
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("%s is not a struct, the uniform is %s", v.Type(), t)
+		}
+		fields := exportedFields(v)
+		if len(fields) != len(t.Members) {
+			return fmt.Errorf("%s has %d exported fields, the uniform is %s", v.Type(), len(fields), t)
+		}
+		offsets, _ := l.Offsets(t.Members)
+		for i, member := range t.Members {
+			if err := l.encode(dst[offsets[i]:], member, v.Field(fields[i])); err != nil {
+				return fmt.Errorf("field `%s`: %w", v.Type().Field(fields[i]).Name, err)
+			}
+		}
+		return nil
+	}
+
+	// A scalar, a vector or a matrix, written column by column.
+	components := flattenComponents(v, nil)
+	if uint32(len(components)) != t.Components*t.Columns {
+		return fmt.Errorf("%s has %d components, the uniform is %s", v.Type(), len(components), t)
+	}
+	columnStride := uint32(0)
+	if t.Columns > 1 {
+		columnStride = l.MatrixStride(t)
+	}
+	size := t.componentSize()
+	for c := uint32(0); c < t.Columns; c++ {
+		for r := uint32(0); r < t.Components; r++ {
+			if err := putComponent(dst[c*columnStride+r*size:], t.Component, components[c*t.Components+r]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportedFields returns the indices of the exported fields of the struct, in order.
+func exportedFields(v reflect.Value) []int {
+	fields := []int{}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+// flattenComponents appends the numbers the value is made of, in memory order.
+func flattenComponents(v reflect.Value, components []reflect.Value) []reflect.Value {
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			components = flattenComponents(v.Index(i), components)
+		}
+	case reflect.Struct:
+		for _, field := range exportedFields(v) {
+			components = flattenComponents(v.Field(field), components)
+		}
+	default:
+		components = append(components, v)
+	}
+	return components
+}
+
+// putComponent writes the number as a component of the given type, in the byte order of the host
+// as the GPU reads it.
+func putComponent(dst []byte, component ShaderUniformType, v reflect.Value) error {
+	var bits uint64
+	switch {
+	case v.CanFloat():
+		if component != ShaderUniformTypeFloat32 {
+			return fmt.Errorf("%s for a %s component", v.Type(), component)
+		}
+		bits = uint64(m.Float32bits(float32(v.Float())))
+	case v.CanInt():
+		bits = uint64(v.Int())
+	case v.CanUint():
+		bits = v.Uint()
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			bits = 1
+		}
+	default:
+		return fmt.Errorf("%s is not a number", v.Type())
+	}
+	if component == ShaderUniformTypeFloat32 && !v.CanFloat() {
+		// Integers are not converted, the shader would read their bits as a float.
+		return fmt.Errorf("%s for a %s component", v.Type(), component)
+	}
+	switch component {
+	case ShaderUniformTypeInt8, ShaderUniformTypeUint8:
+		dst[0] = uint8(bits)
+	case ShaderUniformTypeInt16, ShaderUniformTypeUint16:
+		binary.NativeEndian.PutUint16(dst, uint16(bits))
+	default:
+		binary.NativeEndian.PutUint32(dst, uint32(bits))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"encoding/binary"
+	m "math"
+	"testing"
+)
+
+// floatsAt reads the float32s at the given byte offsets.
+func floatsAt(data []byte, offsets ...int) []float32 {
+	values := make([]float32, len(offsets))
+	for i, offset := range offsets {
+		values[i] = m.Float32frombits(binary.NativeEndian.Uint32(data[offset:]))
+	}
+	return values
+}
+
+func TestShaderBlockLayoutEncodeStd430(t *testing.T) {
+	type attenuation struct {
+		Constant, Linear, Quadratic float32
+	}
+	type light struct {
+		Colour      [4]float32
+		Position    [3]float32
+		Intensity   float32
+		Attenuation attenuation
+		unexported  int
+	}
+	lightType := NewShaderBlockStruct(layoutVec4, layoutVec3, layoutFloat, NewShaderBlockStruct(layoutFloat, layoutFloat, layoutFloat))
+	lights := []light{
+		{Colour: [4]float32{1, 2, 3, 4}, Position: [3]float32{5, 6, 7}, Intensity: 8, Attenuation: attenuation{9, 10, 11}},
+		{Colour: [4]float32{12, 13, 14, 15}, Position: [3]float32{16, 17, 18}, Intensity: 19, Attenuation: attenuation{20, 21, 22}},
+	}
+	data, err := ShaderBlockLayoutStd430.Encode(lightType.Array(3), lights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A light takes 44 bytes, rounded up to 48 by the alignment of its vec4. The third is zeroed.
+	if len(data) != 3*48 {
+		t.Fatalf("got %d bytes, want %d", len(data), 3*48)
+	}
+	for i := range lights {
+		base := i * 48
+		got := floatsAt(data, base, base+4, base+8, base+12, base+16, base+20, base+24, base+28, base+32, base+36, base+40)
+		for j, value := range got {
+			if want := float32(i*11 + j + 1); value != want {
+				t.Errorf("light %d: got %v at float %d, want %v", i, value, j, want)
+			}
+		}
+	}
+	for i, b := range data[96:] {
+		if b != 0 {
+			t.Fatalf("byte %d of the third light is %d, want 0", 96+i, b)
+		}
+	}
+}
+
+func TestShaderBlockLayoutEncodeArrays(t *testing.T) {
+	values := [3]float32{1, 2, 3}
+	tests := []struct {
+		name    string
+		layout  ShaderBlockLayout
+		offsets []int
+		size    int
+	}{
+		// std430 packs the floats, std140 rounds each element up to a vec4.
+		{"std430", ShaderBlockLayoutStd430, []int{0, 4, 8}, 12},
+		{"std140", ShaderBlockLayoutStd140, []int{0, 16, 32}, 48},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.layout.Encode(layoutFloat.Array(3), values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := floatsAt(data, test.offsets...)
+			if len(data) != test.size || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+				t.Errorf("got %v in %d bytes, want %v in %d", got, len(data), values, test.size)
+			}
+		})
+	}
+}
+
+func TestShaderBlockLayoutEncodeMatrix(t *testing.T) {
+	// A mat3 has its columns padded to vec4s in std430 too.
+	columns := [3][3]float32{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	data, err := ShaderBlockLayoutStd430.Encode(layoutMat3, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := floatsAt(data, 0, 4, 8, 16, 20, 24, 32, 36, 40)
+	for i, value := range got {
+		if value != float32(i+1) {
+			t.Errorf("got %v at component %d, want %v", value, i, i+1)
+		}
+	}
+	if padding := floatsAt(data, 12, 28, 44); padding[0] != 0 || padding[1] != 0 || padding[2] != 0 {
+		t.Errorf("got padding %v, want zeroes", padding)
+	}
+}
+
+func TestShaderBlockLayoutEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     *ShaderBlockType
+		value interface{}
+	}{
+		{"too many elements", layoutFloat.Array(2), []float32{1, 2, 3}},
+		{"integer for a float", layoutFloat, int32(1)},
+		{"missing component", layoutVec3, [2]float32{1, 2}},
+		{"missing field", NewShaderBlockStruct(layoutFloat, layoutFloat), struct{ A float32 }{1}},
+		{"not an array", layoutFloat.Array(2), float32(1)},
+		{"too many bytes", layoutVec2, make([]byte, 9)},
+		{"nil", layoutFloat, (*float32)(nil)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ShaderBlockLayoutStd430.Encode(test.t, test.value); err == nil {
+				t.Error("encoded an invalid value")
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package metadata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/**
+ * @brief The rules the members of a uniform or storage block are laid out with, as defined by
+ * GLSL. Uniform buffers use std140, push constants and storage buffers std430.
+ */
+type ShaderBlockLayout uint8
+
+const (
+	/** @brief Arrays, matrix columns and structs are aligned to 16 bytes. */
+	ShaderBlockLayoutStd140 ShaderBlockLayout = iota
+	/** @brief Arrays, matrix columns and structs are aligned to their elements and members. */
+	ShaderBlockLayoutStd430
+)
+
+func ShaderBlockLayoutFromString(s string) (ShaderBlockLayout, error) {
+	switch s {
+	case "std140":
+		return ShaderBlockLayoutStd140, nil
+	case "std430":
+		return ShaderBlockLayoutStd430, nil
+	}
+	return 0, fmt.Errorf("string %s is not a valid ShaderBlockLayout", s)
+}
+
+// String returns the name of the layout, as written in GLSL.
+func (l ShaderBlockLayout) String() string {
+	switch l {
+	case ShaderBlockLayoutStd140:
+		return "std140"
+	case ShaderBlockLayoutStd430:
+		return "std430"
+	}
+	return fmt.Sprintf("ShaderBlockLayout(%d)", uint8(l))
+}
+
+/**
+ * @brief A GLSL type, as far as the layout rules are concerned: a scalar, a vector, a
+ * column-major matrix or a struct, possibly in an array.
+ */
+type ShaderBlockType struct {
+	/** @brief The type of the components, a scalar uniform type, e.g. ShaderUniformTypeFloat32. */
+	Component ShaderUniformType
+	/** @brief The components of the vector, or of a column of the matrix. 1 for scalars. */
+	Components uint32
+	/** @brief The columns of the matrix. 1 for scalars and vectors. */
+	Columns uint32
+	/** @brief The members of the struct, in order. nil when the type is not a struct. */
+	Members []*ShaderBlockType
+	/** @brief The elements of the array. 0 when the type is not an array. */
+	Length uint32
+}
+
+// NewShaderBlockScalar returns a scalar type.
+func NewShaderBlockScalar(component ShaderUniformType) *ShaderBlockType {
+	return &ShaderBlockType{Component: component, Components: 1, Columns: 1}
+}
+
+// NewShaderBlockVector returns a vector type of the given components.
+func NewShaderBlockVector(component ShaderUniformType, components uint32) *ShaderBlockType {
+	return &ShaderBlockType{Component: component, Components: components, Columns: 1}
+}
+
+// NewShaderBlockMatrix returns a column-major matrix type, e.g. 4 columns of 3 rows for a mat4x3.
+func NewShaderBlockMatrix(component ShaderUniformType, columns, rows uint32) *ShaderBlockType {
+	return &ShaderBlockType{Component: component, Components: rows, Columns: columns}
+}
+
+// NewShaderBlockStruct returns a struct type with the given members.
+func NewShaderBlockStruct(members ...*ShaderBlockType) *ShaderBlockType {
+	return &ShaderBlockType{Members: members}
+}
+
+// Array returns the type of an array of length elements of the type.
+func (t *ShaderBlockType) Array(length uint32) *ShaderBlockType {
+	array := *t
+	array.Length = length
+	return &array
+}
+
+// Element returns the type of the elements of the array, or the type itself when it is not one.
+func (t *ShaderBlockType) Element() *ShaderBlockType {
+	element := *t
+	element.Length = 0
+	return &element
+}
+
+// column returns the type of a column of the matrix.
+func (t *ShaderBlockType) column() *ShaderBlockType {
+	return NewShaderBlockVector(t.Component, t.Components)
+}
+
+/**
+ * @brief Returns the base alignment of the type: the offsets of the members of this type are
+ * rounded up to a multiple of it.
+ */
+func (l ShaderBlockLayout) Alignment(t *ShaderBlockType) uint32 {
+	alignment := uint32(0)
+	switch {
+	case t.Length > 0:
+		alignment = l.Alignment(t.Element())
+	case t.Members != nil:
+		alignment = 1
+		for _, member := range t.Members {
+			alignment = max(alignment, l.Alignment(member))
+		}
+	case t.Columns > 1:
+		// Laid out as an array of its columns.
+		alignment = l.Alignment(t.column())
+	case t.Components == 1:
+		return t.componentSize()
+	case t.Components == 2:
+		return 2 * t.componentSize()
+	default:
+		// A vec3 is aligned like a vec4.
+		return 4 * t.componentSize()
+	}
+	if l == ShaderBlockLayoutStd140 {
+		alignment = align(alignment, 16)
+	}
+	return alignment
+}
+
+// Size returns the bytes a member of the type takes, padding included.
+func (l ShaderBlockLayout) Size(t *ShaderBlockType) uint32 {
+	switch {
+	case t.Length > 0:
+		return t.Length * l.ArrayStride(t)
+	case t.Members != nil:
+		_, end := l.Offsets(t.Members)
+		return align(end, l.Alignment(t))
+	case t.Columns > 1:
+		return t.Columns * l.MatrixStride(t)
+	}
+	return t.Components * t.componentSize()
+}
+
+// ArrayStride returns the bytes between two elements of the array type.
+func (l ShaderBlockLayout) ArrayStride(t *ShaderBlockType) uint32 {
+	return align(l.Size(t.Element()), l.Alignment(t))
+}
+
+// MatrixStride returns the bytes between two columns of the matrix type.
+func (l ShaderBlockLayout) MatrixStride(t *ShaderBlockType) uint32 {
+	return align(l.Size(t.column()), l.Alignment(t.column().Array(t.Columns)))
+}
+
+// Offset returns the offset of a member of the type placed after the given end of the previous
+// member.
+func (l ShaderBlockLayout) Offset(end uint32, t *ShaderBlockType) uint32 {
+	return align(end, l.Alignment(t))
+}
+
+// Offsets returns the offsets of the members of a block, and where the last one ends.
+func (l ShaderBlockLayout) Offsets(members []*ShaderBlockType) ([]uint32, uint32) {
+	offsets := make([]uint32, len(members))
+	end := uint32(0)
+	for i, member := range members {
+		offsets[i] = l.Offset(end, member)
+		end = offsets[i] + l.Size(member)
+	}
+	return offsets, end
+}
+
+// componentSize returns the size in bytes of a component.
+func (t *ShaderBlockType) componentSize() uint32 {
+	switch t.Component {
+	case ShaderUniformTypeInt8, ShaderUniformTypeUint8:
+		return 1
+	case ShaderUniformTypeInt16, ShaderUniformTypeUint16:
+		return 2
+	}
+	return 4
+}
+
+// Equal tells whether the types are the same, and so laid out the same.
+func (t *ShaderBlockType) Equal(other *ShaderBlockType) bool {
+	if t.Length != other.Length || len(t.Members) != len(other.Members) || (t.Members == nil) != (other.Members == nil) {
+		return false
+	}
+	if t.Members != nil {
+		for i, member := range t.Members {
+			if !member.Equal(other.Members[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return t.Component == other.Component && t.Components == other.Components && t.Columns == other.Columns
+}
+
+// String returns the type as written in GLSL, e.g. vec3, mat4 or float[4].
+func (t *ShaderBlockType) String() string {
+	name := ""
+	switch {
+	case t.Members != nil:
+		members := make([]string, len(t.Members))
+		for i, member := range t.Members {
+			members[i] = member.String()
+		}
+		name = "struct { " + strings.Join(members, "; ") + " }"
+	case t.Columns > 1 && t.Columns == t.Components:
+		name = fmt.Sprintf("%smat%d", t.vectorPrefix(), t.Columns)
+	case t.Columns > 1:
+		name = fmt.Sprintf("%smat%dx%d", t.vectorPrefix(), t.Columns, t.Components)
+	case t.Components > 1:
+		name = fmt.Sprintf("%svec%d", t.vectorPrefix(), t.Components)
+	default:
+		switch t.Component {
+		case ShaderUniformTypeFloat32:
+			name = "float"
+		case ShaderUniformTypeInt32:
+			name = "int"
+		case ShaderUniformTypeUint32:
+			name = "uint"
+		case ShaderUniformTypeInt8:
+			name = "int8_t"
+		case ShaderUniformTypeUint8:
+			name = "uint8_t"
+		case ShaderUniformTypeInt16:
+			name = "int16_t"
+		case ShaderUniformTypeUint16:
+			name = "uint16_t"
+		default:
+			name = t.Component.String()
+		}
+	}
+	if t.Length > 0 {
+		name = fmt.Sprintf("%s[%d]", name, t.Length)
+	}
+	return name
+}
+
+// vectorPrefix returns the prefix of the vectors and matrices of the component type, e.g. i for
+// ivec3.
+func (t *ShaderBlockType) vectorPrefix() string {
+	switch t.Component {
+	case ShaderUniformTypeInt32:
+		return "i"
+	case ShaderUniformTypeUint32:
+		return "u"
+	case ShaderUniformTypeInt8:
+		return "i8"
+	case ShaderUniformTypeUint8:
+		return "u8"
+	case ShaderUniformTypeInt16:
+		return "i16"
+	case ShaderUniformTypeUint16:
+		return "u16"
+	}
+	return ""
+}
+
+func align(offset, alignment uint32) uint32 {
+	if alignment == 0 {
+		return offset
+	}
+	return (offset + alignment - 1) / alignment * alignment
+}
+
+// BlockType returns the type of the uniform in a block, nil for samplers and custom uniforms.
+func (t ShaderUniformType) BlockType() *ShaderBlockType {
+	switch t {
+	case ShaderUniformTypeFloat32, ShaderUniformTypeInt8, ShaderUniformTypeUint8, ShaderUniformTypeInt16,
+		ShaderUniformTypeUint16, ShaderUniformTypeInt32, ShaderUniformTypeUint32:
+		return NewShaderBlockScalar(t)
+	case ShaderUniformTypeFloat32_2:
+		return NewShaderBlockVector(ShaderUniformTypeFloat32, 2)
+	case ShaderUniformTypeFloat32_3:
+		return NewShaderBlockVector(ShaderUniformTypeFloat32, 3)
+	case ShaderUniformTypeFloat32_4:
+		return NewShaderBlockVector(ShaderUniformTypeFloat32, 4)
+	case ShaderUniformTypeMatrix4:
+		return NewShaderBlockMatrix(ShaderUniformTypeFloat32, 4, 4)
+	}
+	return nil
+}
+
+/**
+ * @brief Returns the type of the uniform as laid out in its block, resolving its struct, and
+ * the structs it holds, among those the shader declares. A custom uniform is taken as bytes.
+ *
+ * @param uniform The uniform, or a member of a struct.
+ * @return The type, or an error for samplers and unknown structs.
+ */
+func (c *ShaderConfig) UniformBlockType(uniform *ShaderUniformConfig) (*ShaderBlockType, error) {
+	return c.uniformBlockType(uniform, len(c.Structs))
+}
+
+// uniformBlockType resolves the struct of the uniform among the first declared structs only, so
+// a struct cannot hold itself.
+func (c *ShaderConfig) uniformBlockType(uniform *ShaderUniformConfig, declared int) (*ShaderBlockType, error) {
+	var t *ShaderBlockType
+	switch uniform.ShaderUniformType {
+	case ShaderUniformTypeSampler:
+		return nil, fmt.Errorf("`%s` is a sampler, which has no layout in a block", uniform.Name)
+	case ShaderUniformTypeCustom:
+		t = NewShaderBlockVector(ShaderUniformTypeUint8, uint32(uniform.Size))
+	case ShaderUniformTypeStruct:
+		i := slices.IndexFunc(c.Structs[:declared], func(s *ShaderStructConfig) bool { return s.Name == uniform.StructName })
+		if i < 0 {
+			return nil, fmt.Errorf("`%s` is of struct `%s`, which is not declared before", uniform.Name, uniform.StructName)
+		}
+		members := make([]*ShaderBlockType, len(c.Structs[i].Members))
+		for j, member := range c.Structs[i].Members {
+			var err error
+			if members[j], err = c.uniformBlockType(member, i); err != nil {
+				return nil, fmt.Errorf("struct `%s`: %w", uniform.StructName, err)
+			}
+		}
+		t = NewShaderBlockStruct(members...)
+	default:
+		if t = uniform.ShaderUniformType.BlockType(); t == nil {
+			return nil, fmt.Errorf("`%s` is of type %s, which has no layout in a block", uniform.Name, uniform.ShaderUniformType)
+		}
+	}
+	if uniform.Length > 0 {
+		t = t.Array(uniform.Length)
+	}
+	return t, nil
+}
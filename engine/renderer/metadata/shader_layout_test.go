@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"slices"
+	"testing"
+)
+
+var (
+	layoutFloat = NewShaderBlockScalar(ShaderUniformTypeFloat32)
+	layoutUint  = NewShaderBlockScalar(ShaderUniformTypeUint32)
+	layoutVec2  = NewShaderBlockVector(ShaderUniformTypeFloat32, 2)
+	layoutVec3  = NewShaderBlockVector(ShaderUniformTypeFloat32, 3)
+	layoutVec4  = NewShaderBlockVector(ShaderUniformTypeFloat32, 4)
+	layoutMat3  = NewShaderBlockMatrix(ShaderUniformTypeFloat32, 3, 3)
+	layoutMat4  = NewShaderBlockMatrix(ShaderUniformTypeFloat32, 4, 4)
+)
+
+func TestShaderBlockLayoutOffsets(t *testing.T) {
+	attenuation := NewShaderBlockStruct(layoutFloat, layoutFloat, layoutFloat)
+	pointLight := NewShaderBlockStruct(layoutVec4, layoutVec3, layoutFloat, attenuation)
+
+	tests := []struct {
+		name    string
+		layout  ShaderBlockLayout
+		members []*ShaderBlockType
+		offsets []uint32
+		end     uint32
+	}{
+		{"std140 vec3 followed by float", ShaderBlockLayoutStd140, []*ShaderBlockType{layoutVec3, layoutFloat}, []uint32{0, 12}, 16},
+		{"std430 vec3 followed by float", ShaderBlockLayoutStd430, []*ShaderBlockType{layoutVec3, layoutFloat}, []uint32{0, 12}, 16},
+		{"std140 float followed by vec3", ShaderBlockLayoutStd140, []*ShaderBlockType{layoutFloat, layoutVec3}, []uint32{0, 16}, 28},
+		{"std140 float followed by vec2", ShaderBlockLayoutStd140, []*ShaderBlockType{layoutFloat, layoutVec2}, []uint32{0, 8}, 16},
+		{"std140 float array", ShaderBlockLayoutStd140, []*ShaderBlockType{layoutFloat.Array(3), layoutFloat}, []uint32{0, 48}, 52},
+		{"std430 float array", ShaderBlockLayoutStd430, []*ShaderBlockType{layoutFloat.Array(3), layoutFloat}, []uint32{0, 12}, 16},
+		{"std140 struct after float", ShaderBlockLayoutStd140, []*ShaderBlockType{layoutFloat, attenuation}, []uint32{0, 16}, 32},
+		{"std430 struct after float", ShaderBlockLayoutStd430, []*ShaderBlockType{layoutFloat, attenuation}, []uint32{0, 4}, 16},
+		{"std140 struct array", ShaderBlockLayoutStd140, []*ShaderBlockType{pointLight.Array(2), layoutUint}, []uint32{0, 96}, 100},
+		{"std430 mat3", ShaderBlockLayoutStd430, []*ShaderBlockType{layoutMat3, layoutFloat}, []uint32{0, 48}, 52},
+		{
+			// The global block of the material shader.
+			"std140 material globals", ShaderBlockLayoutStd140,
+			[]*ShaderBlockType{
+				layoutMat4, layoutMat4, layoutVec4, layoutVec3, layoutUint,
+				NewShaderBlockStruct(layoutVec4, layoutVec3, layoutFloat), layoutUint, layoutUint,
+				pointLight.Array(10),
+				NewShaderBlockStruct(layoutVec4, layoutVec3, layoutFloat, layoutVec3, layoutFloat, layoutFloat, attenuation).Array(8),
+			},
+			[]uint32{0, 64, 128, 144, 156, 160, 192, 196, 208, 688},
+			1328,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offsets, end := test.layout.Offsets(test.members)
+			if !slices.Equal(offsets, test.offsets) || end != test.end {
+				t.Errorf("got offsets %v ending at %d, want %v ending at %d", offsets, end, test.offsets, test.end)
+			}
+		})
+	}
+}
+
+func TestShaderBlockLayoutStrides(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout ShaderBlockLayout
+		array  *ShaderBlockType
+		stride uint32
+	}{
+		{"std140 float[]", ShaderBlockLayoutStd140, layoutFloat.Array(4), 16},
+		{"std430 float[]", ShaderBlockLayoutStd430, layoutFloat.Array(4), 4},
+		{"std140 vec2[]", ShaderBlockLayoutStd140, layoutVec2.Array(4), 16},
+		{"std430 vec2[]", ShaderBlockLayoutStd430, layoutVec2.Array(4), 8},
+		{"std430 vec3[]", ShaderBlockLayoutStd430, layoutVec3.Array(4), 16},
+		{"std140 struct{float}[]", ShaderBlockLayoutStd140, NewShaderBlockStruct(layoutFloat).Array(4), 16},
+		{"std430 struct{float}[]", ShaderBlockLayoutStd430, NewShaderBlockStruct(layoutFloat).Array(4), 4},
+		{"std430 struct{vec3, float}[]", ShaderBlockLayoutStd430, NewShaderBlockStruct(layoutVec3, layoutFloat).Array(4), 16},
+		{"std140 mat3[]", ShaderBlockLayoutStd140, layoutMat3.Array(2), 48},
+		{"std430 mat3[]", ShaderBlockLayoutStd430, layoutMat3.Array(2), 48},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if stride := test.layout.ArrayStride(test.array); stride != test.stride {
+				t.Errorf("got stride %d, want %d", stride, test.stride)
+			}
+		})
+	}
+}
+
+func TestShaderBlockLayoutMatrixStride(t *testing.T) {
+	mat2 := NewShaderBlockMatrix(ShaderUniformTypeFloat32, 2, 2)
+	tests := []struct {
+		name   string
+		layout ShaderBlockLayout
+		matrix *ShaderBlockType
+		stride uint32
+		size   uint32
+	}{
+		{"std140 mat2", ShaderBlockLayoutStd140, mat2, 16, 32},
+		{"std430 mat2", ShaderBlockLayoutStd430, mat2, 8, 16},
+		{"std140 mat3", ShaderBlockLayoutStd140, layoutMat3, 16, 48},
+		{"std430 mat3", ShaderBlockLayoutStd430, layoutMat3, 16, 48},
+		{"std430 mat4", ShaderBlockLayoutStd430, layoutMat4, 16, 64},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stride, size := test.layout.MatrixStride(test.matrix), test.layout.Size(test.matrix)
+			if stride != test.stride || size != test.size {
+				t.Errorf("got stride %d and size %d, want %d and %d", stride, size, test.stride, test.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,844 @@
+package null
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+const (
+	// The number of window attachments, mimicking a triple-buffered swapchain.
+	NULL_WINDOW_ATTACHMENT_COUNT uint8 = 3
+	// The UBO alignment reported to the shaders.
+	NULL_REQUIRED_UBO_ALIGNMENT uint64 = 256
+	// The default amount of frame logs kept around.
+	NULL_DEFAULT_FRAME_HISTORY int = 120
+)
+
+/** @brief Internal data of a texture created by the null renderer. */
+type NullTexture struct {
+	Handle uint32
+	Pixels []uint8
+}
+
+/** @brief Internal data of a geometry uploaded to the null renderer. */
+type NullGeometry struct {
+	Handle      uint32
+	VertexCount uint32
+	IndexCount  uint32
+}
+
+/** @brief Internal data of a shader created by the null renderer. */
+type NullShader struct {
+	Handle       uint32
+	Config       *metadata.ShaderConfig
+	UniformNames map[uint16]string
+	Instances    map[uint32]bool
+	nextInstance uint32
+}
+
+/** @brief Internal data of a renderpass created by the null renderer. */
+type NullRenderPass struct {
+	Handle uint32
+	Name   string
+}
+
+/** @brief Internal data of a renderbuffer created by the null renderer. */
+type NullBuffer struct {
+	Handle uint32
+	Data   []byte
+}
+
+/**
+ * @brief A renderer backend which does not talk to any GPU. Every call
+ * is accepted, resources get fake handles and everything submitted
+ * during a frame is recorded in a FrameLog.
+ */
+type NullRenderer struct {
+	mutex sync.Mutex
+
+	nextHandle uint32
+	stats      ResourceStats
+	geometries map[uint32]*NullGeometry
+
+	FramebufferWidth  uint32
+	FramebufferHeight uint32
+
+	windowAttachments []*metadata.Texture
+	depthAttachments  []*metadata.Texture
+	attachmentIndex   uint64
+
+	frameNumber   uint64
+	currentFrame  *FrameLog
+	currentPass   string
+	currentShader string
+
+	// The maximum number of frame logs retained. Older ones are dropped. 0 means unlimited.
+	MaxFrameHistory int
+	frames          []*FrameLog
+}
+
+var _ metadata.RendererBackend = (*NullRenderer)(nil)
+
+func New(width, height uint32) *NullRenderer {
+	return &NullRenderer{
+		nextHandle:        1,
+		geometries:        map[uint32]*NullGeometry{},
+		FramebufferWidth:  width,
+		FramebufferHeight: height,
+		MaxFrameHistory:   NULL_DEFAULT_FRAME_HISTORY,
+		frames:            []*FrameLog{},
+	}
+}
+
+func (nr *NullRenderer) Initialize(config *metadata.RendererBackendConfig, windowRenderTargetCount *uint8) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.windowAttachments = make([]*metadata.Texture, NULL_WINDOW_ATTACHMENT_COUNT)
+	nr.depthAttachments = make([]*metadata.Texture, NULL_WINDOW_ATTACHMENT_COUNT)
+	for i := uint8(0); i < NULL_WINDOW_ATTACHMENT_COUNT; i++ {
+		nr.windowAttachments[i] = nr.newAttachment(fmt.Sprintf("__null_window_attachment_%d__", i), 0)
+		nr.depthAttachments[i] = nr.newAttachment(fmt.Sprintf("__null_depth_attachment_%d__", i), metadata.TextureFlagDepth)
+	}
+	*windowRenderTargetCount = NULL_WINDOW_ATTACHMENT_COUNT
+
+	core.LogInfo("Null renderer initialized successfully.")
+	return nil
+}
+
+func (nr *NullRenderer) Shutdown() error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.windowAttachments = nil
+	nr.depthAttachments = nil
+	nr.currentFrame = nil
+	return nil
+}
+
+func (nr *NullRenderer) Resized(width, height uint32) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.FramebufferWidth = width
+	nr.FramebufferHeight = height
+	for i := range nr.windowAttachments {
+		nr.resizeTexture(nr.windowAttachments[i], width, height)
+		nr.resizeTexture(nr.depthAttachments[i], width, height)
+	}
+	return nil
+}
+
+func (nr *NullRenderer) BeginFrame(deltaTime float64) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if nr.currentFrame != nil {
+		return fmt.Errorf("BeginFrame called while frame %d is still in progress", nr.currentFrame.FrameNumber)
+	}
+	nr.frameNumber++
+	nr.currentFrame = &FrameLog{
+		FrameNumber:     nr.frameNumber,
+		DeltaTime:       deltaTime,
+		AttachmentIndex: nr.attachmentIndex,
+		Passes:          []PassRecord{},
+		Shaders:         []ShaderRecord{},
+		Uniforms:        []UniformRecord{},
+		Draws:           []DrawRecord{},
+	}
+	return nil
+}
+
+func (nr *NullRenderer) EndFrame(deltaTime float64) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if nr.currentFrame == nil {
+		return fmt.Errorf("EndFrame called without a matching BeginFrame")
+	}
+	nr.frames = append(nr.frames, nr.currentFrame)
+	if nr.MaxFrameHistory > 0 && len(nr.frames) > nr.MaxFrameHistory {
+		nr.frames = nr.frames[len(nr.frames)-nr.MaxFrameHistory:]
+	}
+	nr.currentFrame = nil
+	nr.currentPass = ""
+	nr.currentShader = ""
+	nr.attachmentIndex = (nr.attachmentIndex + 1) % uint64(NULL_WINDOW_ATTACHMENT_COUNT)
+	return nil
+}
+
+// Frames returns the logs of the retained frames, oldest first.
+func (nr *NullRenderer) Frames() []*FrameLog {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	frames := make([]*FrameLog, len(nr.frames))
+	copy(frames, nr.frames)
+	return frames
+}
+
+// LastFrame returns the log of the most recently completed frame, or nil if none completed yet.
+func (nr *NullRenderer) LastFrame() *FrameLog {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if len(nr.frames) == 0 {
+		return nil
+	}
+	return nr.frames[len(nr.frames)-1]
+}
+
+// ClearFrames drops all the retained frame logs.
+func (nr *NullRenderer) ClearFrames() {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.frames = []*FrameLog{}
+}
+
+// Stats returns the counters of the fake resources currently alive.
+func (nr *NullRenderer) Stats() ResourceStats {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	return nr.stats
+}
+
+func (nr *NullRenderer) TextureCreate(pixels []uint8, texture *metadata.Texture) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	data := make([]uint8, len(pixels))
+	copy(data, pixels)
+	texture.InternalData = &NullTexture{
+		Handle: nr.acquireHandle(),
+		Pixels: data,
+	}
+	texture.Generation++
+	nr.stats.Textures++
+	return nil
+}
+
+func (nr *NullRenderer) TextureDestroy(texture *metadata.Texture) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if texture == nil || texture.InternalData == nil {
+		return nil
+	}
+	texture.InternalData = nil
+	nr.stats.Textures--
+	return nil
+}
+
+func (nr *NullRenderer) TextureCreateWriteable(texture *metadata.Texture) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	texture.InternalData = &NullTexture{
+		Handle: nr.acquireHandle(),
+	}
+	nr.resizeTexture(texture, texture.Width, texture.Height)
+	nr.stats.Textures++
+	return nil
+}
+
+func (nr *NullRenderer) TextureResize(texture *metadata.Texture, newWidth, newHeight uint32) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.resizeTexture(texture, newWidth, newHeight)
+	texture.Generation++
+	return nil
+}
+
+func (nr *NullRenderer) TextureWriteData(texture *metadata.Texture, offset, size uint32, pixels []uint8) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	internal, ok := texture.InternalData.(*NullTexture)
+	if !ok {
+		return fmt.Errorf("texture `%s` has no null renderer internal data", texture.Name)
+	}
+	if int(offset+size) > len(internal.Pixels) {
+		grown := make([]uint8, offset+size)
+		copy(grown, internal.Pixels)
+		internal.Pixels = grown
+	}
+	copy(internal.Pixels[offset:offset+size], pixels)
+	return nil
+}
+
+func (nr *NullRenderer) TextureReadData(texture *metadata.Texture, offset, size uint32) (interface{}, error) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	internal, ok := texture.InternalData.(*NullTexture)
+	if !ok {
+		return nil, fmt.Errorf("texture `%s` has no null renderer internal data", texture.Name)
+	}
+	out := make([]uint8, size)
+	if int(offset) < len(internal.Pixels) {
+		copy(out, internal.Pixels[offset:])
+	}
+	return out, nil
+}
+
+func (nr *NullRenderer) TextureReadPixel(texture *metadata.Texture, x, y uint32) ([]uint8, error) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	// Nothing is ever rasterized, so unknown pixels read back as pure white.
+	out := []uint8{255, 255, 255, 255}
+	internal, ok := texture.InternalData.(*NullTexture)
+	if !ok {
+		return nil, fmt.Errorf("texture `%s` has no null renderer internal data", texture.Name)
+	}
+	channels := uint32(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	index := ((y * texture.Width) + x) * channels
+	if int(index+channels) <= len(internal.Pixels) {
+		copy(out, internal.Pixels[index:index+channels])
+	}
+	return out, nil
+}
+
+func (nr *NullRenderer) TextureMapAcquireResources(textureMap *metadata.TextureMap) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	textureMap.InternalData = nr.acquireHandle()
+	nr.stats.TextureMaps++
+	return nil
+}
+
+func (nr *NullRenderer) TextureMapReleaseResources(textureMap *metadata.TextureMap) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if textureMap == nil || textureMap.InternalData == nil {
+		return nil
+	}
+	textureMap.InternalData = nil
+	nr.stats.TextureMaps--
+	return nil
+}
+
+func (nr *NullRenderer) CreateGeometry(geometry *metadata.Geometry, vertexSize, vertexCount uint32, vertices interface{}, indexSize uint32, indexCount uint32, indices []uint32) error {
+	if vertexCount == 0 || vertices == nil {
+		return fmt.Errorf("CreateGeometry requires vertex data, and none was supplied. VertexCount=%d", vertexCount)
+	}
+
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	handle := nr.acquireHandle()
+	geometry.InternalID = handle
+	nr.geometries[handle] = &NullGeometry{
+		Handle:      handle,
+		VertexCount: vertexCount,
+		IndexCount:  indexCount,
+	}
+	nr.stats.Geometries++
+	return nil
+}
+
+func (nr *NullRenderer) DestroyGeometry(geometry *metadata.Geometry) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if geometry == nil || geometry.InternalID == metadata.InvalidID {
+		return nil
+	}
+	delete(nr.geometries, geometry.InternalID)
+	geometry.InternalID = metadata.InvalidID
+	nr.stats.Geometries--
+	return nil
+}
+
+func (nr *NullRenderer) DrawGeometry(data *metadata.GeometryRenderData) error {
+	// Ignore non-uploaded geometries.
+	if data == nil || data.Geometry == nil || data.Geometry.InternalID == metadata.InvalidID {
+		return nil
+	}
+
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if nr.currentFrame == nil {
+		return fmt.Errorf("DrawGeometry called outside of a frame")
+	}
+
+	record := DrawRecord{
+		Pass:     nr.currentPass,
+		Shader:   nr.currentShader,
+		Geometry: data.Geometry.Name,
+		UniqueID: data.UniqueID,
+	}
+	if data.Geometry.Material != nil {
+		record.Material = data.Geometry.Material.Name
+	}
+	if internal, ok := nr.geometries[data.Geometry.InternalID]; ok {
+		record.VertexCount = internal.VertexCount
+		record.IndexCount = internal.IndexCount
+	}
+	nr.currentFrame.Draws = append(nr.currentFrame.Draws, record)
+	return nil
+}
+
+func (nr *NullRenderer) RenderPassCreate(config *metadata.RenderPassConfig) (*metadata.RenderPass, error) {
+	if config == nil {
+		return nil, fmt.Errorf("renderpass config needs to be a valid pointer")
+	}
+	if config.RenderTargetCount == 0 {
+		return nil, fmt.Errorf("cannot have a renderpass target count of 0")
+	}
+
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	pass := &metadata.RenderPass{
+		ID:   metadata.InvalidIDUint16,
+		Name: config.Name,
+		InternalData: &NullRenderPass{
+			Handle: nr.acquireHandle(),
+			Name:   config.Name,
+		},
+		RenderTargetCount: config.RenderTargetCount,
+		Targets:           make([]*metadata.RenderTarget, config.RenderTargetCount),
+		ClearColour:       config.ClearColour,
+		ClearFlags:        uint8(config.ClearFlags),
+		RenderArea:        config.RenderArea,
+	}
+
+	// Copy over config for each target.
+	for t := 0; t < int(pass.RenderTargetCount); t++ {
+		pass.Targets[t] = &metadata.RenderTarget{
+			AttachmentCount: uint8(len(config.Target.Attachments)),
+			Attachments:     make([]*metadata.RenderTargetAttachment, len(config.Target.Attachments)),
+		}
+		for a, attachmentConfig := range config.Target.Attachments {
+			pass.Targets[t].Attachments[a] = &metadata.RenderTargetAttachment{
+				Source:                     attachmentConfig.Source,
+				RenderTargetAttachmentType: attachmentConfig.RenderTargetAttachmentType,
+				LoadOperation:              attachmentConfig.LoadOperation,
+				StoreOperation:             attachmentConfig.StoreOperation,
+				PresentAfter:               attachmentConfig.PresentAfter,
+			}
+		}
+	}
+	nr.stats.RenderPasses++
+	return pass, nil
+}
+
+func (nr *NullRenderer) RenderPassDestroy(pass *metadata.RenderPass) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if pass == nil || pass.InternalData == nil {
+		return nil
+	}
+	pass.InternalData = nil
+	nr.stats.RenderPasses--
+	return nil
+}
+
+func (nr *NullRenderer) RenderPassBegin(pass *metadata.RenderPass, target *metadata.RenderTarget) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if nr.currentFrame == nil {
+		return fmt.Errorf("RenderPassBegin called outside of a frame")
+	}
+	if pass == nil || target == nil {
+		return fmt.Errorf("RenderPassBegin requires a valid pass and target")
+	}
+	if nr.currentPass != "" {
+		return fmt.Errorf("renderpass `%s` begun while `%s` is still active", pass.Name, nr.currentPass)
+	}
+
+	handle, _ := target.InternalFramebuffer.(uint32)
+	nr.currentPass = pass.Name
+	nr.currentFrame.Passes = append(nr.currentFrame.Passes, PassRecord{
+		Name:   pass.Name,
+		Target: handle,
+	})
+	return nil
+}
+
+func (nr *NullRenderer) RenderPassEnd(pass *metadata.RenderPass) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.currentPass = ""
+	nr.currentShader = ""
+	return nil
+}
+
+func (nr *NullRenderer) RenderTargetCreate(attachmentCount uint8, attachments []*metadata.RenderTargetAttachment, pass *metadata.RenderPass, width, height uint32) (*metadata.RenderTarget, error) {
+	if int(attachmentCount) != len(attachments) {
+		return nil, fmt.Errorf("attachments are not correct")
+	}
+
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.stats.RenderTargets++
+	return &metadata.RenderTarget{
+		AttachmentCount:     attachmentCount,
+		Attachments:         attachments,
+		InternalFramebuffer: nr.acquireHandle(),
+	}, nil
+}
+
+func (nr *NullRenderer) RenderTargetDestroy(target *metadata.RenderTarget, freeInternalMemory bool) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if target == nil || target.InternalFramebuffer == nil {
+		return nil
+	}
+	target.InternalFramebuffer = nil
+	if freeInternalMemory {
+		target.Attachments = nil
+		target.AttachmentCount = 0
+	}
+	nr.stats.RenderTargets--
+	return nil
+}
+
+func (nr *NullRenderer) ShaderCreate(shader *metadata.Shader, config *metadata.ShaderConfig, pass *metadata.RenderPass, stageCount uint8, stageFilenames []string, stages []metadata.ShaderStage) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	shader.InternalData = &NullShader{
+		Handle:       nr.acquireHandle(),
+		Config:       config,
+		UniformNames: map[uint16]string{},
+		Instances:    map[uint32]bool{},
+	}
+	nr.stats.Shaders++
+	return nil
+}
+
+func (nr *NullRenderer) ShaderDestroy(shader *metadata.Shader) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if shader == nil || shader.InternalData == nil {
+		return nil
+	}
+	shader.InternalData = nil
+	nr.stats.Shaders--
+	return nil
+}
+
+func (nr *NullRenderer) ShaderInitialize(shader *metadata.Shader) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*NullShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no null renderer internal data", shader.Name)
+	}
+
+	for name, index := range shader.UniformLookup {
+		internal.UniformNames[index] = name
+	}
+
+	// Make sure the UBO is aligned the same way a real device would require.
+	shader.RequiredUboAlignment = NULL_REQUIRED_UBO_ALIGNMENT
+	shader.GlobalUboStride = metadata.GetAligned(shader.GlobalUboSize, shader.RequiredUboAlignment)
+	shader.UboStride = metadata.GetAligned(shader.UboSize, shader.RequiredUboAlignment)
+	return nil
+}
+
+func (nr *NullRenderer) ShaderUse(shader *metadata.Shader) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.currentShader = shader.Name
+	if nr.currentFrame == nil {
+		return nil
+	}
+	nr.currentFrame.Shaders = append(nr.currentFrame.Shaders, ShaderRecord{
+		Pass:   nr.currentPass,
+		Shader: shader.Name,
+	})
+	return nil
+}
+
+func (nr *NullRenderer) ShaderBindGlobals(shader *metadata.Shader) error {
+	if shader == nil {
+		return fmt.Errorf("shader is nil")
+	}
+	shader.BoundUboOffset = uint32(shader.GlobalUboOffset)
+	return nil
+}
+
+func (nr *NullRenderer) ShaderBindInstance(shader *metadata.Shader, instanceID uint32) error {
+	if shader == nil {
+		return fmt.Errorf("shader is nil")
+	}
+	shader.BoundInstanceID = instanceID
+	shader.BoundUboOffset = uint32(shader.GlobalUboStride + (shader.UboStride * uint64(instanceID)))
+	return nil
+}
+
+func (nr *NullRenderer) ShaderApplyGlobals(shader *metadata.Shader) error {
+	return nil
+}
+
+func (nr *NullRenderer) ShaderApplyInstance(shader *metadata.Shader, needsUpdate bool) error {
+	return nil
+}
+
+func (nr *NullRenderer) ShaderAcquireInstanceResources(shader *metadata.Shader, maps []*metadata.TextureMap) (uint32, error) {
+	if shader == nil {
+		return 0, nil
+	}
+
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*NullShader)
+	if !ok {
+		return 0, fmt.Errorf("shader `%s` has no null renderer internal data", shader.Name)
+	}
+	id := internal.nextInstance
+	internal.nextInstance++
+	internal.Instances[id] = true
+	return id, nil
+}
+
+func (nr *NullRenderer) ShaderReleaseInstanceResources(shader *metadata.Shader, instanceID uint32) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*NullShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no null renderer internal data", shader.Name)
+	}
+	delete(internal.Instances, instanceID)
+	return nil
+}
+
+func (nr *NullRenderer) SetUniform(shader *metadata.Shader, uniform metadata.ShaderUniform, value interface{}) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if nr.currentFrame == nil {
+		// Uniforms may be set up front (i.e. default values), nothing to record.
+		return nil
+	}
+	name := ""
+	if internal, ok := shader.InternalData.(*NullShader); ok {
+		name = internal.UniformNames[uniform.Index]
+	}
+	nr.currentFrame.Uniforms = append(nr.currentFrame.Uniforms, UniformRecord{
+		Pass:       nr.currentPass,
+		Shader:     shader.Name,
+		Name:       name,
+		Scope:      uniform.Scope,
+		InstanceID: shader.BoundInstanceID,
+		Value:      value,
+	})
+	return nil
+}
+
+func (nr *NullRenderer) RenderBufferCreate(renderbufferType metadata.RenderBufferType, totalSize uint64) (*metadata.RenderBuffer, error) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	nr.stats.RenderBuffers++
+	return &metadata.RenderBuffer{
+		RenderBufferType: renderbufferType,
+		TotalSize:        totalSize,
+		InternalData: &NullBuffer{
+			Handle: nr.acquireHandle(),
+			Data:   make([]byte, totalSize),
+		},
+	}, nil
+}
+
+func (nr *NullRenderer) RenderBufferDestroy(buffer *metadata.RenderBuffer) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+
+	if buffer == nil || buffer.InternalData == nil {
+		return
+	}
+	buffer.InternalData = nil
+	nr.stats.RenderBuffers--
+}
+
+func (nr *NullRenderer) RenderBufferBind(buffer *metadata.RenderBuffer, offset uint64) error {
+	if buffer == nil {
+		return fmt.Errorf("buffer cannot be nil")
+	}
+	return nil
+}
+
+func (nr *NullRenderer) RenderBufferUnbind(buffer *metadata.RenderBuffer) bool {
+	return buffer != nil
+}
+
+func (nr *NullRenderer) RenderBufferMapMemory(buffer *metadata.RenderBuffer, offset, size uint64) (interface{}, error) {
+	internal, err := nr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	return internal.Data[offset : offset+size], nil
+}
+
+func (nr *NullRenderer) RenderBufferUnmapMemory(buffer *metadata.RenderBuffer, offset, size uint64) error {
+	_, err := nr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (nr *NullRenderer) RenderBufferFlush(buffer *metadata.RenderBuffer, offset, size uint64) error {
+	_, err := nr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (nr *NullRenderer) RenderBufferRead(buffer *metadata.RenderBuffer, offset, size uint64) (interface{}, error) {
+	internal, err := nr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, size)
+	copy(out, internal.Data[offset:offset+size])
+	return out, nil
+}
+
+func (nr *NullRenderer) RenderBufferResize(buffer *metadata.RenderBuffer, newTotalSize uint64) error {
+	internal, ok := buffer.InternalData.(*NullBuffer)
+	if !ok {
+		return fmt.Errorf("renderbuffer has no null renderer internal data")
+	}
+	data := make([]byte, newTotalSize)
+	copy(data, internal.Data)
+	internal.Data = data
+	buffer.TotalSize = newTotalSize
+	return nil
+}
+
+func (nr *NullRenderer) RenderBufferFree(buffer *metadata.RenderBuffer, size, offset uint64) error {
+	_, err := nr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (nr *NullRenderer) RenderBufferLoadRange(buffer *metadata.RenderBuffer, offset, size uint64, data interface{}) error {
+	internal, err := nr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return err
+	}
+	// Only raw bytes can be copied verbatim, anything else is just accepted.
+	if b, ok := data.([]byte); ok {
+		copy(internal.Data[offset:offset+size], b)
+	}
+	return nil
+}
+
+func (nr *NullRenderer) RenderBufferCopyRange(source *metadata.RenderBuffer, sourceOffset uint64, dest *metadata.RenderBuffer, destOffset uint64, size uint64) error {
+	src, err := nr.bufferRange(source, sourceOffset, size)
+	if err != nil {
+		return err
+	}
+	dst, err := nr.bufferRange(dest, destOffset, size)
+	if err != nil {
+		return err
+	}
+	copy(dst.Data[destOffset:destOffset+size], src.Data[sourceOffset:sourceOffset+size])
+	return nil
+}
+
+func (nr *NullRenderer) RenderBufferDraw(buffer *metadata.RenderBuffer, offset uint64, elementCount uint32, bindOnly bool) error {
+	_, err := nr.bufferRange(buffer, offset, 0)
+	return err
+}
+
+func (nr *NullRenderer) WindowAttachmentGet(index uint8) *metadata.Texture {
+	if int(index) >= len(nr.windowAttachments) {
+		core.LogFatal("attempting to get colour attachment index out of range: %d. Attachment count: %d", index, len(nr.windowAttachments))
+		return nil
+	}
+	return nr.windowAttachments[index]
+}
+
+func (nr *NullRenderer) WindowAttachmentIndexGet() uint64 {
+	return nr.attachmentIndex
+}
+
+func (nr *NullRenderer) DepthAttachmentGet(index uint8) *metadata.Texture {
+	if int(index) >= len(nr.depthAttachments) {
+		core.LogFatal("attempting to get depth attachment index out of range: %d. Attachment count: %d", index, len(nr.depthAttachments))
+		return nil
+	}
+	return nr.depthAttachments[index]
+}
+
+func (nr *NullRenderer) GetWindowAttachmentCount() uint8 {
+	return NULL_WINDOW_ATTACHMENT_COUNT
+}
+
+func (nr *NullRenderer) IsMultithreaded() bool {
+	return false
+}
+
+// acquireHandle returns a new fake handle. Must be called with the mutex held.
+func (nr *NullRenderer) acquireHandle() uint32 {
+	handle := nr.nextHandle
+	nr.nextHandle++
+	return handle
+}
+
+func (nr *NullRenderer) newAttachment(name string, flags metadata.TextureFlag) *metadata.Texture {
+	texture := &metadata.Texture{
+		ID:           metadata.InvalidID,
+		TextureType:  metadata.TextureType2d,
+		Name:         name,
+		ChannelCount: 4,
+		Flags:        metadata.TextureFlagBits(metadata.TextureFlagIsWriteable | metadata.TextureFlagIsWrapped | flags),
+		InternalData: &NullTexture{Handle: nr.acquireHandle()},
+	}
+	nr.resizeTexture(texture, nr.FramebufferWidth, nr.FramebufferHeight)
+	return texture
+}
+
+// resizeTexture reallocates the pixels of the texture, cleared to white.
+func (nr *NullRenderer) resizeTexture(texture *metadata.Texture, width, height uint32) {
+	texture.Width = width
+	texture.Height = height
+	internal, ok := texture.InternalData.(*NullTexture)
+	if !ok {
+		return
+	}
+	channels := uint32(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	internal.Pixels = make([]uint8, width*height*channels)
+	for i := range internal.Pixels {
+		internal.Pixels[i] = 255
+	}
+}
+
+func (nr *NullRenderer) bufferRange(buffer *metadata.RenderBuffer, offset, size uint64) (*NullBuffer, error) {
+	if buffer == nil {
+		return nil, fmt.Errorf("buffer cannot be nil")
+	}
+	internal, ok := buffer.InternalData.(*NullBuffer)
+	if !ok {
+		return nil, fmt.Errorf("renderbuffer has no null renderer internal data")
+	}
+	if offset+size > uint64(len(internal.Data)) {
+		return nil, fmt.Errorf("range %d-%d is out of the bounds of the renderbuffer (size %d)", offset, offset+size, len(internal.Data))
+	}
+	return internal, nil
+}
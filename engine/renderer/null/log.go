@@ -0,0 +1,129 @@
+package null
+
+import "github.com/spaghettifunk/anima/engine/renderer/metadata"
+
+/** @brief A renderpass begun during a frame. */
+type PassRecord struct {
+	/** @brief The name of the renderpass. */
+	Name string
+	/** @brief The fake handle of the render target the pass was begun with. */
+	Target uint32
+}
+
+/** @brief A shader bound (used) during a frame. */
+type ShaderRecord struct {
+	/** @brief The renderpass active when the shader was bound. */
+	Pass string
+	/** @brief The name of the shader. */
+	Shader string
+}
+
+/** @brief A uniform set during a frame. */
+type UniformRecord struct {
+	/** @brief The renderpass active when the uniform was set. */
+	Pass string
+	/** @brief The name of the shader owning the uniform. */
+	Shader string
+	/** @brief The name of the uniform. */
+	Name string
+	/** @brief The scope of the uniform. */
+	Scope metadata.ShaderScope
+	/** @brief The instance bound when the uniform was set. Only meaningful for instance scope. */
+	InstanceID uint32
+	/** @brief The value the uniform was set to. */
+	Value interface{}
+}
+
+/** @brief A draw issued during a frame. */
+type DrawRecord struct {
+	/** @brief The renderpass active when the draw was issued. */
+	Pass string
+	/** @brief The shader bound when the draw was issued. */
+	Shader string
+	/** @brief The name of the geometry drawn. */
+	Geometry string
+	/** @brief The name of the material of the geometry, if any. */
+	Material string
+	/** @brief The unique identifier passed along with the render data. */
+	UniqueID uint32
+	/** @brief The number of vertices drawn. */
+	VertexCount uint32
+	/** @brief The number of indices drawn. */
+	IndexCount uint32
+}
+
+/**
+ * @brief The structured log of everything that has been submitted
+ * to the null renderer between a BeginFrame and an EndFrame.
+ */
+type FrameLog struct {
+	/** @brief The index of the frame, starting from 1. */
+	FrameNumber uint64
+	/** @brief The delta time the frame was begun with. */
+	DeltaTime float64
+	/** @brief The window attachment index used for the frame. */
+	AttachmentIndex uint64
+	/** @brief The renderpasses begun, in order. */
+	Passes []PassRecord
+	/** @brief The shaders bound, in order. */
+	Shaders []ShaderRecord
+	/** @brief The uniforms set, in order. */
+	Uniforms []UniformRecord
+	/** @brief The draws issued, in order. */
+	Draws []DrawRecord
+}
+
+// DrawCount returns the amount of draws issued in the given pass with the given
+// shader. An empty pass or shader name matches any.
+func (f *FrameLog) DrawCount(pass, shader string) int {
+	count := 0
+	for _, d := range f.Draws {
+		if (pass == "" || d.Pass == pass) && (shader == "" || d.Shader == shader) {
+			count++
+		}
+	}
+	return count
+}
+
+// PassBegun reports whether the renderpass with the given name was begun in this frame.
+func (f *FrameLog) PassBegun(pass string) bool {
+	for _, p := range f.Passes {
+		if p.Name == pass {
+			return true
+		}
+	}
+	return false
+}
+
+// ShaderBound reports whether the shader with the given name was bound in this frame.
+func (f *FrameLog) ShaderBound(shader string) bool {
+	for _, s := range f.Shaders {
+		if s.Shader == shader {
+			return true
+		}
+	}
+	return false
+}
+
+// UniformValues returns all the values set for the named uniform of the named
+// shader, in the order they were set.
+func (f *FrameLog) UniformValues(shader, name string) []interface{} {
+	values := []interface{}{}
+	for _, u := range f.Uniforms {
+		if u.Shader == shader && u.Name == name {
+			values = append(values, u.Value)
+		}
+	}
+	return values
+}
+
+/** @brief Counters for the fake resources currently alive in the null renderer. */
+type ResourceStats struct {
+	Textures      uint32
+	Geometries    uint32
+	Shaders       uint32
+	RenderPasses  uint32
+	RenderTargets uint32
+	RenderBuffers uint32
+	TextureMaps   uint32
+}
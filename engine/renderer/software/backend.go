@@ -0,0 +1,1046 @@
+package software
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+const (
+	// The software renderer draws straight into a single window attachment.
+	SOFTWARE_WINDOW_ATTACHMENT_COUNT uint8 = 1
+	// The UBO alignment reported to the shaders.
+	SOFTWARE_REQUIRED_UBO_ALIGNMENT uint64 = 256
+)
+
+/** @brief Internal data of a texture created by the software renderer. */
+type SoftwareTexture struct {
+	Handle uint32
+	/** @brief The pixels of the texture. Cube maps hold their 6 faces one after the other. */
+	Pixels []uint8
+	/** @brief The depth values, only allocated for depth textures. */
+	Depth []float32
+}
+
+/** @brief Internal data of a geometry uploaded to the software renderer. */
+type SoftwareGeometry struct {
+	Handle   uint32
+	Vertices []math.Vertex3D
+	Indices  []uint32
+}
+
+/** @brief The uniform values of a single shader instance. */
+type SoftwareShaderInstance struct {
+	Values map[string]interface{}
+}
+
+/** @brief Internal data of a shader created by the software renderer. */
+type SoftwareShader struct {
+	Handle  uint32
+	Config  *metadata.ShaderConfig
+	Program ShadingProgram
+
+	UniformNames map[uint16]string
+	Globals      map[string]interface{}
+	Locals       map[string]interface{}
+	Instances    map[uint32]*SoftwareShaderInstance
+	nextInstance uint32
+
+	// The names of the instance samplers, ordered by location.
+	instanceSamplers []string
+}
+
+/** @brief Internal data of a renderbuffer created by the software renderer. */
+type SoftwareBuffer struct {
+	Handle uint32
+	Data   []byte
+	// Typed copies of the loaded data, used by RenderBufferDraw.
+	Vertices []math.Vertex3D
+	Indices  []uint32
+}
+
+/** @brief The attachments a render target draws into. */
+type softwareFramebuffer struct {
+	colour *metadata.Texture
+	depth  *metadata.Texture
+}
+
+/**
+ * @brief A renderer backend which rasterizes everything on the CPU.
+ * The built-in shaders are re-implemented in Go (see ShadingProgram),
+ * which makes it possible to render scenes and take screenshots on
+ * machines without a GPU.
+ */
+type SoftwareRenderer struct {
+	mutex sync.Mutex
+
+	nextHandle uint32
+	geometries map[uint32]*SoftwareGeometry
+
+	FramebufferWidth  uint32
+	FramebufferHeight uint32
+
+	colourAttachment *metadata.Texture
+	depthAttachment  *metadata.Texture
+
+	frameActive   bool
+	currentPass   *metadata.RenderPass
+	currentTarget *softwareFramebuffer
+	currentShader *metadata.Shader
+	vertexBuffer  *SoftwareBuffer
+
+	// The image presented at the end of the last frame.
+	presented *image.RGBA
+}
+
+var _ metadata.RendererBackend = (*SoftwareRenderer)(nil)
+
+func New(width, height uint32) *SoftwareRenderer {
+	return &SoftwareRenderer{
+		nextHandle:        1,
+		geometries:        map[uint32]*SoftwareGeometry{},
+		FramebufferWidth:  width,
+		FramebufferHeight: height,
+	}
+}
+
+func (sr *SoftwareRenderer) Initialize(config *metadata.RendererBackendConfig, windowRenderTargetCount *uint8) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.colourAttachment = sr.newAttachment("__software_window_attachment__", 0)
+	sr.depthAttachment = sr.newAttachment("__software_depth_attachment__", metadata.TextureFlagDepth)
+	*windowRenderTargetCount = SOFTWARE_WINDOW_ATTACHMENT_COUNT
+
+	core.LogInfo("Software renderer initialized successfully.")
+	return nil
+}
+
+func (sr *SoftwareRenderer) Shutdown() error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.colourAttachment = nil
+	sr.depthAttachment = nil
+	sr.presented = nil
+	return nil
+}
+
+func (sr *SoftwareRenderer) Resized(width, height uint32) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.FramebufferWidth = width
+	sr.FramebufferHeight = height
+	if sr.colourAttachment != nil {
+		sr.resizeTexture(sr.colourAttachment, width, height)
+		sr.resizeTexture(sr.depthAttachment, width, height)
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) BeginFrame(deltaTime float64) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.frameActive {
+		return fmt.Errorf("BeginFrame called while a frame is still in progress")
+	}
+	sr.frameActive = true
+	return nil
+}
+
+func (sr *SoftwareRenderer) EndFrame(deltaTime float64) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if !sr.frameActive {
+		return fmt.Errorf("EndFrame called without a matching BeginFrame")
+	}
+	sr.frameActive = false
+	sr.currentPass = nil
+	sr.currentTarget = nil
+	sr.currentShader = nil
+	sr.vertexBuffer = nil
+
+	// "Present" the window attachment.
+	sr.presented = toImage(sr.colourAttachment)
+	return nil
+}
+
+// Screenshot returns a copy of the image presented at the end of the last frame,
+// or nil if no frame completed yet.
+func (sr *SoftwareRenderer) Screenshot() *image.RGBA {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.presented == nil {
+		return nil
+	}
+	out := image.NewRGBA(sr.presented.Rect)
+	copy(out.Pix, sr.presented.Pix)
+	return out
+}
+
+// SaveScreenshot writes the image presented at the end of the last frame as a PNG file.
+func (sr *SoftwareRenderer) SaveScreenshot(path string) error {
+	img := sr.Screenshot()
+	if img == nil {
+		return fmt.Errorf("no frame has been rendered yet")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// TextureImage returns the contents of the given texture as an image. Useful
+// to inspect offscreen render targets, such as the ones of the pick views.
+func (sr *SoftwareRenderer) TextureImage(texture *metadata.Texture) *image.RGBA {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	return toImage(texture)
+}
+
+func (sr *SoftwareRenderer) TextureCreate(pixels []uint8, texture *metadata.Texture) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	data := make([]uint8, len(pixels))
+	copy(data, pixels)
+	texture.InternalData = &SoftwareTexture{
+		Handle: sr.acquireHandle(),
+		Pixels: data,
+	}
+	texture.Generation++
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureDestroy(texture *metadata.Texture) error {
+	if texture != nil {
+		texture.InternalData = nil
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureCreateWriteable(texture *metadata.Texture) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	texture.InternalData = &SoftwareTexture{
+		Handle: sr.acquireHandle(),
+	}
+	sr.resizeTexture(texture, texture.Width, texture.Height)
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureResize(texture *metadata.Texture, newWidth, newHeight uint32) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.resizeTexture(texture, newWidth, newHeight)
+	texture.Generation++
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureWriteData(texture *metadata.Texture, offset, size uint32, pixels []uint8) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return fmt.Errorf("texture `%s` has no software renderer internal data", texture.Name)
+	}
+	if int(offset+size) > len(internal.Pixels) {
+		grown := make([]uint8, offset+size)
+		copy(grown, internal.Pixels)
+		internal.Pixels = grown
+	}
+	copy(internal.Pixels[offset:offset+size], pixels)
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureReadData(texture *metadata.Texture, offset, size uint32) (interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return nil, fmt.Errorf("texture `%s` has no software renderer internal data", texture.Name)
+	}
+	out := make([]uint8, size)
+	if int(offset) < len(internal.Pixels) {
+		copy(out, internal.Pixels[offset:])
+	}
+	return out, nil
+}
+
+func (sr *SoftwareRenderer) TextureReadPixel(texture *metadata.Texture, x, y uint32) ([]uint8, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return nil, fmt.Errorf("texture `%s` has no software renderer internal data", texture.Name)
+	}
+	if x >= texture.Width || y >= texture.Height {
+		return nil, fmt.Errorf("pixel %d,%d is out of the bounds of texture `%s`", x, y, texture.Name)
+	}
+	channels := uint32(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	out := []uint8{0, 0, 0, 255}
+	index := ((y * texture.Width) + x) * channels
+	if int(index+channels) <= len(internal.Pixels) {
+		copy(out, internal.Pixels[index:index+channels])
+	}
+	return out, nil
+}
+
+func (sr *SoftwareRenderer) TextureMapAcquireResources(textureMap *metadata.TextureMap) error {
+	// Sampling reads the map settings directly, nothing to acquire.
+	return nil
+}
+
+func (sr *SoftwareRenderer) TextureMapReleaseResources(textureMap *metadata.TextureMap) error {
+	return nil
+}
+
+func (sr *SoftwareRenderer) CreateGeometry(geometry *metadata.Geometry, vertexSize, vertexCount uint32, vertices interface{}, indexSize uint32, indexCount uint32, indices []uint32) error {
+	if vertexCount == 0 || vertices == nil {
+		return fmt.Errorf("CreateGeometry requires vertex data, and none was supplied. VertexCount=%d", vertexCount)
+	}
+
+	converted, err := toVertex3D(vertices)
+	if err != nil {
+		return err
+	}
+	if int(vertexCount) < len(converted) {
+		converted = converted[:vertexCount]
+	}
+	copiedIndices := make([]uint32, indexCount)
+	copy(copiedIndices, indices)
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	handle := sr.acquireHandle()
+	geometry.InternalID = handle
+	sr.geometries[handle] = &SoftwareGeometry{
+		Handle:   handle,
+		Vertices: converted,
+		Indices:  copiedIndices,
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) DestroyGeometry(geometry *metadata.Geometry) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if geometry == nil || geometry.InternalID == metadata.InvalidID {
+		return nil
+	}
+	delete(sr.geometries, geometry.InternalID)
+	geometry.InternalID = metadata.InvalidID
+	return nil
+}
+
+func (sr *SoftwareRenderer) DrawGeometry(data *metadata.GeometryRenderData) error {
+	// Ignore non-uploaded geometries.
+	if data == nil || data.Geometry == nil || data.Geometry.InternalID == metadata.InvalidID {
+		return nil
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := sr.geometries[data.Geometry.InternalID]
+	if !ok {
+		return fmt.Errorf("geometry `%s` has no software renderer internal data", data.Geometry.Name)
+	}
+	return sr.draw(internal.Vertices, internal.Indices)
+}
+
+func (sr *SoftwareRenderer) RenderPassCreate(config *metadata.RenderPassConfig) (*metadata.RenderPass, error) {
+	if config == nil {
+		return nil, fmt.Errorf("renderpass config needs to be a valid pointer")
+	}
+	if config.RenderTargetCount == 0 {
+		return nil, fmt.Errorf("cannot have a renderpass target count of 0")
+	}
+
+	pass := &metadata.RenderPass{
+		ID:                metadata.InvalidIDUint16,
+		Name:              config.Name,
+		RenderTargetCount: config.RenderTargetCount,
+		Targets:           make([]*metadata.RenderTarget, config.RenderTargetCount),
+		ClearColour:       config.ClearColour,
+		ClearFlags:        uint8(config.ClearFlags),
+		RenderArea:        config.RenderArea,
+	}
+
+	// Copy over config for each target.
+	for t := 0; t < int(pass.RenderTargetCount); t++ {
+		pass.Targets[t] = &metadata.RenderTarget{
+			AttachmentCount: uint8(len(config.Target.Attachments)),
+			Attachments:     make([]*metadata.RenderTargetAttachment, len(config.Target.Attachments)),
+		}
+		for a, attachmentConfig := range config.Target.Attachments {
+			pass.Targets[t].Attachments[a] = &metadata.RenderTargetAttachment{
+				Source:                     attachmentConfig.Source,
+				RenderTargetAttachmentType: attachmentConfig.RenderTargetAttachmentType,
+				LoadOperation:              attachmentConfig.LoadOperation,
+				StoreOperation:             attachmentConfig.StoreOperation,
+				PresentAfter:               attachmentConfig.PresentAfter,
+			}
+		}
+	}
+	return pass, nil
+}
+
+func (sr *SoftwareRenderer) RenderPassDestroy(pass *metadata.RenderPass) error {
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderPassBegin(pass *metadata.RenderPass, target *metadata.RenderTarget) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if !sr.frameActive {
+		return fmt.Errorf("RenderPassBegin called outside of a frame")
+	}
+	if pass == nil || target == nil {
+		return fmt.Errorf("RenderPassBegin requires a valid pass and target")
+	}
+	framebuffer, ok := target.InternalFramebuffer.(*softwareFramebuffer)
+	if !ok {
+		return fmt.Errorf("render target of renderpass `%s` has no software renderer framebuffer", pass.Name)
+	}
+
+	sr.currentPass = pass
+	sr.currentTarget = framebuffer
+
+	if pass.ClearFlags&uint8(metadata.RENDERPASS_CLEAR_COLOUR_BUFFER_FLAG) != 0 && framebuffer.colour != nil {
+		clearColour(framebuffer.colour, pass.ClearColour)
+	}
+	if pass.ClearFlags&uint8(metadata.RENDERPASS_CLEAR_DEPTH_BUFFER_FLAG) != 0 && framebuffer.depth != nil {
+		if internal, ok := framebuffer.depth.InternalData.(*SoftwareTexture); ok {
+			for i := range internal.Depth {
+				internal.Depth[i] = 1.0
+			}
+		}
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderPassEnd(pass *metadata.RenderPass) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.currentPass = nil
+	sr.currentTarget = nil
+	sr.currentShader = nil
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderTargetCreate(attachmentCount uint8, attachments []*metadata.RenderTargetAttachment, pass *metadata.RenderPass, width, height uint32) (*metadata.RenderTarget, error) {
+	if int(attachmentCount) != len(attachments) {
+		return nil, fmt.Errorf("attachments are not correct")
+	}
+
+	framebuffer := &softwareFramebuffer{}
+	for _, attachment := range attachments {
+		if attachment == nil || attachment.Texture == nil {
+			continue
+		}
+		switch attachment.RenderTargetAttachmentType {
+		case metadata.RENDER_TARGET_ATTACHMENT_TYPE_COLOUR:
+			framebuffer.colour = attachment.Texture
+		case metadata.RENDER_TARGET_ATTACHMENT_TYPE_DEPTH:
+			framebuffer.depth = attachment.Texture
+		}
+	}
+
+	return &metadata.RenderTarget{
+		AttachmentCount:     attachmentCount,
+		Attachments:         attachments,
+		InternalFramebuffer: framebuffer,
+	}, nil
+}
+
+func (sr *SoftwareRenderer) RenderTargetDestroy(target *metadata.RenderTarget, freeInternalMemory bool) error {
+	if target == nil {
+		return nil
+	}
+	target.InternalFramebuffer = nil
+	if freeInternalMemory {
+		target.Attachments = nil
+		target.AttachmentCount = 0
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderCreate(shader *metadata.Shader, config *metadata.ShaderConfig, pass *metadata.RenderPass, stageCount uint8, stageFilenames []string, stages []metadata.ShaderStage) error {
+	program := newProgram(config.Name)
+	if program == nil {
+		core.LogWarn("no software shading program registered for shader `%s`, draws using it will be skipped", config.Name)
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	shader.InternalData = &SoftwareShader{
+		Handle:       sr.acquireHandle(),
+		Config:       config,
+		Program:      program,
+		UniformNames: map[uint16]string{},
+		Globals:      map[string]interface{}{},
+		Locals:       map[string]interface{}{},
+		Instances:    map[uint32]*SoftwareShaderInstance{},
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderDestroy(shader *metadata.Shader) error {
+	if shader != nil {
+		shader.InternalData = nil
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderInitialize(shader *metadata.Shader) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*SoftwareShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no software renderer internal data", shader.Name)
+	}
+
+	type sampler struct {
+		name     string
+		location uint16
+	}
+	samplers := []sampler{}
+	for name, index := range shader.UniformLookup {
+		internal.UniformNames[index] = name
+		if int(index) < len(shader.Uniforms) {
+			uniform := shader.Uniforms[index]
+			if uniform.ShaderUniformType == metadata.ShaderUniformTypeSampler && uniform.Scope == metadata.ShaderScopeInstance {
+				samplers = append(samplers, sampler{name: name, location: uniform.Location})
+			}
+		}
+	}
+	sort.Slice(samplers, func(i, j int) bool { return samplers[i].location < samplers[j].location })
+	internal.instanceSamplers = make([]string, len(samplers))
+	for i, s := range samplers {
+		internal.instanceSamplers[i] = s.name
+	}
+
+	// Make sure the UBO is aligned the same way a real device would require.
+	shader.RequiredUboAlignment = SOFTWARE_REQUIRED_UBO_ALIGNMENT
+	shader.GlobalUboStride = metadata.GetAligned(shader.GlobalUboSize, shader.RequiredUboAlignment)
+	shader.UboStride = metadata.GetAligned(shader.UboSize, shader.RequiredUboAlignment)
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderUse(shader *metadata.Shader) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.currentShader = shader
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderBindGlobals(shader *metadata.Shader) error {
+	if shader == nil {
+		return fmt.Errorf("shader is nil")
+	}
+	shader.BoundUboOffset = uint32(shader.GlobalUboOffset)
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderBindInstance(shader *metadata.Shader, instanceID uint32) error {
+	if shader == nil {
+		return fmt.Errorf("shader is nil")
+	}
+	shader.BoundInstanceID = instanceID
+	shader.BoundUboOffset = uint32(shader.GlobalUboStride + (shader.UboStride * uint64(instanceID)))
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderApplyGlobals(shader *metadata.Shader) error {
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderApplyInstance(shader *metadata.Shader, needsUpdate bool) error {
+	return nil
+}
+
+func (sr *SoftwareRenderer) ShaderAcquireInstanceResources(shader *metadata.Shader, maps []*metadata.TextureMap) (uint32, error) {
+	if shader == nil {
+		return 0, nil
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*SoftwareShader)
+	if !ok {
+		return 0, fmt.Errorf("shader `%s` has no software renderer internal data", shader.Name)
+	}
+	id := internal.nextInstance
+	internal.nextInstance++
+
+	instance := &SoftwareShaderInstance{
+		Values: map[string]interface{}{},
+	}
+	// The provided maps are the defaults of the instance samplers, in order.
+	for i, name := range internal.instanceSamplers {
+		if i < len(maps) && maps[i] != nil {
+			instance.Values[name] = maps[i]
+		}
+	}
+	internal.Instances[id] = instance
+	return id, nil
+}
+
+func (sr *SoftwareRenderer) ShaderReleaseInstanceResources(shader *metadata.Shader, instanceID uint32) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*SoftwareShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no software renderer internal data", shader.Name)
+	}
+	delete(internal.Instances, instanceID)
+	return nil
+}
+
+func (sr *SoftwareRenderer) SetUniform(shader *metadata.Shader, uniform metadata.ShaderUniform, value interface{}) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	internal, ok := shader.InternalData.(*SoftwareShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no software renderer internal data", shader.Name)
+	}
+	name, ok := internal.UniformNames[uniform.Index]
+	if !ok {
+		return fmt.Errorf("shader `%s` has no uniform with index %d", shader.Name, uniform.Index)
+	}
+
+	if uniform.ShaderUniformType == metadata.ShaderUniformTypeSampler && uniform.Scope == metadata.ShaderScopeGlobal {
+		if textureMap, ok := value.(*metadata.TextureMap); ok && int(uniform.Location) < len(shader.GlobalTextureMaps) {
+			shader.GlobalTextureMaps[uniform.Location] = textureMap
+		}
+	}
+
+	switch uniform.Scope {
+	case metadata.ShaderScopeGlobal:
+		internal.Globals[name] = value
+	case metadata.ShaderScopeInstance:
+		instance, ok := internal.Instances[shader.BoundInstanceID]
+		if !ok {
+			return fmt.Errorf("shader `%s` has no instance with id %d", shader.Name, shader.BoundInstanceID)
+		}
+		instance.Values[name] = value
+	default:
+		internal.Locals[name] = value
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferCreate(renderbufferType metadata.RenderBufferType, totalSize uint64) (*metadata.RenderBuffer, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	return &metadata.RenderBuffer{
+		RenderBufferType: renderbufferType,
+		TotalSize:        totalSize,
+		InternalData: &SoftwareBuffer{
+			Handle: sr.acquireHandle(),
+			Data:   make([]byte, totalSize),
+		},
+	}, nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferDestroy(buffer *metadata.RenderBuffer) {
+	if buffer != nil {
+		buffer.InternalData = nil
+	}
+}
+
+func (sr *SoftwareRenderer) RenderBufferBind(buffer *metadata.RenderBuffer, offset uint64) error {
+	if buffer == nil {
+		return fmt.Errorf("buffer cannot be nil")
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferUnbind(buffer *metadata.RenderBuffer) bool {
+	return buffer != nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferMapMemory(buffer *metadata.RenderBuffer, offset, size uint64) (interface{}, error) {
+	internal, err := sr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	return internal.Data[offset : offset+size], nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferUnmapMemory(buffer *metadata.RenderBuffer, offset, size uint64) error {
+	_, err := sr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (sr *SoftwareRenderer) RenderBufferFlush(buffer *metadata.RenderBuffer, offset, size uint64) error {
+	_, err := sr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (sr *SoftwareRenderer) RenderBufferRead(buffer *metadata.RenderBuffer, offset, size uint64) (interface{}, error) {
+	internal, err := sr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, size)
+	copy(out, internal.Data[offset:offset+size])
+	return out, nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferResize(buffer *metadata.RenderBuffer, newTotalSize uint64) error {
+	internal, ok := buffer.InternalData.(*SoftwareBuffer)
+	if !ok {
+		return fmt.Errorf("renderbuffer has no software renderer internal data")
+	}
+	data := make([]byte, newTotalSize)
+	copy(data, internal.Data)
+	internal.Data = data
+	buffer.TotalSize = newTotalSize
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferFree(buffer *metadata.RenderBuffer, size, offset uint64) error {
+	_, err := sr.bufferRange(buffer, offset, size)
+	return err
+}
+
+func (sr *SoftwareRenderer) RenderBufferLoadRange(buffer *metadata.RenderBuffer, offset, size uint64, data interface{}) error {
+	internal, err := sr.bufferRange(buffer, offset, size)
+	if err != nil {
+		return err
+	}
+
+	// Keep typed copies around, so that the content can be drawn.
+	switch d := data.(type) {
+	case []byte:
+		copy(internal.Data[offset:offset+size], d)
+		if buffer.RenderBufferType == metadata.RENDERBUFFER_TYPE_INDEX {
+			internal.Indices = make([]uint32, len(d))
+			for i, index := range d {
+				internal.Indices[i] = uint32(index)
+			}
+		}
+	case []uint32:
+		internal.Indices = make([]uint32, len(d))
+		copy(internal.Indices, d)
+	default:
+		if vertices, err := toVertex3D(data); err == nil {
+			internal.Vertices = vertices
+		}
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferCopyRange(source *metadata.RenderBuffer, sourceOffset uint64, dest *metadata.RenderBuffer, destOffset uint64, size uint64) error {
+	src, err := sr.bufferRange(source, sourceOffset, size)
+	if err != nil {
+		return err
+	}
+	dst, err := sr.bufferRange(dest, destOffset, size)
+	if err != nil {
+		return err
+	}
+	copy(dst.Data[destOffset:destOffset+size], src.Data[sourceOffset:sourceOffset+size])
+	return nil
+}
+
+func (sr *SoftwareRenderer) RenderBufferDraw(buffer *metadata.RenderBuffer, offset uint64, elementCount uint32, bindOnly bool) error {
+	internal, err := sr.bufferRange(buffer, offset, 0)
+	if err != nil {
+		return err
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if buffer.RenderBufferType == metadata.RENDERBUFFER_TYPE_VERTEX {
+		sr.vertexBuffer = internal
+		if bindOnly {
+			return nil
+		}
+		vertices := internal.Vertices
+		if int(elementCount) < len(vertices) {
+			vertices = vertices[:elementCount]
+		}
+		return sr.draw(vertices, nil)
+	}
+	if buffer.RenderBufferType == metadata.RENDERBUFFER_TYPE_INDEX && !bindOnly {
+		if sr.vertexBuffer == nil {
+			return fmt.Errorf("cannot draw an index buffer without a bound vertex buffer")
+		}
+		indices := internal.Indices
+		if int(elementCount) < len(indices) {
+			indices = indices[:elementCount]
+		}
+		return sr.draw(sr.vertexBuffer.Vertices, indices)
+	}
+	return nil
+}
+
+func (sr *SoftwareRenderer) WindowAttachmentGet(index uint8) *metadata.Texture {
+	if index >= SOFTWARE_WINDOW_ATTACHMENT_COUNT {
+		core.LogFatal("attempting to get colour attachment index out of range: %d. Attachment count: %d", index, SOFTWARE_WINDOW_ATTACHMENT_COUNT)
+		return nil
+	}
+	return sr.colourAttachment
+}
+
+func (sr *SoftwareRenderer) WindowAttachmentIndexGet() uint64 {
+	return 0
+}
+
+func (sr *SoftwareRenderer) DepthAttachmentGet(index uint8) *metadata.Texture {
+	if index >= SOFTWARE_WINDOW_ATTACHMENT_COUNT {
+		core.LogFatal("attempting to get depth attachment index out of range: %d. Attachment count: %d", index, SOFTWARE_WINDOW_ATTACHMENT_COUNT)
+		return nil
+	}
+	return sr.depthAttachment
+}
+
+func (sr *SoftwareRenderer) GetWindowAttachmentCount() uint8 {
+	return SOFTWARE_WINDOW_ATTACHMENT_COUNT
+}
+
+func (sr *SoftwareRenderer) IsMultithreaded() bool {
+	return false
+}
+
+// draw rasterizes the triangles with the shader in use. Must be called with the mutex held.
+func (sr *SoftwareRenderer) draw(vertices []math.Vertex3D, indices []uint32) error {
+	if !sr.frameActive || sr.currentTarget == nil {
+		return fmt.Errorf("draw called outside of a renderpass")
+	}
+	if sr.currentShader == nil {
+		return fmt.Errorf("draw called without a shader in use")
+	}
+	shader, ok := sr.currentShader.InternalData.(*SoftwareShader)
+	if !ok {
+		return fmt.Errorf("shader `%s` has no software renderer internal data", sr.currentShader.Name)
+	}
+	if shader.Program == nil || sr.currentTarget.colour == nil {
+		return nil
+	}
+	colourData, ok := sr.currentTarget.colour.InternalData.(*SoftwareTexture)
+	if !ok {
+		return fmt.Errorf("texture `%s` has no software renderer internal data", sr.currentTarget.colour.Name)
+	}
+
+	uniforms := &Uniforms{
+		Global: shader.Globals,
+		Local:  shader.Locals,
+	}
+	if instance, ok := shader.Instances[sr.currentShader.BoundInstanceID]; ok {
+		uniforms.Instance = instance.Values
+	}
+	shader.Program.Prepare(uniforms)
+
+	state := &rasterState{
+		colour:     sr.currentTarget.colour,
+		colourData: colourData,
+		width:      int(sr.currentTarget.colour.Width),
+		height:     int(sr.currentTarget.colour.Height),
+		program:    shader.Program,
+		cullMode:   shader.Config.CullMode,
+		depthTest:  shader.Config.DepthTest,
+		depthWrite: shader.Config.DepthWrite,
+	}
+	if sr.currentTarget.depth != nil {
+		if depthData, ok := sr.currentTarget.depth.InternalData.(*SoftwareTexture); ok && len(depthData.Depth) == state.width*state.height {
+			state.depth = depthData.Depth
+		}
+	}
+	state.drawTriangles(vertices, indices)
+	return nil
+}
+
+// acquireHandle returns a new handle. Must be called with the mutex held.
+func (sr *SoftwareRenderer) acquireHandle() uint32 {
+	handle := sr.nextHandle
+	sr.nextHandle++
+	return handle
+}
+
+func (sr *SoftwareRenderer) newAttachment(name string, flags metadata.TextureFlag) *metadata.Texture {
+	texture := &metadata.Texture{
+		ID:           metadata.InvalidID,
+		TextureType:  metadata.TextureType2d,
+		Name:         name,
+		ChannelCount: 4,
+		Flags:        metadata.TextureFlagBits(metadata.TextureFlagIsWriteable | metadata.TextureFlagIsWrapped | flags),
+		InternalData: &SoftwareTexture{Handle: sr.acquireHandle()},
+	}
+	sr.resizeTexture(texture, sr.FramebufferWidth, sr.FramebufferHeight)
+	return texture
+}
+
+// resizeTexture reallocates the storage of the texture. Depth textures are cleared to
+// the far plane, colour ones to opaque black.
+func (sr *SoftwareRenderer) resizeTexture(texture *metadata.Texture, width, height uint32) {
+	texture.Width = width
+	texture.Height = height
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return
+	}
+	if texture.Flags&metadata.TextureFlagBits(metadata.TextureFlagDepth) != 0 {
+		internal.Pixels = nil
+		internal.Depth = make([]float32, width*height)
+		for i := range internal.Depth {
+			internal.Depth[i] = 1.0
+		}
+		return
+	}
+	channels := uint32(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	internal.Depth = nil
+	internal.Pixels = make([]uint8, width*height*channels)
+	clearColour(texture, math.NewVec4(0, 0, 0, 1))
+}
+
+func (sr *SoftwareRenderer) bufferRange(buffer *metadata.RenderBuffer, offset, size uint64) (*SoftwareBuffer, error) {
+	if buffer == nil {
+		return nil, fmt.Errorf("buffer cannot be nil")
+	}
+	internal, ok := buffer.InternalData.(*SoftwareBuffer)
+	if !ok {
+		return nil, fmt.Errorf("renderbuffer has no software renderer internal data")
+	}
+	if offset+size > uint64(len(internal.Data)) {
+		return nil, fmt.Errorf("range %d-%d is out of the bounds of the renderbuffer (size %d)", offset, offset+size, len(internal.Data))
+	}
+	return internal, nil
+}
+
+func clearColour(texture *metadata.Texture, colour math.Vec4) {
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return
+	}
+	channels := int(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	values := [4]uint8{
+		uint8(math.Clamp(colour.X, 0.0, 1.0)*255.0 + 0.5),
+		uint8(math.Clamp(colour.Y, 0.0, 1.0)*255.0 + 0.5),
+		uint8(math.Clamp(colour.Z, 0.0, 1.0)*255.0 + 0.5),
+		uint8(math.Clamp(colour.W, 0.0, 1.0)*255.0 + 0.5),
+	}
+	for i := 0; i+channels <= len(internal.Pixels); i += channels {
+		for c := 0; c < channels && c < 4; c++ {
+			internal.Pixels[i+c] = values[c]
+		}
+	}
+}
+
+// toImage converts the pixels of a colour texture to an image.
+func toImage(texture *metadata.Texture) *image.RGBA {
+	if texture == nil {
+		return nil
+	}
+	internal, ok := texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(texture.Width), int(texture.Height)))
+	channels := int(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	for p := 0; p < int(texture.Width*texture.Height); p++ {
+		src := p * channels
+		if src+channels > len(internal.Pixels) {
+			break
+		}
+		dst := p * 4
+		img.Pix[dst+3] = 255
+		for c := 0; c < channels && c < 4; c++ {
+			img.Pix[dst+c] = internal.Pixels[src+c]
+		}
+		if channels < 3 {
+			// Greyscale.
+			img.Pix[dst+1] = img.Pix[dst]
+			img.Pix[dst+2] = img.Pix[dst]
+		}
+	}
+	return img
+}
+
+// toVertex3D converts the vertex data handed over by the engine to 3D vertices.
+// 2D vertices are placed on the z=0 plane.
+func toVertex3D(vertices interface{}) ([]math.Vertex3D, error) {
+	switch v := vertices.(type) {
+	case []math.Vertex3D:
+		out := make([]math.Vertex3D, len(v))
+		copy(out, v)
+		return out, nil
+	case []*math.Vertex3D:
+		out := make([]math.Vertex3D, 0, len(v))
+		for _, vertex := range v {
+			if vertex != nil {
+				out = append(out, *vertex)
+			}
+		}
+		return out, nil
+	case []math.Vertex2D:
+		out := make([]math.Vertex3D, len(v))
+		for i, vertex := range v {
+			out[i] = vertex2DTo3D(&vertex)
+		}
+		return out, nil
+	case []*math.Vertex2D:
+		out := make([]math.Vertex3D, 0, len(v))
+		for _, vertex := range v {
+			if vertex != nil {
+				out = append(out, vertex2DTo3D(vertex))
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported vertex data type %T", vertices)
+}
+
+func vertex2DTo3D(v *math.Vertex2D) math.Vertex3D {
+	return math.Vertex3D{
+		Position: math.NewVec3(v.Position.X, v.Position.Y, 0),
+		Texcoord: v.Texcoord,
+		Colour:   math.NewVec4One(),
+	}
+}
@@ -0,0 +1,489 @@
+package software
+
+import (
+	m "math"
+	"sync"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/**
+ * @brief The values of the uniforms visible to a draw call, resolved
+ * by name from the local, instance and global scopes in this order.
+ */
+type Uniforms struct {
+	Global   map[string]interface{}
+	Instance map[string]interface{}
+	Local    map[string]interface{}
+}
+
+func (u *Uniforms) Value(name string) interface{} {
+	if v, ok := u.Local[name]; ok {
+		return v
+	}
+	if v, ok := u.Instance[name]; ok {
+		return v
+	}
+	if v, ok := u.Global[name]; ok {
+		return v
+	}
+	return nil
+}
+
+func (u *Uniforms) Mat4(name string) math.Mat4 {
+	switch v := u.Value(name).(type) {
+	case math.Mat4:
+		return v
+	case *math.Mat4:
+		if v != nil {
+			return *v
+		}
+	}
+	return math.NewMat4Identity()
+}
+
+func (u *Uniforms) Vec4(name string) math.Vec4 {
+	switch v := u.Value(name).(type) {
+	case math.Vec4:
+		return v
+	case *math.Vec4:
+		if v != nil {
+			return *v
+		}
+	case math.Vec3:
+		return math.NewVec4FromVec3(v, 1.0)
+	case *math.Vec3:
+		if v != nil {
+			return math.NewVec4FromVec3(*v, 1.0)
+		}
+	}
+	return math.NewVec4One()
+}
+
+func (u *Uniforms) Vec3(name string) math.Vec3 {
+	switch v := u.Value(name).(type) {
+	case math.Vec3:
+		return v
+	case *math.Vec3:
+		if v != nil {
+			return *v
+		}
+	case math.Vec4:
+		return v.ToVec3()
+	case *math.Vec4:
+		if v != nil {
+			return v.ToVec3()
+		}
+	}
+	return math.NewVec3Zero()
+}
+
+func (u *Uniforms) Float(name string) float32 {
+	switch v := u.Value(name).(type) {
+	case float32:
+		return v
+	case *float32:
+		if v != nil {
+			return *v
+		}
+	case float64:
+		return float32(v)
+	}
+	return 0
+}
+
+func (u *Uniforms) Uint(name string) uint32 {
+	switch v := u.Value(name).(type) {
+	case uint32:
+		return v
+	case *uint32:
+		if v != nil {
+			return *v
+		}
+	case int32:
+		return uint32(v)
+	case int:
+		return uint32(v)
+	case metadata.RendererDebugViewMode:
+		return uint32(v)
+	case *metadata.RendererDebugViewMode:
+		if v != nil {
+			return uint32(*v)
+		}
+	}
+	return 0
+}
+
+func (u *Uniforms) TextureMap(name string) *metadata.TextureMap {
+	switch v := u.Value(name).(type) {
+	case *metadata.TextureMap:
+		return v
+	case *metadata.Texture:
+		return &metadata.TextureMap{
+			Texture:       v,
+			FilterMinify:  metadata.TextureFilterModeLinear,
+			FilterMagnify: metadata.TextureFilterModeLinear,
+			RepeatU:       metadata.TextureRepeatRepeat,
+			RepeatV:       metadata.TextureRepeatRepeat,
+			RepeatW:       metadata.TextureRepeatRepeat,
+		}
+	}
+	return nil
+}
+
+/**
+ * @brief A shading program is the Go counterpart of the vertex and
+ * fragment stages of a shader. Programs are created once per shader,
+ * so they can keep the state set up in Prepare across the draw.
+ */
+type ShadingProgram interface {
+	/** @brief The number of floats passed from the vertex to the fragment stage. */
+	VaryingCount() int
+	/** @brief Called once per draw with the uniforms visible to it. */
+	Prepare(uniforms *Uniforms)
+	/** @brief Transforms the vertex in clip space and writes the varyings in out. */
+	Vertex(vertex *math.Vertex3D, out []float32) math.Vec4
+	/** @brief Computes the colour of a fragment from the interpolated varyings. */
+	Fragment(in []float32) math.Vec4
+}
+
+type ShadingProgramFactory func() ShadingProgram
+
+var (
+	programsMutex sync.RWMutex
+	programs      = map[string]ShadingProgramFactory{
+		"Shader.Builtin.Material":  func() ShadingProgram { return &materialProgram{} },
+		"Shader.Builtin.UI":        func() ShadingProgram { return &uiProgram{} },
+		"Shader.Builtin.Skybox":    func() ShadingProgram { return &skyboxProgram{} },
+		"Shader.Builtin.WorldPick": func() ShadingProgram { return &pickProgram{} },
+		"Shader.Builtin.UIPick":    func() ShadingProgram { return &pickProgram{} },
+	}
+)
+
+// RegisterProgram makes a shading program available for the shader with the given name.
+// Registering a program for an existing name replaces it.
+func RegisterProgram(shaderName string, factory ShadingProgramFactory) {
+	programsMutex.Lock()
+	defer programsMutex.Unlock()
+	programs[shaderName] = factory
+}
+
+func newProgram(shaderName string) ShadingProgram {
+	programsMutex.RLock()
+	defer programsMutex.RUnlock()
+	factory, ok := programs[shaderName]
+	if !ok {
+		return nil
+	}
+	return factory()
+}
+
+// transformPoint multiplies the point by the matrix, following the same
+// convention as the GLSL shaders (matrices are uploaded as is).
+func transformPoint(v math.Vec4, mat math.Mat4) math.Vec4 {
+	d := &mat.Data
+	return math.NewVec4(
+		v.X*d[0]+v.Y*d[4]+v.Z*d[8]+v.W*d[12],
+		v.X*d[1]+v.Y*d[5]+v.Z*d[9]+v.W*d[13],
+		v.X*d[2]+v.Y*d[6]+v.Z*d[10]+v.W*d[14],
+		v.X*d[3]+v.Y*d[7]+v.Z*d[11]+v.W*d[15],
+	)
+}
+
+// transformDirection multiplies the direction by the upper 3x3 of the matrix.
+func transformDirection(v math.Vec3, mat math.Mat4) math.Vec3 {
+	d := &mat.Data
+	return math.NewVec3(
+		v.X*d[0]+v.Y*d[4]+v.Z*d[8],
+		v.X*d[1]+v.Y*d[5]+v.Z*d[9],
+		v.X*d[2]+v.Y*d[6]+v.Z*d[10],
+	)
+}
+
+func normalizeVec3(v math.Vec3) math.Vec3 {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return v.MulScalar(1.0 / l)
+}
+
+func scaleVec4(v math.Vec4, s float32) math.Vec4 {
+	return math.NewVec4(v.X*s, v.Y*s, v.Z*s, v.W*s)
+}
+
+func pow32(x, y float32) float32 {
+	return float32(m.Pow(float64(x), float64(y)))
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// materialProgram mirrors Builtin.MaterialShader.
+type materialProgram struct {
+	mvp          math.Mat4
+	model        math.Mat4
+	ambient      math.Vec4
+	viewPosition math.Vec3
+	mode         uint32
+
+	diffuseColour math.Vec4
+	shininess     float32
+	diffuseMap    *metadata.TextureMap
+	specularMap   *metadata.TextureMap
+	normalMap     *metadata.TextureMap
+
+	// The lights fed in by the light system.
+	directionalLight metadata.DirectionalLight
+	pointLights      []metadata.PointLight
+	spotLights       []metadata.SpotLight
+}
+
+const (
+	materialVaryingTexcoord = 0
+	materialVaryingNormal   = 2
+	materialVaryingPosition = 5
+	materialVaryingColour   = 8
+	materialVaryingTangent  = 12
+	materialVaryingCount    = 15
+)
+
+func (p *materialProgram) VaryingCount() int {
+	return materialVaryingCount
+}
+
+func (p *materialProgram) Prepare(u *Uniforms) {
+	p.model = u.Mat4("model")
+	p.mvp = p.model.Mul(u.Mat4("view")).Mul(u.Mat4("projection"))
+	p.ambient = u.Vec4("ambient_colour")
+	p.viewPosition = u.Vec3("view_position")
+	p.mode = u.Uint("mode")
+	p.diffuseColour = u.Vec4("diffuse_colour")
+	p.shininess = u.Float("shininess")
+	p.diffuseMap = u.TextureMap("diffuse_texture")
+	p.specularMap = u.TextureMap("specular_texture")
+	p.normalMap = u.TextureMap("normal_texture")
+	if p.normalMap != nil && p.normalMap.Use == metadata.TextureUseUnknown {
+		p.normalMap.Use = metadata.TextureUseMapNormal
+	}
+
+	p.directionalLight = metadata.DirectionalLight{}
+	if light, ok := u.Value("dir_light").(metadata.DirectionalLight); ok {
+		p.directionalLight = light
+	}
+	p.pointLights = p.pointLights[:0]
+	if lights, ok := u.Value("point_lights").([metadata.MAX_POINT_LIGHTS]metadata.PointLight); ok {
+		p.pointLights = append(p.pointLights, lights[:min(int(u.Uint("point_light_count")), len(lights))]...)
+	}
+	p.spotLights = p.spotLights[:0]
+	if lights, ok := u.Value("spot_lights").([metadata.MAX_SPOT_LIGHTS]metadata.SpotLight); ok {
+		p.spotLights = append(p.spotLights, lights[:min(int(u.Uint("spot_light_count")), len(lights))]...)
+	}
+}
+
+func (p *materialProgram) Vertex(v *math.Vertex3D, out []float32) math.Vec4 {
+	position := math.NewVec4FromVec3(v.Position, 1.0)
+	world := transformPoint(position, p.model)
+	normal := normalizeVec3(transformDirection(v.Normal, p.model))
+	tangent := normalizeVec3(transformDirection(v.Tangent, p.model))
+
+	out[materialVaryingTexcoord+0] = v.Texcoord.X
+	out[materialVaryingTexcoord+1] = v.Texcoord.Y
+	out[materialVaryingNormal+0] = normal.X
+	out[materialVaryingNormal+1] = normal.Y
+	out[materialVaryingNormal+2] = normal.Z
+	out[materialVaryingPosition+0] = world.X
+	out[materialVaryingPosition+1] = world.Y
+	out[materialVaryingPosition+2] = world.Z
+	out[materialVaryingColour+0] = v.Colour.X
+	out[materialVaryingColour+1] = v.Colour.Y
+	out[materialVaryingColour+2] = v.Colour.Z
+	out[materialVaryingColour+3] = v.Colour.W
+	out[materialVaryingTangent+0] = tangent.X
+	out[materialVaryingTangent+1] = tangent.Y
+	out[materialVaryingTangent+2] = tangent.Z
+
+	return transformPoint(position, p.mvp)
+}
+
+func (p *materialProgram) Fragment(in []float32) math.Vec4 {
+	u := in[materialVaryingTexcoord+0]
+	v := in[materialVaryingTexcoord+1]
+	normal := math.NewVec3(in[materialVaryingNormal+0], in[materialVaryingNormal+1], in[materialVaryingNormal+2])
+	fragPosition := math.NewVec3(in[materialVaryingPosition+0], in[materialVaryingPosition+1], in[materialVaryingPosition+2])
+	tangent := math.NewVec3(in[materialVaryingTangent+0], in[materialVaryingTangent+1], in[materialVaryingTangent+2])
+
+	// Build the TBN and update the normal using the normal map.
+	tangent = tangent.Sub(normal.MulScalar(tangent.Dot(normal)))
+	bitangent := normal.Cross(tangent)
+	ns := Sample2D(p.normalMap, u, v)
+	local := math.NewVec3(2.0*ns.X-1.0, 2.0*ns.Y-1.0, 2.0*ns.Z-1.0)
+	normal = normalizeVec3(tangent.MulScalar(local.X).Add(bitangent.MulScalar(local.Y)).Add(normal.MulScalar(local.Z)))
+
+	if p.mode == uint32(metadata.RENDERER_VIEW_MODE_NORMALS) {
+		return math.NewVec4(kabs(normal.X), kabs(normal.Y), kabs(normal.Z), 1.0)
+	}
+
+	diffuseSample := Sample2D(p.diffuseMap, u, v)
+	specularSample := Sample2D(p.specularMap, u, v)
+	viewDirection := normalizeVec3(p.viewPosition.Sub(fragPosition))
+
+	out := p.directional(p.directionalLight, normal, viewDirection, diffuseSample, specularSample)
+	for _, light := range p.pointLights {
+		out = out.Add(p.point(light, normal, fragPosition, viewDirection, diffuseSample, specularSample))
+	}
+	for _, light := range p.spotLights {
+		out = out.Add(p.spot(light, normal, fragPosition, viewDirection, diffuseSample, specularSample))
+	}
+	return out
+}
+
+func (p *materialProgram) directional(light metadata.DirectionalLight, normal, viewDirection math.Vec3, diffuseSample, specularSample math.Vec4) math.Vec4 {
+	lightDirection := light.Direction.MulScalar(-1.0)
+	diffuseFactor := max32(normal.Dot(lightDirection), 0.0)
+
+	halfDirection := normalizeVec3(viewDirection.Sub(light.Direction))
+	specularFactor := pow32(max32(halfDirection.Dot(normal), 0.0), p.shininess)
+
+	colour := scaleVec4(light.Colour, light.Intensity)
+	ambient := p.ambient.Mul(p.diffuseColour)
+	ambient.W = diffuseSample.W
+	diffuse := scaleVec4(colour, diffuseFactor)
+	diffuse.W = diffuseSample.W
+	specular := scaleVec4(colour, specularFactor)
+	specular.W = diffuseSample.W
+
+	if p.mode == uint32(metadata.RENDERER_VIEW_MODE_DEFAULT) {
+		diffuse = diffuse.Mul(diffuseSample)
+		ambient = ambient.Mul(diffuseSample)
+		specular = specular.Mul(math.NewVec4(specularSample.X, specularSample.Y, specularSample.Z, diffuse.W))
+	}
+	return ambient.Add(diffuse).Add(specular)
+}
+
+func (p *materialProgram) point(light metadata.PointLight, normal, fragPosition, viewDirection math.Vec3, diffuseSample, specularSample math.Vec4) math.Vec4 {
+	ambient, diffuse, specular, attenuation := p.positional(light.Colour, light.Position, light.Intensity, light.Attenuation, normal, fragPosition, viewDirection, diffuseSample, specularSample)
+	return scaleVec4(ambient.Add(diffuse).Add(specular), attenuation)
+}
+
+func (p *materialProgram) spot(light metadata.SpotLight, normal, fragPosition, viewDirection math.Vec3, diffuseSample, specularSample math.Vec4) math.Vec4 {
+	ambient, diffuse, specular, attenuation := p.positional(light.Colour, light.Position, light.Intensity, light.Attenuation, normal, fragPosition, viewDirection, diffuseSample, specularSample)
+
+	// Fade the light out between the inner and the outer cone.
+	theta := normalizeVec3(light.Position.Sub(fragPosition)).Dot(normalizeVec3(light.Direction.MulScalar(-1.0)))
+	cone := min(max((theta-light.OuterCutoff)/(light.InnerCutoff-light.OuterCutoff), 0.0), 1.0)
+
+	// The ambient light is not bound to the cone.
+	return scaleVec4(ambient, attenuation).Add(scaleVec4(diffuse.Add(specular), attenuation*cone))
+}
+
+// positional returns the ambient, diffuse and specular terms of a light at a position, and its
+// attenuation at the fragment.
+func (p *materialProgram) positional(colour math.Vec4, position math.Vec3, intensity float32, falloff metadata.LightAttenuation, normal, fragPosition, viewDirection math.Vec3, diffuseSample, specularSample math.Vec4) (math.Vec4, math.Vec4, math.Vec4, float32) {
+	toLight := position.Sub(fragPosition)
+	lightDirection := normalizeVec3(toLight)
+	diff := max32(normal.Dot(lightDirection), 0.0)
+
+	// reflect(-L, N) = -L - 2 * dot(N, -L) * N
+	incident := lightDirection.MulScalar(-1.0)
+	reflectDirection := incident.Sub(normal.MulScalar(2.0 * normal.Dot(incident)))
+	spec := pow32(max32(viewDirection.Dot(reflectDirection), 0.0), p.shininess)
+
+	// Calculate attenuation, or light falloff over distance.
+	distance := toLight.Length()
+	attenuation := 1.0 / (falloff.Constant + falloff.Linear*distance + falloff.Quadratic*(distance*distance))
+
+	colour = scaleVec4(colour, intensity)
+	ambient := p.ambient
+	diffuse := scaleVec4(colour, diff)
+	specular := scaleVec4(colour, spec)
+
+	if p.mode == uint32(metadata.RENDERER_VIEW_MODE_DEFAULT) {
+		diffuse = diffuse.Mul(diffuseSample)
+		ambient = ambient.Mul(diffuseSample)
+		specular = specular.Mul(math.NewVec4(specularSample.X, specularSample.Y, specularSample.Z, diffuse.W))
+	}
+	return ambient, diffuse, specular, attenuation
+}
+
+// uiProgram mirrors Builtin.UIShader.
+type uiProgram struct {
+	mvp           math.Mat4
+	diffuseColour math.Vec4
+	diffuseMap    *metadata.TextureMap
+}
+
+func (p *uiProgram) VaryingCount() int {
+	return 2
+}
+
+func (p *uiProgram) Prepare(u *Uniforms) {
+	p.mvp = u.Mat4("model").Mul(u.Mat4("view")).Mul(u.Mat4("projection"))
+	p.diffuseColour = u.Vec4("diffuse_colour")
+	p.diffuseMap = u.TextureMap("diffuse_texture")
+}
+
+func (p *uiProgram) Vertex(v *math.Vertex3D, out []float32) math.Vec4 {
+	// NOTE: intentionally flip y texture coordinate, same as the shader.
+	out[0] = v.Texcoord.X
+	out[1] = 1.0 - v.Texcoord.Y
+	return transformPoint(math.NewVec4(v.Position.X, v.Position.Y, 0.0, 1.0), p.mvp)
+}
+
+func (p *uiProgram) Fragment(in []float32) math.Vec4 {
+	return p.diffuseColour.Mul(Sample2D(p.diffuseMap, in[0], in[1]))
+}
+
+// skyboxProgram mirrors Builtin.SkyboxShader.
+type skyboxProgram struct {
+	vp      math.Mat4
+	cubeMap *metadata.TextureMap
+}
+
+func (p *skyboxProgram) VaryingCount() int {
+	return 3
+}
+
+func (p *skyboxProgram) Prepare(u *Uniforms) {
+	p.vp = u.Mat4("view").Mul(u.Mat4("projection"))
+	p.cubeMap = u.TextureMap("cube_texture")
+}
+
+func (p *skyboxProgram) Vertex(v *math.Vertex3D, out []float32) math.Vec4 {
+	out[0] = v.Position.X
+	out[1] = v.Position.Y
+	out[2] = v.Position.Z
+	return transformPoint(math.NewVec4FromVec3(v.Position, 1.0), p.vp)
+}
+
+func (p *skyboxProgram) Fragment(in []float32) math.Vec4 {
+	return SampleCube(p.cubeMap, math.NewVec3(in[0], in[1], in[2]))
+}
+
+// pickProgram mirrors both Builtin.WorldPickShader and Builtin.UIPickShader.
+type pickProgram struct {
+	mvp      math.Mat4
+	idColour math.Vec3
+}
+
+func (p *pickProgram) VaryingCount() int {
+	return 0
+}
+
+func (p *pickProgram) Prepare(u *Uniforms) {
+	p.mvp = u.Mat4("model").Mul(u.Mat4("view")).Mul(u.Mat4("projection"))
+	p.idColour = u.Vec3("id_colour")
+}
+
+func (p *pickProgram) Vertex(v *math.Vertex3D, out []float32) math.Vec4 {
+	return transformPoint(math.NewVec4FromVec3(v.Position, 1.0), p.mvp)
+}
+
+func (p *pickProgram) Fragment(in []float32) math.Vec4 {
+	return math.NewVec4FromVec3(p.idColour, 1.0)
+}
@@ -0,0 +1,286 @@
+package software
+
+import (
+	m "math"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The smallest w accepted before a vertex is considered behind the eye.
+const clipEpsilon float32 = 1e-5
+
+/** @brief The state needed to rasterize triangles into a framebuffer. */
+type rasterState struct {
+	colour     *metadata.Texture
+	colourData *SoftwareTexture
+	depth      []float32
+
+	width  int
+	height int
+
+	program    ShadingProgram
+	cullMode   metadata.FaceCullMode
+	depthTest  bool
+	depthWrite bool
+}
+
+/** @brief A vertex after the vertex stage. */
+type clipVertex struct {
+	position math.Vec4
+	varyings []float32
+}
+
+/** @brief A vertex after the perspective divide and viewport transform. */
+type screenVertex struct {
+	x, y, z float32
+	// 1/w, used for perspective correct interpolation.
+	invW     float32
+	varyings []float32
+}
+
+// drawTriangles runs the program over the indexed triangle list and rasterizes the result.
+func (rs *rasterState) drawTriangles(vertices []math.Vertex3D, indices []uint32) {
+	if rs.program == nil || rs.colourData == nil || rs.width == 0 || rs.height == 0 {
+		return
+	}
+
+	varyingCount := rs.program.VaryingCount()
+
+	// Run the vertex stage once per vertex.
+	transformed := make([]clipVertex, len(vertices))
+	for i := range vertices {
+		varyings := make([]float32, varyingCount)
+		position := rs.program.Vertex(&vertices[i], varyings)
+		transformed[i] = clipVertex{position: position, varyings: varyings}
+	}
+
+	if len(indices) == 0 {
+		for i := 0; i+2 < len(transformed); i += 3 {
+			rs.drawTriangle(transformed[i], transformed[i+1], transformed[i+2])
+		}
+		return
+	}
+	for i := 0; i+2 < len(indices); i += 3 {
+		a, b, c := int(indices[i]), int(indices[i+1]), int(indices[i+2])
+		if a >= len(transformed) || b >= len(transformed) || c >= len(transformed) {
+			continue
+		}
+		rs.drawTriangle(transformed[a], transformed[b], transformed[c])
+	}
+}
+
+func (rs *rasterState) drawTriangle(a, b, c clipVertex) {
+	polygon := clipPolygon([]clipVertex{a, b, c})
+	if len(polygon) < 3 {
+		return
+	}
+
+	screen := make([]screenVertex, len(polygon))
+	for i, v := range polygon {
+		screen[i] = rs.toScreen(v)
+	}
+
+	// Fan triangulate the clipped polygon.
+	for i := 1; i+1 < len(screen); i++ {
+		rs.rasterize(screen[0], screen[i], screen[i+1])
+	}
+}
+
+// clipPolygon clips the polygon against the near plane (z >= -w) using Sutherland-Hodgman.
+// The other planes are handled by the scissoring done while rasterizing.
+func clipPolygon(polygon []clipVertex) []clipVertex {
+	distance := func(v clipVertex) float32 {
+		return m32min(v.position.Z+v.position.W, v.position.W-clipEpsilon)
+	}
+
+	out := make([]clipVertex, 0, len(polygon)+2)
+	for i := range polygon {
+		current := polygon[i]
+		next := polygon[(i+1)%len(polygon)]
+		dc := distance(current)
+		dn := distance(next)
+
+		if dc >= 0 {
+			out = append(out, current)
+		}
+		if (dc >= 0) != (dn >= 0) {
+			t := dc / (dc - dn)
+			out = append(out, lerpClipVertex(current, next, t))
+		}
+	}
+	return out
+}
+
+func lerpClipVertex(a, b clipVertex, t float32) clipVertex {
+	varyings := make([]float32, len(a.varyings))
+	for i := range varyings {
+		varyings[i] = a.varyings[i] + (b.varyings[i]-a.varyings[i])*t
+	}
+	return clipVertex{
+		position: lerpVec4(a.position, b.position, t),
+		varyings: varyings,
+	}
+}
+
+// toScreen performs the perspective divide and maps the vertex to the framebuffer,
+// with the first row at the top of the image and depth in the [0, 1] range.
+func (rs *rasterState) toScreen(v clipVertex) screenVertex {
+	invW := 1.0 / v.position.W
+	ndcX := v.position.X * invW
+	ndcY := v.position.Y * invW
+	ndcZ := v.position.Z * invW
+
+	varyings := make([]float32, len(v.varyings))
+	for i := range varyings {
+		varyings[i] = v.varyings[i] * invW
+	}
+	return screenVertex{
+		x:        (ndcX + 1.0) * 0.5 * float32(rs.width),
+		y:        (1.0 - ndcY) * 0.5 * float32(rs.height),
+		z:        ndcZ*0.5 + 0.5,
+		invW:     invW,
+		varyings: varyings,
+	}
+}
+
+// culled reports whether a triangle with the given signed area in screen space
+// (positive when counter-clockwise on screen) must be discarded.
+func (rs *rasterState) culled(area float32) bool {
+	// Screen space y points down, so counter-clockwise triangles have a negative area.
+	front := area < 0
+	switch rs.cullMode {
+	case metadata.FaceCullModeFront:
+		return front
+	case metadata.FaceCullModeBack:
+		return !front
+	case metadata.FaceCullModeFrontAndBack:
+		return true
+	}
+	return false
+}
+
+func edge(ax, ay, bx, by, px, py float32) float32 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// isTopLeft reports whether the edge a->b is a top or left edge of a triangle with a positive area.
+func isTopLeft(a, b screenVertex) bool {
+	dx := b.x - a.x
+	dy := b.y - a.y
+	return (dy == 0 && dx < 0) || dy > 0
+}
+
+func (rs *rasterState) rasterize(v0, v1, v2 screenVertex) {
+	area := edge(v0.x, v0.y, v1.x, v1.y, v2.x, v2.y)
+	if area == 0 || rs.culled(area) {
+		return
+	}
+	// Work with a consistent winding from here on.
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+
+	minX := int(m.Floor(float64(m32min(v0.x, m32min(v1.x, v2.x)))))
+	maxX := int(m.Ceil(float64(m32max(v0.x, m32max(v1.x, v2.x)))))
+	minY := int(m.Floor(float64(m32min(v0.y, m32min(v1.y, v2.y)))))
+	maxY := int(m.Ceil(float64(m32max(v0.y, m32max(v1.y, v2.y)))))
+	minX = math.Clamp(minX, 0, rs.width-1)
+	maxX = math.Clamp(maxX, 0, rs.width-1)
+	minY = math.Clamp(minY, 0, rs.height-1)
+	maxY = math.Clamp(maxY, 0, rs.height-1)
+
+	topLeft0 := isTopLeft(v1, v2)
+	topLeft1 := isTopLeft(v2, v0)
+	topLeft2 := isTopLeft(v0, v1)
+
+	varyings := make([]float32, len(v0.varyings))
+	invArea := 1.0 / area
+
+	for y := minY; y <= maxY; y++ {
+		py := float32(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float32(x) + 0.5
+
+			w0 := edge(v1.x, v1.y, v2.x, v2.y, px, py)
+			w1 := edge(v2.x, v2.y, v0.x, v0.y, px, py)
+			w2 := edge(v0.x, v0.y, v1.x, v1.y, px, py)
+			if !insideEdge(w0, topLeft0) || !insideEdge(w1, topLeft1) || !insideEdge(w2, topLeft2) {
+				continue
+			}
+
+			b0 := w0 * invArea
+			b1 := w1 * invArea
+			b2 := w2 * invArea
+
+			// Depth is affine in screen space.
+			z := b0*v0.z + b1*v1.z + b2*v2.z
+			if z < 0 || z > 1 {
+				continue
+			}
+			pixel := y*rs.width + x
+			if rs.depthTest && rs.depth != nil && !(z < rs.depth[pixel]) {
+				continue
+			}
+
+			// Perspective correct varyings.
+			invW := b0*v0.invW + b1*v1.invW + b2*v2.invW
+			if invW == 0 {
+				continue
+			}
+			for i := range varyings {
+				varyings[i] = (b0*v0.varyings[i] + b1*v1.varyings[i] + b2*v2.varyings[i]) / invW
+			}
+
+			colour := rs.program.Fragment(varyings)
+			rs.blend(pixel, colour)
+
+			if rs.depthWrite && rs.depth != nil {
+				rs.depth[pixel] = z
+			}
+		}
+	}
+}
+
+func insideEdge(w float32, topLeft bool) bool {
+	if w > 0 {
+		return true
+	}
+	return w == 0 && topLeft
+}
+
+// blend writes the colour into the colour attachment using standard alpha blending.
+func (rs *rasterState) blend(pixel int, colour math.Vec4) {
+	channels := int(rs.colour.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	index := pixel * channels
+	pixels := rs.colourData.Pixels
+	if index+channels > len(pixels) {
+		return
+	}
+
+	alpha := math.Clamp(colour.W, 0.0, 1.0)
+	src := [4]float32{colour.X, colour.Y, colour.Z, colour.W}
+	for c := 0; c < channels && c < 4; c++ {
+		dst := float32(pixels[index+c]) / 255.0
+		value := src[c]*alpha + dst*(1.0-alpha)
+		pixels[index+c] = uint8(math.Clamp(value, 0.0, 1.0)*255.0 + 0.5)
+	}
+}
+
+func m32min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func m32max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,208 @@
+package software
+
+import (
+	m "math"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The colour returned when sampling something which has no pixel data.
+var missingTextureColour = math.NewVec4(1.0, 1.0, 1.0, 1.0)
+
+// The colour returned when sampling a normal map which has no pixel data.
+var missingNormalColour = math.NewVec4(0.5, 0.5, 1.0, 1.0)
+
+// Sample2D samples the given texture map at the provided texture coordinates,
+// honouring the repeat and filter settings of the map. As there are no mip levels,
+// the magnification filter is always used.
+func Sample2D(textureMap *metadata.TextureMap, u, v float32) math.Vec4 {
+	texture, internal := mapTexture(textureMap)
+	if internal == nil || len(internal.Pixels) == 0 || texture.Width == 0 || texture.Height == 0 {
+		if textureMap != nil && textureMap.Use == metadata.TextureUseMapNormal {
+			return missingNormalColour
+		}
+		return missingTextureColour
+	}
+
+	u = wrapCoordinate(u, textureMap.RepeatU)
+	v = wrapCoordinate(v, textureMap.RepeatV)
+
+	if textureMap.FilterMagnify == metadata.TextureFilterModeNearest {
+		x := int(u * float32(texture.Width))
+		y := int(v * float32(texture.Height))
+		return texel(texture, internal.Pixels, 0, x, y, textureMap.RepeatU, textureMap.RepeatV)
+	}
+
+	// Bilinear filtering between the 4 closest texels.
+	fx := u*float32(texture.Width) - 0.5
+	fy := v*float32(texture.Height) - 0.5
+	x0 := int(m.Floor(float64(fx)))
+	y0 := int(m.Floor(float64(fy)))
+	tx := fx - float32(x0)
+	ty := fy - float32(y0)
+
+	c00 := texel(texture, internal.Pixels, 0, x0, y0, textureMap.RepeatU, textureMap.RepeatV)
+	c10 := texel(texture, internal.Pixels, 0, x0+1, y0, textureMap.RepeatU, textureMap.RepeatV)
+	c01 := texel(texture, internal.Pixels, 0, x0, y0+1, textureMap.RepeatU, textureMap.RepeatV)
+	c11 := texel(texture, internal.Pixels, 0, x0+1, y0+1, textureMap.RepeatU, textureMap.RepeatV)
+
+	return lerpVec4(lerpVec4(c00, c10, tx), lerpVec4(c01, c11, tx), ty)
+}
+
+// SampleCube samples the given cube texture map in the provided direction.
+// Faces are expected to be laid out one after the other as +X, -X, +Y, -Y, +Z, -Z.
+func SampleCube(textureMap *metadata.TextureMap, direction math.Vec3) math.Vec4 {
+	texture, internal := mapTexture(textureMap)
+	if internal == nil || len(internal.Pixels) == 0 || texture.Width == 0 || texture.Height == 0 {
+		return missingTextureColour
+	}
+
+	ax := kabs(direction.X)
+	ay := kabs(direction.Y)
+	az := kabs(direction.Z)
+
+	// Select the face and project the direction on it.
+	var face int
+	var sc, tc, ma float32
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if direction.X > 0 {
+			face, sc, tc = 0, -direction.Z, -direction.Y
+		} else {
+			face, sc, tc = 1, direction.Z, -direction.Y
+		}
+	case ay >= az:
+		ma = ay
+		if direction.Y > 0 {
+			face, sc, tc = 2, direction.X, direction.Z
+		} else {
+			face, sc, tc = 3, direction.X, -direction.Z
+		}
+	default:
+		ma = az
+		if direction.Z > 0 {
+			face, sc, tc = 4, direction.X, -direction.Y
+		} else {
+			face, sc, tc = 5, -direction.X, -direction.Y
+		}
+	}
+	if ma == 0 {
+		return missingTextureColour
+	}
+
+	u := 0.5 * (sc/ma + 1.0)
+	v := 0.5 * (tc/ma + 1.0)
+	x := int(u * float32(texture.Width))
+	y := int(v * float32(texture.Height))
+	return texel(texture, internal.Pixels, face, x, y, metadata.TextureRepeatClampToEdge, metadata.TextureRepeatClampToEdge)
+}
+
+func mapTexture(textureMap *metadata.TextureMap) (*metadata.Texture, *SoftwareTexture) {
+	if textureMap == nil || textureMap.Texture == nil {
+		return nil, nil
+	}
+	internal, ok := textureMap.Texture.InternalData.(*SoftwareTexture)
+	if !ok {
+		return textureMap.Texture, nil
+	}
+	return textureMap.Texture, internal
+}
+
+// texel fetches a single texel, resolving out-of-range coordinates with the given repeat modes.
+func texel(texture *metadata.Texture, pixels []uint8, face, x, y int, repeatU, repeatV metadata.TextureRepeat) math.Vec4 {
+	w := int(texture.Width)
+	h := int(texture.Height)
+	x = wrapIndex(x, w, repeatU)
+	y = wrapIndex(y, h, repeatV)
+	if x < 0 || y < 0 {
+		// Clamp to border, which is transparent black.
+		return math.Vec4{}
+	}
+
+	channels := int(texture.ChannelCount)
+	if channels == 0 {
+		channels = 4
+	}
+	index := ((face * w * h) + (y * w) + x) * channels
+	if index+channels > len(pixels) {
+		return missingTextureColour
+	}
+
+	out := math.NewVec4(0, 0, 0, 1)
+	out.X = float32(pixels[index]) / 255.0
+	if channels > 1 {
+		out.Y = float32(pixels[index+1]) / 255.0
+	}
+	if channels > 2 {
+		out.Z = float32(pixels[index+2]) / 255.0
+	}
+	if channels > 3 {
+		out.W = float32(pixels[index+3]) / 255.0
+	}
+	return out
+}
+
+func wrapCoordinate(c float32, repeat metadata.TextureRepeat) float32 {
+	switch repeat {
+	case metadata.TextureRepeatMirroredRepeat:
+		c = float32(m.Mod(float64(c), 2.0))
+		if c < 0 {
+			c += 2.0
+		}
+		if c > 1.0 {
+			c = 2.0 - c
+		}
+	case metadata.TextureRepeatClampToEdge, metadata.TextureRepeatClampToBorder:
+		// Resolved per texel.
+	default:
+		c -= float32(m.Floor(float64(c)))
+	}
+	return c
+}
+
+// wrapIndex resolves a texel index. Returns -1 for texels falling on the border.
+func wrapIndex(i, size int, repeat metadata.TextureRepeat) int {
+	switch repeat {
+	case metadata.TextureRepeatClampToBorder:
+		if i < 0 || i >= size {
+			return -1
+		}
+		return i
+	case metadata.TextureRepeatClampToEdge:
+		return math.Clamp(i, 0, size-1)
+	case metadata.TextureRepeatMirroredRepeat:
+		period := size * 2
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= size {
+			i = period - 1 - i
+		}
+		return i
+	default:
+		i %= size
+		if i < 0 {
+			i += size
+		}
+		return i
+	}
+}
+
+func lerpVec4(a, b math.Vec4, t float32) math.Vec4 {
+	return math.NewVec4(
+		a.X+(b.X-a.X)*t,
+		a.Y+(b.Y-a.Y)*t,
+		a.Z+(b.Z-a.Z)*t,
+		a.W+(b.W-a.W)*t,
+	)
+}
+
+func kabs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
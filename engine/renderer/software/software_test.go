@@ -0,0 +1,561 @@
+package software
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+const (
+	// The largest difference allowed between two channels of a pixel and its golden counterpart.
+	goldenChannelTolerance = 8
+	// The fraction of the pixels allowed to differ by more than the channel tolerance.
+	goldenPixelTolerance = 0.005
+)
+
+func TestMain(m *testing.M) {
+	if err := core.InitializeLogger(core.WarnLevel); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// compareGolden compares the image with testdata/<name>.png, or writes it there with -update.
+func compareGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".png")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create the golden images)", err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("%s: got an image of %v, want %v", name, img.Bounds(), golden.Bounds())
+	}
+
+	differing := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := golden.At(x, y).RGBA()
+			want := [4]int{int(r >> 8), int(g >> 8), int(b >> 8), int(a >> 8)}
+			got := img.Pix[img.PixOffset(x, y):]
+			for c := 0; c < 4; c++ {
+				if d := int(got[c]) - want[c]; d > goldenChannelTolerance || d < -goldenChannelTolerance {
+					differing++
+					break
+				}
+			}
+		}
+	}
+	if limit := int(goldenPixelTolerance * float64(bounds.Dx()*bounds.Dy())); differing > limit {
+		t.Errorf("%s: %d pixels differ from the golden image, at most %d may", name, differing, limit)
+	}
+}
+
+/** @brief A software renderer drawing into its window attachments, cleared every frame. */
+type testScene struct {
+	t        *testing.T
+	renderer *SoftwareRenderer
+	pass     *metadata.RenderPass
+	target   *metadata.RenderTarget
+}
+
+func newTestScene(t *testing.T, width, height uint32) *testScene {
+	t.Helper()
+	sr := New(width, height)
+	var targetCount uint8
+	if err := sr.Initialize(&metadata.RendererBackendConfig{}, &targetCount); err != nil {
+		t.Fatal(err)
+	}
+	pass, err := sr.RenderPassCreate(&metadata.RenderPassConfig{
+		Name:              "Renderpass.Test",
+		ClearColour:       math.NewVec4(0.1, 0.1, 0.2, 1.0),
+		ClearFlags:        metadata.RENDERPASS_CLEAR_COLOUR_BUFFER_FLAG | metadata.RENDERPASS_CLEAR_DEPTH_BUFFER_FLAG,
+		RenderTargetCount: 1,
+		Target:            &metadata.RenderTargetConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments := []*metadata.RenderTargetAttachment{
+		{RenderTargetAttachmentType: metadata.RENDER_TARGET_ATTACHMENT_TYPE_COLOUR, Texture: sr.WindowAttachmentGet(0)},
+		{RenderTargetAttachmentType: metadata.RENDER_TARGET_ATTACHMENT_TYPE_DEPTH, Texture: sr.DepthAttachmentGet(0)},
+	}
+	target, err := sr.RenderTargetCreate(uint8(len(attachments)), attachments, pass, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testScene{t: t, renderer: sr, pass: pass, target: target}
+}
+
+/** @brief A uniform declared by a test shader. */
+type testUniform struct {
+	name    string
+	scope   metadata.ShaderScope
+	sampler bool
+}
+
+// shader creates a shader running the program registered for the given name. Samplers are
+// numbered in the order they are declared, like the texture maps of an instance.
+func (s *testScene) shader(name string, config *metadata.ShaderConfig, uniforms []testUniform) *metadata.Shader {
+	s.t.Helper()
+	config.Name = name
+	shader := &metadata.Shader{
+		Name:          name,
+		UniformLookup: map[string]uint16{},
+	}
+	samplers := uint16(0)
+	for _, u := range uniforms {
+		uniform := metadata.ShaderUniform{Index: uint16(len(shader.Uniforms)), Scope: u.scope}
+		uniform.Location = uniform.Index
+		if u.sampler {
+			uniform.ShaderUniformType = metadata.ShaderUniformTypeSampler
+			uniform.Location = samplers
+			samplers++
+		}
+		shader.UniformLookup[u.name] = uniform.Index
+		shader.Uniforms = append(shader.Uniforms, uniform)
+	}
+	if err := s.renderer.ShaderCreate(shader, config, s.pass, 0, nil, nil); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.renderer.ShaderInitialize(shader); err != nil {
+		s.t.Fatal(err)
+	}
+	return shader
+}
+
+func (s *testScene) set(shader *metadata.Shader, name string, value interface{}) {
+	s.t.Helper()
+	index, ok := shader.UniformLookup[name]
+	if !ok {
+		s.t.Fatalf("shader `%s` has no uniform `%s`", shader.Name, name)
+	}
+	if err := s.renderer.SetUniform(shader, shader.Uniforms[index], value); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func (s *testScene) texture(name string, textureType metadata.TextureType, width, height uint32, pixels []uint8) *metadata.Texture {
+	s.t.Helper()
+	texture := &metadata.Texture{
+		Name:         name,
+		TextureType:  textureType,
+		Width:        width,
+		Height:       height,
+		ChannelCount: 4,
+	}
+	if err := s.renderer.TextureCreate(pixels, texture); err != nil {
+		s.t.Fatal(err)
+	}
+	return texture
+}
+
+func (s *testScene) geometry(name string, vertices []math.Vertex3D, indices []uint32) *metadata.Geometry {
+	s.t.Helper()
+	geometry := &metadata.Geometry{Name: name}
+	if err := s.renderer.CreateGeometry(geometry, 0, uint32(len(vertices)), vertices, 4, uint32(len(indices)), indices); err != nil {
+		s.t.Fatal(err)
+	}
+	return geometry
+}
+
+// frame renders a single frame, running draw inside the pass, and returns the presented image.
+func (s *testScene) frame(draw func()) *image.RGBA {
+	s.t.Helper()
+	if err := s.renderer.BeginFrame(1.0 / 60.0); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.renderer.RenderPassBegin(s.pass, s.target); err != nil {
+		s.t.Fatal(err)
+	}
+	draw()
+	if err := s.renderer.RenderPassEnd(s.pass); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.renderer.EndFrame(1.0 / 60.0); err != nil {
+		s.t.Fatal(err)
+	}
+	return s.renderer.Screenshot()
+}
+
+func (s *testScene) draw(shader *metadata.Shader, geometry *metadata.Geometry) {
+	s.t.Helper()
+	if err := s.renderer.ShaderUse(shader); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.renderer.DrawGeometry(&metadata.GeometryRenderData{Geometry: geometry}); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// quad returns a counter-clockwise quad facing +z, spanning [x0, x1] x [y0, y1] at depth z, with
+// texture coordinates from (0, 0) to (uv, uv).
+func quad(x0, y0, x1, y1, z, uv float32) ([]math.Vertex3D, []uint32) {
+	vertex := func(x, y, u, v float32) math.Vertex3D {
+		return math.Vertex3D{
+			Position: math.NewVec3(x, y, z),
+			Normal:   math.NewVec3(0, 0, 1),
+			Texcoord: math.NewVec2(u, v),
+			Colour:   math.NewVec4One(),
+			Tangent:  math.NewVec3(1, 0, 0),
+		}
+	}
+	vertices := []math.Vertex3D{
+		vertex(x0, y0, 0, uv),
+		vertex(x1, y0, uv, uv),
+		vertex(x1, y1, uv, 0),
+		vertex(x0, y1, 0, 0),
+	}
+	return vertices, []uint32{0, 1, 2, 0, 2, 3}
+}
+
+// cube returns a cube of the given size centered on the origin, its faces wound counter-clockwise
+// when seen from outside.
+func cube(size float32) ([]math.Vertex3D, []uint32) {
+	h := size * 0.5
+	faces := []struct{ normal, tangent, bitangent math.Vec3 }{
+		{math.NewVec3(0, 0, 1), math.NewVec3(1, 0, 0), math.NewVec3(0, 1, 0)},
+		{math.NewVec3(0, 0, -1), math.NewVec3(-1, 0, 0), math.NewVec3(0, 1, 0)},
+		{math.NewVec3(1, 0, 0), math.NewVec3(0, 0, -1), math.NewVec3(0, 1, 0)},
+		{math.NewVec3(-1, 0, 0), math.NewVec3(0, 0, 1), math.NewVec3(0, 1, 0)},
+		{math.NewVec3(0, 1, 0), math.NewVec3(1, 0, 0), math.NewVec3(0, 0, -1)},
+		{math.NewVec3(0, -1, 0), math.NewVec3(1, 0, 0), math.NewVec3(0, 0, 1)},
+	}
+	corners := []struct{ s, t, u, v float32 }{{-1, -1, 0, 1}, {1, -1, 1, 1}, {1, 1, 1, 0}, {-1, 1, 0, 0}}
+	vertices := []math.Vertex3D{}
+	indices := []uint32{}
+	for _, face := range faces {
+		base := uint32(len(vertices))
+		for _, c := range corners {
+			position := face.normal.Add(face.tangent.MulScalar(c.s)).Add(face.bitangent.MulScalar(c.t)).MulScalar(h)
+			vertices = append(vertices, math.Vertex3D{
+				Position: position,
+				Normal:   face.normal,
+				Texcoord: math.NewVec2(c.u, c.v),
+				Colour:   math.NewVec4One(),
+				Tangent:  face.tangent,
+			})
+		}
+		indices = append(indices, base, base+1, base+2, base, base+2, base+3)
+	}
+	return vertices, indices
+}
+
+// checker returns the RGBA pixels of a size x size checkerboard of the two colours.
+func checker(size int, a, b [4]uint8) []uint8 {
+	pixels := make([]uint8, 0, size*size*4)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if (x+y)%2 == 0 {
+				pixels = append(pixels, a[:]...)
+			} else {
+				pixels = append(pixels, b[:]...)
+			}
+		}
+	}
+	return pixels
+}
+
+// The uniforms of the pick shaders, which draw in a flat colour.
+var pickUniforms = []testUniform{
+	{"projection", metadata.ShaderScopeGlobal, false},
+	{"view", metadata.ShaderScopeGlobal, false},
+	{"id_colour", metadata.ShaderScopeInstance, false},
+	{"model", metadata.ShaderScopeLocal, false},
+}
+
+// flatShader creates a shader drawing in a flat colour with identity matrices, so positions are
+// given in clip space.
+func (s *testScene) flatShader(config *metadata.ShaderConfig) *metadata.Shader {
+	s.t.Helper()
+	shader := s.shader("Shader.Builtin.WorldPick", config, pickUniforms)
+	if _, err := s.renderer.ShaderAcquireInstanceResources(shader, nil); err != nil {
+		s.t.Fatal(err)
+	}
+	s.set(shader, "projection", math.NewMat4Identity())
+	s.set(shader, "view", math.NewMat4Identity())
+	s.set(shader, "model", math.NewMat4Identity())
+	return shader
+}
+
+func pixelAt(img *image.RGBA, x, y int) [4]uint8 {
+	i := img.PixOffset(x, y)
+	return [4]uint8{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+}
+
+var (
+	red   = [4]uint8{255, 0, 0, 255}
+	green = [4]uint8{0, 255, 0, 255}
+	blue  = [4]uint8{0, 0, 255, 255}
+	clear = [4]uint8{26, 26, 51, 255}
+)
+
+func TestGoldenDepth(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	vertices, indices := quad(-0.75, -0.75, 0.25, 0.25, -0.5, 1)
+	near := s.geometry("near", vertices, indices)
+	vertices, indices = quad(-0.25, -0.25, 0.75, 0.75, 0.5, 1)
+	far := s.geometry("far", vertices, indices)
+
+	tests := []struct {
+		name   string
+		config metadata.ShaderConfig
+		// The geometries in drawing order.
+		order  []*metadata.Geometry
+		golden string
+		// The colour where the quads overlap.
+		overlap [4]uint8
+	}{
+		{"tested, far first", metadata.ShaderConfig{DepthTest: true, DepthWrite: true}, []*metadata.Geometry{far, near}, "depth", red},
+		{"tested, near first", metadata.ShaderConfig{DepthTest: true, DepthWrite: true}, []*metadata.Geometry{near, far}, "depth", red},
+		{"not written", metadata.ShaderConfig{DepthTest: true}, []*metadata.Geometry{near, far}, "depth_unwritten", green},
+		{"not tested", metadata.ShaderConfig{DepthWrite: true}, []*metadata.Geometry{near, far}, "depth_untested", green},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shader := s.flatShader(&test.config)
+			img := s.frame(func() {
+				for _, geometry := range test.order {
+					if geometry == near {
+						s.set(shader, "id_colour", math.NewVec3(1, 0, 0))
+					} else {
+						s.set(shader, "id_colour", math.NewVec3(0, 1, 0))
+					}
+					s.draw(shader, geometry)
+				}
+			})
+			if got := pixelAt(img, 32, 32); got != test.overlap {
+				t.Errorf("got %v where the quads overlap, want %v", got, test.overlap)
+			}
+			compareGolden(t, test.golden, img)
+		})
+	}
+}
+
+func TestGoldenCulling(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	// The left quad faces the camera, the right one is wound the other way.
+	vertices, indices := quad(-0.9, -0.5, -0.1, 0.5, 0, 1)
+	front := s.geometry("front", vertices, indices)
+	vertices, indices = quad(0.1, -0.5, 0.9, 0.5, 0, 1)
+	back := s.geometry("back", vertices, []uint32{indices[0], indices[2], indices[1], indices[3], indices[5], indices[4]})
+
+	tests := []struct {
+		mode        metadata.FaceCullMode
+		golden      string
+		left, right [4]uint8
+	}{
+		{metadata.FaceCullModeNone, "cull_none", red, blue},
+		{metadata.FaceCullModeBack, "cull_back", red, clear},
+		{metadata.FaceCullModeFront, "cull_front", clear, blue},
+		{metadata.FaceCullModeFrontAndBack, "cull_front_and_back", clear, clear},
+	}
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			shader := s.flatShader(&metadata.ShaderConfig{CullMode: test.mode})
+			img := s.frame(func() {
+				s.set(shader, "id_colour", math.NewVec3(1, 0, 0))
+				s.draw(shader, front)
+				s.set(shader, "id_colour", math.NewVec3(0, 0, 1))
+				s.draw(shader, back)
+			})
+			if got := pixelAt(img, 16, 32); got != test.left {
+				t.Errorf("got %v on the left, want %v", got, test.left)
+			}
+			if got := pixelAt(img, 48, 32); got != test.right {
+				t.Errorf("got %v on the right, want %v", got, test.right)
+			}
+			compareGolden(t, test.golden, img)
+		})
+	}
+}
+
+// The uniforms of the UI shader.
+var uiUniforms = []testUniform{
+	{"projection", metadata.ShaderScopeGlobal, false},
+	{"view", metadata.ShaderScopeGlobal, false},
+	{"diffuse_colour", metadata.ShaderScopeInstance, false},
+	{"diffuse_texture", metadata.ShaderScopeInstance, true},
+	{"model", metadata.ShaderScopeLocal, false},
+}
+
+func TestGoldenTextureMap(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	texture := s.texture("checker", metadata.TextureType2d, 4, 4, checker(4, [4]uint8{255, 255, 255, 255}, [4]uint8{200, 40, 40, 255}))
+	// The quad covers the screen and repeats the texture twice, from -0.5 to 1.5.
+	vertices, indices := quad(-1, -1, 1, 1, 0, 2)
+	for i := range vertices {
+		vertices[i].Texcoord = vertices[i].Texcoord.Sub(math.NewVec2(0.5, 0.5))
+	}
+	screen := s.geometry("screen", vertices, indices)
+
+	tests := []struct {
+		golden string
+		filter metadata.TextureFilter
+		repeat metadata.TextureRepeat
+	}{
+		{"texture_nearest_repeat", metadata.TextureFilterModeNearest, metadata.TextureRepeatRepeat},
+		{"texture_linear_repeat", metadata.TextureFilterModeLinear, metadata.TextureRepeatRepeat},
+		{"texture_nearest_mirrored_repeat", metadata.TextureFilterModeNearest, metadata.TextureRepeatMirroredRepeat},
+		{"texture_nearest_clamp_to_edge", metadata.TextureFilterModeNearest, metadata.TextureRepeatClampToEdge},
+		{"texture_linear_clamp_to_border", metadata.TextureFilterModeLinear, metadata.TextureRepeatClampToBorder},
+	}
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			shader := s.shader("Shader.Builtin.UI", &metadata.ShaderConfig{}, uiUniforms)
+			textureMap := &metadata.TextureMap{
+				Texture:       texture,
+				Use:           metadata.TextureUseMapDiffuse,
+				FilterMinify:  test.filter,
+				FilterMagnify: test.filter,
+				RepeatU:       test.repeat,
+				RepeatV:       test.repeat,
+				RepeatW:       test.repeat,
+			}
+			if _, err := s.renderer.ShaderAcquireInstanceResources(shader, []*metadata.TextureMap{textureMap}); err != nil {
+				t.Fatal(err)
+			}
+			s.set(shader, "projection", math.NewMat4Identity())
+			s.set(shader, "view", math.NewMat4Identity())
+			s.set(shader, "model", math.NewMat4Identity())
+			s.set(shader, "diffuse_colour", math.NewVec4One())
+			compareGolden(t, test.golden, s.frame(func() { s.draw(shader, screen) }))
+		})
+	}
+}
+
+func TestGoldenUI(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	texture := s.texture("checker", metadata.TextureType2d, 2, 2, checker(2, [4]uint8{255, 255, 255, 255}, [4]uint8{0, 0, 0, 128}))
+	shader := s.shader("Shader.Builtin.UI", &metadata.ShaderConfig{}, uiUniforms)
+	textureMap := &metadata.TextureMap{
+		Texture:       texture,
+		Use:           metadata.TextureUseMapDiffuse,
+		FilterMinify:  metadata.TextureFilterModeNearest,
+		FilterMagnify: metadata.TextureFilterModeNearest,
+	}
+	if _, err := s.renderer.ShaderAcquireInstanceResources(shader, []*metadata.TextureMap{textureMap}); err != nil {
+		t.Fatal(err)
+	}
+	// Lay the quad out in pixels, as the UI view does, tinted and half transparent in places.
+	vertices, indices := quad(0, 0, 32, 32, 0, 1)
+	panel := s.geometry("panel", vertices, indices)
+	s.set(shader, "projection", math.NewMat4Orthographic(0, 64, 64, 0, -100, 100))
+	s.set(shader, "view", math.NewMat4Identity())
+	s.set(shader, "model", math.NewMat4Translation(math.NewVec3(16, 8, 0)))
+	s.set(shader, "diffuse_colour", math.NewVec4(0.5, 1.0, 0.5, 1.0))
+	compareGolden(t, "ui", s.frame(func() { s.draw(shader, panel) }))
+}
+
+func TestGoldenSkybox(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	// One colour per face, +X, -X, +Y, -Y, +Z, -Z.
+	faces := [][4]uint8{{255, 0, 0, 255}, {0, 255, 255, 255}, {0, 255, 0, 255}, {255, 0, 255, 255}, {0, 0, 255, 255}, {255, 255, 0, 255}}
+	pixels := []uint8{}
+	for _, face := range faces {
+		for i := 0; i < 4; i++ {
+			pixels = append(pixels, face[:]...)
+		}
+	}
+	texture := s.texture("sky", metadata.TextureTypeCube, 2, 2, pixels)
+	shader := s.shader("Shader.Builtin.Skybox", &metadata.ShaderConfig{CullMode: metadata.FaceCullModeFront}, []testUniform{
+		{"projection", metadata.ShaderScopeGlobal, false},
+		{"view", metadata.ShaderScopeGlobal, false},
+		{"cube_texture", metadata.ShaderScopeInstance, true},
+	})
+	if _, err := s.renderer.ShaderAcquireInstanceResources(shader, []*metadata.TextureMap{{Texture: texture, Use: metadata.TextureUseMapCubemap}}); err != nil {
+		t.Fatal(err)
+	}
+	vertices, indices := cube(10)
+	box := s.geometry("skybox", vertices, indices)
+	// Look at a corner, so three faces are visible.
+	s.set(shader, "projection", math.NewMat4Perspective(math.DegToRad(90), 1, 0.1, 100))
+	s.set(shader, "view", math.NewMat4EulerY(math.DegToRad(45)).Mul(math.NewMat4EulerX(math.DegToRad(30))))
+	compareGolden(t, "skybox", s.frame(func() { s.draw(shader, box) }))
+}
+
+func TestGoldenMaterial(t *testing.T) {
+	s := newTestScene(t, 64, 64)
+	diffuse := s.texture("diffuse", metadata.TextureType2d, 4, 4, checker(4, [4]uint8{230, 230, 230, 255}, [4]uint8{60, 120, 200, 255}))
+	shader := s.shader("Shader.Builtin.Material", &metadata.ShaderConfig{CullMode: metadata.FaceCullModeBack, DepthTest: true, DepthWrite: true}, []testUniform{
+		{"projection", metadata.ShaderScopeGlobal, false},
+		{"view", metadata.ShaderScopeGlobal, false},
+		{"ambient_colour", metadata.ShaderScopeGlobal, false},
+		{"view_position", metadata.ShaderScopeGlobal, false},
+		{"mode", metadata.ShaderScopeGlobal, false},
+		{"dir_light", metadata.ShaderScopeGlobal, false},
+		{"point_light_count", metadata.ShaderScopeGlobal, false},
+		{"point_lights", metadata.ShaderScopeGlobal, false},
+		{"diffuse_colour", metadata.ShaderScopeInstance, false},
+		{"diffuse_texture", metadata.ShaderScopeInstance, true},
+		{"specular_texture", metadata.ShaderScopeInstance, true},
+		{"normal_texture", metadata.ShaderScopeInstance, true},
+		{"shininess", metadata.ShaderScopeInstance, false},
+		{"model", metadata.ShaderScopeLocal, false},
+	})
+	maps := []*metadata.TextureMap{
+		{Texture: diffuse, Use: metadata.TextureUseMapDiffuse, FilterMagnify: metadata.TextureFilterModeNearest},
+		// No specular nor normal texture, which the program replaces with white and a flat normal.
+		{Use: metadata.TextureUseMapSpecular},
+		{Use: metadata.TextureUseMapNormal},
+	}
+	if _, err := s.renderer.ShaderAcquireInstanceResources(shader, maps); err != nil {
+		t.Fatal(err)
+	}
+	vertices, indices := cube(1)
+	box := s.geometry("cube", vertices, indices)
+
+	var pointLights [metadata.MAX_POINT_LIGHTS]metadata.PointLight
+	pointLights[0] = metadata.PointLight{
+		Colour:      math.NewVec4(1.0, 0.4, 0.1, 1.0),
+		Position:    math.NewVec3(-1.5, 0.5, 1.0),
+		Intensity:   1.0,
+		Attenuation: metadata.LightAttenuation{Constant: 1.0, Linear: 0.35, Quadratic: 0.44},
+	}
+	s.set(shader, "projection", math.NewMat4Perspective(math.DegToRad(45), 1, 0.1, 100))
+	s.set(shader, "view", math.NewMat4Translation(math.NewVec3(0, 0, -3)))
+	s.set(shader, "ambient_colour", math.NewVec4(0.2, 0.2, 0.2, 1.0))
+	s.set(shader, "view_position", math.NewVec3(0, 0, 3))
+	s.set(shader, "mode", uint32(metadata.RENDERER_VIEW_MODE_DEFAULT))
+	s.set(shader, "dir_light", metadata.DirectionalLight{
+		Colour:    math.NewVec4(0.8, 0.8, 0.7, 1.0),
+		Direction: normalizeVec3(math.NewVec3(-0.5, -1.0, -0.7)),
+		Intensity: 1.0,
+	})
+	s.set(shader, "point_light_count", uint32(1))
+	s.set(shader, "point_lights", pointLights)
+	s.set(shader, "diffuse_colour", math.NewVec4One())
+	s.set(shader, "shininess", float32(16))
+	s.set(shader, "model", math.NewMat4EulerY(math.DegToRad(35)).Mul(math.NewMat4EulerX(math.DegToRad(25))))
+	compareGolden(t, "material", s.frame(func() { s.draw(shader, box) }))
+}
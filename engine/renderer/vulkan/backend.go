@@ -11,7 +11,7 @@ import (
 	"github.com/spaghettifunk/anima/engine/assets"
 	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/math"
-	"github.com/spaghettifunk/anima/engine/platform"
+	"github.com/spaghettifunk/anima/engine/platform/window"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
 
@@ -23,13 +23,12 @@ const (
 )
 
 type VulkanRenderer struct {
-	platform *platform.Platform
+	platform *window.GLFWPlatform
 	context  *VulkanContext
 
 	assetManager   *assets.AssetManager
 	defaultTexture *metadata.DefaultTexture
 
-	FrameNumber       uint64
 	FramebufferWidth  uint32
 	FramebufferHeight uint32
 
@@ -38,7 +37,9 @@ type VulkanRenderer struct {
 
 var lockPool *VulkanLockPool
 
-func New(p *platform.Platform, am *assets.AssetManager) *VulkanRenderer {
+var _ metadata.RendererBackend = (*VulkanRenderer)(nil)
+
+func New(p *window.GLFWPlatform, am *assets.AssetManager) *VulkanRenderer {
 	defaultTextures := metadata.NewDefaultTexture()
 	defaultTextures.CreateSkeletonTextures()
 	lockPool = NewVulkanLockPool()
@@ -47,7 +48,6 @@ func New(p *platform.Platform, am *assets.AssetManager) *VulkanRenderer {
 		platform:       p,
 		assetManager:   am,
 		defaultTexture: defaultTextures,
-		FrameNumber:    0,
 		context: &VulkanContext{
 			Geometries:                    make([]*VulkanGeometryData, VULKAN_MAX_GEOMETRY_COUNT),
 			FramebufferWidth:              0,
@@ -342,7 +342,7 @@ func (vr *VulkanRenderer) Initialize(config *metadata.RendererBackendConfig, win
 	return nil
 }
 
-func (vr *VulkanRenderer) Shutdow() error {
+func (vr *VulkanRenderer) Shutdown() error {
 	if err := lockPool.SafeCall(DeviceManagement, func() error {
 		if res := vk.DeviceWaitIdle(vr.context.Device.LogicalDevice); !VulkanResultIsSuccess(res) {
 			err := fmt.Errorf("device wait idle failed with error %s", VulkanResultString(res, true))
@@ -1092,7 +1092,8 @@ func (vr *VulkanRenderer) RenderPassCreate(config *metadata.RenderPassConfig) (*
 	}
 
 	pass := &metadata.RenderPass{
-		ID: metadata.InvalidIDUint16,
+		ID:   metadata.InvalidIDUint16,
+		Name: config.Name,
 		InternalData: &VulkanRenderPass{
 			Depth:   config.Depth,
 			Stencil: config.Stencil,
@@ -1374,7 +1375,7 @@ func (vr *VulkanRenderer) RenderPassBegin(pass *metadata.RenderPass, target *met
 	beginInfo := vk.RenderPassBeginInfo{
 		SType:       vk.StructureTypeRenderPassBeginInfo,
 		RenderPass:  internalData.Handle,
-		Framebuffer: target.InternalFramebuffer,
+		Framebuffer: target.InternalFramebuffer.(vk.Framebuffer),
 		RenderArea: vk.Rect2D{
 			Offset: vk.Offset2D{
 				X: int32(pass.RenderArea.X),
@@ -1676,12 +1677,15 @@ func (vr *VulkanRenderer) ShaderCreate(shader *metadata.Shader, config *metadata
 	internalShader.InstanceUniformCount = 0
 	internalShader.InstanceUniformSamplerCount = 0
 	internalShader.LocalUniformCount = 0
+	internalShader.GlobalStorageCount = 0
 
 	totalCount := len(config.Uniforms)
 	for i := 0; i < totalCount; i++ {
 		switch config.Uniforms[i].Scope {
 		case metadata.ShaderScopeGlobal:
-			if config.Uniforms[i].ShaderUniformType == metadata.ShaderUniformTypeSampler {
+			if config.Uniforms[i].Storage {
+				internalShader.GlobalStorageCount++
+			} else if config.Uniforms[i].ShaderUniformType == metadata.ShaderUniformTypeSampler {
 				internalShader.GlobalUniformSamplerCount++
 			} else {
 				internalShader.GlobalUniformCount++
@@ -1697,46 +1701,70 @@ func (vr *VulkanRenderer) ShaderCreate(shader *metadata.Shader, config *metadata
 		}
 	}
 
-	// For now, shaders will only ever have these 2 types of descriptor pools.
+	if uint32(internalShader.GlobalStorageCount) > VULKAN_SHADER_MAX_STORAGE_BUFFERS {
+		err := fmt.Errorf("shader '%s' has %d storage buffers, the max is %d", config.Name, internalShader.GlobalStorageCount, VULKAN_SHADER_MAX_STORAGE_BUFFERS)
+		return err
+	}
+
+	// For now, shaders will only ever have these 2 types of descriptor pools, and one for the storage buffers if any.
 	internalShader.Config.PoolSizes[0] = vk.DescriptorPoolSize{Type: vk.DescriptorTypeUniformBuffer, DescriptorCount: 1024}        // HACK: max number of ubo descriptor sets.
 	internalShader.Config.PoolSizes[1] = vk.DescriptorPoolSize{Type: vk.DescriptorTypeCombinedImageSampler, DescriptorCount: 4096} // HACK: max number of image sampler descriptor sets.
+	if internalShader.GlobalStorageCount > 0 {
+		// One per storage buffer of each of the global descriptor sets, one per frame.
+		internalShader.Config.PoolSizes = append(internalShader.Config.PoolSizes, vk.DescriptorPoolSize{
+			Type:            vk.DescriptorTypeStorageBuffer,
+			DescriptorCount: 3 * uint32(internalShader.GlobalStorageCount),
+		})
+	}
 
-	internalShader.Config.PoolSizes[0].Deref()
-	internalShader.Config.PoolSizes[1].Deref()
+	for i := range internalShader.Config.PoolSizes {
+		internalShader.Config.PoolSizes[i].Deref()
+	}
 
 	// Global descriptor set Config.
 	descriptorSetCount := 0
-	if internalShader.GlobalUniformCount > 0 || internalShader.GlobalUniformSamplerCount > 0 {
+	if internalShader.GlobalUniformCount > 0 || internalShader.GlobalUniformSamplerCount > 0 || internalShader.GlobalStorageCount > 0 {
 		// Global descriptor set Config.
 		setConfig := internalShader.Config.DescriptorSets[descriptorSetCount]
-		if len(setConfig.Bindings) == 0 {
-			// we do not know the size in advance
-			setConfig.Bindings = []vk.DescriptorSetLayoutBinding{{}}
-		}
+		// we do not know the size in advance
+		setConfig.Bindings = []vk.DescriptorSetLayoutBinding{}
 
 		// Global UBO binding is first, if present.
 		if internalShader.GlobalUniformCount > 0 {
-			bindingIndex := setConfig.BindingCount
-			setConfig.Bindings[bindingIndex] = vk.DescriptorSetLayoutBinding{
-				Binding:         uint32(bindingIndex),
+			binding := vk.DescriptorSetLayoutBinding{
+				Binding:         uint32(setConfig.BindingCount),
 				DescriptorCount: 1,
 				DescriptorType:  vk.DescriptorTypeUniformBuffer,
 				StageFlags:      vk.ShaderStageFlags(vk.ShaderStageVertexBit) | vk.ShaderStageFlags(vk.ShaderStageFragmentBit),
 			}
-			setConfig.Bindings[bindingIndex].Deref()
+			binding.Deref()
+			setConfig.Bindings = append(setConfig.Bindings, binding)
 			setConfig.BindingCount++
 		}
 		// Add a binding for Samplers if used.
 		if internalShader.GlobalUniformSamplerCount > 0 {
-			bindingIndex := setConfig.BindingCount
-			setConfig.Bindings[bindingIndex] = vk.DescriptorSetLayoutBinding{
-				Binding:         uint32(bindingIndex),
+			binding := vk.DescriptorSetLayoutBinding{
+				Binding:         uint32(setConfig.BindingCount),
 				DescriptorCount: uint32(internalShader.GlobalUniformSamplerCount), // One descriptor per sampler.
 				DescriptorType:  vk.DescriptorTypeCombinedImageSampler,
 				StageFlags:      vk.ShaderStageFlags(vk.ShaderStageVertexBit) | vk.ShaderStageFlags(vk.ShaderStageFragmentBit),
 			}
-			setConfig.Bindings[bindingIndex].Deref()
-			setConfig.SamplerBindingIndex = bindingIndex
+			binding.Deref()
+			setConfig.Bindings = append(setConfig.Bindings, binding)
+			setConfig.SamplerBindingIndex = setConfig.BindingCount
+			setConfig.BindingCount++
+		}
+		// Then a binding per storage buffer, in the order they are declared.
+		setConfig.StorageBindingIndex = setConfig.BindingCount
+		for i := uint8(0); i < internalShader.GlobalStorageCount; i++ {
+			binding := vk.DescriptorSetLayoutBinding{
+				Binding:         uint32(setConfig.BindingCount),
+				DescriptorCount: 1,
+				DescriptorType:  vk.DescriptorTypeStorageBuffer,
+				StageFlags:      vk.ShaderStageFlags(vk.ShaderStageVertexBit) | vk.ShaderStageFlags(vk.ShaderStageFragmentBit),
+			}
+			binding.Deref()
+			setConfig.Bindings = append(setConfig.Bindings, binding)
 			setConfig.BindingCount++
 		}
 		// Increment the set counter.
@@ -1810,11 +1838,23 @@ func (vr *VulkanRenderer) ShaderCreate(shader *metadata.Shader, config *metadata
 func (vr *VulkanRenderer) ShaderDestroy(s *metadata.Shader) error {
 	if s != nil && s.InternalData != nil {
 		shader := s.InternalData.(*VulkanShader)
-		if shader != nil {
+		if shader == nil {
 			err := fmt.Errorf("vulkan_renderer_shader_destroy requires a valid pointer to a shader")
 			return err
 		}
 
+		// The shader can be destroyed while running (i.e. when reloaded), so make sure
+		// no frame in flight still uses it.
+		if err := lockPool.SafeCall(DeviceManagement, func() error {
+			if res := vk.DeviceWaitIdle(vr.context.Device.LogicalDevice); !VulkanResultIsSuccess(res) {
+				err := fmt.Errorf("device wait idle failed with error %s", VulkanResultString(res, true))
+				return err
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
 		logicalDevice := vr.context.Device.LogicalDevice
 		vkAllocator := vr.context.Allocator
 
@@ -1849,6 +1889,14 @@ func (vr *VulkanRenderer) ShaderDestroy(s *metadata.Shader) error {
 
 		vr.RenderBufferDestroy(shader.UniformBuffer)
 
+		// Storage buffer.
+		if shader.StorageBuffer != nil {
+			vr.RenderBufferUnmapMemory(shader.StorageBuffer, 0, vk.WholeSize)
+			shader.MappedStorageBufferBlock = nil
+			vr.RenderBufferDestroy(shader.StorageBuffer)
+			shader.StorageBuffer = nil
+		}
+
 		// Pipeline
 		shader.Pipeline.Destroy(vr.context)
 
@@ -1928,7 +1976,7 @@ func (vr *VulkanRenderer) ShaderInitialize(shader *metadata.Shader) error {
 	// Descriptor pool.
 	poolInfo := vk.DescriptorPoolCreateInfo{
 		SType:         vk.StructureTypeDescriptorPoolCreateInfo,
-		PoolSizeCount: 2,
+		PoolSizeCount: uint32(len(internalShader.Config.PoolSizes)),
 		PPoolSizes:    internalShader.Config.PoolSizes,
 		MaxSets:       uint32(internalShader.Config.MaxDescriptorSetCount),
 		Flags:         vk.DescriptorPoolCreateFlags(vk.DescriptorPoolCreateFreeDescriptorSetBit),
@@ -2015,6 +2063,7 @@ func (vr *VulkanRenderer) ShaderInitialize(shader *metadata.Shader) error {
 		Scissor:              scissor,
 		CullMode:             internalShader.Config.CullMode,
 		PushConstantRanges:   shader.PushConstantRanges,
+		PushConstantStages:   shaderStageFlags(shader.PushConstantStages),
 		IsWireframe:          false,
 		ShaderFlags:          shader.Flags,
 	}
@@ -2052,6 +2101,20 @@ func (vr *VulkanRenderer) ShaderInitialize(shader *metadata.Shader) error {
 		return err
 	}
 
+	// Storage buffers, all in one buffer at the offsets given by the frontend.
+	if shader.StorageSize > 0 {
+		internalShader.StorageBuffer, err = vr.RenderBufferCreate(metadata.RENDERBUFFER_TYPE_STORAGE, shader.StorageSize)
+		if err != nil {
+			core.LogError("Vulkan storage buffer creation failed for shader '%s'.", shader.Name)
+			return err
+		}
+		vr.RenderBufferBind(internalShader.StorageBuffer, 0)
+		internalShader.MappedStorageBufferBlock, err = vr.RenderBufferMapMemory(internalShader.StorageBuffer, 0, vk.WholeSize)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Allocate global descriptor sets, one per frame. Global is always the first set.
 	globalLayouts := []vk.DescriptorSetLayout{
 		internalShader.DescriptorSetLayouts[DESC_SET_INDEX_GLOBAL],
@@ -2094,7 +2157,7 @@ func (vr *VulkanRenderer) createModule(shader *VulkanShader, config *VulkanShade
 
 	shaderStage.CreateInfo = vk.ShaderModuleCreateInfo{
 		SType:    vk.StructureTypeShaderModuleCreateInfo,
-		CodeSize: binaryResource.DataSize * 4,
+		CodeSize: binaryResource.DataSize,
 		PCode:    binaryResource.Data.([]uint32),
 	}
 	shaderStage.CreateInfo.Deref()
@@ -2187,23 +2250,53 @@ func (vr *VulkanRenderer) ShaderApplyGlobals(shader *metadata.Shader) error {
 	}
 	uboWrite.Deref()
 
-	descriptorWrites := []vk.WriteDescriptorSet{uboWrite}
+	descriptorWrites := []vk.WriteDescriptorSet{}
+	if internal.GlobalUniformCount > 0 {
+		descriptorWrites = append(descriptorWrites, uboWrite)
+	}
 
-	globalSetBindingCount := uint32(internal.Config.DescriptorSets[DESC_SET_INDEX_GLOBAL].BindingCount)
-	if globalSetBindingCount > 1 {
+	if internal.GlobalUniformSamplerCount > 0 {
 		// TODO: There are samplers to be written. Support this.
-		globalSetBindingCount = 1
 		core.LogError("Global image samplers are not yet supported.")
 
 		// VkWriteDescriptorSet sampler_write = {VK_STRUCTURE_TYPE_WRITE_DESCRIPTOR_SET};
 		// descriptor_writes[1] = ...
 	}
 
-	if err := lockPool.SafeCall(PipelineManagement, func() error {
-		vk.UpdateDescriptorSets(vr.context.Device.LogicalDevice, globalSetBindingCount, descriptorWrites, 0, nil)
-		return nil
-	}); err != nil {
-		return err
+	// Then the storage buffers, one binding each in the order of the uniforms.
+	storageBinding := uint32(internal.Config.DescriptorSets[DESC_SET_INDEX_GLOBAL].StorageBindingIndex)
+	for _, uniform := range shader.Uniforms {
+		if !uniform.Storage {
+			continue
+		}
+		storageInfo := vk.DescriptorBufferInfo{
+			Buffer: (internal.StorageBuffer.InternalData.(*VulkanBuffer)).Handle,
+			Offset: vk.DeviceSize(uniform.Offset),
+			Range:  vk.DeviceSize(uniform.Size),
+		}
+		storageInfo.Deref()
+
+		storageWrite := vk.WriteDescriptorSet{
+			SType:           vk.StructureTypeWriteDescriptorSet,
+			DstSet:          internal.GlobalDescriptorSets[imageIndex],
+			DstBinding:      storageBinding,
+			DstArrayElement: 0,
+			DescriptorType:  vk.DescriptorTypeStorageBuffer,
+			DescriptorCount: 1,
+			PBufferInfo:     []vk.DescriptorBufferInfo{storageInfo},
+		}
+		storageWrite.Deref()
+		descriptorWrites = append(descriptorWrites, storageWrite)
+		storageBinding++
+	}
+
+	if len(descriptorWrites) > 0 {
+		if err := lockPool.SafeCall(PipelineManagement, func() error {
+			vk.UpdateDescriptorSets(vr.context.Device.LogicalDevice, uint32(len(descriptorWrites)), descriptorWrites, 0, nil)
+			return nil
+		}); err != nil {
+			return err
+		}
 	}
 
 	// Bind the global descriptor set to be updated.
@@ -2488,37 +2581,26 @@ func (vr *VulkanRenderer) SetUniform(shader *metadata.Shader, uniform metadata.S
 			internal.InstanceStates[shader.BoundInstanceID].InstanceTextureMaps[uniform.Location] = value.(*metadata.TextureMap)
 		}
 	} else {
+		// Laid out as the shader reads it: std430 for push constants and storage buffers, std140
+		// for uniform buffers.
+		layout := metadata.ShaderBlockLayoutStd140
+		if uniform.Scope == metadata.ShaderScopeLocal || uniform.Storage {
+			layout = metadata.ShaderBlockLayoutStd430
+		}
+		data, err := layout.Encode(uniform.Type, value)
+		if err != nil {
+			return fmt.Errorf("failed to set uniform %d of shader '%s': %w", uniform.Index, shader.Name, err)
+		}
+
 		if uniform.Scope == metadata.ShaderScopeLocal {
 			// Is local, using push constants. Do this immediately.
 			commandBuffer := vr.context.GraphicsCommandBuffers[vr.context.ImageIndex].Handle
-
-			var dataPtr unsafe.Pointer
-			var dataSize int
-			switch v := value.(type) {
-			case math.Mat4:
-				dataSize = int(unsafe.Sizeof(v))
-				dataPtr = unsafe.Pointer(&v.Data[0])
-			case math.Vec3:
-				dataSize = int(unsafe.Sizeof(v))
-				dataPtr = unsafe.Pointer(&v)
-			case float32:
-				dataSize = int(unsafe.Sizeof(v))
-				dataPtr = unsafe.Pointer(&v)
-			default:
-				err := fmt.Errorf("unsupported push constant type: %T", value)
-				return err
-			}
-
-			// Check size consistency
-			if dataSize != int(uniform.Size) {
-				err := fmt.Errorf("size mismatch: expected %d, got %d", uniform.Size, dataSize)
-				return err
-			}
+			dataPtr := unsafe.Pointer(&data[0])
 
 			if err := lockPool.SafeCall(CommandBufferManagement, func() error {
 				vk.CmdPushConstants(commandBuffer, internal.Pipeline.PipelineLayout,
-					vk.ShaderStageFlags(vk.ShaderStageVertexBit)|vk.ShaderStageFlags(vk.ShaderStageFragmentBit),
-					uint32(uniform.Offset), uint32(uniform.Size), dataPtr,
+					shaderStageFlags(shader.PushConstantStages),
+					uint32(uniform.Offset), uint32(len(data)), dataPtr,
 				)
 				return nil
 			}); err != nil {
@@ -2526,11 +2608,17 @@ func (vr *VulkanRenderer) SetUniform(shader *metadata.Shader, uniform metadata.S
 			}
 
 			// Ensure the Go pointer is kept alive during the Vulkan call
-			runtime.KeepAlive(value)
+			runtime.KeepAlive(data)
 		} else {
 			// Map the appropriate memory location and copy the data over.
-			addr := internal.MappedUniformBufferBlock.(uint64)
-			addr += uint64(shader.BoundUboOffset) + uniform.Offset
+			var addr uint64
+			if uniform.Storage {
+				addr = internal.MappedStorageBufferBlock.(uint64) + uniform.Offset
+			} else {
+				addr = internal.MappedUniformBufferBlock.(uint64)
+				addr += uint64(shader.BoundUboOffset) + uniform.Offset
+			}
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(uintptr(addr))), len(data)), data)
 		}
 	}
 	return nil
@@ -2620,7 +2708,7 @@ func (vr *VulkanRenderer) RenderTargetCreate(attachmentCount uint8, attachments
 	outTarget := &metadata.RenderTarget{
 		AttachmentCount:     attachmentCount,
 		Attachments:         attachments,
-		InternalFramebuffer: vk.NullFramebuffer,
+		InternalFramebuffer: nil,
 	}
 
 	rp := pass.InternalData.(*VulkanRenderPass)
@@ -2657,14 +2745,17 @@ func (vr *VulkanRenderer) RenderTargetCreate(attachmentCount uint8, attachments
 }
 
 func (vr *VulkanRenderer) RenderTargetDestroy(target *metadata.RenderTarget, freeInternalMemory bool) error {
-	if target != nil && target.InternalFramebuffer != nil {
+	if target == nil {
+		return nil
+	}
+	if fb, ok := target.InternalFramebuffer.(vk.Framebuffer); ok && fb != vk.NullFramebuffer {
 		if err := lockPool.SafeCall(PipelineManagement, func() error {
-			vk.DestroyFramebuffer(vr.context.Device.LogicalDevice, target.InternalFramebuffer, vr.context.Allocator)
+			vk.DestroyFramebuffer(vr.context.Device.LogicalDevice, fb, vr.context.Allocator)
 			return nil
 		}); err != nil {
 			return err
 		}
-		target.InternalFramebuffer = vk.NullFramebuffer
+		target.InternalFramebuffer = nil
 		if freeInternalMemory {
 			target.Attachments = nil
 			target.AttachmentCount = 0
@@ -2706,8 +2797,9 @@ func (vr *VulkanRenderer) RenderBufferCreate(renderbufferType metadata.RenderBuf
 		internalBuffer.Usage = vk.BufferUsageFlags(vk.BufferUsageTransferDstBit)
 		internalBuffer.MemoryPropertyFlags = uint32(vk.MemoryPropertyHostVisibleBit) | uint32(vk.MemoryPropertyHostCoherentBit)
 	case metadata.RENDERBUFFER_TYPE_STORAGE:
-		err := fmt.Errorf("storage buffer not yet supported")
-		return nil, err
+		// Written by the host every frame, like the uniform buffers.
+		internalBuffer.Usage = vk.BufferUsageFlags(vk.BufferUsageStorageBufferBit) | vk.BufferUsageFlags(vk.BufferUsageTransferDstBit)
+		internalBuffer.MemoryPropertyFlags = uint32(vk.MemoryPropertyHostVisibleBit) | uint32(vk.MemoryPropertyHostCoherentBit)
 	default:
 		err := fmt.Errorf("unsupported buffer type: %d", outBuffer.RenderBufferType)
 		return nil, err
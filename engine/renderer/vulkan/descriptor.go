@@ -12,6 +12,8 @@ type VulkanDescriptorSetConfig struct {
 	Bindings []vk.DescriptorSetLayoutBinding
 	/** @brief The index of the sampler binding. */
 	SamplerBindingIndex uint8
+	/** @brief The index of the binding of the first storage buffer, one binding per buffer. */
+	StorageBindingIndex uint8
 }
 
 /**
@@ -41,6 +41,8 @@ type VulkanPipelineConfig struct {
 	ShaderFlags metadata.ShaderFlagBits
 	/** @brief An array of push constant data ranges. */
 	PushConstantRanges []*metadata.MemoryRange
+	/** @brief The stages the push constant ranges are visible to. */
+	PushConstantStages vk.ShaderStageFlags
 }
 
 func NewGraphicsPipeline(context *VulkanContext, config *VulkanPipelineConfig) (*VulkanPipeline, error) {
@@ -197,7 +199,7 @@ func NewGraphicsPipeline(context *VulkanContext, config *VulkanPipelineConfig) (
 		// NOTE: 32 is the max number of ranges we can ever have, since spec only guarantees 128 bytes with 4-byte alignment.
 		ranges := make([]vk.PushConstantRange, 32)
 		for i := 0; i < len(config.PushConstantRanges); i++ {
-			ranges[i].StageFlags = vk.ShaderStageFlags(vk.ShaderStageVertexBit) | vk.ShaderStageFlags(vk.ShaderStageFragmentBit)
+			ranges[i].StageFlags = config.PushConstantStages
 			ranges[i].Offset = uint32(config.PushConstantRanges[i].Offset)
 			ranges[i].Size = uint32(config.PushConstantRanges[i].Size)
 			ranges[i].Deref()
@@ -0,0 +1,22 @@
+package vulkan
+
+import (
+	"fmt"
+
+	"github.com/spaghettifunk/anima/engine/assets"
+	"github.com/spaghettifunk/anima/engine/platform"
+	"github.com/spaghettifunk/anima/engine/platform/window"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+	"github.com/spaghettifunk/anima/engine/systems"
+)
+
+func init() {
+	systems.RegisterRendererBackend(metadata.RendererBackendTypeVulkan, func(appWidth, appHeight uint32, p platform.Platform, am *assets.AssetManager) (metadata.RendererBackend, error) {
+		// Vulkan needs a real window to create its surface.
+		windowed, ok := p.(*window.GLFWPlatform)
+		if !ok {
+			return nil, fmt.Errorf("renderer backend `%s` requires a windowed platform, got %T", metadata.RendererBackendTypeVulkan, p)
+		}
+		return New(windowed, am), nil
+	})
+}
@@ -25,8 +25,10 @@ const (
 	 * will ever be needed.
 	 */
 	VULKAN_SHADER_MAX_UNIFORMS uint32 = 128
-	/** @brief The maximum number of bindings per descriptor set. */
-	VULKAN_SHADER_MAX_BINDINGS uint32 = 2
+	/** @brief The maximum number of storage buffers allowed at the global level. */
+	VULKAN_SHADER_MAX_STORAGE_BUFFERS uint32 = 8
+	/** @brief The maximum number of bindings per descriptor set: the UBO, the samplers and the storage buffers. */
+	VULKAN_SHADER_MAX_BINDINGS uint32 = 2 + VULKAN_SHADER_MAX_STORAGE_BUFFERS
 	/** @brief The maximum number of push constant ranges for a shader. */
 	VULKAN_SHADER_MAX_PUSH_CONST_RANGES uint32 = 32
 )
@@ -119,6 +121,10 @@ type VulkanShader struct {
 	GlobalDescriptorSets []vk.DescriptorSet
 	/** @brief The uniform buffer used by this shader. */
 	UniformBuffer *metadata.RenderBuffer
+	/** @brief The buffer holding the storage buffers of this shader, nil if it has none. */
+	StorageBuffer *metadata.RenderBuffer
+	/** @brief The block of memory mapped to the storage buffer. */
+	MappedStorageBufferBlock interface{}
 
 	/** @brief The Pipeline associated with this shader. */
 	Pipeline *VulkanPipeline
@@ -137,4 +143,24 @@ type VulkanShader struct {
 	InstanceUniformSamplerCount uint8
 	/** @brief The number of local non-sampler uniforms. */
 	LocalUniformCount uint8
+	/** @brief The number of storage buffers, which are global. */
+	GlobalStorageCount uint8
+}
+
+// shaderStageFlags returns the Vulkan stage flags of a mask of shader stages.
+func shaderStageFlags(stages metadata.ShaderStage) vk.ShaderStageFlags {
+	flags := vk.ShaderStageFlags(0)
+	if stages&metadata.ShaderStageVertex != 0 {
+		flags |= vk.ShaderStageFlags(vk.ShaderStageVertexBit)
+	}
+	if stages&metadata.ShaderStageGeometry != 0 {
+		flags |= vk.ShaderStageFlags(vk.ShaderStageGeometryBit)
+	}
+	if stages&metadata.ShaderStageFragment != 0 {
+		flags |= vk.ShaderStageFlags(vk.ShaderStageFragmentBit)
+	}
+	if stages&metadata.ShaderStageCompute != 0 {
+		flags |= vk.ShaderStageFlags(vk.ShaderStageComputeBit)
+	}
+	return flags
 }
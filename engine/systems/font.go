@@ -8,6 +8,8 @@ package systems
 import "C"
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"unicode/utf8"
 	"unsafe"
 
@@ -330,6 +332,116 @@ func (fs *FontSystem) SetupFontData(font *metadata.FontData) error {
 		return err
 	}
 
+	fs.setupTabXAdvance(font)
+	return nil
+}
+
+/**
+ * @brief Reloads the bitmap or system font loaded from the resource with the given name, if any.
+ * The font data is updated in place, so texts using the font pick the changes up with their
+ * next geometry update. Must be called from the main thread.
+ *
+ * @param resourceName The name of the font resource (e.g. UbuntuMono21px).
+ * @param resourceType Either ResourceTypeBitmapFont or ResourceTypeSystemFont.
+ */
+func (fs *FontSystem) Reload(resourceName string, resourceType metadata.ResourceType) error {
+	switch resourceType {
+	case metadata.ResourceTypeBitmapFont:
+		for _, lookup := range fs.BitmapFonts {
+			if lookup == nil || lookup.ID == metadata.InvalidIDUint16 || lookup.Font == nil || lookup.Font.LoadedResource == nil {
+				continue
+			}
+			if fontResourceName(lookup.Font.LoadedResource.FullPath) == resourceName {
+				return fs.reloadBitmapFont(lookup, resourceName)
+			}
+		}
+	case metadata.ResourceTypeSystemFont:
+		res, err := fs.assetManager.LoadAsset(resourceName, metadata.ResourceTypeSystemFont, nil)
+		if err != nil {
+			return err
+		}
+		resourceData := res.Data.(*metadata.SystemFontResourceData)
+		for i, face := range resourceData.Fonts {
+			id, ok := fs.SystemFontLookup[face.Name]
+			if !ok || id == metadata.InvalidIDUint16 {
+				continue
+			}
+			if err := fs.reloadSystemFont(fs.SystemFonts[id], resourceData, int32(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *FontSystem) reloadBitmapFont(lookup *BitmapFontLookup, resourceName string) error {
+	res, err := fs.assetManager.LoadAsset(resourceName, metadata.ResourceTypeBitmapFont, nil)
+	if err != nil {
+		return err
+	}
+	resourceData := res.Data.(*metadata.BitmapFontResourceData)
+
+	// TODO: only accounts for one page at the moment.
+	texture, err := fs.textureSystem.Aquire(resourceData.Pages[0].File, true)
+	if err != nil {
+		return err
+	}
+
+	// Keep the font data and its atlas map, as texts point to them.
+	font := lookup.Font.ResourceData.Data
+	atlas := font.Atlas
+	if atlas.Texture != nil {
+		fs.textureSystem.Release(atlas.Texture.Name)
+	}
+	atlas.Texture = texture
+
+	*font = *resourceData.Data
+	font.Atlas = atlas
+	fs.setupTabXAdvance(font)
+
+	resourceData.Data = font
+	lookup.Font.LoadedResource = res
+	lookup.Font.ResourceData = resourceData
+
+	core.LogInfo("Reloaded bitmap font '%s'.", resourceName)
+	return nil
+}
+
+func (fs *FontSystem) reloadSystemFont(lookup *SystemFontLookup, resourceData *metadata.SystemFontResourceData, index int32) error {
+	data := []byte(resourceData.FontBinary.([]byte))
+	cData := (*C.uchar)(unsafe.Pointer(&data[0]))
+
+	offset := C.stbtt_GetFontOffsetForIndex(cData, C.int(index))
+	var info C.stbtt_fontinfo
+	if C.stbtt_InitFont(&info, cData, offset) == 0 {
+		return fmt.Errorf("failed to init system font %s at index %d", lookup.Face, index)
+	}
+
+	lookup.BinarySize = resourceData.BinarySize
+	lookup.FontBinary = resourceData.FontBinary
+	lookup.Index = index
+	lookup.Offset = int32(offset)
+	lookup.Info = info
+
+	// Rebuild every size variant, in place.
+	for _, variant := range lookup.SizeVariants {
+		if err := fs.RebuildSystemFontVariantAtlas(lookup, variant); err != nil {
+			return err
+		}
+	}
+
+	core.LogInfo("Reloaded system font '%s'.", lookup.Face)
+	return nil
+}
+
+// fontResourceName returns the name a font resource is loaded by, from its path.
+func fontResourceName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// setupTabXAdvance makes sure the font knows how wide a tab is.
+func (fs *FontSystem) setupTabXAdvance(font *metadata.FontData) {
 	// Check for a tab glyph, as there may not always be one exported. If there is, store its
 	// x_advance and just use that. If there is not, then create one based off spacex4
 	if font.TabXAdvance == 0 {
@@ -355,7 +467,6 @@ func (fs *FontSystem) SetupFontData(font *metadata.FontData) error {
 			}
 		}
 	}
-	return nil
 }
 
 func (fs *FontSystem) Acquire(font_name string, font_size uint16, text *metadata.UIText) error {
@@ -430,10 +541,10 @@ func (fs *FontSystem) CreateSystemFontVariant(lookup *SystemFontLookup, size uin
 		FontType:         metadata.FONT_TYPE_SYSTEM,
 		Face:             font_name,
 		InternalDataSize: uint32(unsafe.Sizeof(SystemFontVariantData{})),
-		InternalData:     SystemFontVariantData{},
+		InternalData:     &SystemFontVariantData{},
 	}
 
-	internal_data := out_variant.InternalData.(SystemFontVariantData)
+	internal_data := out_variant.InternalData.(*SystemFontVariantData)
 
 	// Push default codepoints (ascii 32-127) always, plus a -1 for unknown.
 	internal_data.Codepoints = make([]int32, 96)
@@ -337,8 +337,7 @@ func (gs *GeometrySystem) GenerateCubeConfig(width, height, depth, tileX, tileY
 
 	config := &metadata.GeometryConfig{
 		VertexSize:  uint32(unsafe.Sizeof(math.Vertex3D{})),
-		VertexCount: 4 * 6, // 4 verts per side, 6 side
-		Vertices:    make([]math.Vertex3D, 4*6),
+		VertexCount: 4 * 6,                            // 4 verts per side, 6 side
 		IndexSize:   uint32(unsafe.Sizeof(uint32(1))), // number of bytes of a uint32
 		IndexCount:  6 * 6,                            // 6 indices per side, 6 side
 		Indices:     make([]uint32, 6*6),
@@ -362,8 +361,8 @@ func (gs *GeometrySystem) GenerateCubeConfig(width, height, depth, tileX, tileY
 	config.MinExtents.Y = min_y
 	config.MinExtents.Z = min_z
 	config.MaxExtents.X = max_x
-	config.MinExtents.Y = max_y
-	config.MinExtents.Z = max_z
+	config.MaxExtents.Y = max_y
+	config.MaxExtents.Z = max_z
 	// Always 0 since min/max of each axis are -/+ half of the size.
 	config.Center.X = 0
 	config.Center.Y = 0
@@ -478,7 +477,7 @@ func (gs *GeometrySystem) GenerateCubeConfig(width, height, depth, tileX, tileY
 		config.MaterialName = metadata.DefaultMaterialName
 	}
 
-	config.Vertices = math.GeometryGenerateTangents(config.VertexCount, config.Vertices, config.IndexCount, config.Indices)
+	config.Vertices = math.GeometryGenerateTangents(config.VertexCount, verts, config.IndexCount, config.Indices)
 
 	return config, nil
 }
@@ -12,6 +12,17 @@ type JobSystem struct {
 	numWorkers int
 	jobQueue   chan metadata.JobTask
 	wg         sync.WaitGroup
+
+	// The finished jobs, their callbacks are run on the main thread by Update.
+	resultMutex sync.Mutex
+	results     []jobResult
+}
+
+/** @brief A finished job, waiting for its callbacks to be run. */
+type jobResult struct {
+	job        metadata.JobTask
+	paramsChan chan interface{}
+	failed     bool
 }
 
 var ErrNoWorkers = fmt.Errorf("attempting to create worker pool with less than 1 worker")
@@ -47,21 +58,19 @@ func (js *JobSystem) start() {
 				err := job.OnStart(job.InputParams, paramsChan)
 				if err != nil {
 					core.LogError(err.Error())
-					if job.OnFailure != nil {
-						// TODO: refactor to take actual values
-						job.OnFailure(paramsChan)
-					}
-				} else {
-					if job.OnComplete != nil {
-						// TODO: refactor to take actual values
-						job.OnComplete(paramsChan)
-					}
 				}
 
-				// Call the completion callback if set
-				if job.OnCompletionCallback != nil {
-					job.OnCompletionCallback()
+				// The callbacks usually touch GPU resources, so they wait for the main thread.
+				js.resultMutex.Lock()
+				js.results = append(js.results, jobResult{
+					job:        job,
+					paramsChan: paramsChan,
+					failed:     err != nil,
+				})
+				if len(js.results) > metadata.MAX_JOB_RESULTS {
+					core.LogWarn("%d job results waiting for the main thread", len(js.results))
 				}
+				js.resultMutex.Unlock()
 			}
 		}()
 	}
@@ -77,9 +86,30 @@ func (js *JobSystem) Shutdown() error {
 }
 
 /**
- * @brief Updates the job system. Should happen once an update cycle.
+ * @brief Updates the job system. Should happen once an update cycle, on the main thread:
+ * it runs the callbacks of the jobs finished since the last update.
  */
-func (js *JobSystem) Update() {}
+func (js *JobSystem) Update() {
+	js.resultMutex.Lock()
+	results := js.results
+	js.results = nil
+	js.resultMutex.Unlock()
+
+	for _, result := range results {
+		if result.failed {
+			if result.job.OnFailure != nil {
+				result.job.OnFailure(result.paramsChan)
+			}
+		} else if result.job.OnComplete != nil {
+			result.job.OnComplete(result.paramsChan)
+		}
+
+		// Call the completion callback if set
+		if result.job.OnCompletionCallback != nil {
+			result.job.OnCompletionCallback()
+		}
+	}
+}
 
 // AddWorkNonBlocking adds work to the SimplePool and returns immediately
 func (js *JobSystem) AddWorkNonBlocking(jt metadata.JobTask) {
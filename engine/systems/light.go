@@ -0,0 +1,220 @@
+package systems
+
+import (
+	"fmt"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+/** @brief The light system configuration. */
+type LightSystemConfig struct {
+	/**
+	 * @brief The maximum number of point lights lit at once, at most
+	 * metadata.MAX_POINT_LIGHTS, what the material shader holds.
+	 */
+	MaxPointLightCount uint32
+	/**
+	 * @brief The maximum number of spot lights lit at once, at most
+	 * metadata.MAX_SPOT_LIGHTS, what the material shader holds.
+	 */
+	MaxSpotLightCount uint32
+}
+
+/**
+ * @brief Holds the lights of the scene, uploaded to the material shader every frame. The lights
+ * are owned by the caller: a light added is moved or recoloured by changing its fields, the next
+ * frame picks the change up.
+ */
+type LightSystem struct {
+	Config *LightSystemConfig
+	// The single directional light, nil if there is none.
+	directionalLight *metadata.DirectionalLight
+	pointLights      []*metadata.PointLight
+	spotLights       []*metadata.SpotLight
+}
+
+/**
+ * @brief Initializes the light system.
+ *
+ * @param config The configuration for this system.
+ * @return The light system, or an error if the configuration exceeds what the material shader holds.
+ */
+func NewLightSystem(config *LightSystemConfig) (*LightSystem, error) {
+	if config.MaxPointLightCount > uint32(metadata.MAX_POINT_LIGHTS) {
+		err := fmt.Errorf("func NewLightSystem - config.MaxPointLightCount must be <= %d", metadata.MAX_POINT_LIGHTS)
+		core.LogError(err.Error())
+		return nil, err
+	}
+	if config.MaxSpotLightCount > uint32(metadata.MAX_SPOT_LIGHTS) {
+		err := fmt.Errorf("func NewLightSystem - config.MaxSpotLightCount must be <= %d", metadata.MAX_SPOT_LIGHTS)
+		core.LogError(err.Error())
+		return nil, err
+	}
+	return &LightSystem{
+		Config:      config,
+		pointLights: make([]*metadata.PointLight, 0, config.MaxPointLightCount),
+		spotLights:  make([]*metadata.SpotLight, 0, config.MaxSpotLightCount),
+	}, nil
+}
+
+/**
+ * @brief Shuts down the light system, removing every light.
+ */
+func (ls *LightSystem) Shutdown() error {
+	ls.directionalLight = nil
+	ls.pointLights = ls.pointLights[:0]
+	ls.spotLights = ls.spotLights[:0]
+	return nil
+}
+
+/**
+ * @brief Adds the directional light of the scene. There is at most one.
+ *
+ * @param light The light to add.
+ * @return An error if there already is a directional light.
+ */
+func (ls *LightSystem) AddDirectional(light *metadata.DirectionalLight) error {
+	if light == nil {
+		return fmt.Errorf("func AddDirectional requires a light")
+	}
+	if ls.directionalLight != nil {
+		err := fmt.Errorf("func AddDirectional - there is already a directional light, remove it first")
+		core.LogError(err.Error())
+		return err
+	}
+	ls.directionalLight = light
+	return nil
+}
+
+/**
+ * @brief Removes the directional light of the scene.
+ *
+ * @param light The light to remove.
+ * @return An error if the light is not the directional light of the scene.
+ */
+func (ls *LightSystem) RemoveDirectional(light *metadata.DirectionalLight) error {
+	if light == nil || ls.directionalLight != light {
+		err := fmt.Errorf("func RemoveDirectional - the light was not added")
+		core.LogError(err.Error())
+		return err
+	}
+	ls.directionalLight = nil
+	return nil
+}
+
+/**
+ * @brief Adds a point light.
+ *
+ * @param light The light to add.
+ * @return An error if the light was already added, or if there are config.MaxPointLightCount point lights.
+ */
+func (ls *LightSystem) AddPoint(light *metadata.PointLight) error {
+	if light == nil {
+		return fmt.Errorf("func AddPoint requires a light")
+	}
+	for _, l := range ls.pointLights {
+		if l == light {
+			return fmt.Errorf("func AddPoint - the light was already added")
+		}
+	}
+	if uint32(len(ls.pointLights)) >= ls.Config.MaxPointLightCount {
+		err := fmt.Errorf("func AddPoint - there are already %d point lights. Adjust light system config to allow more", len(ls.pointLights))
+		core.LogError(err.Error())
+		return err
+	}
+	ls.pointLights = append(ls.pointLights, light)
+	return nil
+}
+
+/**
+ * @brief Removes a point light.
+ *
+ * @param light The light to remove.
+ * @return An error if the light was not added.
+ */
+func (ls *LightSystem) RemovePoint(light *metadata.PointLight) error {
+	for i, l := range ls.pointLights {
+		if l == light {
+			ls.pointLights = append(ls.pointLights[:i], ls.pointLights[i+1:]...)
+			return nil
+		}
+	}
+	err := fmt.Errorf("func RemovePoint - the light was not added")
+	core.LogError(err.Error())
+	return err
+}
+
+/**
+ * @brief Adds a spot light.
+ *
+ * @param light The light to add.
+ * @return An error if the light was already added, or if there are config.MaxSpotLightCount spot lights.
+ */
+func (ls *LightSystem) AddSpot(light *metadata.SpotLight) error {
+	if light == nil {
+		return fmt.Errorf("func AddSpot requires a light")
+	}
+	for _, l := range ls.spotLights {
+		if l == light {
+			return fmt.Errorf("func AddSpot - the light was already added")
+		}
+	}
+	if uint32(len(ls.spotLights)) >= ls.Config.MaxSpotLightCount {
+		err := fmt.Errorf("func AddSpot - there are already %d spot lights. Adjust light system config to allow more", len(ls.spotLights))
+		core.LogError(err.Error())
+		return err
+	}
+	ls.spotLights = append(ls.spotLights, light)
+	return nil
+}
+
+/**
+ * @brief Removes a spot light.
+ *
+ * @param light The light to remove.
+ * @return An error if the light was not added.
+ */
+func (ls *LightSystem) RemoveSpot(light *metadata.SpotLight) error {
+	for i, l := range ls.spotLights {
+		if l == light {
+			ls.spotLights = append(ls.spotLights[:i], ls.spotLights[i+1:]...)
+			return nil
+		}
+	}
+	err := fmt.Errorf("func RemoveSpot - the light was not added")
+	core.LogError(err.Error())
+	return err
+}
+
+// GetDirectional returns the directional light of the scene, nil if there is none.
+func (ls *LightSystem) GetDirectional() *metadata.DirectionalLight {
+	return ls.directionalLight
+}
+
+// GetPointLights returns the point lights, in the order they were added.
+func (ls *LightSystem) GetPointLights() []*metadata.PointLight {
+	return ls.pointLights
+}
+
+// GetSpotLights returns the spot lights, in the order they were added.
+func (ls *LightSystem) GetSpotLights() []*metadata.SpotLight {
+	return ls.spotLights
+}
+
+// fillMaterialGlobals copies the lights into the global uniforms of the material shader. Without
+// a directional light its colour is black, which leaves the ambient light alone.
+func (ls *LightSystem) fillMaterialGlobals(globals *materialGlobals) {
+	globals.DirectionalLight = metadata.DirectionalLight{}
+	if ls.directionalLight != nil {
+		globals.DirectionalLight = *ls.directionalLight
+	}
+	globals.PointLightCount = uint32(len(ls.pointLights))
+	for i, light := range ls.pointLights {
+		globals.PointLights[i] = *light
+	}
+	globals.SpotLightCount = uint32(len(ls.spotLights))
+	for i, light := range ls.spotLights {
+		globals.SpotLights[i] = *light
+	}
+}
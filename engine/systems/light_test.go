@@ -0,0 +1,180 @@
+package systems
+
+import (
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+func TestNewLightSystemLimits(t *testing.T) {
+	tests := []struct {
+		config LightSystemConfig
+		ok     bool
+	}{
+		{LightSystemConfig{}, true},
+		{LightSystemConfig{MaxPointLightCount: uint32(metadata.MAX_POINT_LIGHTS), MaxSpotLightCount: uint32(metadata.MAX_SPOT_LIGHTS)}, true},
+		{LightSystemConfig{MaxPointLightCount: uint32(metadata.MAX_POINT_LIGHTS) + 1}, false},
+		{LightSystemConfig{MaxSpotLightCount: uint32(metadata.MAX_SPOT_LIGHTS) + 1}, false},
+	}
+	for _, test := range tests {
+		config := test.config
+		ls, err := NewLightSystem(&config)
+		if (err == nil) != test.ok || (ls != nil) != test.ok {
+			t.Errorf("%+v: got %v, %v", test.config, ls, err)
+		}
+	}
+}
+
+func TestLightSystemMaxCount(t *testing.T) {
+	ls, err := NewLightSystem(&LightSystemConfig{MaxPointLightCount: 2, MaxSpotLightCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := []*metadata.PointLight{{}, {}, {}}
+	for _, light := range points[:2] {
+		if err := ls.AddPoint(light); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ls.AddPoint(points[2]); err == nil {
+		t.Error("added a point light past the maximum")
+	}
+	// A removed light frees its place.
+	if err := ls.RemovePoint(points[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.AddPoint(points[2]); err != nil {
+		t.Error(err)
+	}
+
+	spots := []*metadata.SpotLight{{}, {}}
+	if err := ls.AddSpot(spots[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.AddSpot(spots[1]); err == nil {
+		t.Error("added a spot light past the maximum")
+	}
+
+	directional := &metadata.DirectionalLight{}
+	if err := ls.AddDirectional(directional); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.AddDirectional(&metadata.DirectionalLight{}); err == nil {
+		t.Error("added a second directional light")
+	}
+}
+
+func TestLightSystemAddRemoveOrder(t *testing.T) {
+	ls, err := NewLightSystem(&LightSystemConfig{MaxPointLightCount: 4, MaxSpotLightCount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c, d := &metadata.PointLight{}, &metadata.PointLight{}, &metadata.PointLight{}, &metadata.PointLight{}
+	for _, light := range []*metadata.PointLight{a, b, c} {
+		if err := ls.AddPoint(light); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ls.AddPoint(b); err == nil {
+		t.Error("added a point light twice")
+	}
+	if err := ls.RemovePoint(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.RemovePoint(b); err == nil {
+		t.Error("removed a point light twice")
+	}
+	if err := ls.AddPoint(d); err != nil {
+		t.Fatal(err)
+	}
+	// The remaining lights keep their order, the new one comes last.
+	want := []*metadata.PointLight{a, c, d}
+	got := ls.GetPointLights()
+	if len(got) != len(want) {
+		t.Fatalf("got %d point lights, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("point light %d: got %p, want %p", i, got[i], want[i])
+		}
+	}
+
+	s1, s2 := &metadata.SpotLight{}, &metadata.SpotLight{}
+	for _, light := range []*metadata.SpotLight{s1, s2} {
+		if err := ls.AddSpot(light); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ls.RemoveSpot(s1); err != nil {
+		t.Fatal(err)
+	}
+	if spots := ls.GetSpotLights(); len(spots) != 1 || spots[0] != s2 {
+		t.Errorf("got spot lights %v, want [%p]", spots, s2)
+	}
+
+	directional := &metadata.DirectionalLight{}
+	if err := ls.RemoveDirectional(directional); err == nil {
+		t.Error("removed a directional light which was not added")
+	}
+	if err := ls.AddDirectional(directional); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.RemoveDirectional(directional); err != nil {
+		t.Fatal(err)
+	}
+	if ls.GetDirectional() != nil {
+		t.Error("the directional light was not removed")
+	}
+}
+
+func TestLightSystemFillMaterialGlobals(t *testing.T) {
+	ls, err := NewLightSystem(&LightSystemConfig{MaxPointLightCount: 3, MaxSpotLightCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	directional := &metadata.DirectionalLight{Colour: math.NewVec4(0.4, 0.4, 0.2, 1), Direction: math.NewVec3(0, -1, 0), Intensity: 1}
+	p1 := &metadata.PointLight{Colour: math.NewVec4(1, 0, 0, 1), Position: math.NewVec3(1, 2, 3), Intensity: 2}
+	p2 := &metadata.PointLight{Colour: math.NewVec4(0, 1, 0, 1), Position: math.NewVec3(-1, 0, 0), Intensity: 1}
+	spot := &metadata.SpotLight{Colour: math.NewVec4(0, 0, 1, 1), Direction: math.NewVec3(0, 0, -1), Intensity: 3}
+	if err := ls.AddDirectional(directional); err != nil {
+		t.Fatal(err)
+	}
+	for _, light := range []*metadata.PointLight{p1, p2} {
+		if err := ls.AddPoint(light); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ls.AddSpot(spot); err != nil {
+		t.Fatal(err)
+	}
+
+	globals := &materialGlobals{}
+	ls.fillMaterialGlobals(globals)
+	if globals.DirectionalLight != *directional {
+		t.Errorf("got directional light %+v, want %+v", globals.DirectionalLight, *directional)
+	}
+	if globals.PointLightCount != 2 || globals.PointLights[0] != *p1 || globals.PointLights[1] != *p2 {
+		t.Errorf("got %d point lights %+v", globals.PointLightCount, globals.PointLights[:2])
+	}
+	if globals.SpotLightCount != 1 || globals.SpotLights[0] != *spot {
+		t.Errorf("got %d spot lights %+v", globals.SpotLightCount, globals.SpotLights[:1])
+	}
+
+	// The lights are copied every frame, changing them is picked up by the next fill.
+	p2.Intensity = 5
+	if err := ls.RemovePoint(p1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.RemoveDirectional(directional); err != nil {
+		t.Fatal(err)
+	}
+	ls.fillMaterialGlobals(globals)
+	if globals.PointLightCount != 1 || globals.PointLights[0] != *p2 {
+		t.Errorf("got %d point lights %+v", globals.PointLightCount, globals.PointLights[:1])
+	}
+	// Without a directional light, its colour is black.
+	if globals.DirectionalLight != (metadata.DirectionalLight{}) {
+		t.Errorf("got directional light %+v, want none", globals.DirectionalLight)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"runtime"
 
 	"github.com/spaghettifunk/anima/engine/assets"
+	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/platform"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
 )
@@ -19,15 +20,18 @@ type SystemManager struct {
 	TextureSystem    *TextureSystem
 	RendererSystem   *RendererSystem
 	FontSystem       *FontSystem
+	LightSystem      *LightSystem
 	AssetManager     *assets.AssetManager
+	// hot-reload
+	subscriptions []core.Subscription
 }
 
 var (
 	MaxNumberOfWorkers int = runtime.NumCPU()
 )
 
-func NewSystemManager(appName string, width, height uint32, platform *platform.Platform, am *assets.AssetManager) (*SystemManager, error) {
-	renderer, err := NewRendererSystem(appName, width, height, platform, am)
+func NewSystemManager(appName string, width, height uint32, backendType metadata.RendererBackendType, platform platform.Platform, am *assets.AssetManager) (*SystemManager, error) {
+	renderer, err := NewRendererSystem(appName, width, height, backendType, platform, am)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +50,7 @@ func NewSystemManager(appName string, width, height uint32, platform *platform.P
 
 	ts, err := NewTextureSystem(&TextureSystemConfig{
 		MaxTextureCount: 65536,
-	}, js, am, renderer)
+	}, am, renderer)
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +65,17 @@ func NewSystemManager(appName string, width, height uint32, platform *platform.P
 		return nil, err
 	}
 
+	ls, err := NewLightSystem(&LightSystemConfig{
+		MaxPointLightCount: uint32(metadata.MAX_POINT_LIGHTS),
+		MaxSpotLightCount:  uint32(metadata.MAX_SPOT_LIGHTS),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	ms, err := NewMaterialSystem(&MaterialSystemConfig{
 		MaxMaterialCount: 4096,
-	}, ssys, ts, am, renderer)
+	}, ssys, ts, ls, am, renderer)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +119,7 @@ func NewSystemManager(appName string, width, height uint32, platform *platform.P
 		return nil, err
 	}
 
-	mls, err := NewMeshLoaderSystem(gs, am)
+	mls, err := NewMeshLoaderSystem(gs, js, am)
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +135,7 @@ func NewSystemManager(appName string, width, height uint32, platform *platform.P
 		MeshLoaderSystem: mls,
 		RenderViewSystem: rvs,
 		FontSystem:       fs,
+		LightSystem:      ls,
 		AssetManager:     am,
 	}, nil
 }
@@ -140,6 +153,10 @@ func (sm *SystemManager) Initialize() error {
 	if err := sm.GeometrySystem.Initialize(); err != nil {
 		return err
 	}
+	sm.subscriptions = append(sm.subscriptions,
+		core.Subscribe(core.Events(), sm.onAssetChanged),
+		core.Subscribe(core.Events(), sm.onShaderReloaded),
+	)
 	// if err := sm.FontSystem.Initialize(); err != nil {
 	// 	return err
 	// }
@@ -161,9 +178,18 @@ func (sm *SystemManager) OnResize(width, height uint16) error {
 }
 
 func (sm *SystemManager) Shutdown() error {
+	for _, subscription := range sm.subscriptions {
+		subscription.Unsubscribe()
+	}
+	sm.subscriptions = nil
+
 	if err := sm.JobSystem.Shutdown(); err != nil {
 		return err
 	}
+	// The views release the resources they hold on the shaders and textures first.
+	if err := sm.RenderViewSystem.Shutdown(); err != nil {
+		return err
+	}
 	// if err := sm.FontSystem.Shutdown(); err != nil {
 	// 	return err
 	// }
@@ -176,6 +202,9 @@ func (sm *SystemManager) Shutdown() error {
 	if err := sm.MaterialSystem.Shutdown(); err != nil {
 		return err
 	}
+	if err := sm.LightSystem.Shutdown(); err != nil {
+		return err
+	}
 	if err := sm.ShaderSystem.Shutdown(); err != nil {
 		return err
 	}
@@ -185,11 +214,44 @@ func (sm *SystemManager) Shutdown() error {
 	if err := sm.CameraSystem.Shutdown(); err != nil {
 		return err
 	}
-	if err := sm.RenderViewSystem.Shutdown(); err != nil {
-		return err
-	}
 	if err := sm.RendererSystem.Shutdown(); err != nil {
 		return err
 	}
 	return nil
 }
+
+// onAssetChanged hands an asset changed on disk to the system owning it. Runs on the main
+// thread between frames, as the changes are posted.
+func (sm *SystemManager) onAssetChanged(event assets.AssetChangedEvent) {
+	// Textures are reloaded in place and the users of a shader are told when it is rebuilt,
+	// so only shaders need to be reloaded when something they reference changed.
+	if event.Cause != "" && event.Type != metadata.ResourceTypeShader {
+		return
+	}
+
+	var err error
+	switch event.Type {
+	case metadata.ResourceTypeImage:
+		// Acquired by name or by GUID.
+		if err = sm.TextureSystem.Reload(event.Name); err == nil && event.GUID != "" {
+			err = sm.TextureSystem.Reload(event.GUID)
+		}
+	case metadata.ResourceTypeMaterial:
+		if err = sm.MaterialSystem.Reload(event.Name); err == nil && event.GUID != "" {
+			err = sm.MaterialSystem.Reload(event.GUID)
+		}
+	case metadata.ResourceTypeShader:
+		err = sm.ShaderSystem.Reload(event.Name)
+	case metadata.ResourceTypeBitmapFont, metadata.ResourceTypeSystemFont:
+		err = sm.FontSystem.Reload(event.Name, event.Type)
+	}
+	if err != nil {
+		core.LogError("failed to reload '%s': %s", event.Path, err)
+	}
+}
+
+func (sm *SystemManager) onShaderReloaded(event ShaderReloadedEvent) {
+	if err := sm.MaterialSystem.OnShaderReloaded(event.Shader); err != nil {
+		core.LogError("failed to acquire material resources for shader '%s': %s", event.Shader.Name, err)
+	}
+}
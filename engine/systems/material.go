@@ -25,16 +25,34 @@ type MaterialSystem struct {
 	// Known locations for the material shader.
 	MaterialLocations *metadata.MaterialShaderUniformLocations
 	MaterialShaderID  uint32
+	// The global uniforms of the material shader, bound to materialGlobals.
+	MaterialGlobals *UniformBinding
 	// Known locations for the UI shader.
 	UILocations *metadata.UIShaderUniformLocations
 	UIShaderID  uint32
 	// sub systems
 	shaderSystem  *ShaderSystem
 	textureSystem *TextureSystem
+	lightSystem   *LightSystem
 	renderer      *RendererSystem
 	assetManager  *assets.AssetManager
 }
 
+/** @brief The global uniforms of the material shader, set once per frame. */
+type materialGlobals struct {
+	Projection    math.Mat4 `uniform:"projection"`
+	View          math.Mat4 `uniform:"view"`
+	AmbientColour math.Vec4 `uniform:"ambient_colour"`
+	ViewPosition  math.Vec3 `uniform:"view_position"`
+	RenderMode    uint32    `uniform:"mode"`
+	// The lights, from the light system.
+	DirectionalLight metadata.DirectionalLight                      `uniform:"dir_light"`
+	PointLightCount  uint32                                         `uniform:"point_light_count"`
+	SpotLightCount   uint32                                         `uniform:"spot_light_count"`
+	PointLights      [metadata.MAX_POINT_LIGHTS]metadata.PointLight `uniform:"point_lights"`
+	SpotLights       [metadata.MAX_SPOT_LIGHTS]metadata.SpotLight   `uniform:"spot_lights"`
+}
+
 /**
  * @brief Initializes the material system.
  * Should be called twice; once to get the memory requirement (passing state=0), and a second
@@ -45,7 +63,7 @@ type MaterialSystem struct {
  * @param config The configuration for this system.
  * @return True on success; otherwise false.
  */
-func NewMaterialSystem(config *MaterialSystemConfig, shaderSytem *ShaderSystem, ts *TextureSystem, am *assets.AssetManager, r *RendererSystem) (*MaterialSystem, error) {
+func NewMaterialSystem(config *MaterialSystemConfig, shaderSytem *ShaderSystem, ts *TextureSystem, ls *LightSystem, am *assets.AssetManager, r *RendererSystem) (*MaterialSystem, error) {
 	if config.MaxMaterialCount == 0 {
 		err := fmt.Errorf("func NewMaterialSystem - config.MaxMaterialCount must be > 0")
 		return nil, err
@@ -89,6 +107,7 @@ func NewMaterialSystem(config *MaterialSystemConfig, shaderSytem *ShaderSystem,
 		RegisteredMaterialTable: make(map[string]*metadata.MaterialReference),
 		shaderSystem:            shaderSytem,
 		textureSystem:           ts,
+		lightSystem:             ls,
 		assetManager:            am,
 		renderer:                r,
 		Config:                  config,
@@ -228,34 +247,14 @@ func (ms *MaterialSystem) AcquireFromConfig(config *metadata.MaterialConfig) (*m
 			return nil, err
 		}
 
-		// Get the uniform indices.
-		shader, err := ms.shaderSystem.GetShaderByID(material.ShaderID)
+		// Get the uniform indices, from the shader itself when the material uses one of its
+		// variants, which share them.
+		shader, err := ms.shaderSystem.GetShaderByID(ms.shaderSystem.BaseShaderID(material.ShaderID))
 		if err != nil {
 			core.LogError(err.Error())
 			return nil, err
 		}
-		// Save off the locations for known types for quick lookups.
-		if ms.MaterialShaderID == metadata.InvalidID && config.ShaderName == "Shader.Builtin.Material" {
-			ms.MaterialShaderID = shader.ID
-			ms.MaterialLocations.Projection = ms.shaderSystem.GetUniformIndex(shader, "projection")
-			ms.MaterialLocations.View = ms.shaderSystem.GetUniformIndex(shader, "view")
-			ms.MaterialLocations.AmbientColour = ms.shaderSystem.GetUniformIndex(shader, "ambient_colour")
-			ms.MaterialLocations.ViewPosition = ms.shaderSystem.GetUniformIndex(shader, "view_position")
-			ms.MaterialLocations.DiffuseColour = ms.shaderSystem.GetUniformIndex(shader, "diffuse_colour")
-			ms.MaterialLocations.DiffuseTexture = ms.shaderSystem.GetUniformIndex(shader, "diffuse_texture")
-			ms.MaterialLocations.SpecularTexture = ms.shaderSystem.GetUniformIndex(shader, "specular_texture")
-			ms.MaterialLocations.NormalTexture = ms.shaderSystem.GetUniformIndex(shader, "normal_texture")
-			ms.MaterialLocations.Shininess = ms.shaderSystem.GetUniformIndex(shader, "shininess")
-			ms.MaterialLocations.Model = ms.shaderSystem.GetUniformIndex(shader, "model")
-			ms.MaterialLocations.RenderMode = ms.shaderSystem.GetUniformIndex(shader, "mode")
-		} else if ms.UIShaderID == metadata.InvalidID && config.ShaderName == "Shader.Builtin.UI" {
-			ms.UIShaderID = shader.ID
-			ms.UILocations.Projection = ms.shaderSystem.GetUniformIndex(shader, "projection")
-			ms.UILocations.View = ms.shaderSystem.GetUniformIndex(shader, "view")
-			ms.UILocations.DiffuseColour = ms.shaderSystem.GetUniformIndex(shader, "diffuse_colour")
-			ms.UILocations.DiffuseTexture = ms.shaderSystem.GetUniformIndex(shader, "diffuse_texture")
-			ms.UILocations.Model = ms.shaderSystem.GetUniformIndex(shader, "model")
-		}
+		ms.TrackShader(shader)
 
 		if material.Generation == metadata.InvalidID {
 			material.Generation = 0
@@ -332,7 +331,7 @@ func (ms *MaterialSystem) GetDefault() *metadata.Material {
  * @param render_mode The render mode.
  * @return True on success; otherwise false.
  */
-func (ms *MaterialSystem) ApplyGlobal(shaderID uint32, renderer_frame_number uint64, projection math.Mat4, view math.Mat4, ambient_colour math.Vec3, view_position math.Vec3, render_mode uint32) bool {
+func (ms *MaterialSystem) ApplyGlobal(shaderID uint32, renderer_frame_number uint64, projection math.Mat4, view math.Mat4, ambient_colour math.Vec4, view_position math.Vec3, render_mode uint32) bool {
 	shader, err := ms.shaderSystem.GetShaderByID(shaderID)
 	if err != nil {
 		core.LogError(err.Error())
@@ -341,30 +340,28 @@ func (ms *MaterialSystem) ApplyGlobal(shaderID uint32, renderer_frame_number uin
 	if shader.RenderFrameNumber == renderer_frame_number {
 		return true
 	}
-	if shaderID == ms.MaterialShaderID {
-		if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.Projection, projection); err != nil {
-			return ms.materialFail("msState.MaterialLocations.Projection")
-		}
-		if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.View, view); err != nil {
-			return ms.materialFail("msState.MaterialLocations.View")
-		}
-		if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.AmbientColour, ambient_colour); err != nil {
-			return ms.materialFail("msState.MaterialLocations.AmbientColour")
+	switch ms.shaderSystem.BaseShaderID(shaderID) {
+	case ms.MaterialShaderID:
+		globals := &materialGlobals{
+			Projection:    projection,
+			View:          view,
+			AmbientColour: ambient_colour,
+			ViewPosition:  view_position,
+			RenderMode:    render_mode,
 		}
-		if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.ViewPosition, view_position); err != nil {
-			return ms.materialFail("msState.MaterialLocations.ViewPosition")
-		}
-		if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.RenderMode, &render_mode); err != nil {
-			return ms.materialFail("msState.MaterialLocations.RenderMode")
+		ms.lightSystem.fillMaterialGlobals(globals)
+		if err := ms.shaderSystem.SetUniforms(ms.MaterialGlobals, globals); err != nil {
+			core.LogError(err.Error())
+			return ms.materialFail("msState.MaterialGlobals")
 		}
-	} else if shaderID == ms.UIShaderID {
+	case ms.UIShaderID:
 		if err := ms.shaderSystem.SetUniformByIndex(ms.UILocations.Projection, projection); err != nil {
 			return ms.materialFail("msState.UILocations.Projection")
 		}
 		if err := ms.shaderSystem.SetUniformByIndex(ms.UILocations.View, view); err != nil {
 			return ms.materialFail("msState.UILocations.View")
 		}
-	} else {
+	default:
 		core.LogError("func MaterialSystemApplyGlobal(): Unrecognized shader id '%d' ", shaderID)
 		return false
 	}
@@ -388,7 +385,8 @@ func (ms *MaterialSystem) ApplyInstance(material *metadata.Material, needsUpdate
 		return ms.materialFail("material.InternalID")
 	}
 	if needsUpdate {
-		if material.ShaderID == ms.MaterialShaderID {
+		switch ms.shaderSystem.BaseShaderID(material.ShaderID) {
+		case ms.MaterialShaderID:
 			// Material shader
 			if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.DiffuseColour, material.DiffuseColour); err != nil {
 				return ms.materialFail("msState.MaterialLocations.DiffuseColour")
@@ -405,7 +403,7 @@ func (ms *MaterialSystem) ApplyInstance(material *metadata.Material, needsUpdate
 			if err := ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.Shininess, material.Shininess); err != nil {
 				return ms.materialFail("msState.MaterialLocations.Shininess")
 			}
-		} else if material.ShaderID == ms.UIShaderID {
+		case ms.UIShaderID:
 			// UI shader
 			if err := ms.shaderSystem.SetUniformByIndex(ms.UILocations.DiffuseColour, material.DiffuseColour); err != nil {
 				return ms.materialFail("msState.UILocations.DiffuseColour")
@@ -413,7 +411,7 @@ func (ms *MaterialSystem) ApplyInstance(material *metadata.Material, needsUpdate
 			if err := ms.shaderSystem.SetUniformByIndex(ms.UILocations.DiffuseTexture, material.DiffuseMap); err != nil {
 				return ms.materialFail("msState.UILocations.DiffuseTexture")
 			}
-		} else {
+		default:
 			core.LogError("material_system_apply_instance(): Unrecognized shader id '%d' on shader '%s'.", material.ShaderID, material.Name)
 			return false
 		}
@@ -432,39 +430,193 @@ func (ms *MaterialSystem) ApplyInstance(material *metadata.Material, needsUpdate
  * @return True on success; otherwise false.
  */
 func (ms *MaterialSystem) ApplyLocal(material *metadata.Material, model math.Mat4) error {
-	if material.ShaderID == ms.MaterialShaderID {
+	switch ms.shaderSystem.BaseShaderID(material.ShaderID) {
+	case ms.MaterialShaderID:
 		return ms.shaderSystem.SetUniformByIndex(ms.MaterialLocations.Model, model)
-	} else if material.ShaderID == ms.UIShaderID {
+	case ms.UIShaderID:
 		return ms.shaderSystem.SetUniformByIndex(ms.UILocations.Model, model)
 	}
 	err := fmt.Errorf("unrecognized shader id '%d'", material.ShaderID)
 	return err
 }
 
+/**
+ * @brief Reloads the material with the given name from its configuration on disk, if it is loaded.
+ * The material is updated in place, so geometries using it pick the changes up, and its
+ * generation is incremented. Must be called from the main thread.
+ *
+ * @param name The name of the material to reload.
+ */
+func (ms *MaterialSystem) Reload(name string) error {
+	ref, ok := ms.RegisteredMaterialTable[name]
+	if !ok || ref.Handle == metadata.InvalidID {
+		// Not loaded, nothing to do.
+		return nil
+	}
+	material := ms.RegisteredMaterials[ref.Handle]
+	if material.ID == metadata.InvalidID {
+		return nil
+	}
+
+	materialResource, err := ms.assetManager.LoadAsset(name, metadata.ResourceTypeMaterial, nil)
+	if err != nil {
+		return err
+	}
+	defer ms.assetManager.UnloadAsset(materialResource)
+
+	config, ok := materialResource.Data.(*metadata.MaterialConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast to `*metadata.MaterialConfig`")
+	}
+
+	// Load the new version first, so a broken file leaves the current one untouched.
+	reloaded, err := ms.loadMaterial(config)
+	if err != nil {
+		return err
+	}
+
+	id := material.ID
+	generation := material.Generation
+	if err := ms.destroyMaterial(material); err != nil {
+		core.LogError(err.Error())
+	}
+	*material = *reloaded
+	material.ID = id
+	material.Generation = generation + 1
+
+	core.LogInfo("Reloaded material '%s'.", name)
+	return nil
+}
+
+/**
+ * @brief Re-acquires the instance resources of the materials using the given shader.
+ * To be called after the shader has been rebuilt, as rebuilding it releases them.
+ *
+ * @param shader The rebuilt shader.
+ */
+func (ms *MaterialSystem) OnShaderReloaded(shader *metadata.Shader) error {
+	if shader.ID == ms.MaterialShaderID || shader.ID == ms.UIShaderID {
+		ms.updateLocations(shader)
+	}
+
+	materials := append([]*metadata.Material{ms.DefaultMaterial}, ms.RegisteredMaterials...)
+	for _, material := range materials {
+		if material.ShaderID != shader.ID || material.InternalID == metadata.InvalidID {
+			continue
+		}
+		maps := []*metadata.TextureMap{material.DiffuseMap, material.SpecularMap, material.NormalMap}
+		internalID, err := ms.renderer.ShaderAcquireInstanceResources(shader, maps)
+		if err != nil {
+			return err
+		}
+		material.InternalID = internalID
+		if material.Generation != metadata.InvalidID {
+			material.Generation++
+		}
+	}
+	return nil
+}
+
+/**
+ * @brief Saves off the id and the uniform locations of the given shader when it is one of
+ * the known types, the material and UI shaders, for quick lookups. Called when the views
+ * create their shaders, so the globals can be applied before any material is acquired.
+ *
+ * @param shader The shader to track.
+ */
+func (ms *MaterialSystem) TrackShader(shader *metadata.Shader) {
+	if ms.MaterialShaderID == metadata.InvalidID && shader.Name == "Shader.Builtin.Material" {
+		ms.MaterialShaderID = shader.ID
+		ms.updateLocations(shader)
+		if ms.DefaultMaterial.ShaderID == metadata.InvalidID {
+			if err := ms.acquireDefaultInstance(shader); err != nil {
+				core.LogError("failed to acquire the instance resources of the default material: %s", err)
+			}
+		}
+	} else if ms.UIShaderID == metadata.InvalidID && shader.Name == "Shader.Builtin.UI" {
+		ms.UIShaderID = shader.ID
+		ms.updateLocations(shader)
+	}
+}
+
+// updateLocations saves off the uniform locations of the given known shader.
+func (ms *MaterialSystem) updateLocations(shader *metadata.Shader) {
+	if shader.ID == ms.MaterialShaderID {
+		ms.MaterialLocations.Projection = ms.shaderSystem.GetUniformIndex(shader, "projection")
+		ms.MaterialLocations.View = ms.shaderSystem.GetUniformIndex(shader, "view")
+		ms.MaterialLocations.AmbientColour = ms.shaderSystem.GetUniformIndex(shader, "ambient_colour")
+		ms.MaterialLocations.ViewPosition = ms.shaderSystem.GetUniformIndex(shader, "view_position")
+		ms.MaterialLocations.DiffuseColour = ms.shaderSystem.GetUniformIndex(shader, "diffuse_colour")
+		ms.MaterialLocations.DiffuseTexture = ms.shaderSystem.GetUniformIndex(shader, "diffuse_texture")
+		ms.MaterialLocations.SpecularTexture = ms.shaderSystem.GetUniformIndex(shader, "specular_texture")
+		ms.MaterialLocations.NormalTexture = ms.shaderSystem.GetUniformIndex(shader, "normal_texture")
+		ms.MaterialLocations.Shininess = ms.shaderSystem.GetUniformIndex(shader, "shininess")
+		ms.MaterialLocations.Model = ms.shaderSystem.GetUniformIndex(shader, "model")
+		ms.MaterialLocations.RenderMode = ms.shaderSystem.GetUniformIndex(shader, "mode")
+
+		binding, err := ms.shaderSystem.BindUniforms(shader.ID, metadata.ShaderScopeGlobal, materialGlobals{})
+		if err != nil {
+			core.LogError(err.Error())
+		}
+		ms.MaterialGlobals = binding
+	} else if shader.ID == ms.UIShaderID {
+		ms.UILocations.Projection = ms.shaderSystem.GetUniformIndex(shader, "projection")
+		ms.UILocations.View = ms.shaderSystem.GetUniformIndex(shader, "view")
+		ms.UILocations.DiffuseColour = ms.shaderSystem.GetUniformIndex(shader, "diffuse_colour")
+		ms.UILocations.DiffuseTexture = ms.shaderSystem.GetUniformIndex(shader, "diffuse_texture")
+		ms.UILocations.Model = ms.shaderSystem.GetUniformIndex(shader, "model")
+	}
+}
+
+// textureFilter returns the filtering set in the import settings of the image, linear by
+// default.
+func (ms *MaterialSystem) textureFilter(name string) metadata.TextureFilter {
+	if name == "" {
+		return metadata.TextureFilterModeLinear
+	}
+	meta, err := ms.assetManager.Meta(name, metadata.ResourceTypeImage)
+	if err != nil || meta == nil {
+		return metadata.TextureFilterModeLinear
+	}
+	return meta.Image.TextureFilter()
+}
+
+// resolveReferences returns a copy of the config naming the textures and the shader it
+// references by GUID (e.g. cooked materials), the texture and shader systems know them by name.
+func (ms *MaterialSystem) resolveReferences(config *metadata.MaterialConfig) *metadata.MaterialConfig {
+	resolved := *config
+	resolved.DiffuseMapName = ms.assetManager.ReferenceName(config.DiffuseMapName)
+	resolved.SpecularMapName = ms.assetManager.ReferenceName(config.SpecularMapName)
+	resolved.NormalMapName = ms.assetManager.ReferenceName(config.NormalMapName)
+	resolved.ShaderName = ms.assetManager.ReferenceName(config.ShaderName)
+	return &resolved
+}
+
 func (ms *MaterialSystem) loadMaterial(config *metadata.MaterialConfig) (*metadata.Material, error) {
+	config = ms.resolveReferences(config)
 	material := &metadata.Material{
 		Name:          config.Name,
-		ShaderID:      ms.shaderSystem.GetShaderID(config.ShaderName),
+		ShaderID:      metadata.InvalidID,
 		DiffuseColour: config.DiffuseColour,
 		Shininess:     config.Shininess,
 		DiffuseMap: &metadata.TextureMap{
-			FilterMinify:  metadata.TextureFilterModeLinear,
-			FilterMagnify: metadata.TextureFilterModeLinear,
+			FilterMinify:  ms.textureFilter(config.DiffuseMapName),
+			FilterMagnify: ms.textureFilter(config.DiffuseMapName),
 			RepeatU:       metadata.TextureRepeatRepeat,
 			RepeatV:       metadata.TextureRepeatRepeat,
 			RepeatW:       metadata.TextureRepeatRepeat,
 		},
 		SpecularMap: &metadata.TextureMap{
-			FilterMinify:  metadata.TextureFilterModeLinear,
-			FilterMagnify: metadata.TextureFilterModeLinear,
+			FilterMinify:  ms.textureFilter(config.SpecularMapName),
+			FilterMagnify: ms.textureFilter(config.SpecularMapName),
 			RepeatU:       metadata.TextureRepeatRepeat,
 			RepeatV:       metadata.TextureRepeatRepeat,
 			RepeatW:       metadata.TextureRepeatRepeat,
 			InternalData:  new(interface{}),
 		},
 		NormalMap: &metadata.TextureMap{
-			FilterMinify:  metadata.TextureFilterModeLinear,
-			FilterMagnify: metadata.TextureFilterModeLinear,
+			FilterMinify:  ms.textureFilter(config.NormalMapName),
+			FilterMagnify: ms.textureFilter(config.NormalMapName),
 			RepeatU:       metadata.TextureRepeatRepeat,
 			RepeatV:       metadata.TextureRepeatRepeat,
 			RepeatW:       metadata.TextureRepeatRepeat,
@@ -539,12 +691,14 @@ func (ms *MaterialSystem) loadMaterial(config *metadata.MaterialConfig) (*metada
 	}
 
 	// TODO: other maps
-	// Send it off to the renderer to acquire resources.
-	shader, err := ms.shaderSystem.GetShader(config.ShaderName)
+	// Send it off to the renderer to acquire resources, from the variant of the shader
+	// compiled for the features the material uses.
+	shader, err := ms.shaderSystem.GetVariant(config.ShaderName, shaderKeywords(config))
 	if err != nil {
 		core.LogError("Unable to load material because its shader was not found: '%s'. This is likely a problem with the material asset.", config.ShaderName)
 		return nil, err
 	}
+	material.ShaderID = shader.ID
 
 	// Gather a list of pointers to texture maps;
 	texture_map := []*metadata.TextureMap{material.DiffuseMap, material.SpecularMap, material.NormalMap}
@@ -556,6 +710,16 @@ func (ms *MaterialSystem) loadMaterial(config *metadata.MaterialConfig) (*metada
 	return material, nil
 }
 
+// shaderKeywords returns the keywords of the shader variant the material needs. The shaders
+// ignore the keywords they do not declare.
+func shaderKeywords(config *metadata.MaterialConfig) []string {
+	keywords := []string{}
+	if config.NormalMapName != "" {
+		keywords = append(keywords, metadata.ShaderKeywordNormalMap)
+	}
+	return keywords
+}
+
 func (ms *MaterialSystem) destroyMaterial(material *metadata.Material) error {
 	// KTRACE("Destroying material '%s'...", material.name);
 
@@ -610,25 +774,31 @@ func (ms *MaterialSystem) createDefaultMaterial() error {
 	ms.DefaultMaterial.NormalMap.Use = metadata.TextureUseMapSpecular
 	ms.DefaultMaterial.NormalMap.Texture = ms.textureSystem.GetDefaultNormalTexture()
 
-	texture_maps := []*metadata.TextureMap{ms.DefaultMaterial.DiffuseMap, ms.DefaultMaterial.SpecularMap, ms.DefaultMaterial.NormalMap}
-
+	// The material shader is created with the world view, after the systems are initialized.
+	// The instance resources are acquired once it is tracked.
+	ms.DefaultMaterial.ShaderID = metadata.InvalidID
+	ms.DefaultMaterial.InternalID = metadata.InvalidID
+	if _, ok := ms.shaderSystem.Lookup["Shader.Builtin.Material"]; !ok {
+		return nil
+	}
 	shader, err := ms.shaderSystem.GetShader("Shader.Builtin.Material")
 	if err != nil {
 		return err
 	}
+	return ms.acquireDefaultInstance(shader)
+}
+
+// acquireDefaultInstance acquires the instance resources of the default material from the
+// material shader.
+func (ms *MaterialSystem) acquireDefaultInstance(shader *metadata.Shader) error {
+	texture_maps := []*metadata.TextureMap{ms.DefaultMaterial.DiffuseMap, ms.DefaultMaterial.SpecularMap, ms.DefaultMaterial.NormalMap}
 
-	ms.DefaultMaterial.InternalID, err = ms.renderer.ShaderAcquireInstanceResources(shader, texture_maps)
+	internalID, err := ms.renderer.ShaderAcquireInstanceResources(shader, texture_maps)
 	if err != nil {
 		return err
 	}
-
-	// Make sure to assign the shader id.
-	if shader == nil {
-		ms.DefaultMaterial.ShaderID = 0
-		return nil
-	}
+	ms.DefaultMaterial.InternalID = internalID
 	ms.DefaultMaterial.ShaderID = shader.ID
-
 	return nil
 }
 
@@ -10,12 +10,14 @@ import (
 
 type MeshLoaderSystem struct {
 	geometrySystem *GeometrySystem
+	jobSystem      *JobSystem
 	assetManager   *assets.AssetManager
 }
 
-func NewMeshLoaderSystem(gs *GeometrySystem, am *assets.AssetManager) (*MeshLoaderSystem, error) {
+func NewMeshLoaderSystem(gs *GeometrySystem, js *JobSystem, am *assets.AssetManager) (*MeshLoaderSystem, error) {
 	return &MeshLoaderSystem{
 		geometrySystem: gs,
+		jobSystem:      js,
 		assetManager:   am,
 	}, nil
 }
@@ -24,79 +26,150 @@ func (mls *MeshLoaderSystem) Shutdown() error {
 	return nil
 }
 
+/**
+ * @brief Loads the named mesh asynchronously. The file is parsed on a job thread, and the
+ * geometries are uploaded on the main thread once it is done. Until then, the mesh keeps its
+ * current geometries and generation, which stays invalid for a mesh that never loaded. The
+ * generation becomes valid, or is incremented on reload, when the mesh is ready.
+ *
+ * @param resourceName The name of the mesh resource to load.
+ * @param mesh The mesh to load into. Its transform is kept.
+ * @return True if the load was queued; otherwise false.
+ */
 func (mls *MeshLoaderSystem) LoadFromResource(resourceName string, mesh *metadata.Mesh) bool {
-	return false
+	if mesh == nil {
+		core.LogError("cannot load mesh '%s' into a nil mesh", resourceName)
+		return false
+	}
+	params := &metadata.MeshLoadParams{
+		ResourceName: resourceName,
+		OutMesh:      mesh,
+	}
+	mls.jobSystem.Submit(metadata.JobTask{
+		JobType:     metadata.JOB_TYPE_RESOURCE_LOAD,
+		Priority:    metadata.JOB_PRIORITY_NORMAL,
+		InputParams: params,
+		OnStart:     mls.meshLoadJobStart,
+		OnComplete:  mls.meshLoadJobSuccess,
+		OnFailure:   mls.meshLoadJobFail,
+	})
+	return true
 }
 
+/**
+ * @brief Releases the geometries of the mesh and invalidates it.
+ *
+ * @param mesh The mesh to unload.
+ */
 func (mls *MeshLoaderSystem) Unload(mesh *metadata.Mesh) {
-
+	if mesh == nil {
+		return
+	}
+	for _, g := range mesh.Geometries {
+		mls.geometrySystem.Release(g)
+	}
+	mesh.Geometries = nil
+	mesh.GeometryCount = 0
+	mesh.Generation = metadata.InvalidIDUint8
 }
 
 /**
- * @brief Called when the job completes successfully.
+ * @brief Called on the main thread when the job completes successfully.
  *
- * @param params The parameters passed from the job after completion.
+ * @param paramsChan The parameters passed from the job after completion.
  */
-func (mls *MeshLoaderSystem) meshLoadJobSuccess(params interface{}) error {
-	mesh_params, ok := params.(metadata.MeshLoadParams)
+func (mls *MeshLoaderSystem) meshLoadJobSuccess(paramsChan <-chan interface{}) {
+	params, ok := <-paramsChan
 	if !ok {
-		err := fmt.Errorf("failed to cast params to metadata.MeshLoadParams")
-		core.LogError(err.Error())
-		return err
+		return
 	}
+	meshParams, ok := params.(*metadata.MeshLoadParams)
+	if !ok {
+		core.LogError("failed to cast params to `*metadata.MeshLoadParams`")
+		return
+	}
+	defer mls.assetManager.UnloadAsset(meshParams.MeshResource)
 
-	// This also handles the GPU upload. Can't be jobified until the renderer is multithreaded.
-	configs := mesh_params.MeshResource.Data.([]*metadata.GeometryConfig)
-	mesh_params.OutMesh.GeometryCount = uint16(mesh_params.MeshResource.DataSize)
-
-	mesh_params.OutMesh.Geometries = make([]*metadata.Geometry, mesh_params.OutMesh.GeometryCount)
+	configs, ok := meshParams.MeshResource.Data.([]*metadata.GeometryConfig)
+	if !ok {
+		core.LogError("failed to cast mesh '%s' data to `[]*metadata.GeometryConfig`", meshParams.ResourceName)
+		return
+	}
 
-	for i := uint16(0); i < mesh_params.OutMesh.GeometryCount; i++ {
-		g, err := mls.geometrySystem.AcquireFromConfig(configs[i], true)
+	// This also handles the GPU upload. Can't be jobified until the renderer is multithreaded.
+	geometries := make([]*metadata.Geometry, 0, len(configs))
+	for _, config := range configs {
+		g, err := mls.geometrySystem.AcquireFromConfig(config, true)
 		if err != nil {
-			core.LogError(err.Error())
-			return err
+			core.LogError("failed to acquire geometry '%s' of mesh '%s': %s", config.Name, meshParams.ResourceName, err.Error())
+			for _, acquired := range geometries {
+				mls.geometrySystem.Release(acquired)
+			}
+			return
 		}
-		mesh_params.OutMesh.Geometries[i] = g
+		geometries = append(geometries, g)
 	}
-	mesh_params.OutMesh.Generation++
 
-	core.LogDebug("Successfully loaded mesh '%s'.", mesh_params.ResourceName)
+	// Release the geometries of a previous load.
+	mesh := meshParams.OutMesh
+	for _, g := range mesh.Geometries {
+		mls.geometrySystem.Release(g)
+	}
+	mesh.Geometries = geometries
+	mesh.GeometryCount = uint16(len(geometries))
+	if mesh.Generation == metadata.InvalidIDUint8 {
+		mesh.Generation = 0
+	} else if mesh.Generation++; mesh.Generation == metadata.InvalidIDUint8 {
+		// Skip the invalid generation when wrapping around.
+		mesh.Generation = 0
+	}
 
-	return mls.assetManager.UnloadAsset(mesh_params.MeshResource)
+	core.LogDebug("Successfully loaded mesh '%s'.", meshParams.ResourceName)
 }
 
 /**
- * @brief Called when the job fails.
+ * @brief Called on the main thread when the job fails.
  *
- * @param params Parameters passed when a job fails.
+ * @param paramsChan Parameters passed when a job fails.
  */
-func (mls *MeshLoaderSystem) meshLoadJobFail(params interface{}) {
-	meshParams := params.(metadata.MeshLoadParams)
+func (mls *MeshLoaderSystem) meshLoadJobFail(paramsChan <-chan interface{}) {
+	params, ok := <-paramsChan
+	if !ok {
+		return
+	}
+	meshParams, ok := params.(*metadata.MeshLoadParams)
+	if !ok {
+		core.LogError("failed to cast params to `*metadata.MeshLoadParams`")
+		return
+	}
 	core.LogError("Failed to load mesh '%s'.", meshParams.ResourceName)
+	// Nil when the file could not be read.
 	if err := mls.assetManager.UnloadAsset(meshParams.MeshResource); err != nil {
 		core.LogError(err.Error())
 	}
 }
 
 /**
- * @brief Called when a mesh loading job begins.
+ * @brief Called on a job thread when a mesh loading job begins. Parses the mesh file.
  *
  * @param params Mesh loading parameters.
- * @param result_data Result data passed to the completion callback.
- * @return True on job success; otherwise false.
+ * @param resultChan Receives the parameters, passed to the completion callbacks.
+ * @return An error if the mesh could not be loaded.
  */
-func (mls *MeshLoaderSystem) meshLoadJobStart(params interface{}) (*metadata.Resource, error) {
-	load_params, ok := params.(*metadata.MeshLoadParams)
+func (mls *MeshLoaderSystem) meshLoadJobStart(params interface{}, resultChan chan<- interface{}) error {
+	loadParams, ok := params.(*metadata.MeshLoadParams)
 	if !ok {
 		err := fmt.Errorf("failed to cast params to `*metadata.MeshLoadParams`")
 		core.LogError(err.Error())
-		return nil, err
+		return err
 	}
-	mesh, err := mls.assetManager.LoadAsset(load_params.ResourceName, metadata.ResourceTypeMesh, 0)
+	// Always hand the parameters over, the failure callback needs them too.
+	resultChan <- loadParams
+
+	resource, err := mls.assetManager.LoadAsset(loadParams.ResourceName, metadata.ResourceTypeMesh, nil)
 	if err != nil {
-		core.LogError(err.Error())
-		return nil, err
+		return err
 	}
-	return mesh, nil
+	loadParams.MeshResource = resource
+	return nil
 }
@@ -8,21 +8,51 @@ import (
 	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/platform"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
-	"github.com/spaghettifunk/anima/engine/renderer/vulkan"
+	"github.com/spaghettifunk/anima/engine/renderer/null"
+	"github.com/spaghettifunk/anima/engine/renderer/software"
 )
 
+/**
+ * @brief Creates a renderer backend drawing to the given platform. A backend needing a window
+ * checks that the platform has one.
+ */
+type RendererBackendFactory func(appWidth, appHeight uint32, p platform.Platform, am *assets.AssetManager) (metadata.RendererBackend, error)
+
+// The backends NewRendererSystem creates. The GPU backends register themselves when their package
+// is imported (e.g. `_ "github.com/spaghettifunk/anima/engine/renderer/vulkan"`), so a headless
+// build links neither them nor the windowing they need.
+var rendererBackends = map[metadata.RendererBackendType]RendererBackendFactory{
+	metadata.RendererBackendTypeNull: func(appWidth, appHeight uint32, p platform.Platform, am *assets.AssetManager) (metadata.RendererBackend, error) {
+		return null.New(appWidth, appHeight), nil
+	},
+	metadata.RendererBackendTypeSoftware: func(appWidth, appHeight uint32, p platform.Platform, am *assets.AssetManager) (metadata.RendererBackend, error) {
+		return software.New(appWidth, appHeight), nil
+	},
+}
+
+// RegisterRendererBackend makes a backend type available to NewRendererSystem. Meant to be called
+// from the init function of the backend package.
+func RegisterRendererBackend(backendType metadata.RendererBackendType, factory RendererBackendFactory) {
+	rendererBackends[backendType] = factory
+}
+
 type RendererSystem struct {
-	backend      *vulkan.VulkanRenderer
+	backend      metadata.RendererBackend
 	assetManager *assets.AssetManager
 
+	// The type of the backend in use.
+	BackendType metadata.RendererBackendType
+
 	// application
 	AppName   string
 	AppWidth  uint32
 	AppHeight uint32
 
 	// engine specific
-	Platform *platform.Platform
+	Platform platform.Platform
 
+	// The number of frames rendered so far.
+	FrameNumber uint64
 	// The number of render targets. Typically lines up with the amount of swapchain images.
 	WindowRenderTargetCount uint8
 	// The current window framebuffer width.
@@ -37,15 +67,36 @@ type RendererSystem struct {
 	FramesSinceResize uint8
 }
 
-func NewRendererSystem(appName string, appWidth, appHeight uint32, platform *platform.Platform, am *assets.AssetManager) (*RendererSystem, error) {
-	renderer := &RendererSystem{
-		backend:      vulkan.New(platform, am),
+func NewRendererSystem(appName string, appWidth, appHeight uint32, backendType metadata.RendererBackendType, p platform.Platform, am *assets.AssetManager) (*RendererSystem, error) {
+	factory, ok := rendererBackends[backendType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported renderer backend type `%s`, is its package imported?", backendType)
+	}
+	backend, err := factory(appWidth, appHeight, p, am)
+	if err != nil {
+		return nil, err
+	}
+	renderer := NewRendererSystemWithBackend(appName, appWidth, appHeight, backend, p, am)
+	renderer.BackendType = backendType
+	return renderer, nil
+}
+
+// NewRendererSystemWithBackend creates a renderer system on top of an already
+// constructed backend. Useful to plug in custom or mocked backends.
+func NewRendererSystemWithBackend(appName string, appWidth, appHeight uint32, backend metadata.RendererBackend, platform platform.Platform, am *assets.AssetManager) *RendererSystem {
+	return &RendererSystem{
+		backend:      backend,
 		assetManager: am,
 		AppName:      appName,
 		AppWidth:     appWidth,
 		AppHeight:    appHeight,
+		Platform:     platform,
 	}
-	return renderer, nil
+}
+
+// Backend returns the backend the renderer system forwards to.
+func (r *RendererSystem) Backend() metadata.RendererBackend {
+	return r.backend
 }
 
 func (r *RendererSystem) Initialize(shaderSystem *ShaderSystem, renderViewSystem *RenderViewSystem) error {
@@ -54,7 +105,7 @@ func (r *RendererSystem) Initialize(shaderSystem *ShaderSystem, renderViewSystem
 	r.FramebufferHeight = 720
 	r.Resizing = false
 	r.FramesSinceResize = 0
-	r.backend.FrameNumber = 0
+	r.FrameNumber = 0
 
 	rbc := &metadata.RendererBackendConfig{
 		ApplicationName: r.AppName,
@@ -68,7 +119,7 @@ func (r *RendererSystem) Initialize(shaderSystem *ShaderSystem, renderViewSystem
 }
 
 func (r *RendererSystem) Shutdown() error {
-	return r.backend.Shutdow()
+	return r.backend.Shutdown()
 }
 
 func (r *RendererSystem) OnResize(width, height uint16) error {
@@ -83,7 +134,7 @@ func (r *RendererSystem) OnResize(width, height uint16) error {
 }
 
 func (r *RendererSystem) DrawFrame(packet *metadata.RenderPacket, renderViewSystem *RenderViewSystem) error {
-	r.backend.FrameNumber++
+	r.FrameNumber++
 
 	// Make sure the window is not currently being resized by waiting a designated
 	// number of frames after the last resize operation before performing the backend updates.
@@ -125,7 +176,7 @@ func (r *RendererSystem) DrawFrame(packet *metadata.RenderPacket, renderViewSyst
 
 	// Render each view.
 	for i := 0; i < len(packet.ViewPackets); i++ {
-		if err := renderViewSystem.OnRender(packet.ViewPackets[i], r.backend.FrameNumber, attachmentIndex); err != nil {
+		if err := renderViewSystem.OnRender(packet.ViewPackets[i], r.FrameNumber, attachmentIndex); err != nil {
 			core.LogError("error rendering view index %d", i)
 			return err
 		}
@@ -139,14 +190,22 @@ func (r *RendererSystem) DrawFrame(packet *metadata.RenderPacket, renderViewSyst
 	return nil
 }
 
-func (r *RendererSystem) TextureCreate(pixels []uint8, texture *metadata.Texture) {
-	r.backend.TextureCreate(pixels, texture)
+func (r *RendererSystem) TextureCreate(pixels []uint8, texture *metadata.Texture) error {
+	return r.backend.TextureCreate(pixels, texture)
 }
 
 func (r *RendererSystem) TextureDestroy(texture *metadata.Texture) error {
 	return r.backend.TextureDestroy(texture)
 }
 
+func (r *RendererSystem) TextureReadData(texture *metadata.Texture, offset, size uint32) (interface{}, error) {
+	return r.backend.TextureReadData(texture, offset, size)
+}
+
+func (r *RendererSystem) TextureReadPixel(texture *metadata.Texture, x, y uint32) ([]uint8, error) {
+	return r.backend.TextureReadPixel(texture, x, y)
+}
+
 func (r *RendererSystem) TextureCreateWriteable(texture *metadata.Texture) error {
 	return r.backend.TextureCreateWriteable(texture)
 }
@@ -179,6 +238,18 @@ func (r *RendererSystem) GetWindowAttachmentCount() uint8 {
 	return r.backend.GetWindowAttachmentCount()
 }
 
+func (r *RendererSystem) WindowAttachmentGet(index uint8) *metadata.Texture {
+	return r.backend.WindowAttachmentGet(index)
+}
+
+func (r *RendererSystem) WindowAttachmentIndexGet() uint64 {
+	return r.backend.WindowAttachmentIndexGet()
+}
+
+func (r *RendererSystem) DepthAttachmentGet(index uint8) *metadata.Texture {
+	return r.backend.DepthAttachmentGet(index)
+}
+
 func (r *RendererSystem) RenderPassDestroy(pass *metadata.RenderPass, freeInternalMemory bool) error {
 	// Destroy its rendertargets.
 	for i := 0; i < int(pass.RenderTargetCount); i++ {
@@ -0,0 +1,162 @@
+package systems
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/platform"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+	"github.com/spaghettifunk/anima/engine/renderer/null"
+)
+
+func TestMain(m *testing.M) {
+	if err := core.InitializeLogger(core.WarnLevel); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newNullRendererSystem(t *testing.T) (*RendererSystem, *null.NullRenderer) {
+	t.Helper()
+	r, err := NewRendererSystem("test", 640, 480, metadata.RendererBackendTypeNull, platform.NewHeadlessPlatform(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Initialize(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	backend, ok := r.Backend().(*null.NullRenderer)
+	if !ok {
+		t.Fatalf("got a %T backend, want the null renderer", r.Backend())
+	}
+	return r, backend
+}
+
+func TestRendererSystemNullFrames(t *testing.T) {
+	r, backend := newNullRendererSystem(t)
+	if r.WindowRenderTargetCount != null.NULL_WINDOW_ATTACHMENT_COUNT {
+		t.Fatalf("got %d window render targets, want %d", r.WindowRenderTargetCount, null.NULL_WINDOW_ATTACHMENT_COUNT)
+	}
+
+	deltas := []float64{0.016, 0.017, 0.018, 0.019}
+	for _, delta := range deltas {
+		if err := r.DrawFrame(&metadata.RenderPacket{DeltaTime: delta}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frames := backend.Frames()
+	if len(frames) != len(deltas) || r.FrameNumber != uint64(len(deltas)) {
+		t.Fatalf("got %d frames logged and %d drawn, want %d", len(frames), r.FrameNumber, len(deltas))
+	}
+	for i, frame := range frames {
+		if frame.FrameNumber != uint64(i+1) || frame.DeltaTime != deltas[i] {
+			t.Errorf("frame %d: got number %d and delta time %f", i, frame.FrameNumber, frame.DeltaTime)
+		}
+		// The attachments rotate like the images of a swapchain.
+		if want := uint64(i) % uint64(null.NULL_WINDOW_ATTACHMENT_COUNT); frame.AttachmentIndex != want {
+			t.Errorf("frame %d: got attachment %d, want %d", i, frame.AttachmentIndex, want)
+		}
+		if len(frame.Passes) != 0 || len(frame.Draws) != 0 {
+			t.Errorf("frame %d: an empty packet recorded %d passes and %d draws", i, len(frame.Passes), len(frame.Draws))
+		}
+	}
+
+	backend.ClearFrames()
+	if backend.LastFrame() != nil {
+		t.Fatal("LastFrame returned a frame after ClearFrames")
+	}
+}
+
+func TestRendererSystemNullFrameLog(t *testing.T) {
+	r, backend := newNullRendererSystem(t)
+
+	pass, err := r.RenderPassCreate(&metadata.RenderPassConfig{
+		Name:              "Renderpass.Builtin.World",
+		RenderTargetCount: 1,
+		Target:            &metadata.RenderTargetConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := r.RenderTargetCreate(0, nil, pass, 640, 480)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shader := &metadata.Shader{
+		Name:          "Shader.Builtin.Material",
+		UniformLookup: map[string]uint16{"projection": 0, "diffuse_colour": 1},
+	}
+	if err := r.ShaderCreate(shader, &metadata.ShaderConfig{}, pass, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ShaderInitialize(shader); err != nil {
+		t.Fatal(err)
+	}
+
+	geometry := &metadata.Geometry{Name: "cube"}
+	vertices := make([]math.Vertex3D, 24)
+	indices := make([]uint32, 36)
+	if err := r.CreateGeometry(geometry, 0, uint32(len(vertices)), vertices, 4, uint32(len(indices)), indices); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pass cannot begin outside of a frame.
+	if err := r.RenderPassBegin(pass, target); err == nil {
+		t.Fatal("RenderPassBegin succeeded outside of a frame")
+	}
+
+	if err := r.Backend().BeginFrame(0.016); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RenderPassBegin(pass, target); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ShaderUse(shader); err != nil {
+		t.Fatal(err)
+	}
+	projection := math.NewMat4Identity()
+	if err := r.ShaderSetUniform(shader, metadata.ShaderUniform{Index: 0, Scope: metadata.ShaderScopeGlobal}, projection); err != nil {
+		t.Fatal(err)
+	}
+	for id := uint32(1); id <= 2; id++ {
+		r.DrawGeometry(&metadata.GeometryRenderData{Geometry: geometry, UniqueID: id})
+	}
+	// Geometries not uploaded are skipped.
+	r.DrawGeometry(&metadata.GeometryRenderData{Geometry: &metadata.Geometry{Name: "missing", InternalID: metadata.InvalidID}})
+	if err := r.RenderPassEnd(pass); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Backend().EndFrame(0.016); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := backend.LastFrame()
+	if frame == nil {
+		t.Fatal("no frame logged")
+	}
+	if !frame.PassBegun("Renderpass.Builtin.World") || frame.Passes[0].Target != target.InternalFramebuffer.(uint32) {
+		t.Errorf("got passes %+v", frame.Passes)
+	}
+	if !frame.ShaderBound("Shader.Builtin.Material") || frame.Shaders[0].Pass != "Renderpass.Builtin.World" {
+		t.Errorf("got shaders %+v", frame.Shaders)
+	}
+	if values := frame.UniformValues("Shader.Builtin.Material", "projection"); len(values) != 1 || values[0] != projection {
+		t.Errorf("got projection values %v", values)
+	}
+	if count := frame.DrawCount("Renderpass.Builtin.World", "Shader.Builtin.Material"); count != 2 {
+		t.Errorf("got %d draws, want 2", count)
+	}
+	for i, draw := range frame.Draws {
+		if draw.Geometry != "cube" || draw.UniqueID != uint32(i+1) || draw.VertexCount != 24 || draw.IndexCount != 36 {
+			t.Errorf("got draw %+v", draw)
+		}
+	}
+
+	stats := backend.Stats()
+	if stats.Geometries != 1 || stats.Shaders != 1 || stats.RenderPasses != 1 || stats.RenderTargets != 1 {
+		t.Errorf("got resource counters %+v", stats)
+	}
+}
@@ -87,6 +87,9 @@ func (rvs *RenderViewSystem) Shutdown() error {
 				}
 			}
 
+			// Stop listening for the events of the view.
+			unregisterViewEvents(view)
+
 			// Destroy its renderpasses.
 			for p := 0; p < int(view.RenderpassCount); p++ {
 				if err := rvs.renderer.RenderPassDestroy(view.Passes[p], true); err != nil {
@@ -177,9 +180,12 @@ func (rvs *RenderViewSystem) Create(config *metadata.RenderViewConfig) error {
 	}
 
 	// register event for each view
-	core.EventRegister(core.EVENT_CODE_DEFAULT_RENDERTARGET_REFRESH_REQUIRED, rvs.renderViewOnEvent)
+	view.EventHandles = append(view.EventHandles, core.EventRegister(core.EVENT_CODE_DEFAULT_RENDERTARGET_REFRESH_REQUIRED, func(context core.EventContext) bool {
+		return rvs.renderViewOnEvent(view, context)
+	}))
 
 	if err := rvs.RegenerateRenderTargets(view); err != nil {
+		unregisterViewEvents(view)
 		return err
 	}
 
@@ -331,9 +337,9 @@ func (rvs *RenderViewSystem) RegenerateRenderTargets(view *metadata.RenderView)
 				if attachment.Source == metadata.RENDER_TARGET_ATTACHMENT_SOURCE_DEFAULT {
 					switch attachment.RenderTargetAttachmentType {
 					case metadata.RENDER_TARGET_ATTACHMENT_TYPE_COLOUR:
-						attachment.Texture = rvs.renderer.backend.WindowAttachmentGet(i)
+						attachment.Texture = rvs.renderer.WindowAttachmentGet(i)
 					case metadata.RENDER_TARGET_ATTACHMENT_TYPE_DEPTH:
-						attachment.Texture = rvs.renderer.backend.DepthAttachmentGet(i)
+						attachment.Texture = rvs.renderer.DepthAttachmentGet(i)
 					default:
 						err := fmt.Errorf("unsupported attachment type: 0x%d", attachment.RenderTargetAttachmentType)
 						return err
@@ -367,16 +373,27 @@ func (rvs *RenderViewSystem) RegenerateRenderTargets(view *metadata.RenderView)
 	return nil
 }
 
-func (rvs *RenderViewSystem) renderViewOnEvent(context core.EventContext) {
-	view, ok := context.Data.(*metadata.RenderView)
-	if !ok {
-		return
+// unregisterViewEvents unregisters the event listeners and subscriptions of the view, which capture it.
+func unregisterViewEvents(view *metadata.RenderView) {
+	for _, handle := range view.EventHandles {
+		handle.Unregister()
+	}
+	view.EventHandles = nil
+	for _, subscription := range view.Subscriptions {
+		subscription.Unsubscribe()
 	}
+	view.Subscriptions = nil
+}
 
+func (rvs *RenderViewSystem) renderViewOnEvent(view *metadata.RenderView, context core.EventContext) bool {
 	switch context.Type {
 	case core.EVENT_CODE_DEFAULT_RENDERTARGET_REFRESH_REQUIRED:
-		rvs.RegenerateRenderTargets(view)
+		if err := rvs.RegenerateRenderTargets(view); err != nil {
+			core.LogError("failed to regenerate render targets for view `%s`: %s", view.Name, err)
+		}
 	}
+	// Every view needs to refresh, so never mark the event as handled.
+	return false
 }
 
 // Dedicated functions for each renderview
@@ -395,6 +412,8 @@ func (rvs *RenderViewSystem) skyboxOnRenderViewCreate(view *metadata.RenderView)
 		return err
 	}
 	rvskb := &metadata.RenderViewSkybox{
+		ShaderID:           shader.ID,
+		Shader:             shader,
 		ProjectionLocation: rvs.shaderSystem.GetUniformIndex(shader, "projection"),
 		ViewLocation:       rvs.shaderSystem.GetUniformIndex(shader, "view"),
 		CubeMapLocation:    rvs.shaderSystem.GetUniformIndex(shader, "cube_texture"),
@@ -503,6 +522,7 @@ func (rvs *RenderViewSystem) skyboxOnBuildPacket(view *metadata.RenderView, data
 
 	// Set matrices, etc.
 	out_packet := &metadata.RenderViewPacket{
+		View:             view,
 		ProjectionMatrix: vs.ProjectionMatrix,
 		ViewMatrix:       vs.WorldCamera.GetView(),
 		ViewPosition:     vs.WorldCamera.GetPosition(),
@@ -528,6 +548,7 @@ func (rvs *RenderViewSystem) uiOnRenderViewCreate(view *metadata.RenderView) err
 	if err != nil {
 		return err
 	}
+	rvs.materialSystem.TrackShader(shader)
 
 	rvui := &metadata.RenderViewUI{
 		ShaderID:              shader.ID,
@@ -566,7 +587,7 @@ func (rvs *RenderViewSystem) uiOnRenderView(packet *metadata.RenderViewPacket, f
 		}
 
 		// Apply globals
-		if !rvs.materialSystem.ApplyGlobal(data.ShaderID, frameNumber, packet.ProjectionMatrix, packet.ViewMatrix, math.NewVec3Zero(), math.NewVec3Zero(), 0) {
+		if !rvs.materialSystem.ApplyGlobal(data.ShaderID, frameNumber, packet.ProjectionMatrix, packet.ViewMatrix, math.NewVec4Zero(), math.NewVec3Zero(), 0) {
 			err := fmt.Errorf("failed to use apply globals for material shader. Render frame failed")
 			return err
 		}
@@ -649,6 +670,7 @@ func (rvs *RenderViewSystem) uiOnBuildPacket(view *metadata.RenderView, data int
 	rvu := view.InternalData.(*metadata.RenderViewUI)
 
 	out_packet := &metadata.RenderViewPacket{
+		View:       view,
 		Geometries: []*metadata.GeometryRenderData{},
 		// Set matrices, etc.
 		ProjectionMatrix: rvu.ProjectionMatrix,
@@ -691,6 +713,7 @@ func (rvs *RenderViewSystem) worldOnRenderViewCreate(view *metadata.RenderView)
 	if err != nil {
 		return err
 	}
+	rvs.materialSystem.TrackShader(shader)
 
 	rvw := &metadata.RenderViewWorld{
 		ShaderID:    shader.ID,
@@ -708,7 +731,7 @@ func (rvs *RenderViewSystem) worldOnRenderViewCreate(view *metadata.RenderView)
 	rvw.ProjectionMatrix = math.NewMat4Perspective(rvw.FOV, 1280/720.0, rvw.NearClip, rvw.FarClip)
 
 	// Listen for mode changes.
-	core.EventRegister(core.EVENT_CODE_SET_RENDER_MODE, rvw.OnSetRenderMode)
+	view.EventHandles = append(view.EventHandles, core.EventRegister(core.EVENT_CODE_SET_RENDER_MODE, rvw.OnSetRenderMode))
 
 	view.InternalData = rvw
 
@@ -725,20 +748,8 @@ func (rvs *RenderViewSystem) worldOnRenderView(packet *metadata.RenderViewPacket
 			return err
 		}
 
-		if err := rvs.shaderSystem.UseShaderByID(rvw.ShaderID); err != nil {
-			core.LogError("failed to use material shader. Render frame failed")
-			return err
-		}
-
-		// Apply globals
-		// TODO: Find a generic way to request data such as ambient colour (which should be from a scene),
-		// and mode (from the renderer)
-		if !rvs.materialSystem.ApplyGlobal(rvw.ShaderID, frameNumber, packet.ProjectionMatrix, packet.ViewMatrix, packet.AmbientColour.ToVec3(), packet.ViewPosition, uint32(rvw.RenderMode)) {
-			err := fmt.Errorf("failed to use apply globals for material shader. Render frame failed")
-			return err
-		}
-
-		// Draw geometries.
+		// Draw geometries, each with the variant of the material shader its material uses.
+		shaderID := metadata.InvalidID
 		count := packet.GeometryCount
 		for i := uint32(0); i < count; i++ {
 			material := &metadata.Material{}
@@ -748,6 +759,26 @@ func (rvs *RenderViewSystem) worldOnRenderView(packet *metadata.RenderViewPacket
 				material = rvs.materialSystem.DefaultMaterial
 			}
 
+			materialShaderID := material.ShaderID
+			if materialShaderID == metadata.InvalidID {
+				materialShaderID = rvw.ShaderID
+			}
+			if materialShaderID != shaderID {
+				shaderID = materialShaderID
+				if err := rvs.shaderSystem.UseShaderByID(shaderID); err != nil {
+					core.LogError("failed to use material shader. Render frame failed")
+					return err
+				}
+
+				// Apply globals, once per frame for each variant.
+				// TODO: Find a generic way to request data such as ambient colour (which should be from a scene),
+				// and mode (from the renderer)
+				if !rvs.materialSystem.ApplyGlobal(shaderID, frameNumber, packet.ProjectionMatrix, packet.ViewMatrix, packet.AmbientColour, packet.ViewPosition, uint32(rvw.RenderMode)) {
+					err := fmt.Errorf("failed to use apply globals for material shader. Render frame failed")
+					return err
+				}
+			}
+
 			// Update the material if it hasn't already been this frame. This keeps the
 			// same material from being updated multiple times. It still needs to be bound
 			// either way, so this check result gets passed to the backend which either
@@ -786,6 +817,7 @@ func (rvs *RenderViewSystem) worldOnBuildPacket(view *metadata.RenderView, data
 	rvw := view.InternalData.(*metadata.RenderViewWorld)
 
 	out_packet := &metadata.RenderViewPacket{
+		View:             view,
 		Geometries:       []*metadata.GeometryRenderData{},
 		ProjectionMatrix: rvw.ProjectionMatrix,
 		ViewMatrix:       rvw.WorldCamera.GetView(),
@@ -829,6 +861,12 @@ func (rvs *RenderViewSystem) worldOnBuildPacket(view *metadata.RenderView, data
 		}
 	}
 
+	// Group the opaque geometries by the shader variant of their material, to switch between
+	// them as little as possible.
+	sort.SliceStable(out_packet.Geometries, func(i, j int) bool {
+		return out_packet.Geometries[i].Geometry.Material.ShaderID < out_packet.Geometries[j].Geometry.Material.ShaderID
+	})
+
 	// Sort the distances
 	// FIXME: validate if it is the correct ordering
 	sort.Slice(geometry_distances, func(i, j int) bool {
@@ -909,7 +947,7 @@ func (rvs *RenderViewSystem) pickOnRenderViewCreate(view *metadata.RenderView) e
 	rvp.UIShaderInfo.Projection = math.NewMat4Orthographic(0.0, 1280.0, 720.0, 0.0, rvp.UIShaderInfo.NearClip, rvp.UIShaderInfo.FarClip)
 	rvp.WorldShaderInfo.Projection = math.NewMat4Perspective(rvp.WorldShaderInfo.FOV, 1280/720.0, rvp.WorldShaderInfo.NearClip, rvp.WorldShaderInfo.FarClip)
 
-	core.EventRegister(core.EVENT_CODE_MOUSE_MOVED, rvp.OnMouseMoved)
+	view.Subscriptions = append(view.Subscriptions, core.Subscribe(core.Events(), rvp.OnMouseMoved))
 
 	view.InternalData = rvp
 
@@ -1076,7 +1114,7 @@ func (rvs *RenderViewSystem) pickOnRenderView(packet *metadata.RenderViewPacket,
 	x_coord := math.Clamp(uint32(data.MouseX), 0, uint32(packet.View.Width-1))
 	y_coord := math.Clamp(uint32(data.MouseY), 0, uint32(packet.View.Height-1))
 
-	pixel, err := rvs.renderer.backend.TextureReadPixel(data.ColourTargetAttachmentTexture, x_coord, y_coord)
+	pixel, err := rvs.renderer.TextureReadPixel(data.ColourTargetAttachmentTexture, x_coord, y_coord)
 	if err != nil {
 		err := fmt.Errorf("failed to read pixel from texture")
 		return err
@@ -1109,6 +1147,7 @@ func (rvs *RenderViewSystem) pickOnBuildPacket(view *metadata.RenderView, data i
 	packet_data := data.(*metadata.PickPacketData)
 
 	out_packet := &metadata.RenderViewPacket{
+		View:         view,
 		Geometries:   make([]*metadata.GeometryRenderData, 1),
 		ExtendedData: packet_data,
 	}
@@ -2,7 +2,7 @@ package systems
 
 import (
 	"fmt"
-	"math"
+	"strings"
 
 	"github.com/spaghettifunk/anima/engine/core"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
@@ -20,6 +20,19 @@ type ShaderSystemConfig struct {
 	MaxInstanceTextures uint8
 }
 
+/** @brief Published on the engine event bus after a shader has been rebuilt. Instance resources acquired from it must be acquired again. */
+type ShaderReloadedEvent struct {
+	Shader *metadata.Shader
+}
+
+// What a shader was created from, kept to rebuild it.
+type shaderSource struct {
+	pass   *metadata.RenderPass
+	config *metadata.ShaderConfig
+	// The id of the shader this one is a variant of, InvalidID for the shaders themselves.
+	base uint32
+}
+
 type ShaderSystem struct {
 	// This system's configuration.
 	Config *ShaderSystemConfig
@@ -29,6 +42,10 @@ type ShaderSystem struct {
 	CurrentShaderID uint32
 	// A collection of created shaders.
 	Shaders []*metadata.Shader
+	// The pass and configuration each shader was created with, by id.
+	sources map[uint32]shaderSource
+	// The variants which failed to be created, by name, not to try again every time.
+	brokenVariants map[string]bool
 	// sub systems
 	textureSystem *TextureSystem
 	renderer      *RendererSystem
@@ -53,6 +70,8 @@ func NewShaderSystem(config *ShaderSystemConfig, ts *TextureSystem, r *RendererS
 		Shaders:         make([]*metadata.Shader, config.MaxShaderCount),
 		CurrentShaderID: metadata.InvalidID,
 		Lookup:          make(map[string]uint32),
+		sources:         make(map[uint32]shaderSource),
+		brokenVariants:  make(map[string]bool),
 		textureSystem:   ts,
 		renderer:        r,
 	}
@@ -98,17 +117,190 @@ func (shaderSystem *ShaderSystem) Shutdown() error {
  * @return True on success; otherwise false.
  */
 func (shaderSystem *ShaderSystem) CreateShader(pass *metadata.RenderPass, config *metadata.ShaderConfig, initialize bool) (*metadata.Shader, error) {
+	return shaderSystem.createShader(pass, config, initialize, metadata.InvalidID)
+}
+
+func (shaderSystem *ShaderSystem) createShader(pass *metadata.RenderPass, config *metadata.ShaderConfig, initialize bool, base uint32) (*metadata.Shader, error) {
 	id := shaderSystem.newShaderID()
+	if id == metadata.InvalidID {
+		err := fmt.Errorf("unable to find free slot to create new shader. Aborting")
+		core.LogError(err.Error())
+		return nil, err
+	}
 
 	shader := shaderSystem.Shaders[id]
 	shader.ID = id
 
-	if shader.ID == metadata.InvalidID {
-		err := fmt.Errorf("unable to find free slot to create new shader. Aborting")
-		core.LogError(err.Error())
+	if err := shaderSystem.create(shader, pass, config, initialize); err != nil {
+		shader.ID = metadata.InvalidID
 		return nil, err
 	}
 
+	// At this point, creation is successful, so store the shader id in the hashtable
+	// so this can be looked up by name later.
+	shaderSystem.Lookup[config.Name] = shader.ID
+	shaderSystem.sources[shader.ID] = shaderSource{pass: pass, config: config, base: base}
+
+	return shader, nil
+}
+
+/**
+ * @brief Returns the variant of a shader compiled with the given keywords, e.g. NORMAL_MAP,
+ * creating it the first time it is asked for. The keywords the shader does not declare are
+ * ignored, and the shader itself is returned when none is left. The name may be the one of a
+ * variant, e.g. Shader.Builtin.Material.NORMAL_MAP, its keywords are then enabled as well. A
+ * variant which fails to be created, e.g. because its stages were not compiled, falls back to
+ * the shader itself.
+ *
+ * @param shaderName The name of the shader, or of one of its variants.
+ * @param keywords The keywords to enable.
+ * @return A pointer to the variant, or to the shader itself.
+ */
+func (shaderSystem *ShaderSystem) GetVariant(shaderName string, keywords []string) (*metadata.Shader, error) {
+	baseID, nameKeywords, ok := shaderSystem.resolveVariant(shaderName)
+	if !ok {
+		return nil, fmt.Errorf("shader `%s` not found", shaderName)
+	}
+	source := shaderSystem.sources[baseID]
+	enabled := source.config.EnabledKeywords(append(nameKeywords, keywords...))
+	if len(enabled) == 0 {
+		return shaderSystem.GetShaderByID(baseID)
+	}
+
+	name := metadata.ShaderVariantName(source.config.Name, enabled)
+	if id, ok := shaderSystem.Lookup[name]; ok && id != metadata.InvalidID {
+		return shaderSystem.GetShaderByID(id)
+	}
+	if shaderSystem.brokenVariants[name] {
+		return shaderSystem.GetShaderByID(baseID)
+	}
+	variant, err := shaderSystem.createShader(source.pass, source.config.Variant(enabled), true, baseID)
+	if err != nil {
+		core.LogWarn("failed to create shader variant '%s', using '%s' instead: %s", name, source.config.Name, err)
+		shaderSystem.brokenVariants[name] = true
+		return shaderSystem.GetShaderByID(baseID)
+	}
+	return variant, nil
+}
+
+/**
+ * @brief Returns the identifier of the shader the given one is a variant of, or the given
+ * identifier when it is not a variant. The variants of a shader share its uniforms, so the
+ * uniform indices of the shader are valid for all of them.
+ *
+ * @param shaderID The identifier of the shader or variant.
+ */
+func (shaderSystem *ShaderSystem) BaseShaderID(shaderID uint32) uint32 {
+	if source, ok := shaderSystem.sources[shaderID]; ok && source.base != metadata.InvalidID {
+		return source.base
+	}
+	return shaderID
+}
+
+// resolveVariant returns the shader the name is a variant of, and the keywords the name
+// enables: the name of a shader, followed by keywords it declares separated by dots.
+func (shaderSystem *ShaderSystem) resolveVariant(name string) (uint32, []string, bool) {
+	if id, ok := shaderSystem.Lookup[name]; ok && id != metadata.InvalidID {
+		source := shaderSystem.sources[id]
+		if source.base == metadata.InvalidID {
+			return id, nil, true
+		}
+		return source.base, source.config.VariantKeywords, true
+	}
+	keywords := []string{}
+	for shaderName := name; strings.Contains(shaderName, "."); {
+		i := strings.LastIndex(shaderName, ".")
+		keywords = append([]string{shaderName[i+1:]}, keywords...)
+		shaderName = shaderName[:i]
+
+		id, ok := shaderSystem.Lookup[shaderName]
+		if !ok || id == metadata.InvalidID || shaderSystem.sources[id].base != metadata.InvalidID {
+			continue
+		}
+		if len(shaderSystem.sources[id].config.EnabledKeywords(keywords)) != len(keywords) {
+			return metadata.InvalidID, nil, false
+		}
+		return id, keywords, true
+	}
+	return metadata.InvalidID, nil, false
+}
+
+/**
+ * @brief Rebuilds the shader with the given name from its configuration on disk, if it exists.
+ * The shader keeps its id, and uniform indices stay valid as long as the uniforms in the
+ * configuration did not change. Rebuilding releases all the instance resources acquired from
+ * the shader, so a ShaderReloadedEvent is published for their owners to acquire them again.
+ * Must be called from the main thread.
+ *
+ * @param shaderName The name of the shader to rebuild.
+ */
+func (shaderSystem *ShaderSystem) Reload(shaderName string) error {
+	id, ok := shaderSystem.Lookup[shaderName]
+	if !ok || id == metadata.InvalidID {
+		// Not created, nothing to do.
+		return nil
+	}
+	source := shaderSystem.sources[id]
+
+	res, err := shaderSystem.renderer.assetManager.LoadAsset(shaderName, metadata.ResourceTypeShader, nil)
+	if err != nil {
+		return err
+	}
+	defer shaderSystem.renderer.assetManager.UnloadAsset(res)
+
+	config, ok := res.Data.(*metadata.ShaderConfig)
+	if !ok {
+		return fmt.Errorf("failed to cast to `*metadata.ShaderConfig`")
+	}
+	if err := shaderSystem.rebuild(id, source.pass, config); err != nil {
+		return err
+	}
+
+	// The variants follow the shader, and those which failed may build now.
+	for name := range shaderSystem.brokenVariants {
+		if baseID, _, ok := shaderSystem.resolveVariant(name); ok && baseID == id {
+			delete(shaderSystem.brokenVariants, name)
+		}
+	}
+	for variantID, variantSource := range shaderSystem.sources {
+		if variantSource.base != id {
+			continue
+		}
+		if err := shaderSystem.rebuild(variantID, variantSource.pass, config.Variant(variantSource.config.VariantKeywords)); err != nil {
+			core.LogError("failed to reload shader variant '%s': %s", variantSource.config.Name, err)
+		}
+	}
+	return nil
+}
+
+// rebuild recreates the shader with the given id. The new one is built aside first, so a
+// broken file leaves the current one untouched.
+func (shaderSystem *ShaderSystem) rebuild(id uint32, pass *metadata.RenderPass, config *metadata.ShaderConfig) error {
+	shader := shaderSystem.Shaders[id]
+	rebuilt := &metadata.Shader{
+		ID:                id,
+		RenderFrameNumber: metadata.InvalidIDUint64,
+	}
+	if err := shaderSystem.create(rebuilt, pass, config, true); err != nil {
+		return err
+	}
+
+	shaderSystem.renderer.ShaderDestroy(shader)
+	*shader = *rebuilt
+	shaderSystem.sources[id] = shaderSource{pass: pass, config: config, base: shaderSystem.sources[id].base}
+
+	// Force the next use to bind the new pipeline.
+	if shaderSystem.CurrentShaderID == id {
+		shaderSystem.CurrentShaderID = metadata.InvalidID
+	}
+
+	core.LogInfo("Reloaded shader '%s'.", shader.Name)
+	core.Publish(core.Events(), ShaderReloadedEvent{Shader: shader})
+	return nil
+}
+
+// create sets the shader up from the configuration and creates its backend resources.
+func (shaderSystem *ShaderSystem) create(shader *metadata.Shader, pass *metadata.RenderPass, config *metadata.ShaderConfig, initialize bool) error {
 	shader.State = metadata.SHADER_STATE_NOT_CREATED
 	shader.Name = config.Name
 	shader.PushConstantRangeCount = 0
@@ -132,6 +324,9 @@ func (shaderSystem *ShaderSystem) CreateShader(pass *metadata.RenderPass, config
 	// lowest common denominator of 128B will be used.
 	shader.PushConstantStride = 128
 	shader.PushConstantSize = 0
+	shader.PushConstantRanges = nil
+	shader.PushConstantStages = config.PushConstantStageMask()
+	shader.StorageSize = 0
 
 	// Process flags.
 	shader.Flags = 0
@@ -144,7 +339,7 @@ func (shaderSystem *ShaderSystem) CreateShader(pass *metadata.RenderPass, config
 
 	if err := shaderSystem.renderer.ShaderCreate(shader, config, pass, uint8(len(config.Stages)), config.StageFilenames, config.Stages); err != nil {
 		core.LogError("shader was not created")
-		return nil, err
+		return err
 	}
 
 	// Ready to be initialized.
@@ -164,7 +359,7 @@ func (shaderSystem *ShaderSystem) CreateShader(pass *metadata.RenderPass, config
 		if config.Uniforms[i].ShaderUniformType == metadata.ShaderUniformTypeSampler {
 			shaderSystem.addSampler(shader, config.Uniforms[i])
 		} else {
-			shaderSystem.addUniform(shader, config.Uniforms[i])
+			shaderSystem.addUniform(shader, config, config.Uniforms[i])
 		}
 	}
 
@@ -173,15 +368,10 @@ func (shaderSystem *ShaderSystem) CreateShader(pass *metadata.RenderPass, config
 		if err := shaderSystem.renderer.ShaderInitialize(shader); err != nil {
 			core.LogError("func ShaderInitialize: initialization failed for shader '%s'", config.Name)
 			// NOTE: initialize automatically destroys the shader if it fails.
-			return nil, err
+			return err
 		}
 	}
-
-	// At this point, creation is successful, so store the shader id in the hashtable
-	// so this can be looked up by name later.
-	shaderSystem.Lookup[config.Name] = shader.ID
-
-	return shader, nil
+	return nil
 }
 
 /**
@@ -451,7 +641,7 @@ func (shaderSystem *ShaderSystem) addSampler(shader *metadata.Shader, config *me
 	// hashtable entry's 'location' field value directly, and is then set to the index of the uniform array.
 	// This allows location lookups for samplers as if they were uniforms as well (since technically they are).
 	// TODO: might need to store this elsewhere
-	if !shaderSystem.uniformAdd(shader, config.Name, 0, config.ShaderUniformType, config.Scope, location, true) {
+	if !shaderSystem.uniformAdd(shader, config, nil, location, true) {
 		err := fmt.Errorf("unable to add sampler uniform")
 		return err
 	}
@@ -459,11 +649,18 @@ func (shaderSystem *ShaderSystem) addSampler(shader *metadata.Shader, config *me
 	return nil
 }
 
-func (shaderSystem *ShaderSystem) addUniform(shader *metadata.Shader, config *metadata.ShaderUniformConfig) bool {
+func (shaderSystem *ShaderSystem) addUniform(shader *metadata.Shader, shaderConfig *metadata.ShaderConfig, config *metadata.ShaderUniformConfig) bool {
 	if !shaderSystem.shaderUniformAddStateValid(shader) || !shaderSystem.uniformNameValid(shader, config.Name) {
 		return false
 	}
-	return shaderSystem.uniformAdd(shader, config.Name, uint32(config.Size), config.ShaderUniformType, config.Scope, 0, false)
+	// The uniforms are laid out as the members of the block they are in, following the rules of
+	// GLSL: std140 for uniform buffers, std430 for push constants and storage buffers.
+	blockType, err := shaderConfig.UniformBlockType(config)
+	if err != nil {
+		core.LogError("Uniform '%s' cannot be laid out in a block: %s", config.Name, err)
+		return false
+	}
+	return shaderSystem.uniformAdd(shader, config, blockType, 0, false)
 }
 
 func (shaderSystem *ShaderSystem) getShaderID(shader_name string) uint32 {
@@ -484,7 +681,7 @@ func (s *ShaderSystem) newShaderID() uint32 {
 	return metadata.InvalidID
 }
 
-func (shaderSystem *ShaderSystem) uniformAdd(shader *metadata.Shader, uniform_name string, size uint32, shader_uniform_type metadata.ShaderUniformType, scope metadata.ShaderScope, set_location uint32, is_sampler bool) bool {
+func (shaderSystem *ShaderSystem) uniformAdd(shader *metadata.Shader, config *metadata.ShaderUniformConfig, blockType *metadata.ShaderBlockType, set_location uint32, is_sampler bool) bool {
 	uniform_count := len(shader.Uniforms)
 	if uniform_count+1 > int(shaderSystem.Config.MaxUniformCount) {
 		core.LogError("A shader can only accept a combined maximum of %d uniforms and samplers at global, instance and local scopes.", shaderSystem.Config.MaxUniformCount)
@@ -492,11 +689,13 @@ func (shaderSystem *ShaderSystem) uniformAdd(shader *metadata.Shader, uniform_na
 	}
 	entry := metadata.ShaderUniform{
 		Index:             uint16(uniform_count), // Index is saved to the hashtable for lookups.
-		Scope:             scope,
-		ShaderUniformType: shader_uniform_type,
+		Scope:             config.Scope,
+		ShaderUniformType: config.ShaderUniformType,
+		Type:              blockType,
+		Storage:           config.Storage,
 	}
 
-	is_global := (scope == metadata.ShaderScopeGlobal)
+	is_global := (config.Scope == metadata.ShaderScopeGlobal)
 	if is_sampler {
 		// Just use the passed in location
 		entry.Location = uint16(set_location)
@@ -504,47 +703,59 @@ func (shaderSystem *ShaderSystem) uniformAdd(shader *metadata.Shader, uniform_na
 		entry.Location = entry.Index
 	}
 
-	if scope != metadata.ShaderScopeLocal {
-		entry.SetIndex = uint8(scope)
+	switch {
+	case config.Storage:
+		// A storage buffer of its own, in the global set, placed in the buffer holding all of them.
+		if !is_global {
+			core.LogError("Storage buffer '%s' is of scope %d, storage buffers are global.", config.Name, config.Scope)
+			return false
+		}
+		entry.SetIndex = uint8(config.Scope)
+		entry.Offset = metadata.GetAligned(shader.StorageSize, metadata.STORAGE_BUFFER_ALIGNMENT)
+		entry.Size = metadata.ShaderBlockLayoutStd430.Size(blockType)
+		shader.StorageSize = entry.Offset + uint64(entry.Size)
+	case config.Scope != metadata.ShaderScopeLocal:
+		entry.SetIndex = uint8(config.Scope)
 		entry.Offset = 0
+		entry.Size = 0
 		if !is_sampler {
+			end := shader.UboSize
 			if is_global {
-				entry.Offset = shader.GlobalUboSize
-			} else {
-				entry.Offset = shader.UboSize
+				end = shader.GlobalUboSize
 			}
+			entry.Offset = uint64(metadata.ShaderBlockLayoutStd140.Offset(uint32(end), blockType))
+			entry.Size = metadata.ShaderBlockLayoutStd140.Size(blockType)
 		}
-		entry.Size = 0
-		if !is_sampler {
-			entry.Size = uint16(size)
-		}
-	} else {
-		// Push a new aligned range (align to 4, as required by Vulkan spec)
+	default:
+		// A member of the push constant block, which is a single range visible to the push
+		// constant stages, sized to 4 bytes as required by the Vulkan spec.
 		entry.SetIndex = metadata.InvalidIDUint8
-		r := metadata.GetAlignedRange(shader.PushConstantSize, uint64(size), 4)
-		// utilize the aligned offset/range
-		entry.Offset = r.Offset
-		entry.Size = uint16(r.Size)
-
-		// Track in configuration for use in initialization.
-		if len(shader.PushConstantRanges) == 0 {
-			shader.PushConstantRanges = make([]*metadata.MemoryRange, int(math.Max(1, float64(shader.PushConstantRangeCount))))
+		end := uint32(0)
+		for _, u := range shader.Uniforms {
+			if u.Scope == metadata.ShaderScopeLocal {
+				end = uint32(u.Offset) + u.Size
+			}
 		}
-		shader.PushConstantRanges[shader.PushConstantRangeCount] = r
-		shader.PushConstantRangeCount++
-
-		// Increase the push constant's size by the total value.
-		shader.PushConstantSize += r.Size
+		entry.Offset = uint64(metadata.ShaderBlockLayoutStd430.Offset(end, blockType))
+		entry.Size = metadata.ShaderBlockLayoutStd430.Size(blockType)
+		size := metadata.GetAligned(entry.Offset+uint64(entry.Size), 4)
+		if size > shader.PushConstantStride {
+			core.LogError("Push constant '%s' ends at %d bytes, over the %d available.", config.Name, size, shader.PushConstantStride)
+			return false
+		}
+		shader.PushConstantSize = size
+		shader.PushConstantRanges = []*metadata.MemoryRange{{Offset: 0, Size: size}}
+		shader.PushConstantRangeCount = 1
 	}
 
-	shader.UniformLookup[uniform_name] = entry.Index
+	shader.UniformLookup[config.Name] = entry.Index
 	shader.Uniforms = append(shader.Uniforms, entry)
 
-	if !is_sampler {
+	if !is_sampler && !entry.Storage {
 		if entry.Scope == metadata.ShaderScopeGlobal {
-			shader.GlobalUboSize += uint64(entry.Size)
+			shader.GlobalUboSize = entry.Offset + uint64(entry.Size)
 		} else if entry.Scope == metadata.ShaderScopeInstance {
-			shader.UboSize += uint64(entry.Size)
+			shader.UboSize = entry.Offset + uint64(entry.Size)
 		}
 	}
 
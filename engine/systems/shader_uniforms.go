@@ -0,0 +1,228 @@
+package systems
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/spaghettifunk/anima/engine/math"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+)
+
+// The tag naming the uniform a struct field is bound to, e.g. `uniform:"projection"`.
+const uniformTag = "uniform"
+
+// The block types of the Go types the uniforms are set with, besides the scalars.
+var uniformGoTypes = map[reflect.Type]*metadata.ShaderBlockType{
+	reflect.TypeOf(math.Vec2{}):       metadata.NewShaderBlockVector(metadata.ShaderUniformTypeFloat32, 2),
+	reflect.TypeOf(math.Vec3{}):       metadata.NewShaderBlockVector(metadata.ShaderUniformTypeFloat32, 3),
+	reflect.TypeOf(math.Vec4{}):       metadata.NewShaderBlockVector(metadata.ShaderUniformTypeFloat32, 4),
+	reflect.TypeOf(math.Quaternion{}): metadata.NewShaderBlockVector(metadata.ShaderUniformTypeFloat32, 4),
+	reflect.TypeOf(math.Mat4{}):       metadata.NewShaderBlockMatrix(metadata.ShaderUniformTypeFloat32, 4, 4),
+}
+
+// The Go type samplers are set with.
+var samplerGoType = reflect.TypeOf((*metadata.TextureMap)(nil))
+
+/**
+ * @brief The uniforms of a scope of a shader bound to the fields of a Go struct, each tagged
+ * with the name of its uniform, e.g. `uniform:"projection"`. Created by BindUniforms, which
+ * checks that every field has the type of its uniform, and set in one call with SetUniforms.
+ */
+type UniformBinding struct {
+	/** @brief The identifier of the shader, the binding is valid for its variants as well. */
+	ShaderID uint32
+	/** @brief The scope of the uniforms bound. */
+	Scope metadata.ShaderScope
+	/** @brief The struct type bound. */
+	Type reflect.Type
+	// The fields bound, in the order of the struct.
+	fields []uniformField
+}
+
+// A field of a struct bound to a uniform.
+type uniformField struct {
+	index   []int
+	uniform uint16
+}
+
+/**
+ * @brief Binds the uniforms of the given scope of a shader to the fields of a struct tagged with
+ * their names. Every uniform of the scope, except the samplers, must be bound, and every field
+ * must have the layout of its uniform in the block (e.g. a math.Vec4 for a vec4), so a struct
+ * drifting from the shader config is caught here rather than when drawing.
+ *
+ * @param shaderID The identifier of the shader, or of one of its variants.
+ * @param scope The scope of the uniforms.
+ * @param prototype A value of the struct type, or a pointer to one.
+ * @return The binding to set the uniforms with.
+ */
+func (shaderSystem *ShaderSystem) BindUniforms(shaderID uint32, scope metadata.ShaderScope, prototype interface{}) (*UniformBinding, error) {
+	shader, err := shaderSystem.GetShaderByID(shaderSystem.BaseShaderID(shaderID))
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(prototype)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("uniforms can only be bound to a struct, not %v", t)
+	}
+
+	binding := &UniformBinding{
+		ShaderID: shader.ID,
+		Scope:    scope,
+		Type:     t,
+	}
+	errs := []error{}
+	bound := map[uint16]string{}
+	for _, field := range reflect.VisibleFields(t) {
+		name, ok := field.Tag.Lookup(uniformTag)
+		if !ok || name == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			errs = append(errs, fmt.Errorf("field `%s` is not exported", field.Name))
+			continue
+		}
+		index, ok := shader.UniformLookup[name]
+		if !ok || index == metadata.InvalidIDUint16 {
+			errs = append(errs, fmt.Errorf("field `%s` is bound to uniform `%s`, which the shader does not have", field.Name, name))
+			continue
+		}
+		uniform := shader.Uniforms[index]
+		if uniform.Scope != scope {
+			errs = append(errs, fmt.Errorf("field `%s` is bound to uniform `%s` of scope %d", field.Name, name, uniform.Scope))
+			continue
+		}
+		if other, ok := bound[index]; ok {
+			errs = append(errs, fmt.Errorf("fields `%s` and `%s` are both bound to uniform `%s`", other, field.Name, name))
+			continue
+		}
+		if err := checkUniformField(field, uniform); err != nil {
+			errs = append(errs, fmt.Errorf("field `%s` cannot be bound to uniform `%s`: %w", field.Name, name, err))
+			continue
+		}
+		bound[index] = field.Name
+		binding.fields = append(binding.fields, uniformField{index: field.Index, uniform: index})
+	}
+	names := map[uint16]string{}
+	for name, index := range shader.UniformLookup {
+		names[index] = name
+	}
+	for _, uniform := range shader.Uniforms {
+		if _, ok := bound[uniform.Index]; !ok && uniform.Scope == scope && uniform.ShaderUniformType != metadata.ShaderUniformTypeSampler {
+			errs = append(errs, fmt.Errorf("uniform `%s` is not bound by any field", names[uniform.Index]))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to bind `%s` to the uniforms of scope %d of shader '%s': %w", t, scope, shader.Name, errors.Join(errs...))
+	}
+	return binding, nil
+}
+
+/**
+ * @brief Sets the uniforms bound to the fields of the struct to their values.
+ * NOTE: Operates against the currently-used shader, which must be the one of the binding or one
+ * of its variants.
+ *
+ * @param binding The binding, from BindUniforms.
+ * @param value A value of the struct type bound, or a pointer to one.
+ * @return An error if the value is not of the type bound, or if a uniform failed to be set.
+ */
+func (shaderSystem *ShaderSystem) SetUniforms(binding *UniformBinding, value interface{}) error {
+	if binding == nil {
+		return fmt.Errorf("func SetUniforms called without a binding")
+	}
+	if shaderSystem.CurrentShaderID == metadata.InvalidID || shaderSystem.BaseShaderID(shaderSystem.CurrentShaderID) != binding.ShaderID {
+		return fmt.Errorf("func SetUniforms called without the shader of the binding in use")
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != binding.Type {
+		return fmt.Errorf("func SetUniforms called with %T, the binding is for %s", value, binding.Type)
+	}
+	for _, field := range binding.fields {
+		if err := shaderSystem.SetUniformByIndex(field.uniform, v.FieldByIndex(field.index).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUniformField checks that the field has the type of the uniform, laid out the same way in
+// the block.
+func checkUniformField(field reflect.StructField, uniform metadata.ShaderUniform) error {
+	if uniform.ShaderUniformType == metadata.ShaderUniformTypeSampler {
+		if field.Type != samplerGoType {
+			return fmt.Errorf("it is %s, samplers are set with %s", field.Type, samplerGoType)
+		}
+		return nil
+	}
+	uniformType := uniform.Type
+	if uniformType == nil {
+		return fmt.Errorf("uniforms of type %s cannot be bound", uniform.ShaderUniformType)
+	}
+	fieldType, err := uniformBlockTypeOf(field.Type)
+	if err != nil {
+		return err
+	}
+	if !fieldType.Equal(uniformType) {
+		return fmt.Errorf("it is %s (%s), the uniform is %s", field.Type, fieldType, uniformType)
+	}
+	return nil
+}
+
+// uniformBlockTypeOf returns the type a Go type is laid out as in a block. A struct is laid out as
+// the struct of its exported fields, in order.
+func uniformBlockTypeOf(t reflect.Type) (*metadata.ShaderBlockType, error) {
+	if blockType, ok := uniformGoTypes[t]; ok {
+		return blockType, nil
+	}
+	switch t.Kind() {
+	case reflect.Float32:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeFloat32), nil
+	case reflect.Int32:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeInt32), nil
+	case reflect.Uint32:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeUint32), nil
+	case reflect.Int16:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeInt16), nil
+	case reflect.Uint16:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeUint16), nil
+	case reflect.Int8:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeInt8), nil
+	case reflect.Uint8:
+		return metadata.NewShaderBlockScalar(metadata.ShaderUniformTypeUint8), nil
+	case reflect.Array:
+		element, err := uniformBlockTypeOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if element.Length > 0 {
+			return nil, fmt.Errorf("%s is an array of arrays, which blocks cannot hold", t)
+		}
+		return element.Array(uint32(t.Len())), nil
+	case reflect.Struct:
+		members := []*metadata.ShaderBlockType{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			member, err := uniformBlockTypeOf(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field `%s` of %s: %w", field.Name, t, err)
+			}
+			members = append(members, member)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("%s has no exported fields", t)
+		}
+		return metadata.NewShaderBlockStruct(members...), nil
+	}
+	return nil, fmt.Errorf("%s has no layout in a block", t)
+}
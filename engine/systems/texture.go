@@ -2,6 +2,7 @@ package systems
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spaghettifunk/anima/engine/assets"
 	"github.com/spaghettifunk/anima/engine/core"
@@ -20,13 +21,14 @@ type TextureSystem struct {
 	RegisteredTextures []*metadata.Texture
 	// Hashtable for texture lookups.
 	RegisteredTextureTable map[string]*metadata.TextureReference
+	// The latest load of each texture, by name.
+	loads map[string]*assets.AssetHandle
 	// sub systems
-	jobSystem    *JobSystem
 	assetManager *assets.AssetManager
 	renderer     *RendererSystem
 }
 
-func NewTextureSystem(config *TextureSystemConfig, js *JobSystem, am *assets.AssetManager, r *RendererSystem) (*TextureSystem, error) {
+func NewTextureSystem(config *TextureSystemConfig, am *assets.AssetManager, r *RendererSystem) (*TextureSystem, error) {
 	if config.MaxTextureCount == 0 {
 		err := fmt.Errorf("func NewTextureSystem - config.MaxTextureCount must be > 0")
 		core.LogFatal(err.Error())
@@ -38,7 +40,7 @@ func NewTextureSystem(config *TextureSystemConfig, js *JobSystem, am *assets.Ass
 		RegisteredTextures:     make([]*metadata.Texture, config.MaxTextureCount),
 		RegisteredTextureTable: make(map[string]*metadata.TextureReference),
 		DefaultTexture:         metadata.NewDefaultTexture(),
-		jobSystem:              js,
+		loads:                  make(map[string]*assets.AssetHandle),
 		assetManager:           am,
 		renderer:               r,
 	}
@@ -108,6 +110,19 @@ func (ts *TextureSystem) Aquire(name string, autoRelease bool) (*metadata.Textur
 	return ts.RegisteredTextures[id], nil
 }
 
+/**
+ * @brief Like Aquire, but also returns the load of the texture. The texture holds the default
+ * data until the load completes, the handle tells when the real pixels replaced them.
+ * The handle is nil when the texture is not backed by an image file (e.g. the default texture).
+ */
+func (ts *TextureSystem) AquireAsync(name string, autoRelease bool) (*metadata.Texture, *assets.AssetHandle, error) {
+	texture, err := ts.Aquire(name, autoRelease)
+	if err != nil {
+		return nil, nil, err
+	}
+	return texture, ts.loads[name], nil
+}
+
 /**
  * @brief Attempts to acquire a cubemap texture with the given name. If it has not yet been loaded,
  * this triggers it to load. If the texture is not found, a pointer to the default texture
@@ -225,32 +240,93 @@ func (ts *TextureSystem) GetDefaultNormalTexture() *metadata.Texture {
 	return ts.DefaultTexture.DefaultNormalTexture
 }
 
+// LoadTexture loads the image of the texture asynchronously. The texture keeps its current
+// data until the image is uploaded, on the main thread.
 func (ts *TextureSystem) LoadTexture(textureName string, texture *metadata.Texture) bool {
-	ts.jobSystem.Submit(metadata.JobTask{
-		JobType:  metadata.JOB_TYPE_GENERAL,
-		Priority: metadata.JOB_PRIORITY_NORMAL,
-		InputParams: []interface{}{
-			// Kick off a texture loading job. Only handles loading from disk
-			// to CPU. GPU upload is handled after completion of this job.
-			&metadata.TextureLoadParams{
-				ResourceName:      textureName,
-				OutTexture:        texture,
-				ImageResource:     &metadata.Resource{},
-				CurrentGeneration: texture.Generation,
-				TempTexture:       &metadata.Texture{},
-			},
-		},
-		OnStart:    ts.TextureLoadJobStart,
-		OnComplete: ts.TextureLoadJobSuccess,
-		OnFailure:  ts.TextureLoadJobFail,
+	texture.Name = textureName
+	h := ts.assetManager.LoadAsync(textureName, metadata.ResourceTypeImage, &metadata.ImageResourceParams{
+		FlipY: true,
+	})
+	ts.loads[textureName] = h
+	// Registered first, so the texture is uploaded when the callbacks of the callers run.
+	h.OnComplete(func(h *assets.AssetHandle) {
+		ts.onTextureLoaded(h, texture)
 	})
-
 	return true
 }
 
+// onTextureLoaded uploads the loaded image into the texture. Runs on the main thread.
+func (ts *TextureSystem) onTextureLoaded(h *assets.AssetHandle, texture *metadata.Texture) {
+	defer h.Release()
+
+	// Released or loaded again meanwhile.
+	if ts.loads[h.Name] != h {
+		return
+	}
+	if err := h.Err(); err != nil {
+		core.LogError("Failed to load texture '%s': %s", h.Name, err)
+		return
+	}
+	resourceData, ok := h.Resource().Data.(*metadata.ImageResourceData)
+	if !ok {
+		core.LogError("failed to type cast imgResource.Data to `*metadata.ImageResourceData`")
+		return
+	}
+
+	// Acquire internal texture resources and upload to GPU. Can't be jobified until the renderer is multithreaded.
+	temp, err := ts.textureFromImage(h.Name, resourceData)
+	if err != nil {
+		// The texture keeps its current data, the default texture for a first load.
+		core.LogError("Failed to upload texture '%s': %s", h.Name, err)
+		return
+	}
+	ts.swapTexture(texture, temp)
+
+	core.LogDebug("Successfully loaded texture '%s'.", h.Name)
+}
+
+// textureFromImage creates a 2D texture with the pixels of the image.
+func (ts *TextureSystem) textureFromImage(name string, resourceData *metadata.ImageResourceData) (*metadata.Texture, error) {
+	temp := &metadata.Texture{
+		TextureType:  metadata.TextureType2d,
+		Name:         name,
+		Width:        resourceData.Width,
+		Height:       resourceData.Height,
+		ChannelCount: resourceData.ChannelCount,
+	}
+	totalSize := temp.Width * temp.Height * uint32(temp.ChannelCount)
+	for i := uint32(0); i+3 < totalSize; i += uint32(temp.ChannelCount) {
+		if resourceData.Pixels[i+3] < 255 {
+			temp.Flags |= metadata.TextureFlagBits(metadata.TextureFlagHasTransparency)
+			break
+		}
+	}
+	if err := ts.renderer.TextureCreate(resourceData.Pixels, temp); err != nil {
+		return nil, err
+	}
+	return temp, nil
+}
+
+// swapTexture moves the data of temp into the texture, keeping the identity of the texture,
+// and increments its generation.
+func (ts *TextureSystem) swapTexture(texture *metadata.Texture, temp *metadata.Texture) {
+	if err := ts.renderer.TextureDestroy(texture); err != nil {
+		core.LogError(err.Error())
+	}
+	id := texture.ID
+	generation := texture.Generation
+	*texture = *temp
+	texture.ID = id
+	if generation == metadata.InvalidID {
+		texture.Generation = 0
+	} else {
+		texture.Generation = generation + 1
+	}
+}
+
 func (ts *TextureSystem) LoadCubeTextures(name string, textureNames []string, texture *metadata.Texture) bool {
 	pixels := make([]uint8, 0)
-	// image_size := uint64(0)
+	imageSize := uint32(0)
 	for i := 0; i < len(textureNames); i++ {
 		params := &metadata.ImageResourceParams{
 			FlipY: false,
@@ -276,10 +352,10 @@ func (ts *TextureSystem) LoadCubeTextures(name string, textureNames []string, te
 			texture.Generation = 0
 			texture.Name = name
 
-			image_size := texture.Width * texture.Height * uint32(texture.ChannelCount)
+			imageSize = texture.Width * texture.Height * uint32(texture.ChannelCount)
 			// NOTE: no need for transparency in cube maps, so not checking for it.
 
-			pixels = make([]uint8, image_size*6)
+			pixels = make([]uint8, imageSize*6)
 		} else {
 			// Verify all textures are the same size.
 			if texture.Width != resourceData.Width || texture.Height != resourceData.Height || texture.ChannelCount != resourceData.ChannelCount {
@@ -290,25 +366,107 @@ func (ts *TextureSystem) LoadCubeTextures(name string, textureNames []string, te
 		}
 
 		// Copy to the relevant portion of the array.
-		// kcopy_memory(pixels+image_size*i, resource_data.pixels, image_size)
+		copy(pixels[imageSize*uint32(i):imageSize*uint32(i+1)], resourceData.Pixels)
 
 		// Clean up data.
 		ts.assetManager.UnloadAsset(imgResource)
 	}
 
 	// Acquire internal texture resources and upload to GPU.
-	ts.renderer.TextureCreate(pixels, texture)
+	if err := ts.renderer.TextureCreate(pixels, texture); err != nil {
+		core.LogError("func LoadCubeTextures - Failed to upload cube texture '%s': %s", name, err)
+		return false
+	}
 	pixels = nil
 
 	return true
 }
 
+/**
+ * @brief Reloads the texture with the given name from disk, if it is loaded. The texture is
+ * updated in place, so everything referencing it picks the new data up, and its generation
+ * is incremented. Changes to the face of a cubemap (e.g. skybox_f) reload the whole cubemap.
+ * Must be called from the main thread.
+ */
+func (ts *TextureSystem) Reload(name string) error {
+	texture := ts.registeredTexture(name)
+	if texture == nil {
+		// Possibly the face of a cubemap.
+		for _, suffix := range []string{"_r", "_l", "_u", "_d", "_f", "_b"} {
+			if strings.HasSuffix(name, suffix) {
+				cube := ts.registeredTexture(strings.TrimSuffix(name, suffix))
+				if cube != nil && cube.TextureType == metadata.TextureTypeCube {
+					return ts.Reload(cube.Name)
+				}
+			}
+		}
+		// Not loaded, nothing to do.
+		return nil
+	}
+	if texture.Flags&metadata.TextureFlagBits(metadata.TextureFlagIsWriteable) != 0 {
+		// Writeable textures are not backed by a file.
+		return nil
+	}
+
+	temp := &metadata.Texture{
+		TextureType: texture.TextureType,
+	}
+	if texture.TextureType == metadata.TextureTypeCube {
+		textureNames := make([]string, 6)
+		// Same order as in ProcessTextureReference.
+		for i, suffix := range []string{"_r", "_l", "_u", "_d", "_f", "_b"} {
+			textureNames[i] = fmt.Sprintf("%s%s", name, suffix)
+		}
+		if !ts.LoadCubeTextures(name, textureNames, temp) {
+			return fmt.Errorf("failed to reload cube texture '%s'", name)
+		}
+	} else {
+		imgResource, err := ts.assetManager.LoadAsset(name, metadata.ResourceTypeImage, &metadata.ImageResourceParams{
+			FlipY: true,
+		})
+		if err != nil {
+			return err
+		}
+		resourceData, ok := imgResource.Data.(*metadata.ImageResourceData)
+		if !ok {
+			return fmt.Errorf("failed to type cast imgResource.Data to `*metadata.ImageResourceData`")
+		}
+		temp, err = ts.textureFromImage(name, resourceData)
+		ts.assetManager.UnloadAsset(imgResource)
+		if err != nil {
+			// The texture keeps its current data.
+			return err
+		}
+	}
+
+	// Swap the new data in, keeping the identity of the texture.
+	ts.swapTexture(texture, temp)
+
+	core.LogInfo("Reloaded texture '%s'.", name)
+	return nil
+}
+
+// registeredTexture returns the loaded texture with the given name, or nil.
+func (ts *TextureSystem) registeredTexture(name string) *metadata.Texture {
+	ref, ok := ts.RegisteredTextureTable[name]
+	if !ok || ref.Handle == metadata.InvalidID {
+		return nil
+	}
+	texture := ts.RegisteredTextures[ref.Handle]
+	if texture.ID == metadata.InvalidID {
+		return nil
+	}
+	return texture
+}
+
 func (ts *TextureSystem) DestroyTexture(texture *metadata.Texture) error {
 	// Clean up backend resources.
 	if err := ts.renderer.TextureDestroy(texture); err != nil {
 		return err
 	}
 
+	// A load in progress is dropped.
+	delete(ts.loads, texture.Name)
 	texture.ID = metadata.InvalidID
 	texture.Generation = metadata.InvalidID
 	return nil
@@ -317,12 +475,14 @@ func (ts *TextureSystem) DestroyTexture(texture *metadata.Texture) error {
 func (ts *TextureSystem) ProcessTextureReference(name string, textureType metadata.TextureType, referenceDiff int8, autoRelease, skipLoad bool) (uint32, error) {
 	outTextureID := metadata.InvalidID
 
-	ts.RegisteredTextureTable[name] = &metadata.TextureReference{
-		Handle: metadata.InvalidID,
-	}
 	ref, ok := ts.RegisteredTextureTable[name]
 	if !ok {
-		return 0, fmt.Errorf("texture with name `%s` does not exist", name)
+		if referenceDiff < 0 {
+			return 0, fmt.Errorf("texture with name `%s` does not exist", name)
+		}
+		ref = &metadata.TextureReference{
+			Handle: metadata.InvalidID,
+		}
 	}
 
 	// If the reference count starts off at zero, one of two things can be
@@ -428,110 +588,3 @@ func (ts *TextureSystem) ProcessTextureReference(name string, textureType metada
 
 	return outTextureID, nil
 }
-
-func (ts *TextureSystem) TextureLoadJobSuccess(paramsChan <-chan interface{}) {
-	if params, ok := <-paramsChan; ok {
-		textureParams, ok := params.(*metadata.TextureLoadParams)
-		if !ok {
-			core.LogError("params are not of type *TextureLoadParams")
-			return
-		}
-
-		// This also handles the GPU upload. Can't be jobified until the renderer is multithreaded.
-		resourceData := textureParams.ImageResource.Data.(*metadata.ImageResourceData)
-
-		// Acquire internal texture resources and upload to GPU. Can't be jobified until the renderer is multithreaded.
-		ts.renderer.TextureCreate(resourceData.Pixels, textureParams.TempTexture)
-
-		// Take a copy of the old texture.
-		old := textureParams.OutTexture
-
-		// Assign the temp texture to the pointer.
-		textureParams.OutTexture = textureParams.TempTexture
-
-		// Destroy the old texture.
-		if err := ts.renderer.TextureDestroy(old); err != nil {
-			core.LogError(err.Error())
-		}
-
-		if textureParams.CurrentGeneration == metadata.InvalidID {
-			textureParams.OutTexture.Generation = 0
-		} else {
-			textureParams.OutTexture.Generation = textureParams.CurrentGeneration + 1
-		}
-
-		core.LogDebug("Successfully loaded texture '%s'.", textureParams.ResourceName)
-
-		// Clean up data.
-		ts.assetManager.UnloadAsset(textureParams.ImageResource)
-		if textureParams.ResourceName != "" {
-			textureParams.ResourceName = ""
-		}
-	}
-}
-
-func (ts *TextureSystem) TextureLoadJobFail(paramsChan <-chan interface{}) {
-	if params, ok := <-paramsChan; ok {
-		textureParams := params.(*metadata.TextureLoadParams)
-		core.LogError("Failed to load texture '%s'.", textureParams.ResourceName)
-		ts.assetManager.UnloadAsset(textureParams.ImageResource)
-	}
-}
-
-func (ts *TextureSystem) TextureLoadJobStart(params interface{}, resultChan chan<- interface{}) error {
-	tmpParams := params.([]interface{})
-
-	loadParams := tmpParams[0].(*metadata.TextureLoadParams)
-
-	resource_params := &metadata.ImageResourceParams{
-		FlipY: true,
-	}
-
-	result, err := ts.assetManager.LoadAsset(loadParams.ResourceName, metadata.ResourceTypeImage, resource_params)
-	if err != nil {
-		core.LogError(err.Error())
-		resultChan <- loadParams
-		return err
-	}
-
-	core.LogDebug("texture asset load successful")
-
-	loadParams.ImageResource = result
-
-	resourceData := loadParams.ImageResource.Data.(*metadata.ImageResourceData)
-
-	// Use a temporary texture to load into.
-	loadParams.TempTexture.Width = resourceData.Width
-	loadParams.TempTexture.Height = resourceData.Height
-	loadParams.TempTexture.ChannelCount = resourceData.ChannelCount
-
-	loadParams.CurrentGeneration = loadParams.OutTexture.Generation
-	loadParams.OutTexture.Generation = metadata.InvalidID
-
-	total_size := loadParams.TempTexture.Width * loadParams.TempTexture.Height * uint32(loadParams.TempTexture.ChannelCount)
-	// Check for transparency
-	hasTransparency := false
-	for i := uint32(0); i < total_size; i += uint32(loadParams.TempTexture.ChannelCount) {
-		a := resourceData.Pixels[i+3]
-		if a < 255 {
-			hasTransparency = true
-			break
-		}
-	}
-
-	core.LogDebug("texture load pixel successful")
-
-	// Take a copy of the name.
-	loadParams.TempTexture.Name = loadParams.ResourceName
-	loadParams.TempTexture.Generation = metadata.InvalidID
-	loadParams.TempTexture.Flags |= 0
-	if hasTransparency {
-		loadParams.TempTexture.Flags |= metadata.TextureFlagBits(metadata.TextureFlagHasTransparency)
-	}
-
-	core.LogDebug("texture load successful")
-
-	resultChan <- loadParams
-
-	return nil
-}
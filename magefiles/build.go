@@ -20,6 +20,9 @@ func buildShaders() error {
 	if _, err := executeCmd(fmt.Sprintf("%s/bin/glslc", vkSDKPath), withArgs("-fshader-stage=frag", "assets/shaders/Builtin.MaterialShader.frag.glsl", "-o", "assets/shaders/Builtin.MaterialShader.frag.spv"), withStream()); err != nil {
 		return err
 	}
+	if _, err := executeCmd(fmt.Sprintf("%s/bin/glslc", vkSDKPath), withArgs("-fshader-stage=frag", "-DNORMAL_MAP", "assets/shaders/Builtin.MaterialShader.frag.glsl", "-o", "assets/shaders/Builtin.MaterialShader.NORMAL_MAP.frag.spv"), withStream()); err != nil {
+		return err
+	}
 	if _, err := executeCmd(fmt.Sprintf("%s/bin/glslc", vkSDKPath), withArgs("-fshader-stage=vert", "assets/shaders/Builtin.SkyboxShader.vert.glsl", "-o", "assets/shaders/Builtin.SkyboxShader.vert.spv"), withStream()); err != nil {
 		return err
 	}
@@ -51,3 +54,12 @@ func buildShaders() error {
 func (Build) Shaders() error {
 	return buildShaders()
 }
+
+// Cooks the assets into build/assets, only the ones changed since the last cook.
+func (Build) Assets() error {
+	fmt.Println("Cook assets...")
+	if _, err := executeCmd("go", withArgs("run", "./cmd/anima", "cook", "-source", "assets", "-output", "build/assets"), withStream()); err != nil {
+		return err
+	}
+	return nil
+}
@@ -18,6 +18,15 @@ func (Run) StaticAnalysis() error {
 	return nil
 }
 
+// Checks the assets for problems, e.g. in CI before changes to them merge.
+func (Run) AssetsCheck() error {
+	fmt.Println("Check assets...")
+	if _, err := executeCmd("go", withArgs("run", "./cmd/anima", "assets", "check", "-source", "assets"), withStream()); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Runs go mod download and then installs the binary.
 func (Run) Engine() error {
 	if err := buildShaders(); err != nil {
@@ -5,20 +5,42 @@ engine package to test things out
 package main
 
 import (
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/spaghettifunk/anima/engine"
 	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/platform"
+	"github.com/spaghettifunk/anima/engine/platform/window"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+	_ "github.com/spaghettifunk/anima/engine/renderer/vulkan"
 	"github.com/spaghettifunk/anima/testbed"
 )
 
 func main() {
+	renderer := flag.String("renderer", "vulkan", "renderer backend to use (vulkan, null, software)")
+	headless := flag.Bool("headless", false, "run without a window, with a virtual clock ticking at 60 FPS")
+	flag.Parse()
+
+	backendType, err := metadata.RendererBackendTypeFromString(*renderer)
+	if err != nil {
+		panic(err.Error())
+	}
+
 	tb, err := testbed.NewTestGame()
 	if err != nil {
 		panic(err.Error())
 	}
+	tb.ApplicationConfig.RendererBackendType = backendType
+	if *headless {
+		p := platform.NewHeadlessPlatform()
+		p.FrameTime = 1.0 / 60.0
+		tb.ApplicationConfig.Platform = p
+	} else {
+		tb.ApplicationConfig.Platform = window.NewGLFWPlatform()
+	}
 
 	engine, err := engine.New(tb.Game)
 	if err != nil {
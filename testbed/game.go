@@ -8,6 +8,7 @@ import (
 	"github.com/spaghettifunk/anima/engine/math"
 	"github.com/spaghettifunk/anima/engine/renderer/components"
 	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+	"github.com/spaghettifunk/anima/engine/systems"
 )
 
 type TestGame struct {
@@ -27,6 +28,8 @@ type gameState struct {
 	carMesh      *metadata.Mesh
 	sponzaMesh   *metadata.Mesh
 	modelsLoaded bool
+	dirLight     *metadata.DirectionalLight
+	pointLights  []*metadata.PointLight
 
 	uiMeshes    []*metadata.Mesh
 	testText    *metadata.UIText
@@ -253,12 +256,41 @@ func (g *TestGame) Initialize() error {
 	state.sponzaMesh.Transform = math.TransformFromPositionRotationScale(math.NewVec3(15.0, 0.0, 1.0), math.NewQuatIdentity(), math.NewVec3(0.05, 0.05, 0.05))
 	meshCount++
 
+	// Lights
+	state.dirLight = &metadata.DirectionalLight{
+		Colour:    math.NewVec4(0.4, 0.4, 0.2, 1.0),
+		Direction: math.NewVec3(-0.57735, -0.57735, -0.57735),
+		Intensity: 1.0,
+	}
+	if err := g.SystemManager.LightSystem.AddDirectional(state.dirLight); err != nil {
+		return err
+	}
+	state.pointLights = []*metadata.PointLight{
+		{
+			Colour:      math.NewVec4(0.0, 1.0, 0.0, 1.0),
+			Position:    math.NewVec3(-5.5, 0.0, -5.5),
+			Intensity:   1.0,
+			Attenuation: metadata.LightAttenuation{Constant: 1.0, Linear: 0.35, Quadratic: 0.44},
+		},
+		{
+			Colour:      math.NewVec4(1.0, 0.0, 0.0, 1.0),
+			Position:    math.NewVec3(5.5, 0.0, -5.5),
+			Intensity:   1.0,
+			Attenuation: metadata.LightAttenuation{Constant: 1.0, Linear: 0.35, Quadratic: 0.44},
+		},
+	}
+	for _, light := range state.pointLights {
+		if err := g.SystemManager.LightSystem.AddPoint(light); err != nil {
+			return err
+		}
+	}
+
 	core.EventRegister(core.EVENT_CODE_DEBUG0, g.gameOnDebugEvent)
 	core.EventRegister(core.EVENT_CODE_DEBUG1, g.gameOnDebugEvent)
 	core.EventRegister(core.EVENT_CODE_OBJECT_HOVER_ID_CHANGED, g.gameOnEvent)
 
-	core.EventRegister(core.EVENT_CODE_KEY_PRESSED, g.gameOnKey)
-	core.EventRegister(core.EVENT_CODE_KEY_RELEASED, g.gameOnKey)
+	core.SubscribeWithPriority(core.Events(), core.EVENT_PRIORITY_DEFAULT, g.gameOnKey)
+	core.Subscribe(core.Events(), g.gameOnShaderReloaded)
 
 	return nil
 }
@@ -404,7 +436,7 @@ func (g *TestGame) Update(deltaTime float64) error {
 	return nil
 }
 
-func (g *TestGame) Render(packet *metadata.RenderPacket, deltaTime float64) error {
+func (g *TestGame) Render(packet *metadata.RenderPacket, deltaTime float64, alpha float64) error {
 	state := g.State.(*gameState)
 
 	packet.DeltaTime = deltaTime
@@ -695,17 +727,19 @@ func (g *TestGame) configureRenderViews(config *engine.ApplicationConfig) error
 	return nil
 }
 
-func (g *TestGame) gameOnEvent(context core.EventContext) {
+func (g *TestGame) gameOnEvent(context core.EventContext) bool {
 	state := g.State.(*gameState)
 	switch context.Type {
 	case core.EVENT_CODE_OBJECT_HOVER_ID_CHANGED:
 		{
 			state.hoveredObjectID = context.Data.(uint32)
+			return true
 		}
 	}
+	return false
 }
 
-func (g *TestGame) gameOnDebugEvent(data core.EventContext) {
+func (g *TestGame) gameOnDebugEvent(data core.EventContext) bool {
 	state := g.State.(*gameState)
 
 	if data.Type == core.EVENT_CODE_DEBUG0 {
@@ -728,7 +762,7 @@ func (g *TestGame) gameOnDebugEvent(data core.EventContext) {
 			m, err := g.SystemManager.MaterialSystem.Acquire(names[choice])
 			if err != nil {
 				core.LogError("failed to retrieve material with name %s", names[choice])
-				return
+				return false
 			}
 			geom.Material = m
 			if geom.Material == nil {
@@ -750,30 +784,48 @@ func (g *TestGame) gameOnDebugEvent(data core.EventContext) {
 				core.LogError("Failed to load sponza mesh!")
 			}
 		}
+		return true
+	}
+	return data.Type == core.EVENT_CODE_DEBUG0
+}
+
+// gameOnShaderReloaded acquires the skybox instance again when its shader got rebuilt.
+func (g *TestGame) gameOnShaderReloaded(event systems.ShaderReloadedEvent) {
+	state := g.State.(*gameState)
+	if event.Shader.Name != "Shader.Builtin.Skybox" {
+		return
+	}
+	maps := []*metadata.TextureMap{state.skybox.Cubemap}
+	instanceID, err := g.SystemManager.RendererSystem.ShaderAcquireInstanceResources(event.Shader, maps)
+	if err != nil {
+		core.LogError("failed to acquire skybox resources: %s", err)
+		return
 	}
+	state.skybox.InstanceID = instanceID
 }
 
-func (g *TestGame) gameOnKey(context core.EventContext) {
-	if context.Type == core.EVENT_CODE_KEY_PRESSED {
-		key_code := context.Data
+func (g *TestGame) gameOnKey(event core.KeyEvent) bool {
+	key_code := event.KeyCode
+	if event.Pressed {
 		if key_code == core.KEY_ESCAPE {
 			// NOTE: Technically firing an event to itself, but there may be other listeners.
 			core.EventFire(core.EventContext{
 				Type: core.EVENT_CODE_APPLICATION_QUIT,
 			})
+			return true
 		} else if key_code == core.KEY_A {
 			// Example on checking for a key
 			core.LogDebug("Explicit - A key pressed!")
 		} else {
-			core.LogDebug("'%s' key pressed in window.", key_code)
+			core.LogDebug("'%c' key pressed in window.", key_code)
 		}
-	} else if context.Type == core.EVENT_CODE_KEY_RELEASED {
-		key_code := context.Data
+	} else {
 		if key_code == core.KEY_B {
 			// Example on checking for a key
 			core.LogDebug("Explicit - B key released!")
 		} else {
-			core.LogDebug("'%s' key released in window.", key_code)
+			core.LogDebug("'%c' key released in window.", key_code)
 		}
 	}
+	return false
 }
@@ -0,0 +1,209 @@
+package testbed
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spaghettifunk/anima/engine"
+	"github.com/spaghettifunk/anima/engine/core"
+	"github.com/spaghettifunk/anima/engine/platform"
+	"github.com/spaghettifunk/anima/engine/renderer/metadata"
+	"github.com/spaghettifunk/anima/engine/renderer/null"
+	"github.com/spaghettifunk/anima/engine/renderer/software"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+const (
+	// The largest difference allowed between two channels of a pixel and its golden counterpart.
+	goldenChannelTolerance = 8
+	// The fraction of the pixels allowed to differ by more than the channel tolerance.
+	goldenPixelTolerance = 0.005
+)
+
+func TestMain(m *testing.M) {
+	// The engine reads the assets from the working directory.
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// runTestGame boots the testbed on a headless platform with the given backend, runs it for
+// the given number of frames once its textures are loaded and shuts it down.
+func runTestGame(t *testing.T, backend metadata.RendererBackendType, frames int) *TestGame {
+	t.Helper()
+	game, err := NewTestGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := platform.NewHeadlessPlatform()
+	p.FrameTime = 1.0 / 60.0
+	game.ApplicationConfig.Platform = p
+	// Small enough for the golden images to stay small.
+	game.ApplicationConfig.StartWidth = 320
+	game.ApplicationConfig.StartHeight = 180
+	game.ApplicationConfig.RendererBackendType = backend
+	game.ApplicationConfig.LogLevel = core.WarnLevel
+
+	fnUpdate := game.FnUpdate
+	updates := 0
+	start := time.Now()
+	game.FnUpdate = func(deltaTime float64) error {
+		// The scene stays still while the textures load, for every run to render the same frames.
+		if !texturesLoaded(game) {
+			if time.Since(start) > 10*time.Second {
+				t.Error("the textures did not load within 10s")
+				return errTexturesNotLoaded
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		}
+		if updates++; updates >= frames {
+			p.RequestClose()
+		}
+		return fnUpdate(deltaTime)
+	}
+
+	e, err := engine.New(game.Game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := e.Shutdown(); err != nil {
+			t.Error(err)
+		}
+	})
+	return game
+}
+
+var errTexturesNotLoaded = errors.New("the textures did not load")
+
+// texturesLoaded reports whether every acquired texture has been uploaded.
+func texturesLoaded(game *TestGame) bool {
+	for _, texture := range game.SystemManager.TextureSystem.RegisteredTextures {
+		if texture.ID != metadata.InvalidID && texture.Generation == metadata.InvalidID {
+			return false
+		}
+	}
+	return true
+}
+
+// compareGolden compares the image with testdata/<name>.png, or writes it there with -update.
+func compareGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testbed", "testdata", name+".png")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create the golden images)", err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("%s: got an image of %v, want %v", name, img.Bounds(), golden.Bounds())
+	}
+
+	differing := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := golden.At(x, y).RGBA()
+			want := [4]int{int(r >> 8), int(g >> 8), int(b >> 8), int(a >> 8)}
+			got := img.Pix[img.PixOffset(x, y):]
+			for c := 0; c < 4; c++ {
+				if d := int(got[c]) - want[c]; d > goldenChannelTolerance || d < -goldenChannelTolerance {
+					differing++
+					break
+				}
+			}
+		}
+	}
+	if limit := int(goldenPixelTolerance * float64(bounds.Dx()*bounds.Dy())); differing > limit {
+		t.Errorf("%s: %d pixels differ from the golden image, at most %d may", name, differing, limit)
+	}
+}
+
+func TestTestGameRendersViews(t *testing.T) {
+	// The renderer skips the 30 frames following the resize of the window at startup.
+	game := runTestGame(t, metadata.RendererBackendTypeNull, 40)
+
+	backend, ok := game.SystemManager.RendererSystem.Backend().(*null.NullRenderer)
+	if !ok {
+		t.Fatalf("got a %T backend, want the null renderer", game.SystemManager.RendererSystem.Backend())
+	}
+	frame := backend.LastFrame()
+	if frame == nil {
+		t.Fatal("no frame logged")
+	}
+	passes := []struct {
+		pass, shader string
+		draws        int
+	}{
+		{"Renderpass.Builtin.Skybox", "Shader.Builtin.Skybox", 1},
+		// The three cubes of the world, the car and sponza are only loaded on demand.
+		{"Renderpass.Builtin.World", "Shader.Builtin.Material", 3},
+		{"Renderpass.Builtin.UI", "Shader.Builtin.UI", 0},
+	}
+	for _, p := range passes {
+		if !frame.PassBegun(p.pass) || !frame.ShaderBound(p.shader) {
+			t.Errorf("%s: got passes %+v and shaders %+v", p.pass, frame.Passes, frame.Shaders)
+		}
+		if count := frame.DrawCount(p.pass, p.shader); count != p.draws {
+			t.Errorf("%s: got %d draws with %s, want %d", p.pass, count, p.shader, p.draws)
+		}
+	}
+	for _, draw := range frame.Draws {
+		if draw.Pass == "Renderpass.Builtin.World" && draw.Material != "test_material" {
+			t.Errorf("got a world draw with material %q, want test_material", draw.Material)
+		}
+	}
+	if values := frame.UniformValues("Shader.Builtin.Material", "projection"); len(values) == 0 {
+		t.Error("the globals of the material shader were not applied")
+	}
+}
+
+// The testbed rendered by the software backend: the skybox behind the textured cubes of the
+// world, lit by the directional and point lights.
+func TestTestGameGolden(t *testing.T) {
+	game := runTestGame(t, metadata.RendererBackendTypeSoftware, 40)
+
+	backend, ok := game.SystemManager.RendererSystem.Backend().(*software.SoftwareRenderer)
+	if !ok {
+		t.Fatalf("got a %T backend, want the software renderer", game.SystemManager.RendererSystem.Backend())
+	}
+	img := backend.Screenshot()
+	if img == nil {
+		t.Fatal("no frame presented")
+	}
+	compareGolden(t, "testgame", img)
+}